@@ -0,0 +1,484 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	// the time zones of users are validated without the zoneinfo of the host
+	_ "time/tzdata"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/auth"
+	"github.com/SakataAtsuki/e-architecture/pkg/authz"
+	gateway "github.com/SakataAtsuki/e-architecture/pkg/gateway/grpc"
+	"github.com/SakataAtsuki/e-architecture/pkg/notify"
+	"github.com/SakataAtsuki/e-architecture/pkg/proto/api"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/cache"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/pgx"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/postgres"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/sqlite"
+	"github.com/SakataAtsuki/e-architecture/pkg/usecase"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/log"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/metrics"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/trace"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
+)
+
+func main() {
+	logger, err := log.New(os.Stderr, os.Getenv("LOG_FORMAT"), os.Getenv("LOG_LEVEL"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	log.SetDefault(logger)
+
+	ctx := context.Background()
+	if err := run(ctx); err != nil {
+		log.Error(ctx, "server stopped", log.Err(err))
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context) error {
+	var err error
+	sampleRatio := 1.0
+	if v := os.Getenv("TRACE_SAMPLE_RATIO"); v != "" {
+		sampleRatio, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			return errcode.New(err)
+		}
+	}
+	shutdownTracing, err := trace.Setup(ctx, &trace.Config{
+		ServiceName: "e-architecture",
+		Exporter:    os.Getenv("TRACE_EXPORTER"),
+		File:        os.Getenv("TRACE_FILE"),
+		SampleRatio: sampleRatio,
+	})
+	if err != nil {
+		return errcode.New(err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Error(ctx, "failed to flush spans", log.Err(err))
+		}
+	}()
+
+	m := metrics.New()
+	go serveMetrics(ctx, m)
+	db, err := newRepository(ctx, m)
+	if err != nil {
+		return err
+	}
+
+	authenticator, err := newAuthenticator()
+	if err != nil {
+		return errcode.New(err)
+	}
+	// logging in and refreshing a session are how callers get a token, and
+	// the verification token is the only credential of a new user
+	authInterceptor := gateway.NewAuthInterceptor(authenticator,
+		"/e_architecture.api.EArchitecture/Login",
+		"/e_architecture.api.EArchitecture/RefreshSession",
+		"/e_architecture.api.EArchitecture/VerifyEmail",
+	)
+	deadlineCfg := gateway.DefaultDeadlineConfig()
+	if path := os.Getenv("DEADLINE_FILE"); path != "" {
+		deadlineCfg, err = gateway.LoadDeadlineConfig(path)
+		if err != nil {
+			return errcode.New(err)
+		}
+	}
+	// the error interceptor is the outermost so that the other interceptors
+	// still see errcode errors with their stack traces
+	unaryInterceptors := []grpc.UnaryServerInterceptor{
+		gateway.ErrorUnaryServerInterceptor(),
+		gateway.RequestIDUnaryServerInterceptor(),
+		gateway.TraceUnaryServerInterceptor(),
+		gateway.LoggingUnaryServerInterceptor(),
+		gateway.MetricsUnaryServerInterceptor(m),
+		gateway.DeadlineUnaryServerInterceptor(deadlineCfg),
+		gateway.RecoveryUnaryServerInterceptor(m),
+		authInterceptor.Unary(),
+		gateway.TenantUnaryServerInterceptor(),
+	}
+	streamInterceptors := []grpc.StreamServerInterceptor{
+		gateway.ErrorStreamServerInterceptor(),
+		gateway.RequestIDStreamServerInterceptor(),
+		gateway.TraceStreamServerInterceptor(),
+		gateway.LoggingStreamServerInterceptor(),
+		gateway.MetricsStreamServerInterceptor(m),
+		gateway.DeadlineStreamServerInterceptor(deadlineCfg),
+		gateway.RecoveryStreamServerInterceptor(m),
+		authInterceptor.Stream(),
+		gateway.TenantStreamServerInterceptor(),
+	}
+
+	if os.Getenv("DB_REPLICA_HOSTS") != "" {
+		// reads that follow a write of the same call go to the primary; the
+		// API has no streaming calls
+		unaryInterceptors = append(unaryInterceptors, readYourWritesUnaryServerInterceptor())
+	}
+
+	if path := os.Getenv("RATE_LIMIT_FILE"); path != "" {
+		rateLimitCfg, err := gateway.LoadRateLimitConfig(path)
+		if err != nil {
+			return errcode.New(err)
+		}
+		rateLimitInterceptor := gateway.NewRateLimitInterceptor(rateLimitCfg)
+		unaryInterceptors = append(unaryInterceptors, rateLimitInterceptor.Unary())
+		streamInterceptors = append(streamInterceptors, rateLimitInterceptor.Stream())
+	}
+
+	userCache, err := newUserCache(repository.WithUserMetrics(db.User, m))
+	if err != nil {
+		return errcode.New(err)
+	}
+	cfg := &usecase.Config{
+		DB: &repository.Database{
+			User:         userCache,
+			Credential:   repository.WithCredentialMetrics(db.Credential, m),
+			Session:      repository.WithSessionMetrics(db.Session, m),
+			Verification: repository.WithVerificationMetrics(db.Verification, m),
+			Group:        repository.WithGroupMetrics(db.Group, m),
+			Relationship: repository.WithRelationshipMetrics(db.Relationship, m),
+		},
+	}
+	if err := configureLogin(cfg); err != nil {
+		return errcode.New(err)
+	}
+	if err := configureVerification(cfg); err != nil {
+		return errcode.New(err)
+	}
+	// sessions exist only when tokens are issued
+	if cfg.Tokens != nil {
+		sweepInterval := 10 * time.Minute
+		if v := os.Getenv("SESSION_SWEEP_INTERVAL"); v != "" {
+			if sweepInterval, err = time.ParseDuration(v); err != nil {
+				return errcode.New(err)
+			}
+			if sweepInterval <= 0 {
+				return errcode.NewInvalidArgument("SESSION_SWEEP_INTERVAL must be positive: %s", v)
+			}
+		}
+		go usecase.NewSessionSweeper(cfg.DB.Session, 0).Run(ctx, sweepInterval)
+	}
+
+	if path := os.Getenv("AUTHZ_POLICY_FILE"); path != "" {
+		engine, err := authz.Load(path)
+		if err != nil {
+			return errcode.New(err)
+		}
+		authzInterceptor := gateway.NewAuthzInterceptor(engine)
+		unaryInterceptors = append(unaryInterceptors, authzInterceptor.Unary())
+		streamInterceptors = append(streamInterceptors, authzInterceptor.Stream())
+		cfg.Authorizer = engine
+	}
+	uc := usecase.WithMetrics(usecase.New(cfg), m)
+
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	)
+	api.RegisterEArchitectureServer(server, gateway.New(uc))
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "50051"
+	}
+	lis, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return errcode.New(err)
+	}
+	log.Info(ctx, "listening", "addr", lis.Addr().String())
+	if err := server.Serve(lis); err != nil {
+		return errcode.New(err)
+	}
+	return nil
+}
+
+func readYourWritesUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(postgres.ReadYourWrites(ctx), req)
+	}
+}
+
+// newRepository connects to the database with the driver selected by
+// DB_DRIVER: pq (the default) or pgx for Postgres, or sqlite for the file at
+// SQLITE_PATH.
+func newRepository(ctx context.Context, m *metrics.Metrics) (*repository.Database, error) {
+	onTxRetry := func(op string) {
+		m.TxRetries.WithLabelValues("user", op).Inc()
+	}
+
+	switch driver := os.Getenv("DB_DRIVER"); driver {
+	case "", "pq":
+	case "pgx":
+		if os.Getenv("DB_REPLICA_HOSTS") != "" {
+			return nil, errcode.NewInvalidArgument("DB_REPLICA_HOSTS is not supported by the pgx driver")
+		}
+		pool, err := pgxpool.New(ctx, dsn(os.Getenv("DB_HOST")))
+		if err != nil {
+			return nil, errcode.New(err)
+		}
+		if err := pool.Ping(ctx); err != nil {
+			return nil, errcode.New(err)
+		}
+		log.Info(ctx, "successfully connected to database", "driver", driver)
+		users := pgx.NewUser(pool, pgx.WithTxRetryObserver(onTxRetry))
+		return &repository.Database{
+			User:         users,
+			Credential:   pgx.NewCredential(users),
+			Session:      pgx.NewSession(users),
+			Verification: pgx.NewVerification(users),
+			Group:        pgx.NewGroup(users),
+			Relationship: pgx.NewRelationship(users),
+		}, nil
+	case "sqlite":
+		path := os.Getenv("SQLITE_PATH")
+		if path == "" {
+			path = "e-architecture.db"
+		}
+		db, err := sqlite.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := sqlite.Migrate(ctx, db); err != nil {
+			return nil, err
+		}
+		if err := m.RegisterDB(db, "sqlite"); err != nil {
+			return nil, errcode.New(err)
+		}
+		log.Info(ctx, "successfully opened database", "driver", driver, "path", path)
+		users := sqlite.NewUser(db)
+		return &repository.Database{
+			User:         users,
+			Credential:   sqlite.NewCredential(users),
+			Session:      sqlite.NewSession(users),
+			Verification: sqlite.NewVerification(users),
+			Group:        sqlite.NewGroup(users),
+			Relationship: sqlite.NewRelationship(users),
+		}, nil
+	default:
+		return nil, errcode.NewInvalidArgument("unknown DB_DRIVER: %s", driver)
+	}
+
+	db, err := openDB(os.Getenv("DB_HOST"))
+	if err != nil {
+		return nil, err
+	}
+	if err := m.RegisterDB(db, "primary"); err != nil {
+		return nil, errcode.New(err)
+	}
+	log.Info(ctx, "successfully connected to database", "driver", "pq")
+
+	opts := []postgres.Option{postgres.WithTxRetryObserver(onTxRetry)}
+	if v := os.Getenv("DB_REPLICA_HOSTS"); v != "" {
+		var replicas []*sql.DB
+		for i, host := range strings.Split(v, ",") {
+			replica, err := openDB(strings.TrimSpace(host))
+			if err != nil {
+				return nil, err
+			}
+			if err := m.RegisterDB(replica, fmt.Sprintf("replica-%d", i)); err != nil {
+				return nil, errcode.New(err)
+			}
+			replicas = append(replicas, replica)
+		}
+		log.Info(ctx, "successfully connected to replicas", "replicas", len(replicas))
+		opts = append(opts, postgres.WithReplicas(replicas...))
+	}
+	if v := os.Getenv("DB_REPLICA_MAX_LAG"); v != "" {
+		maxLag, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, errcode.New(err)
+		}
+		opts = append(opts, postgres.WithMaxReplicaLag(maxLag))
+	}
+	users := postgres.NewUser(db, opts...)
+	if os.Getenv("DB_REPLICA_HOSTS") != "" {
+		go users.MonitorReplicas(ctx, 5*time.Second)
+	}
+	return &repository.Database{
+		User:         users,
+		Credential:   postgres.NewCredential(users),
+		Session:      postgres.NewSession(users),
+		Verification: postgres.NewVerification(users),
+		Group:        postgres.NewGroup(users),
+		Relationship: postgres.NewRelationship(users),
+	}, nil
+}
+
+func dsn(host string) string {
+	return fmt.Sprintf("postgres://%s/%s?sslmode=disable&user=%s&password=%s&port=%s&timezone=Asia/Tokyo",
+		host, os.Getenv("DB_NAME"), os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"), os.Getenv("DB_PORT"))
+}
+
+// openDB connects to the database on host with the DB_* credentials.
+func openDB(host string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn(host))
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	if err := db.Ping(); err != nil {
+		return nil, errcode.New(err)
+	}
+	return db, nil
+}
+
+func serveMetrics(ctx context.Context, m *metrics.Metrics) {
+	addr := os.Getenv("METRICS_ADDR")
+	if addr == "" {
+		addr = ":9090"
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	log.Info(ctx, "serving metrics", "addr", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Error(ctx, "metrics server stopped", log.Err(errcode.New(err)))
+	}
+}
+
+// newUserCache wraps repo with the cache enabled by CACHE_TTL. Users are
+// cached in process unless REDIS_ADDR is set. CACHE_STATS_TTL also caches
+// the results of GetUserStats.
+func newUserCache(repo repository.User) (repository.User, error) {
+	v := os.Getenv("CACHE_TTL")
+	if v == "" {
+		return repo, nil
+	}
+	cfg := &cache.Config{}
+	var err error
+	if cfg.TTL, err = time.ParseDuration(v); err != nil {
+		return nil, errcode.New(err)
+	}
+	if v := os.Getenv("CACHE_NEGATIVE_TTL"); v != "" {
+		if cfg.NegativeTTL, err = time.ParseDuration(v); err != nil {
+			return nil, errcode.New(err)
+		}
+	}
+	if v := os.Getenv("CACHE_STATS_TTL"); v != "" {
+		if cfg.StatsTTL, err = time.ParseDuration(v); err != nil {
+			return nil, errcode.New(err)
+		}
+	}
+	if addr := os.Getenv("REDIS_ADDR"); addr != "" {
+		cfg.Store = cache.NewRedis(redis.NewClient(&redis.Options{Addr: addr}), "e-architecture:")
+		return cache.NewUser(repo, cfg), nil
+	}
+	size := 10000
+	if v := os.Getenv("CACHE_SIZE"); v != "" {
+		if size, err = strconv.Atoi(v); err != nil {
+			return nil, errcode.New(err)
+		}
+	}
+	cfg.Store = cache.NewLRU(size)
+	return cache.NewUser(repo, cfg), nil
+}
+
+// configureLogin enables Login and sessions with tokens signed with
+// AUTH_HS256_SECRET, so that the JWT authenticator accepts them. They expire
+// after AUTH_SESSION_TTL, while the sessions that refresh them last
+// SESSION_TTL. LOGIN_MAX_FAILURES failed attempts in a row lock a password out
+// for LOGIN_LOCKOUT.
+func configureLogin(cfg *usecase.Config) error {
+	var err error
+	if v := os.Getenv("LOGIN_MAX_FAILURES"); v != "" {
+		if cfg.MaxLoginFailures, err = strconv.Atoi(v); err != nil {
+			return errcode.New(err)
+		}
+	}
+	if v := os.Getenv("LOGIN_LOCKOUT"); v != "" {
+		if cfg.LockoutDuration, err = time.ParseDuration(v); err != nil {
+			return errcode.New(err)
+		}
+	}
+	if v := os.Getenv("SESSION_TTL"); v != "" {
+		if cfg.SessionTTL, err = time.ParseDuration(v); err != nil {
+			return errcode.New(err)
+		}
+	}
+	secret := os.Getenv("AUTH_HS256_SECRET")
+	if secret == "" {
+		return nil
+	}
+	issuerCfg := &auth.IssuerConfig{
+		HS256Secret: []byte(secret),
+		Issuer:      os.Getenv("AUTH_JWT_ISSUER"),
+		Audience:    os.Getenv("AUTH_JWT_AUDIENCE"),
+	}
+	if v := os.Getenv("AUTH_SESSION_TTL"); v != "" {
+		if issuerCfg.TTL, err = time.ParseDuration(v); err != nil {
+			return errcode.New(err)
+		}
+	}
+	issuer, err := auth.NewIssuer(issuerCfg)
+	if err != nil {
+		return err
+	}
+	cfg.Tokens = issuer
+	return nil
+}
+
+// configureVerification delivers verification emails by appending them to
+// NOTIFY_FILE, or by logging them with NOTIFY=log. The tokens expire after
+// VERIFICATION_TTL.
+func configureVerification(cfg *usecase.Config) error {
+	if v := os.Getenv("VERIFICATION_TTL"); v != "" {
+		var err error
+		if cfg.VerificationTTL, err = time.ParseDuration(v); err != nil {
+			return errcode.New(err)
+		}
+	}
+	if path := os.Getenv("NOTIFY_FILE"); path != "" {
+		cfg.Notifier = notify.NewFile(path)
+		return nil
+	}
+	// the tokens are not logged unless asked for; without a notifier, the
+	// verification RPCs are unimplemented
+	switch v := os.Getenv("NOTIFY"); v {
+	case "":
+	case "log":
+		cfg.Notifier = notify.NewLog()
+	default:
+		return errcode.NewInvalidArgument("unknown NOTIFY: %s", v)
+	}
+	return nil
+}
+
+// newAuthenticator builds the authenticators enabled by AUTH_* environment variables.
+func newAuthenticator() (auth.Authenticator, error) {
+	var auths []auth.Authenticator
+	secret, jwks := os.Getenv("AUTH_HS256_SECRET"), os.Getenv("AUTH_JWKS_FILE")
+	if secret != "" || jwks != "" {
+		a, err := auth.NewJWT(&auth.JWTConfig{
+			HS256Secret: []byte(secret),
+			JWKSFile:    jwks,
+			Issuer:      os.Getenv("AUTH_JWT_ISSUER"),
+			Audience:    os.Getenv("AUTH_JWT_AUDIENCE"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		auths = append(auths, a)
+	}
+	if path := os.Getenv("AUTH_API_KEYS_FILE"); path != "" {
+		a, err := auth.LoadAPIKey(path)
+		if err != nil {
+			return nil, err
+		}
+		auths = append(auths, a)
+	}
+	if len(auths) == 0 {
+		return nil, errcode.NewInvalidArgument("no authenticator configured: set AUTH_HS256_SECRET, AUTH_JWKS_FILE or AUTH_API_KEYS_FILE")
+	}
+	return auth.Chain(auths...), nil
+}
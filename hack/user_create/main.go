@@ -4,31 +4,39 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"log"
 	"os"
 
 	"github.com/SakataAtsuki/e-architecture/pkg/entity"
 	"github.com/SakataAtsuki/e-architecture/pkg/repository"
 	"github.com/SakataAtsuki/e-architecture/pkg/repository/postgres"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
 	"github.com/SakataAtsuki/e-architecture/pkg/usecase"
 	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/log"
 )
 
 func main() {
+	logger, err := log.New(os.Stderr, log.FormatText, "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	log.SetDefault(logger)
+	ctx := tenant.NewContext(context.Background(), tenant.Default)
+
 	uri := fmt.Sprintf("postgres://%s/%s?sslmode=disable&user=%s&password=%s&port=%s&timezone=Asia/Tokyo",
 		os.Getenv("DB_HOST"), os.Getenv("DB_NAME"), os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"), os.Getenv("DB_PORT"))
 	db, err := sql.Open("postgres", uri)
 	if err != nil {
-		log.Println(errcode.New(err))
+		log.Error(ctx, "failed", log.Err(errcode.New(err)))
 		os.Exit(1)
 	}
 	if err := db.Ping(); err != nil {
-		log.Println(errcode.New(err))
+		log.Error(ctx, "failed", log.Err(errcode.New(err)))
 		os.Exit(1)
 	}
-	log.Println("successfully connected to database")
+	log.Info(ctx, "successfully connected to database")
 
-	ctx := context.Background()
 	cfg := &usecase.Config{
 		DB: &repository.Database{User: postgres.NewUser(db)},
 	}
@@ -43,8 +51,8 @@ func main() {
 
 	resp, err := uc.CreateUser(ctx, req)
 	if err != nil {
-		log.Println(errcode.New(err))
+		log.Error(ctx, "failed", log.Err(errcode.New(err)))
 		os.Exit(1)
 	}
-	log.Println(resp.User.ID, resp.User.Name)
+	log.Info(ctx, "user", "id", resp.User.ID, "name", resp.User.Name)
 }
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"os"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"gopkg.in/yaml.v3"
+)
+
+var _ Authenticator = (*APIKey)(nil)
+
+type APIKeyEntry struct {
+	Key     string   `yaml:"key"`
+	Subject string   `yaml:"subject"`
+	Roles   []string `yaml:"roles"`
+	Tenant  string   `yaml:"tenant"`
+}
+
+type apiKey struct {
+	digest    [sha256.Size]byte
+	principal Principal
+}
+
+// APIKey authenticates static API keys. Keys are kept as SHA-256 digests and
+// compared in constant time.
+type APIKey struct {
+	keys []apiKey
+}
+
+func NewAPIKey(entries []*APIKeyEntry) *APIKey {
+	a := &APIKey{keys: make([]apiKey, 0, len(entries))}
+	for _, e := range entries {
+		a.keys = append(a.keys, apiKey{
+			digest: sha256.Sum256([]byte(e.Key)),
+			principal: Principal{
+				Subject: e.Subject,
+				Roles:   e.Roles,
+				Tenant:  e.Tenant,
+				Method:  "apikey",
+			},
+		})
+	}
+	return a
+}
+
+// LoadAPIKey reads API keys from a YAML or JSON file of the form
+// {"keys": [{"key": "...", "subject": "...", "roles": ["..."]}]}.
+func LoadAPIKey(path string) (*APIKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	var file struct {
+		Keys []*APIKeyEntry `yaml:"keys"`
+	}
+	if err := yaml.Unmarshal(b, &file); err != nil {
+		return nil, errcode.New(err)
+	}
+	for _, e := range file.Keys {
+		if e.Key == "" || e.Subject == "" {
+			return nil, errcode.NewInvalidArgument("api key entry requires key and subject")
+		}
+	}
+	return NewAPIKey(file.Keys), nil
+}
+
+func (a *APIKey) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	digest := sha256.Sum256([]byte(token))
+	var found *Principal
+	for i := range a.keys {
+		// keep iterating so that timing does not depend on the matching index
+		if subtle.ConstantTimeCompare(digest[:], a.keys[i].digest[:]) == 1 {
+			p := a.keys[i].principal
+			found = &p
+		}
+	}
+	if found == nil {
+		return nil, errcode.NewUnauthenticated("invalid api key")
+	}
+	return found, nil
+}
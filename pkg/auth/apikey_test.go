@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKey_Authenticate(t *testing.T) {
+	a := NewAPIKey([]*APIKeyEntry{
+		{Key: "key-1", Subject: "batch", Roles: []string{"ops"}},
+		{Key: "key-2", Subject: "admin", Roles: []string{"admin"}},
+	})
+
+	got, err := a.Authenticate(context.Background(), "key-2")
+	require.NoError(t, err)
+	require.Equal(t, &Principal{Subject: "admin", Roles: []string{"admin"}, Method: "apikey"}, got)
+
+	_, err = a.Authenticate(context.Background(), "key-3")
+	require.True(t, errcode.IsUnauthenticated(err))
+}
+
+func TestLoadAPIKey(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "keys.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("keys:\n  - key: key-1\n    subject: batch\n    roles: [ops]\n"), 0o600))
+	a, err := LoadAPIKey(path)
+	require.NoError(t, err)
+	got, err := a.Authenticate(context.Background(), "key-1")
+	require.NoError(t, err)
+	require.Equal(t, "batch", got.Subject)
+
+	path = filepath.Join(dir, "keys.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"keys": [{"key": "key-1"}]}`), 0o600))
+	_, err = LoadAPIKey(path)
+	require.True(t, errcode.IsInvalidArgument(err))
+}
+
+func TestChain(t *testing.T) {
+	a := Chain(
+		NewAPIKey([]*APIKeyEntry{{Key: "key-1", Subject: "first"}}),
+		NewAPIKey([]*APIKeyEntry{{Key: "key-2", Subject: "second"}}),
+	)
+
+	got, err := a.Authenticate(context.Background(), "key-2")
+	require.NoError(t, err)
+	require.Equal(t, "second", got.Subject)
+
+	_, err = a.Authenticate(context.Background(), "key-3")
+	require.True(t, errcode.IsUnauthenticated(err))
+}
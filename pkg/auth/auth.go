@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+)
+
+type Principal struct {
+	Subject string
+	Roles   []string
+	// Tenant pins the principal to a tenant. It is empty for principals that
+	// may act on behalf of any tenant.
+	Tenant string
+	// Method is the authenticator that verified the principal, e.g. "jwt" or "apikey".
+	Method string
+}
+
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type Authenticator interface {
+	// Authenticate verifies the bearer token and returns the calling principal.
+	// It returns a CodeUnauthenticated error when the token is not accepted.
+	Authenticate(ctx context.Context, token string) (*Principal, error)
+}
+
+type principalKey struct{}
+
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+type chain []Authenticator
+
+// Chain returns an Authenticator that tries each authenticator in order and
+// returns the first principal accepted.
+func Chain(auths ...Authenticator) Authenticator {
+	return chain(auths)
+}
+
+func (c chain) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	for _, a := range c {
+		p, err := a.Authenticate(ctx, token)
+		if err == nil {
+			return p, nil
+		}
+		if !errcode.IsUnauthenticated(err) {
+			return nil, errcode.New(err)
+		}
+	}
+	return nil, errcode.NewUnauthenticated("invalid credentials")
+}
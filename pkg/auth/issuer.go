@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/requestid"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// DefaultTokenTTL is the lifetime of the tokens of an Issuer without TTL.
+const DefaultTokenTTL = time.Hour
+
+// IssuerConfig configures the session tokens of Issuer. They are HS256 JWTs
+// accepted by a JWT configured with the same secret, issuer and audience.
+type IssuerConfig struct {
+	HS256Secret []byte
+	Issuer      string
+	Audience    string
+	TTL         time.Duration
+}
+
+// Issuer signs the session tokens of users who logged in.
+type Issuer struct {
+	secret   []byte
+	issuer   string
+	audience string
+	ttl      time.Duration
+	now      func() time.Time
+}
+
+func NewIssuer(cfg *IssuerConfig) (*Issuer, error) {
+	if len(cfg.HS256Secret) == 0 {
+		return nil, errcode.NewInvalidArgument("issuer: HS256 secret is required")
+	}
+	ttl := cfg.TTL
+	if ttl <= 0 {
+		ttl = DefaultTokenTTL
+	}
+	return &Issuer{
+		secret:   cfg.HS256Secret,
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+		ttl:      ttl,
+		now:      time.Now,
+	}, nil
+}
+
+// Issue returns a token for subject pinned to tenant, and its expiry.
+func (i *Issuer) Issue(ctx context.Context, subject, tenant string) (string, time.Time, error) {
+	now := i.now().Truncate(time.Second)
+	expiresAt := now.Add(i.ttl)
+	claims := &jwtClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        requestid.New(),
+			Subject:   subject,
+			Issuer:    i.issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		Tenant: tenant,
+	}
+	if i.audience != "" {
+		claims.Audience = jwt.ClaimStrings{i.audience}
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(i.secret)
+	if err != nil {
+		return "", time.Time{}, errcode.New(err)
+	}
+	return token, expiresAt, nil
+}
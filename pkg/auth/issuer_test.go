@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssuer_Issue(t *testing.T) {
+	cfg := &IssuerConfig{HS256Secret: []byte("secret"), Issuer: "issuer", Audience: "audience", TTL: time.Minute}
+	i, err := NewIssuer(cfg)
+	require.NoError(t, err)
+	now := time.Now()
+	i.now = func() time.Time { return now }
+
+	token, expiresAt, err := i.Issue(context.Background(), "user-1", "tenant-1")
+	require.NoError(t, err)
+	require.Equal(t, now.Truncate(time.Second).Add(time.Minute), expiresAt)
+
+	// the tokens are accepted by the JWT authenticator of the same secret
+	a, err := NewJWT(&JWTConfig{HS256Secret: cfg.HS256Secret, Issuer: cfg.Issuer, Audience: cfg.Audience})
+	require.NoError(t, err)
+	got, err := a.Authenticate(context.Background(), token)
+	require.NoError(t, err)
+	require.Equal(t, &Principal{Subject: "user-1", Tenant: "tenant-1", Method: "jwt"}, got)
+
+	other, err := NewJWT(&JWTConfig{HS256Secret: []byte("other")})
+	require.NoError(t, err)
+	_, err = other.Authenticate(context.Background(), token)
+	require.True(t, errcode.IsUnauthenticated(err), err)
+
+	// expired tokens are rejected
+	i.now = func() time.Time { return now.Add(-2 * time.Minute) }
+	token, _, err = i.Issue(context.Background(), "user-1", "tenant-1")
+	require.NoError(t, err)
+	_, err = a.Authenticate(context.Background(), token)
+	require.True(t, errcode.IsUnauthenticated(err), err)
+
+	_, err = NewIssuer(&IssuerConfig{})
+	require.True(t, errcode.IsInvalidArgument(err), err)
+}
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var _ Authenticator = (*JWT)(nil)
+
+type JWTConfig struct {
+	// HS256Secret enables HS256 tokens signed with the shared secret.
+	HS256Secret []byte
+	// JWKSFile enables RS256 tokens verified with the RSA keys of a local JWKS file.
+	JWKSFile string
+	// Issuer and Audience are checked against the "iss" and "aud" claims when set.
+	Issuer   string
+	Audience string
+}
+
+type JWT struct {
+	secret   []byte
+	keys     map[string]*rsa.PublicKey
+	issuer   string
+	audience string
+	parser   *jwt.Parser
+}
+
+type jwtClaims struct {
+	jwt.RegisteredClaims
+	Roles  []string `json:"roles"`
+	Tenant string   `json:"tenant"`
+}
+
+func NewJWT(cfg *JWTConfig) (*JWT, error) {
+	j := &JWT{
+		secret:   cfg.HS256Secret,
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+	}
+	var methods []string
+	if len(cfg.HS256Secret) > 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.JWKSFile != "" {
+		keys, err := LoadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, errcode.New(err)
+		}
+		j.keys = keys
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, errcode.NewInvalidArgument("jwt: either HS256 secret or JWKS file is required")
+	}
+	j.parser = jwt.NewParser(jwt.WithValidMethods(methods))
+	return j, nil
+}
+
+func (j *JWT) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	claims := &jwtClaims{}
+	if _, err := j.parser.ParseWithClaims(token, claims, j.key); err != nil {
+		return nil, errcode.NewUnauthenticated("invalid token: %v", err)
+	}
+	if claims.Subject == "" {
+		return nil, errcode.NewUnauthenticated("invalid token: missing subject")
+	}
+	if j.issuer != "" && !claims.VerifyIssuer(j.issuer, true) {
+		return nil, errcode.NewUnauthenticated("invalid token: unexpected issuer")
+	}
+	if j.audience != "" && !claims.VerifyAudience(j.audience, true) {
+		return nil, errcode.NewUnauthenticated("invalid token: unexpected audience")
+	}
+	return &Principal{
+		Subject: claims.Subject,
+		Roles:   claims.Roles,
+		Tenant:  claims.Tenant,
+		Method:  "jwt",
+	}, nil
+}
+
+func (j *JWT) key(t *jwt.Token) (interface{}, error) {
+	switch t.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return j.secret, nil
+	case jwt.SigningMethodRS256.Alg():
+		kid, _ := t.Header["kid"].(string)
+		if key, ok := j.keys[kid]; ok {
+			return key, nil
+		}
+		// a token without kid is accepted only when there is no ambiguity
+		if kid == "" && len(j.keys) == 1 {
+			for _, key := range j.keys {
+				return key, nil
+			}
+		}
+		return nil, errors.New("unknown key id")
+	}
+	return nil, errors.New("unexpected signing method")
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// LoadJWKS reads the RSA signing keys of a JWKS file keyed by "kid".
+func LoadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, errcode.New(err)
+	}
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, errcode.NewInvalidArgument("jwks: invalid modulus of key %q: %v", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, errcode.NewInvalidArgument("jwks: invalid exponent of key %q: %v", k.Kid, err)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errcode.NewInvalidArgument("jwks: no RSA signing keys in %s", path)
+	}
+	return keys, nil
+}
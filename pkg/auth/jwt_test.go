@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func writeJWKS(t *testing.T, kid string, key *rsa.PublicKey) string {
+	t.Helper()
+	set := map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	}
+	b, err := json.Marshal(set)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, b, 0o600))
+	return path
+}
+
+func TestJWT_Authenticate(t *testing.T) {
+	secret := []byte("secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	a, err := NewJWT(&JWTConfig{
+		HS256Secret: secret,
+		JWKSFile:    writeJWKS(t, "key-1", &rsaKey.PublicKey),
+		Issuer:      "issuer",
+		Audience:    "audience",
+	})
+	require.NoError(t, err)
+
+	claims := func(mod func(c *jwtClaims)) *jwtClaims {
+		c := &jwtClaims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "user-1",
+				Issuer:    "issuer",
+				Audience:  jwt.ClaimStrings{"audience"},
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+			Roles: []string{"admin"},
+		}
+		if mod != nil {
+			mod(c)
+		}
+		return c
+	}
+	sign := func(method jwt.SigningMethod, kid string, key interface{}, c *jwtClaims) string {
+		token := jwt.NewWithClaims(method, c)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		s, err := token.SignedString(key)
+		require.NoError(t, err)
+		return s
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "hs256", token: sign(jwt.SigningMethodHS256, "", secret, claims(nil))},
+		{name: "rs256", token: sign(jwt.SigningMethodRS256, "key-1", rsaKey, claims(nil))},
+		{name: "rs256 without kid", token: sign(jwt.SigningMethodRS256, "", rsaKey, claims(nil))},
+		{name: "rs256 unknown kid", token: sign(jwt.SigningMethodRS256, "key-2", rsaKey, claims(nil)), wantErr: true},
+		{name: "rs256 wrong key", token: sign(jwt.SigningMethodRS256, "key-1", otherKey, claims(nil)), wantErr: true},
+		{name: "hs256 wrong secret", token: sign(jwt.SigningMethodHS256, "", []byte("other"), claims(nil)), wantErr: true},
+		{name: "hs384 not allowed", token: sign(jwt.SigningMethodHS384, "", secret, claims(nil)), wantErr: true},
+		{
+			name: "expired",
+			token: sign(jwt.SigningMethodHS256, "", secret, claims(func(c *jwtClaims) {
+				c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+			})),
+			wantErr: true,
+		},
+		{
+			name:    "wrong issuer",
+			token:   sign(jwt.SigningMethodHS256, "", secret, claims(func(c *jwtClaims) { c.Issuer = "other" })),
+			wantErr: true,
+		},
+		{
+			name:    "wrong audience",
+			token:   sign(jwt.SigningMethodHS256, "", secret, claims(func(c *jwtClaims) { c.Audience = jwt.ClaimStrings{"other"} })),
+			wantErr: true,
+		},
+		{
+			name:    "missing subject",
+			token:   sign(jwt.SigningMethodHS256, "", secret, claims(func(c *jwtClaims) { c.Subject = "" })),
+			wantErr: true,
+		},
+		{name: "malformed", token: "foo.bar.baz", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := a.Authenticate(context.Background(), tt.token)
+			if tt.wantErr {
+				require.True(t, errcode.IsUnauthenticated(err))
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, &Principal{Subject: "user-1", Roles: []string{"admin"}, Method: "jwt"}, got)
+		})
+	}
+}
+
+func TestNewJWT(t *testing.T) {
+	_, err := NewJWT(&JWTConfig{})
+	require.True(t, errcode.IsInvalidArgument(err))
+
+	_, err = NewJWT(&JWTConfig{JWKSFile: filepath.Join(t.TempDir(), "missing.json")})
+	require.Error(t, err)
+}
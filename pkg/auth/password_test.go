@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2Params keep the tests fast.
+var testArgon2Params = Argon2Params{Time: 1, Memory: 64, Threads: 1}
+
+func TestPasswords(t *testing.T) {
+	p := NewPasswords(testArgon2Params)
+
+	hash, err := p.Hash("correct horse")
+	require.NoError(t, err)
+	require.Regexp(t, `^\$argon2id\$v=19\$m=64,t=1,p=1\$[A-Za-z0-9+/]{22}\$[A-Za-z0-9+/]{43}$`, hash)
+	ok, err := p.Verify(hash, "correct horse")
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = p.Verify(hash, "correct horse ")
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.False(t, p.NeedsRehash(hash))
+
+	// the salt is random
+	other, err := p.Hash("correct horse")
+	require.NoError(t, err)
+	require.NotEqual(t, hash, other)
+
+	// hashes of other parameters still verify but are replaced
+	stronger := NewPasswords(Argon2Params{Time: 2, Memory: 128, Threads: 1})
+	ok, err = stronger.Verify(hash, "correct horse")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.True(t, stronger.NeedsRehash(hash))
+}
+
+func TestPasswords_Bcrypt(t *testing.T) {
+	p := NewPasswords(testArgon2Params)
+	b, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	ok, err := p.Verify(string(b), "correct horse")
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = p.Verify(string(b), "wrong")
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.True(t, p.NeedsRehash(string(b)))
+}
+
+func TestPasswords_Malformed(t *testing.T) {
+	p := NewPasswords(testArgon2Params)
+	for _, hash := range []string{
+		"",
+		"plain",
+		"$argon2i$v=19$m=64,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=16$m=64,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=x,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$!!$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdA$",
+		"$2b$04$short",
+	} {
+		t.Run(hash, func(t *testing.T) {
+			_, err := p.Verify(hash, "password")
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+)
+
+// tokenSize is the number of random bytes of a token.
+const tokenSize = 32
+
+// NewToken returns a random opaque token, such as a refresh token, and its
+// hash, which is what is stored.
+func NewToken() (token, hash string, err error) {
+	b := make([]byte, tokenSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", errcode.New(err)
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// HashToken returns the hex SHA-256 digest of token. The tokens of NewToken
+// are random, so they need no salt or slow hash.
+func HashToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
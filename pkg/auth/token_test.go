@@ -0,0 +1,21 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewToken(t *testing.T) {
+	token, hash, err := NewToken()
+	require.NoError(t, err)
+	require.Len(t, token, 43)
+	require.Len(t, hash, 64)
+	require.Equal(t, hash, HashToken(token))
+	require.NotEqual(t, token, hash)
+
+	other, otherHash, err := NewToken()
+	require.NoError(t, err)
+	require.NotEqual(t, token, other)
+	require.NotEqual(t, hash, otherHash)
+}
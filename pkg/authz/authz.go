@@ -0,0 +1,224 @@
+package authz
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/auth"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// AnyRole matches every caller, including anonymous ones.
+	AnyRole = "*"
+
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// Policy is the authorization policy file. Methods are full RPC method names,
+// e.g. "/e_architecture.api.EArchitecture/DeleteUser", or a service wildcard
+// such as "/e_architecture.api.EArchitecture/*".
+//
+//	default: deny
+//	rules:
+//	  - methods: [/e_architecture.api.EArchitecture/GetUser]
+//	    roles: [ops, admin]
+//	  - methods: [/e_architecture.api.EArchitecture/UpdateUser]
+//	    roles: [admin]
+//	    self: true
+//	  - methods: [/e_architecture.api.EArchitecture/AddMember]
+//	    roles: [admin]
+//	    group_roles: [owner, admin]
+type Policy struct {
+	Default string  `yaml:"default"`
+	Rules   []*Rule `yaml:"rules"`
+}
+
+type Rule struct {
+	Methods []string `yaml:"methods"`
+	Roles   []string `yaml:"roles"`
+	// Self also admits callers without the roles, but only for resources they
+	// own. The ownership is checked by the usecase with AuthorizeResource.
+	Self bool `yaml:"self"`
+	// GroupRoles also admits callers without the roles, but only for groups
+	// where their membership has one of these roles, such as "owner", or any
+	// role with "*". The membership is checked by the usecase with
+	// AuthorizeGroup.
+	GroupRoles []string `yaml:"group_roles"`
+}
+
+type Engine struct {
+	allowByDefault bool
+	exact          map[string][]*Rule
+	prefix         map[string][]*Rule
+}
+
+func New(p *Policy) (*Engine, error) {
+	e := &Engine{
+		exact:  map[string][]*Rule{},
+		prefix: map[string][]*Rule{},
+	}
+	switch p.Default {
+	case "", EffectDeny:
+	case EffectAllow:
+		e.allowByDefault = true
+	default:
+		return nil, errcode.NewInvalidArgument("authz: unknown default effect %q", p.Default)
+	}
+	for _, r := range p.Rules {
+		if len(r.Methods) == 0 {
+			return nil, errcode.NewInvalidArgument("authz: rule without methods")
+		}
+		for _, m := range r.Methods {
+			switch {
+			case m == "*":
+				e.prefix["/"] = append(e.prefix["/"], r)
+			case strings.HasSuffix(m, "/*"):
+				e.prefix[strings.TrimSuffix(m, "*")] = append(e.prefix[strings.TrimSuffix(m, "*")], r)
+			case strings.HasPrefix(m, "/"):
+				e.exact[m] = append(e.exact[m], r)
+			default:
+				return nil, errcode.NewInvalidArgument("authz: invalid method %q", m)
+			}
+		}
+	}
+	return e, nil
+}
+
+// Load reads a YAML or JSON policy file.
+func Load(path string) (*Engine, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	p := &Policy{}
+	if err := yaml.Unmarshal(b, p); err != nil {
+		return nil, errcode.New(err)
+	}
+	return New(p)
+}
+
+// Authorize checks whether the principal in ctx may call fullMethod. On success
+// it returns a context carrying the grant, which AuthorizeResource consults.
+func (e *Engine) Authorize(ctx context.Context, fullMethod string) (context.Context, error) {
+	rules := e.rules(fullMethod)
+	if len(rules) == 0 {
+		if e.allowByDefault {
+			return newContext(ctx, &grant{}), nil
+		}
+		return nil, errcode.NewForbidden("%s is not allowed by policy", fullMethod)
+	}
+
+	p, ok := auth.FromContext(ctx)
+	restricted := &grant{restricted: true}
+	for _, r := range rules {
+		if matchRoles(r.Roles, p) {
+			return newContext(ctx, &grant{}), nil
+		}
+		restricted.self = restricted.self || r.Self
+		restricted.groupRoles = append(restricted.groupRoles, r.GroupRoles...)
+	}
+	if !ok {
+		return nil, errcode.NewUnauthenticated("%s requires an authenticated caller", fullMethod)
+	}
+	if restricted.self || len(restricted.groupRoles) > 0 {
+		restricted.subject = p.Subject
+		return newContext(ctx, restricted), nil
+	}
+	return nil, errcode.NewForbidden("%s is not allowed for %s", fullMethod, p.Subject)
+}
+
+// AuthorizeResource checks that the caller may act on a resource owned by
+// ownerID. Callers granted only by a self rule must own the resource.
+func (e *Engine) AuthorizeResource(ctx context.Context, ownerID string) error {
+	g, ok := fromContext(ctx)
+	if !ok {
+		return errcode.NewForbidden("request has not been authorized")
+	}
+	if g.restricted && !(g.self && g.subject == ownerID) {
+		return errcode.NewForbidden("%s may access only its own resources", g.subject)
+	}
+	return nil
+}
+
+// AuthorizeAdmin checks that the caller may act on the resources of anyone,
+// which callers granted only by self or group_roles rules may not.
+func (e *Engine) AuthorizeAdmin(ctx context.Context) error {
+	g, ok := fromContext(ctx)
+	if !ok {
+		return errcode.NewForbidden("request has not been authorized")
+	}
+	if g.restricted {
+		return errcode.NewForbidden("%s may access only its own resources", g.subject)
+	}
+	return nil
+}
+
+// AuthorizeGroup checks that the caller may act on a group. Callers granted
+// only by self or group_roles rules must be members of the group with one of
+// the group roles; memberRole returns the role of the subject in the group,
+// or "" when it is not a member, and is called only for them.
+func (e *Engine) AuthorizeGroup(ctx context.Context, memberRole func(subject string) (string, error)) error {
+	g, ok := fromContext(ctx)
+	if !ok {
+		return errcode.NewForbidden("request has not been authorized")
+	}
+	if !g.restricted {
+		return nil
+	}
+	if len(g.groupRoles) > 0 {
+		role, err := memberRole(g.subject)
+		if err != nil {
+			return err
+		}
+		for _, r := range g.groupRoles {
+			if role != "" && (r == AnyRole || r == role) {
+				return nil
+			}
+		}
+	}
+	return errcode.NewForbidden("%s may access only groups where it is a member with one of the roles %v", g.subject, g.groupRoles)
+}
+
+func (e *Engine) rules(fullMethod string) []*Rule {
+	rules := append([]*Rule{}, e.exact[fullMethod]...)
+	for prefix, rs := range e.prefix {
+		if strings.HasPrefix(fullMethod, prefix) {
+			rules = append(rules, rs...)
+		}
+	}
+	return rules
+}
+
+func matchRoles(roles []string, p *auth.Principal) bool {
+	for _, r := range roles {
+		if r == AnyRole || (p != nil && p.HasRole(r)) {
+			return true
+		}
+	}
+	return false
+}
+
+// grant is the result of Authorize. A restricted grant admits only the
+// resources of the subject when self is set, and the groups where it has one
+// of groupRoles.
+type grant struct {
+	restricted bool
+	self       bool
+	groupRoles []string
+	subject    string
+}
+
+type grantKey struct{}
+
+func newContext(ctx context.Context, g *grant) context.Context {
+	return context.WithValue(ctx, grantKey{}, g)
+}
+
+func fromContext(ctx context.Context) (*grant, bool) {
+	g, ok := ctx.Value(grantKey{}).(*grant)
+	return g, ok
+}
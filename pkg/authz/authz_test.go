@@ -0,0 +1,171 @@
+package authz
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/auth"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	methodGetUser    = "/e_architecture.api.EArchitecture/GetUser"
+	methodUpdateUser = "/e_architecture.api.EArchitecture/UpdateUser"
+	methodDeleteUser = "/e_architecture.api.EArchitecture/DeleteUser"
+	methodGetGroup   = "/e_architecture.api.EArchitecture/GetGroup"
+	methodAddMember  = "/e_architecture.api.EArchitecture/AddMember"
+	methodHealth     = "/grpc.health.v1.Health/Check"
+)
+
+const policy = `
+default: deny
+rules:
+  - methods: ["/e_architecture.api.EArchitecture/*"]
+    roles: [admin]
+  - methods: [/e_architecture.api.EArchitecture/GetUser]
+    roles: [ops]
+  - methods: [/e_architecture.api.EArchitecture/UpdateUser]
+    roles: [admin]
+    self: true
+  - methods: [/grpc.health.v1.Health/Check]
+    roles: ["*"]
+  - methods: [/e_architecture.api.EArchitecture/GetGroup]
+    group_roles: ["*"]
+  - methods: [/e_architecture.api.EArchitecture/AddMember]
+    roles: [admin]
+    group_roles: [owner, admin]
+`
+
+func loadEngine(t *testing.T) *Engine {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(policy), 0o600))
+	e, err := Load(path)
+	require.NoError(t, err)
+	return e
+}
+
+func TestEngine_Authorize(t *testing.T) {
+	e := loadEngine(t)
+	admin := &auth.Principal{Subject: "admin", Roles: []string{"admin"}}
+	ops := &auth.Principal{Subject: "ops", Roles: []string{"ops"}}
+	user := &auth.Principal{Subject: "user"}
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		method    string
+		want      func(error) bool
+	}{
+		{name: "admin delete", principal: admin, method: methodDeleteUser},
+		{name: "admin get", principal: admin, method: methodGetUser},
+		{name: "ops get", principal: ops, method: methodGetUser},
+		{name: "ops delete", principal: ops, method: methodDeleteUser, want: errcode.IsForbidden},
+		{name: "user update self", principal: user, method: methodUpdateUser},
+		{name: "user get", principal: user, method: methodGetUser, want: errcode.IsForbidden},
+		{name: "anonymous get", method: methodGetUser, want: errcode.IsUnauthenticated},
+		{name: "anonymous health", method: methodHealth},
+		{name: "unknown method", principal: admin, method: "/foo.Bar/Baz", want: errcode.IsForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = auth.NewContext(ctx, tt.principal)
+			}
+			_, err := e.Authorize(ctx, tt.method)
+			if tt.want == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.True(t, tt.want(err), err)
+		})
+	}
+}
+
+func TestEngine_AuthorizeResource(t *testing.T) {
+	e := loadEngine(t)
+
+	ctx, err := e.Authorize(auth.NewContext(context.Background(), &auth.Principal{Subject: "user"}), methodUpdateUser)
+	require.NoError(t, err)
+	require.NoError(t, e.AuthorizeResource(ctx, "user"))
+	require.True(t, errcode.IsForbidden(e.AuthorizeResource(ctx, "other")))
+
+	ctx, err = e.Authorize(auth.NewContext(context.Background(), &auth.Principal{Subject: "admin", Roles: []string{"admin"}}), methodUpdateUser)
+	require.NoError(t, err)
+	require.NoError(t, e.AuthorizeResource(ctx, "other"))
+
+	require.True(t, errcode.IsForbidden(e.AuthorizeResource(context.Background(), "user")))
+}
+
+func TestEngine_AuthorizeAdmin(t *testing.T) {
+	e := loadEngine(t)
+
+	// self rules do not reach the resources of others
+	ctx, err := e.Authorize(auth.NewContext(context.Background(), &auth.Principal{Subject: "user"}), methodUpdateUser)
+	require.NoError(t, err)
+	require.True(t, errcode.IsForbidden(e.AuthorizeAdmin(ctx)))
+
+	ctx, err = e.Authorize(auth.NewContext(context.Background(), &auth.Principal{Subject: "admin", Roles: []string{"admin"}}), methodUpdateUser)
+	require.NoError(t, err)
+	require.NoError(t, e.AuthorizeAdmin(ctx))
+
+	require.True(t, errcode.IsForbidden(e.AuthorizeAdmin(context.Background())))
+}
+
+func TestEngine_AuthorizeGroup(t *testing.T) {
+	e := loadEngine(t)
+	roles := map[string]string{"owner": "owner", "admin": "admin", "member": "member"}
+	memberRole := func(subject string) (string, error) { return roles[subject], nil }
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		method    string
+		want      func(error) bool
+	}{
+		{name: "admin role", principal: &auth.Principal{Subject: "outsider", Roles: []string{"admin"}}, method: methodAddMember},
+		{name: "owner", principal: &auth.Principal{Subject: "owner"}, method: methodAddMember},
+		{name: "group admin", principal: &auth.Principal{Subject: "admin"}, method: methodAddMember},
+		{name: "member", principal: &auth.Principal{Subject: "member"}, method: methodAddMember, want: errcode.IsForbidden},
+		{name: "outsider", principal: &auth.Principal{Subject: "outsider"}, method: methodAddMember, want: errcode.IsForbidden},
+		{name: "any role member", principal: &auth.Principal{Subject: "member"}, method: methodGetGroup},
+		{name: "any role outsider", principal: &auth.Principal{Subject: "outsider"}, method: methodGetGroup, want: errcode.IsForbidden},
+		// a self grant is not a membership
+		{name: "self", principal: &auth.Principal{Subject: "outsider"}, method: methodUpdateUser, want: errcode.IsForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, err := e.Authorize(auth.NewContext(context.Background(), tt.principal), tt.method)
+			require.NoError(t, err)
+			err = e.AuthorizeGroup(ctx, memberRole)
+			if tt.want == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.True(t, tt.want(err), err)
+		})
+	}
+
+	// group roles do not admit other resources
+	ctx, err := e.Authorize(auth.NewContext(context.Background(), &auth.Principal{Subject: "owner"}), methodAddMember)
+	require.NoError(t, err)
+	require.True(t, errcode.IsForbidden(e.AuthorizeResource(ctx, "owner")))
+	require.True(t, errcode.IsForbidden(e.AuthorizeGroup(context.Background(), memberRole)))
+}
+
+func TestNew(t *testing.T) {
+	e, err := New(&Policy{Default: EffectAllow})
+	require.NoError(t, err)
+	_, err = e.Authorize(context.Background(), methodGetUser)
+	require.NoError(t, err)
+
+	_, err = New(&Policy{Default: "maybe"})
+	require.True(t, errcode.IsInvalidArgument(err))
+
+	_, err = New(&Policy{Rules: []*Rule{{Methods: []string{"GetUser"}}}})
+	require.True(t, errcode.IsInvalidArgument(err))
+}
@@ -0,0 +1,159 @@
+package entity
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/proto/api"
+)
+
+const maxAttributeLength = 1024
+
+var attributeKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+
+// Attributes are arbitrary typed values of a user. The values are string,
+// int64, float64 or bool, which survive encoding to JSON: floats always carry
+// a fraction or an exponent. Empty Attributes decode as nil.
+type Attributes map[string]interface{}
+
+// ValidAttributeKey reports whether key is an identifier of at most 64 bytes,
+// so that it can be named in filters as attributes.<key>.
+func ValidAttributeKey(key string) bool {
+	return attributeKey.MatchString(key)
+}
+
+// ValidAttributeValue reports whether v has one of the types of Attributes.
+// Strings are at most 1024 characters and floats are finite.
+func ValidAttributeValue(v interface{}) bool {
+	switch v := v.(type) {
+	case string:
+		return utf8.RuneCountInString(v) <= maxAttributeLength
+	case float64:
+		return !math.IsNaN(v) && !math.IsInf(v, 0)
+	case int64, bool:
+		return true
+	}
+	return false
+}
+
+// Clone returns a copy of a, which holds no references since the values are
+// scalars.
+func (a Attributes) Clone() Attributes {
+	if a == nil {
+		return nil
+	}
+	ret := make(Attributes, len(a))
+	for k, v := range a {
+		ret[k] = v
+	}
+	return ret
+}
+
+func AttributesFromProto(in map[string]*api.AttributeValue) Attributes {
+	if len(in) == 0 {
+		return nil
+	}
+	ret := make(Attributes, len(in))
+	for k, v := range in {
+		switch v := v.GetKind().(type) {
+		case *api.AttributeValue_StringValue:
+			ret[k] = v.StringValue
+		case *api.AttributeValue_IntValue:
+			ret[k] = v.IntValue
+		case *api.AttributeValue_DoubleValue:
+			ret[k] = v.DoubleValue
+		case *api.AttributeValue_BoolValue:
+			ret[k] = v.BoolValue
+		default:
+			// an unset value fails validation
+			ret[k] = nil
+		}
+	}
+	return ret
+}
+
+func (a Attributes) Proto() map[string]*api.AttributeValue {
+	if len(a) == 0 {
+		return nil
+	}
+	ret := make(map[string]*api.AttributeValue, len(a))
+	for k, v := range a {
+		switch v := v.(type) {
+		case string:
+			ret[k] = &api.AttributeValue{Kind: &api.AttributeValue_StringValue{StringValue: v}}
+		case int64:
+			ret[k] = &api.AttributeValue{Kind: &api.AttributeValue_IntValue{IntValue: v}}
+		case float64:
+			ret[k] = &api.AttributeValue{Kind: &api.AttributeValue_DoubleValue{DoubleValue: v}}
+		case bool:
+			ret[k] = &api.AttributeValue{Kind: &api.AttributeValue_BoolValue{BoolValue: v}}
+		}
+	}
+	return ret
+}
+
+// MarshalJSON encodes a as a JSON object. Nil encodes as {}.
+func (a Attributes) MarshalJSON() ([]byte, error) {
+	raw := make(map[string]json.RawMessage, len(a))
+	for k, v := range a {
+		if f, ok := v.(float64); ok {
+			s := strconv.FormatFloat(f, 'g', -1, 64)
+			if !strings.ContainsAny(s, ".eEIN") {
+				// 1.0 must not decode as the integer 1
+				s += ".0"
+			}
+			raw[k] = json.RawMessage(s)
+			continue
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		raw[k] = b
+	}
+	return json.Marshal(raw)
+}
+
+// UnmarshalJSON decodes numbers with a fraction or an exponent as float64 and
+// the others as int64.
+func (a *Attributes) UnmarshalJSON(b []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil {
+		return err
+	}
+	if len(m) == 0 {
+		*a = nil
+		return nil
+	}
+	ret := make(Attributes, len(m))
+	for k, v := range m {
+		switch v := v.(type) {
+		case string, bool:
+			ret[k] = v
+		case json.Number:
+			if !strings.ContainsAny(v.String(), ".eE") {
+				if n, err := v.Int64(); err == nil {
+					ret[k] = n
+					continue
+				}
+			}
+			f, err := v.Float64()
+			if err != nil {
+				return fmt.Errorf("attribute %s: %w", k, err)
+			}
+			ret[k] = f
+		default:
+			return fmt.Errorf("attribute %s must be a string, number or bool", k)
+		}
+	}
+	*a = ret
+	return nil
+}
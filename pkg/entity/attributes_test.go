@@ -0,0 +1,43 @@
+package entity
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttributes_JSON(t *testing.T) {
+	tests := []struct {
+		name string
+		in   Attributes
+		json string
+	}{
+		{name: "nil", in: nil, json: `{}`},
+		{
+			name: "typed values",
+			in:   Attributes{"s": "1", "i": int64(1), "f": 1.0, "g": 1.5, "e": 1e21, "b": true},
+			json: `{"b":true,"e":1e+21,"f":1.0,"g":1.5,"i":1,"s":"1"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(tt.in)
+			require.NoError(t, err)
+			require.Equal(t, tt.json, string(b))
+
+			var got Attributes
+			require.NoError(t, json.Unmarshal(b, &got))
+			require.Equal(t, tt.in, got)
+		})
+	}
+}
+
+func TestAttributes_UnmarshalJSON_Invalid(t *testing.T) {
+	for _, in := range []string{`{"a":null}`, `{"a":[1]}`, `{"a":{"b":1}}`, `[]`} {
+		t.Run(in, func(t *testing.T) {
+			var got Attributes
+			require.Error(t, json.Unmarshal([]byte(in), &got))
+		})
+	}
+}
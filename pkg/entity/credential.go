@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+// Credential is the password of a user.
+type Credential struct {
+	UserID string
+	// PasswordHash is an argon2id hash in the PHC string format, or a bcrypt
+	// hash imported from another system.
+	PasswordHash string
+	// FailedAttempts counts the failed attempts since the last successful one
+	// or the last lockout.
+	FailedAttempts int
+	// LockedUntil ends the lockout after too many failed attempts. It is zero
+	// when the credential has never been locked.
+	LockedUntil time.Time
+	// UpdatedAt is set by the repository on every write.
+	UpdatedAt time.Time
+}
+
+// Locked reports whether the credential is locked out at now.
+func (c *Credential) Locked(now time.Time) bool {
+	return now.Before(c.LockedUntil)
+}
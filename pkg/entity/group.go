@@ -0,0 +1,95 @@
+package entity
+
+import (
+	"strings"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/proto/api"
+)
+
+// GroupRole is the role of a member in a group. Authorization policies refer
+// to it by PolicyName.
+type GroupRole int32
+
+const (
+	GroupRoleMember GroupRole = iota
+	GroupRoleAdmin
+	GroupRoleOwner
+)
+
+func (r GroupRole) String() string {
+	return api.GroupRole(r).String()
+}
+
+// PolicyName is the name of the role in the group_roles of authorization
+// policies, e.g. "owner".
+func (r GroupRole) PolicyName() string {
+	return strings.ToLower(r.String())
+}
+
+type Group struct {
+	ID          string `validate:"required,max=64"`
+	Name        string `validate:"required,max=100"`
+	Description string `validate:"max=1000"`
+	// CreatedAt is set by the repository when the group is created.
+	CreatedAt time.Time
+	// UpdatedAt is set by the repository on every write.
+	UpdatedAt time.Time
+}
+
+func (g *Group) Proto() *api.Group {
+	ret := &api.Group{
+		Id:          g.ID,
+		Name:        g.Name,
+		Description: g.Description,
+	}
+	if !g.CreatedAt.IsZero() {
+		ret.CreatedAt = g.CreatedAt.Unix()
+	}
+	if !g.UpdatedAt.IsZero() {
+		ret.UpdatedAt = g.UpdatedAt.Unix()
+	}
+	return ret
+}
+
+type Groups []*Group
+
+func (gs Groups) Proto() []*api.Group {
+	ret := make([]*api.Group, 0, len(gs))
+	for _, g := range gs {
+		ret = append(ret, g.Proto())
+	}
+	return ret
+}
+
+// GroupMember is the membership of a user in a group. A user is a member of
+// a group at most once.
+type GroupMember struct {
+	GroupID string    `validate:"required"`
+	UserID  string    `validate:"required"`
+	Role    GroupRole `validate:"gte=0,lte=2"`
+	// CreatedAt is set by the repository when the member is added.
+	CreatedAt time.Time
+}
+
+func (m *GroupMember) Proto() *api.GroupMember {
+	ret := &api.GroupMember{
+		GroupId: m.GroupID,
+		UserId:  m.UserID,
+		Role:    api.GroupRole(m.Role),
+	}
+	if !m.CreatedAt.IsZero() {
+		ret.CreatedAt = m.CreatedAt.Unix()
+	}
+	return ret
+}
+
+type GroupMembers []*GroupMember
+
+func (ms GroupMembers) Proto() []*api.GroupMember {
+	ret := make([]*api.GroupMember, 0, len(ms))
+	for _, m := range ms {
+		ret = append(ret, m.Proto())
+	}
+	return ret
+}
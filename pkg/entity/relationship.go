@@ -0,0 +1,53 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/proto/api"
+)
+
+// RelationshipKind is the kind of a relationship from a user to another.
+type RelationshipKind int32
+
+const (
+	RelationshipKindFollow RelationshipKind = iota
+	// RelationshipKindBlock removes the follows between the two users and
+	// keeps either of them from following the other.
+	RelationshipKindBlock
+)
+
+func (k RelationshipKind) String() string {
+	return api.RelationshipKind(k).String()
+}
+
+// Relationship is a follow or a block from UserID to TargetID. A user has at
+// most one relationship to another.
+type Relationship struct {
+	UserID   string
+	TargetID string
+	Kind     RelationshipKind
+	// CreatedAt is set by the repository when the relationship is created.
+	CreatedAt time.Time
+}
+
+func (r *Relationship) Proto() *api.Relationship {
+	ret := &api.Relationship{
+		UserId:   r.UserID,
+		TargetId: r.TargetID,
+		Kind:     api.RelationshipKind(r.Kind),
+	}
+	if !r.CreatedAt.IsZero() {
+		ret.CreatedAt = r.CreatedAt.Unix()
+	}
+	return ret
+}
+
+type Relationships []*Relationship
+
+func (rs Relationships) Proto() []*api.Relationship {
+	ret := make([]*api.Relationship, 0, len(rs))
+	for _, r := range rs {
+		ret = append(ret, r.Proto())
+	}
+	return ret
+}
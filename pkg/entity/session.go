@@ -0,0 +1,53 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/proto/api"
+)
+
+// Session is a family of refresh tokens of a user, each replacing the
+// previous one when the session is refreshed. The times are set by the
+// caller of the repository.
+type Session struct {
+	ID          string
+	UserID      string
+	CreatedAt   time.Time
+	RefreshedAt time.Time
+	ExpiresAt   time.Time
+	// RevokedAt is zero while the session is not revoked.
+	RevokedAt time.Time
+	// ReuseDetected records that the session was revoked because a refresh
+	// token was presented again after it had been replaced.
+	ReuseDetected bool
+}
+
+// Active reports whether the session can be refreshed at now.
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt.IsZero() && now.Before(s.ExpiresAt)
+}
+
+func (s *Session) Proto() *api.Session {
+	ret := &api.Session{
+		Id:            s.ID,
+		UserId:        s.UserID,
+		CreatedAt:     s.CreatedAt.Unix(),
+		RefreshedAt:   s.RefreshedAt.Unix(),
+		ExpiresAt:     s.ExpiresAt.Unix(),
+		ReuseDetected: s.ReuseDetected,
+	}
+	if !s.RevokedAt.IsZero() {
+		ret.RevokedAt = s.RevokedAt.Unix()
+	}
+	return ret
+}
+
+type Sessions []*Session
+
+func (ss Sessions) Proto() []*api.Session {
+	ret := make([]*api.Session, 0, len(ss))
+	for _, s := range ss {
+		ret = append(ret, s.Proto())
+	}
+	return ret
+}
@@ -1,15 +1,103 @@
 package entity
 
-import "github.com/SakataAtsuki/e-architecture/pkg/proto/api"
+import (
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/proto/api"
+)
+
+type Gender int32
+
+const (
+	GenderOther Gender = iota
+	GenderMale
+	GenderFemale
+)
+
+// UserState is the lifecycle state of a user. Users start pending until
+// their email is verified; only the transitions of CanTransitionTo are
+// allowed.
+type UserState int32
+
+const (
+	UserStatePending UserState = iota
+	UserStateActive
+	UserStateSuspended
+	UserStateDeactivated
+)
+
+// userStateTransitions are the states each state may change to.
+var userStateTransitions = map[UserState][]UserState{
+	UserStatePending:     {UserStateActive, UserStateDeactivated},
+	UserStateActive:      {UserStateSuspended, UserStateDeactivated},
+	UserStateSuspended:   {UserStateActive, UserStateDeactivated},
+	UserStateDeactivated: {UserStateActive},
+}
+
+// CanTransitionTo reports whether a user in state s may change to state to.
+func (s UserState) CanTransitionTo(to UserState) bool {
+	for _, state := range userStateTransitions[s] {
+		if state == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CanSignIn reports whether users in state s may log in and use sessions.
+func (s UserState) CanSignIn() bool {
+	return s == UserStatePending || s == UserStateActive
+}
+
+func (s UserState) String() string {
+	return api.UserState(s).String()
+}
 
 type User struct {
-	ID   string
-	Name string
+	ID     string
+	Name   string
+	Gender Gender `validate:"gte=0,lte=2"`
+	// Email is unique in the tenant regardless of case. Empty means none.
+	Email string `validate:"omitempty,email,max=254"`
+	// Locale is a BCP 47 language tag such as "ja-JP".
+	Locale string `validate:"omitempty,bcp47_language_tag"`
+	// Timezone is an IANA time zone such as "Asia/Tokyo".
+	Timezone   string     `validate:"omitempty,timezone"`
+	Attributes Attributes `validate:"max=64,dive,keys,attribute_key,endkeys,attribute_value"`
+	// State changes only through the transitions of UserState.
+	State UserState `validate:"gte=0,lte=3"`
+	// CreatedAt is set by the repository when the user is created.
+	CreatedAt time.Time
+	// UpdatedAt is set by the repository on every write.
+	UpdatedAt time.Time
 }
 
 func (u *User) Proto() *api.User {
-	return &api.User{
-		Id:   u.ID,
-		Name: u.Name,
+	ret := &api.User{
+		Id:         u.ID,
+		Name:       u.Name,
+		Gender:     api.Gender(u.Gender),
+		Email:      u.Email,
+		Locale:     u.Locale,
+		Timezone:   u.Timezone,
+		Attributes: u.Attributes.Proto(),
+		State:      api.UserState(u.State),
+	}
+	if !u.CreatedAt.IsZero() {
+		ret.CreatedAt = u.CreatedAt.Unix()
+	}
+	if !u.UpdatedAt.IsZero() {
+		ret.UpdatedAt = u.UpdatedAt.Unix()
+	}
+	return ret
+}
+
+type Users []*User
+
+func (us Users) Proto() []*api.User {
+	ret := make([]*api.User, 0, len(us))
+	for _, u := range us {
+		ret = append(ret, u.Proto())
 	}
+	return ret
 }
@@ -0,0 +1,31 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserState_CanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from, to UserState
+		want     bool
+	}{
+		{from: UserStatePending, to: UserStateActive, want: true},
+		{from: UserStatePending, to: UserStateSuspended, want: false},
+		{from: UserStatePending, to: UserStateDeactivated, want: true},
+		{from: UserStateActive, to: UserStateActive, want: false},
+		{from: UserStateActive, to: UserStatePending, want: false},
+		{from: UserStateActive, to: UserStateSuspended, want: true},
+		{from: UserStateActive, to: UserStateDeactivated, want: true},
+		{from: UserStateSuspended, to: UserStateActive, want: true},
+		{from: UserStateSuspended, to: UserStateDeactivated, want: true},
+		{from: UserStateDeactivated, to: UserStateActive, want: true},
+		{from: UserStateDeactivated, to: UserStateSuspended, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from.String()+"->"+tt.to.String(), func(t *testing.T) {
+			require.Equal(t, tt.want, tt.from.CanTransitionTo(tt.to))
+		})
+	}
+}
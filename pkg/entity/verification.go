@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+// Verification is an outstanding email verification of a user. It is for the
+// email the user had when it was sent, and is void once the email changes.
+type Verification struct {
+	UserID    string
+	Email     string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// Expired reports whether the verification can no longer be completed at now.
+func (v *Verification) Expired(now time.Time) bool {
+	return !now.Before(v.ExpiresAt)
+}
@@ -0,0 +1,84 @@
+// Package filter implements the AIP-160 filter language for list requests:
+// parsing into an AST checked against a schema, evaluation in process and
+// compilation to parameterized SQL.
+//
+//	gender = FEMALE AND updated_at > "2026-01-01"
+//	NOT (name = "alice" OR name = "bob")
+//	attributes.team = "core" AND attributes:beta
+package filter
+
+import (
+	"fmt"
+	"time"
+)
+
+// Expr is a node of a parsed filter.
+type Expr interface {
+	expr()
+}
+
+type And struct {
+	Left, Right Expr
+}
+
+type Or struct {
+	Left, Right Expr
+}
+
+type Not struct {
+	Expr Expr
+}
+
+type Op string
+
+const (
+	OpEq  Op = "="
+	OpNe  Op = "!="
+	OpLt  Op = "<"
+	OpLe  Op = "<="
+	OpGt  Op = ">"
+	OpGe  Op = ">="
+	OpHas Op = ":"
+)
+
+// Comparison compares a field with a value. Value has the Go type of the
+// field: string, int64 (also for enums), bool or time.Time.
+//
+// For maps, Key names the compared key and Value is a string, int64, float64
+// or bool. OpHas on a map has no Key and tests for the key in Value.
+type Comparison struct {
+	Field string
+	Key   string
+	Op    Op
+	Value interface{}
+}
+
+func (*And) expr()        {}
+func (*Or) expr()         {}
+func (*Not) expr()        {}
+func (*Comparison) expr() {}
+
+// String formats e canonically, with explicit parentheses and values in their
+// Go form, for logs and cache keys. Nil formats as "".
+func String(e Expr) string {
+	switch e := e.(type) {
+	case *And:
+		return "(" + String(e.Left) + " AND " + String(e.Right) + ")"
+	case *Or:
+		return "(" + String(e.Left) + " OR " + String(e.Right) + ")"
+	case *Not:
+		return "NOT " + String(e.Expr)
+	case *Comparison:
+		v := e.Value
+		if t, ok := v.(time.Time); ok {
+			v = t.UTC().Format(time.RFC3339Nano)
+		}
+		field := e.Field
+		if e.Key != "" {
+			field += "." + e.Key
+		}
+		// %T keeps 1 and "1" of map keys apart
+		return fmt.Sprintf("%s %s %T(%q)", field, e.Op, v, fmt.Sprint(v))
+	}
+	return ""
+}
@@ -0,0 +1,104 @@
+package filter
+
+import (
+	"strings"
+	"time"
+)
+
+// Eval reports whether the record whose fields are returned by get matches e,
+// for the backends that cannot compile filters. get must return the values
+// with the Go types of Comparison.Value, and maps as map[string]interface{}.
+func Eval(e Expr, get func(field string) interface{}) bool {
+	switch e := e.(type) {
+	case nil:
+		return true
+	case *And:
+		return Eval(e.Left, get) && Eval(e.Right, get)
+	case *Or:
+		return Eval(e.Left, get) || Eval(e.Right, get)
+	case *Not:
+		return !Eval(e.Expr, get)
+	case *Comparison:
+		v := get(e.Field)
+		if m, isMap := v.(map[string]interface{}); isMap {
+			if e.Op == OpHas {
+				_, ok := m[e.Value.(string)]
+				return ok
+			}
+			// a missing key matches no comparison
+			var ok bool
+			if v, ok = m[e.Key]; !ok {
+				return false
+			}
+		}
+		c, ok := compare(v, e.Value)
+		if !ok {
+			return false
+		}
+		switch e.Op {
+		case OpEq:
+			return c == 0
+		case OpNe:
+			return c != 0
+		case OpLt:
+			return c < 0
+		case OpLe:
+			return c <= 0
+		case OpGt:
+			return c > 0
+		case OpGe:
+			return c >= 0
+		}
+	}
+	return false
+}
+
+// compare returns the order of a and b, or false when they are not
+// comparable. Integers and floats compare as numbers, as in SQL.
+func compare(a, b interface{}) (int, bool) {
+	switch a := a.(type) {
+	case string:
+		b, ok := b.(string)
+		return strings.Compare(a, b), ok
+	case int64:
+		if f, ok := b.(float64); ok {
+			return compareFloat(float64(a), f), true
+		}
+		b, ok := b.(int64)
+		switch {
+		case a < b:
+			return -1, ok
+		case a > b:
+			return 1, ok
+		}
+		return 0, ok
+	case float64:
+		switch b := b.(type) {
+		case float64:
+			return compareFloat(a, b), true
+		case int64:
+			return compareFloat(a, float64(b)), true
+		}
+		return 0, false
+	case bool:
+		b, ok := b.(bool)
+		if a == b {
+			return 0, ok
+		}
+		return 1, ok
+	case time.Time:
+		b, ok := b.(time.Time)
+		return a.Compare(b), ok
+	}
+	return 0, false
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
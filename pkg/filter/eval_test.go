@@ -0,0 +1,48 @@
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEval(t *testing.T) {
+	record := map[string]interface{}{
+		"name":       "alice",
+		"age":        int64(20),
+		"active":     true,
+		"gender":     int64(2),
+		"updated_at": time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		"labels":     map[string]interface{}{"team": "core", "level": int64(3), "score": 0.5, "beta": true},
+	}
+	get := func(field string) interface{} { return record[field] }
+
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{filter: "", want: true},
+		{filter: `name = alice`, want: true},
+		{filter: `name != alice`, want: false},
+		{filter: `name < bob AND age <= 20 AND age > 19`, want: true},
+		{filter: `gender = FEMALE AND updated_at > "2026-01-01"`, want: true},
+		{filter: `updated_at >= "2026-03-01T00:00:01Z"`, want: false},
+		{filter: `active = false OR age = 20`, want: true},
+		{filter: `NOT active = true`, want: false},
+		{filter: `labels.team = core AND labels.beta = true`, want: true},
+		{filter: `labels.level = 3.0 AND labels.level < 3.5 AND labels.score >= 0`, want: true},
+		{filter: `labels.level = "3"`, want: false},
+		{filter: `labels.level != "3"`, want: false},
+		{filter: `labels.missing != x`, want: false},
+		{filter: `NOT labels.missing = x`, want: true},
+		{filter: `labels:team AND NOT labels:missing`, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			e, err := Parse(tt.filter, testSchema)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, Eval(e, get))
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package filter
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+)
+
+// OrderKey sorts by a field of the schema.
+type OrderKey struct {
+	Field string
+	Desc  bool
+}
+
+// Order is a parsed AIP-132 order_by, such as "name desc, updated_at".
+type Order []OrderKey
+
+// ParseOrder parses orderBy and checks it against the sortable fields of
+// schema. unique names a field whose values are unique, which is appended as
+// the last key unless orderBy has it, so that the order is total and page
+// tokens can resume after any record.
+func ParseOrder(orderBy string, schema Schema, unique string) (Order, error) {
+	if len(orderBy) > maxLength {
+		return nil, errcode.NewInvalidArgument("order_by is longer than %d bytes", maxLength)
+	}
+	order := Order{}
+	seen := map[string]bool{}
+	if strings.TrimSpace(orderBy) != "" {
+		for _, key := range strings.Split(orderBy, ",") {
+			words := strings.Fields(key)
+			if len(words) == 0 || len(words) > 2 {
+				return nil, errcode.NewInvalidArgument("invalid order_by: %q", orderBy)
+			}
+			k := OrderKey{Field: words[0]}
+			if len(words) == 2 {
+				switch strings.ToLower(words[1]) {
+				case "asc":
+				case "desc":
+					k.Desc = true
+				default:
+					return nil, errcode.NewInvalidArgument("invalid direction in order_by: %s", words[1])
+				}
+			}
+			if field, ok := schema[k.Field]; !ok || !field.Sortable {
+				return nil, errcode.NewInvalidArgument("cannot order by %q", k.Field)
+			}
+			if seen[k.Field] {
+				return nil, errcode.NewInvalidArgument("duplicate field in order_by: %s", k.Field)
+			}
+			seen[k.Field] = true
+			order = append(order, k)
+		}
+	}
+	if !seen[unique] {
+		order = append(order, OrderKey{Field: unique})
+	}
+	return order, nil
+}
+
+// String formats o as an order_by.
+func (o Order) String() string {
+	keys := make([]string, 0, len(o))
+	for _, k := range o {
+		if k.Desc {
+			keys = append(keys, k.Field+" desc")
+			continue
+		}
+		keys = append(keys, k.Field)
+	}
+	return strings.Join(keys, ", ")
+}
+
+// Compare returns the order of the records whose fields are returned by a and
+// b, for the backends that sort in process.
+func (o Order) Compare(a, b func(field string) interface{}) int {
+	for _, k := range o {
+		c, _ := compare(a(k.Field), b(k.Field))
+		if k.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// OrderSQL compiles o into the terms of an ORDER BY clause.
+func OrderSQL(o Order, column func(field string) string) string {
+	terms := make([]string, 0, len(o))
+	for _, k := range o {
+		if k.Desc {
+			terms = append(terms, column(k.Field)+" DESC")
+			continue
+		}
+		terms = append(terms, column(k.Field))
+	}
+	return strings.Join(terms, ", ")
+}
+
+// Cursor returns the values of the keys of o in the record whose fields are
+// returned by get, as filter literals so that page tokens can carry them.
+func (o Order) Cursor(schema Schema, get func(field string) interface{}) []string {
+	cursor := make([]string, 0, len(o))
+	for _, k := range o {
+		cursor = append(cursor, schema.format(k.Field, get(k.Field)))
+	}
+	return cursor
+}
+
+// After returns an Expr matching the records that follow cursor in the order
+// o. For "a, b desc" it is a > x OR (a = x AND b < y).
+func (o Order) After(schema Schema, cursor []string) (Expr, error) {
+	if len(cursor) != len(o) {
+		return nil, errcode.NewInvalidArgument("cursor does not match the order")
+	}
+	var e Expr
+	for i := len(o) - 1; i >= 0; i-- {
+		k := o[i]
+		v, err := schema.value(k.Field, OpEq, cursor[i])
+		if err != nil {
+			return nil, err
+		}
+		op := OpGt
+		if k.Desc {
+			op = OpLt
+		}
+		var after Expr = &Comparison{Field: k.Field, Op: op, Value: v}
+		if e != nil {
+			after = &Or{Left: after, Right: &And{Left: &Comparison{Field: k.Field, Op: OpEq, Value: v}, Right: e}}
+		}
+		e = after
+	}
+	return e, nil
+}
+
+// AndOf joins the non-nil expressions with AND.
+func AndOf(es ...Expr) Expr {
+	var ret Expr
+	for _, e := range es {
+		switch {
+		case e == nil:
+		case ret == nil:
+			ret = e
+		default:
+			ret = &And{Left: ret, Right: e}
+		}
+	}
+	return ret
+}
+
+// format is the inverse of value.
+func (s Schema) format(name string, v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int64:
+		names := make([]string, 0, 1)
+		for n, ev := range s[name].Enum {
+			if ev == v {
+				names = append(names, n)
+			}
+		}
+		if len(names) > 0 {
+			sort.Strings(names)
+			return names[0]
+		}
+		return strconv.FormatInt(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
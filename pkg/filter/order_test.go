@@ -0,0 +1,123 @@
+package filter
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+)
+
+var orderSchema = Schema{
+	"id":         {Type: TypeString, Sortable: true},
+	"name":       {Type: TypeString, Sortable: true},
+	"gender":     {Type: TypeEnum, Enum: map[string]int64{"MALE": 1, "FEMALE": 2}, Sortable: true},
+	"updated_at": {Type: TypeTimestamp, Sortable: true},
+	"secret":     {Type: TypeString},
+}
+
+func TestParseOrder(t *testing.T) {
+	tests := []struct {
+		orderBy string
+		want    Order
+	}{
+		{orderBy: "", want: Order{{Field: "id"}}},
+		{orderBy: "name", want: Order{{Field: "name"}, {Field: "id"}}},
+		{orderBy: " name desc ,updated_at ", want: Order{{Field: "name", Desc: true}, {Field: "updated_at"}, {Field: "id"}}},
+		{orderBy: "gender ASC, id DESC", want: Order{{Field: "gender"}, {Field: "id", Desc: true}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.orderBy, func(t *testing.T) {
+			got, err := ParseOrder(tt.orderBy, orderSchema, "id")
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseOrder_Invalid(t *testing.T) {
+	for _, orderBy := range []string{
+		"unknown",
+		"secret",
+		"name desc desc",
+		"name sideways",
+		"name,,id",
+		"name, name desc",
+		"name; DROP TABLE users",
+		"(SELECT 1)",
+		"name desc, id --",
+	} {
+		t.Run(orderBy, func(t *testing.T) {
+			_, err := ParseOrder(orderBy, orderSchema, "id")
+			require.True(t, errcode.IsInvalidArgument(err), err)
+		})
+	}
+}
+
+func TestOrderSQL(t *testing.T) {
+	order, err := ParseOrder("name desc, updated_at", orderSchema, "id")
+	require.NoError(t, err)
+	require.Equal(t, "u.name DESC, u.updated_at, u.id", OrderSQL(order, func(field string) string { return "u." + field }))
+	require.Equal(t, "name desc, updated_at, id", order.String())
+}
+
+func TestOrder_After(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+	var records []map[string]interface{}
+	for i, name := range []string{"b", "a", "b", "c", "a", "b"} {
+		records = append(records, map[string]interface{}{
+			"id":         fmt.Sprintf("id-%d", i),
+			"name":       name,
+			"gender":     int64(1 + i%2),
+			"updated_at": day(1 + i%3),
+		})
+	}
+	getter := func(r map[string]interface{}) func(string) interface{} {
+		return func(field string) interface{} { return r[field] }
+	}
+
+	for _, orderBy := range []string{"", "name", "name desc", "gender desc, updated_at", "updated_at desc, name, id desc"} {
+		t.Run(orderBy, func(t *testing.T) {
+			order, err := ParseOrder(orderBy, orderSchema, "id")
+			require.NoError(t, err)
+			sorted := append([]map[string]interface{}{}, records...)
+			sort.Slice(sorted, func(i, j int) bool { return order.Compare(getter(sorted[i]), getter(sorted[j])) < 0 })
+
+			// resuming after each record yields exactly the records that follow it
+			for i, r := range sorted {
+				cursor := order.Cursor(orderSchema, getter(r))
+				after, err := order.After(orderSchema, cursor)
+				require.NoError(t, err)
+				got := []map[string]interface{}{}
+				for _, s := range sorted {
+					if Eval(after, getter(s)) {
+						got = append(got, s)
+					}
+				}
+				require.Equal(t, sorted[i+1:], got)
+			}
+		})
+	}
+}
+
+func TestOrder_Cursor(t *testing.T) {
+	order := Order{{Field: "gender"}, {Field: "updated_at", Desc: true}, {Field: "id"}}
+	record := map[string]interface{}{
+		"gender":     int64(2),
+		"updated_at": time.Date(2026, 1, 2, 3, 4, 5, 6000, time.UTC),
+		"id":         `x"y`,
+	}
+	cursor := order.Cursor(orderSchema, func(field string) interface{} { return record[field] })
+	require.Equal(t, []string{"FEMALE", "2026-01-02T03:04:05.000006Z", `x"y`}, cursor)
+
+	after, err := order.After(orderSchema, cursor)
+	require.NoError(t, err)
+	require.False(t, Eval(after, func(field string) interface{} { return record[field] }))
+
+	_, err = order.After(orderSchema, cursor[:2])
+	require.True(t, errcode.IsInvalidArgument(err), err)
+	_, err = order.After(orderSchema, []string{"UNKNOWN", "2026-01-02", "id"})
+	require.True(t, errcode.IsInvalidArgument(err), err)
+}
@@ -0,0 +1,258 @@
+package filter
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+)
+
+const (
+	maxLength = 2048
+	maxDepth  = 32
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenLParen
+	tokenRParen
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenMinus
+	tokenComparator
+	tokenText
+	tokenString
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// Parse parses filter and checks it against schema. An empty filter returns
+// a nil Expr, which matches everything.
+func Parse(filter string, schema Schema) (Expr, error) {
+	if len(filter) > maxLength {
+		return nil, errcode.NewInvalidArgument("filter is longer than %d bytes", maxLength)
+	}
+	tokens, err := lex(filter)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, schema: schema}
+	if p.peek().kind == tokenEOF {
+		return nil, nil
+	}
+	e, err := p.expression(0)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.unexpected(t)
+	}
+	return e, nil
+}
+
+func lex(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case c == '"' || c == '\'':
+			text, n, err := lexString(s[i:])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: i})
+			i += n
+		case strings.HasPrefix(s[i:], "<=") || strings.HasPrefix(s[i:], ">=") || strings.HasPrefix(s[i:], "!="):
+			tokens = append(tokens, token{kind: tokenComparator, text: s[i : i+2], pos: i})
+			i += 2
+		case c == '<' || c == '>' || c == '=' || c == ':':
+			tokens = append(tokens, token{kind: tokenComparator, text: s[i : i+1], pos: i})
+			i++
+		case c == '-' && (i+1 >= len(s) || !unicode.IsDigit(rune(s[i+1]))):
+			tokens = append(tokens, token{kind: tokenMinus, text: "-", pos: i})
+			i++
+		default:
+			start := i
+			for i < len(s) && !strings.ContainsRune(" \t\n\r()\"'<>=!:", rune(s[i])) {
+				i++
+			}
+			if i == start {
+				return nil, errcode.NewInvalidArgument("unexpected %q at %d in filter", s[i], i)
+			}
+			text := s[start:i]
+			kind := tokenText
+			switch text {
+			case "AND":
+				kind = tokenAnd
+			case "OR":
+				kind = tokenOr
+			case "NOT":
+				kind = tokenNot
+			}
+			tokens = append(tokens, token{kind: kind, text: text, pos: start})
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(s)}), nil
+}
+
+// lexString reads the quoted string at the start of s and returns it unquoted
+// with the number of bytes read.
+func lexString(s string) (string, int, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case quote:
+			return b.String(), i + 1, nil
+		case '\\':
+			if i+1 < len(s) {
+				i++
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return "", 0, errcode.NewInvalidArgument("unterminated string in filter")
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	schema Schema
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) unexpected(t token) error {
+	if t.kind == tokenEOF {
+		return errcode.NewInvalidArgument("unexpected end of filter")
+	}
+	return errcode.NewInvalidArgument("unexpected %q at %d in filter", t.text, t.pos)
+}
+
+// expression := sequence {AND sequence}
+func (p *parser) expression(depth int) (Expr, error) {
+	if depth > maxDepth {
+		return nil, errcode.NewInvalidArgument("filter is nested deeper than %d", maxDepth)
+	}
+	left, err := p.sequence(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenAnd {
+		p.next()
+		right, err := p.sequence(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &And{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+// sequence := factor {factor}, where adjacent factors are ANDed.
+func (p *parser) sequence(depth int) (Expr, error) {
+	left, err := p.factor(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek().kind {
+		case tokenText, tokenLParen, tokenNot, tokenMinus:
+		default:
+			return left, nil
+		}
+		right, err := p.factor(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &And{Left: left, Right: right}
+	}
+}
+
+// factor := term {OR term}. OR binds tighter than AND in AIP-160.
+func (p *parser) factor(depth int) (Expr, error) {
+	left, err := p.term(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOr {
+		p.next()
+		right, err := p.term(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+// term := [NOT | -] simple
+// simple := ( expression ) | restriction
+func (p *parser) term(depth int) (Expr, error) {
+	if k := p.peek().kind; k == tokenNot || k == tokenMinus {
+		p.next()
+		e, err := p.term(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Expr: e}, nil
+	}
+	if p.peek().kind == tokenLParen {
+		p.next()
+		e, err := p.expression(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokenRParen {
+			return nil, p.unexpected(t)
+		}
+		return e, nil
+	}
+	return p.restriction()
+}
+
+// restriction := field comparator value
+func (p *parser) restriction() (Expr, error) {
+	field := p.next()
+	if field.kind != tokenText {
+		return nil, p.unexpected(field)
+	}
+	comparator := p.next()
+	if comparator.kind != tokenComparator {
+		if comparator.kind == tokenEOF || comparator.kind == tokenRParen {
+			return nil, errcode.NewInvalidArgument("%s must be compared with a value", field.text)
+		}
+		return nil, p.unexpected(comparator)
+	}
+	arg := p.next()
+	if arg.kind != tokenText && arg.kind != tokenString {
+		return nil, p.unexpected(arg)
+	}
+
+	return p.schema.comparison(field.text, Op(comparator.text), arg.text, arg.kind == tokenString)
+}
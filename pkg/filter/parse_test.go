@@ -0,0 +1,133 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+)
+
+var testSchema = Schema{
+	"name":       {Type: TypeString},
+	"age":        {Type: TypeInt},
+	"active":     {Type: TypeBool},
+	"gender":     {Type: TypeEnum, Enum: map[string]int64{"MALE": 1, "FEMALE": 2}},
+	"updated_at": {Type: TypeTimestamp},
+	"labels":     {Type: TypeMap},
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		want   Expr
+	}{
+		{name: "empty", filter: " ", want: nil},
+		{
+			name:   "comparison",
+			filter: `name = "alice"`,
+			want:   &Comparison{Field: "name", Op: OpEq, Value: "alice"},
+		},
+		{
+			name:   "typed values",
+			filter: `gender = FEMALE AND updated_at > "2026-01-01" AND age >= -3 AND active != false`,
+			want: &And{
+				Left: &And{
+					Left: &And{
+						Left:  &Comparison{Field: "gender", Op: OpEq, Value: int64(2)},
+						Right: &Comparison{Field: "updated_at", Op: OpGt, Value: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+					},
+					Right: &Comparison{Field: "age", Op: OpGe, Value: int64(-3)},
+				},
+				Right: &Comparison{Field: "active", Op: OpNe, Value: false},
+			},
+		},
+		{
+			name:   "OR binds tighter than AND",
+			filter: `age = 1 AND age = 2 OR age = 3`,
+			want: &And{
+				Left: &Comparison{Field: "age", Op: OpEq, Value: int64(1)},
+				Right: &Or{
+					Left:  &Comparison{Field: "age", Op: OpEq, Value: int64(2)},
+					Right: &Comparison{Field: "age", Op: OpEq, Value: int64(3)},
+				},
+			},
+		},
+		{
+			name:   "negation and parentheses",
+			filter: `NOT (name = a OR -name = 'b\'c') age < 5`,
+			want: &And{
+				Left: &Not{Expr: &Or{
+					Left:  &Comparison{Field: "name", Op: OpEq, Value: "a"},
+					Right: &Not{Expr: &Comparison{Field: "name", Op: OpEq, Value: "b'c"}},
+				}},
+				Right: &Comparison{Field: "age", Op: OpLt, Value: int64(5)},
+			},
+		},
+		{
+			name:   "map keys typed by the literal",
+			filter: `labels.team = "1" labels.team = 1 labels.score > 0.5 labels.beta = true labels.team = core labels:team`,
+			want: &And{
+				Left: &And{
+					Left: &And{
+						Left: &And{
+							Left: &And{
+								Left:  &Comparison{Field: "labels", Key: "team", Op: OpEq, Value: "1"},
+								Right: &Comparison{Field: "labels", Key: "team", Op: OpEq, Value: int64(1)},
+							},
+							Right: &Comparison{Field: "labels", Key: "score", Op: OpGt, Value: 0.5},
+						},
+						Right: &Comparison{Field: "labels", Key: "beta", Op: OpEq, Value: true},
+					},
+					Right: &Comparison{Field: "labels", Key: "team", Op: OpEq, Value: "core"},
+				},
+				Right: &Comparison{Field: "labels", Op: OpHas, Value: "team"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.filter, testSchema)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+	}{
+		{name: "unknown field", filter: `email = "a"`},
+		{name: "unknown enum value", filter: `gender = OTHER`},
+		{name: "ordered enum", filter: `gender > MALE`},
+		{name: "not an integer", filter: `age = old`},
+		{name: "not a timestamp", filter: `updated_at > yesterday`},
+		{name: "has on scalar", filter: `name : a`},
+		{name: "map without key", filter: `labels = a`},
+		{name: "invalid map key", filter: `labels.a-b = a`},
+		{name: "nested map key", filter: `labels.a.b = a`},
+		{name: "has on map key", filter: `labels.a : b`},
+		{name: "has invalid key", filter: `labels : "a b"`},
+		{name: "ordered bool key", filter: `labels.beta > false`},
+		{name: "key of scalar", filter: `name.first = a`},
+		{name: "missing value", filter: `name =`},
+		{name: "bare value", filter: `alice`},
+		{name: "unbalanced", filter: `(name = a`},
+		{name: "trailing", filter: `name = a)`},
+		{name: "unterminated string", filter: `name = "a`},
+		{name: "dangling operator", filter: `name = a AND`},
+		{name: "bang", filter: `name ! a`},
+		{name: "too deep", filter: strings.Repeat("(", maxDepth+2) + "name = a" + strings.Repeat(")", maxDepth+2)},
+		{name: "too long", filter: `name = "` + strings.Repeat("a", maxLength) + `"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.filter, testSchema)
+			require.True(t, errcode.IsInvalidArgument(err), err)
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package filter
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+)
+
+type Type int
+
+const (
+	TypeString Type = iota
+	TypeInt
+	TypeBool
+	TypeEnum
+	TypeTimestamp
+	// TypeMap is a map from identifiers to strings, integers, floats or bools.
+	// Its keys are compared as field.key, with the type of the literal, and
+	// tested for presence with field:key.
+	TypeMap
+)
+
+type Field struct {
+	Type Type
+	// Enum maps the names of the values of an enum field to the values.
+	Enum map[string]int64
+	// Sortable allows the field in order_by.
+	Sortable bool
+}
+
+// Schema lists the fields that a filter may refer to.
+type Schema map[string]Field
+
+// timestampLayouts are the accepted forms of timestamps. Timestamps without a
+// zone are in UTC.
+var timestampLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"}
+
+// mapKey is the form of the keys of maps.
+var mapKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// comparison checks the restriction of name by op against text, which was
+// quoted when quoted is set.
+func (s Schema) comparison(name string, op Op, text string, quoted bool) (*Comparison, error) {
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		if field, ok := s[name[:i]]; ok && field.Type == TypeMap {
+			key := name[i+1:]
+			if !mapKey.MatchString(key) {
+				return nil, errcode.NewInvalidArgument("invalid key of %s in filter: %s", name[:i], key)
+			}
+			if op == OpHas {
+				return nil, errcode.NewInvalidArgument("%s does not support %s", name, op)
+			}
+			v, err := mapValue(name, op, text, quoted)
+			if err != nil {
+				return nil, err
+			}
+			return &Comparison{Field: name[:i], Key: key, Op: op, Value: v}, nil
+		}
+	}
+	if field, ok := s[name]; ok && field.Type == TypeMap {
+		if op != OpHas {
+			return nil, errcode.NewInvalidArgument("%s must be compared by key, as %s.key", name, name)
+		}
+		if !mapKey.MatchString(text) {
+			return nil, errcode.NewInvalidArgument("invalid key of %s in filter: %s", name, text)
+		}
+		return &Comparison{Field: name, Op: op, Value: text}, nil
+	}
+	v, err := s.value(name, op, text)
+	if err != nil {
+		return nil, err
+	}
+	return &Comparison{Field: name, Op: op, Value: v}, nil
+}
+
+// mapValue types the text compared with a key of a map: quoted text is a
+// string, and unquoted text a bool, an integer or a float when it reads as
+// one.
+func mapValue(name string, op Op, text string, quoted bool) (interface{}, error) {
+	if quoted {
+		return text, nil
+	}
+	if text == "true" || text == "false" {
+		if op != OpEq && op != OpNe {
+			return nil, errcode.NewInvalidArgument("%s does not support %s with %s", name, op, text)
+		}
+		return text == "true", nil
+	}
+	if v, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return v, nil
+	}
+	if v, err := strconv.ParseFloat(text, 64); err == nil && !math.IsNaN(v) && !math.IsInf(v, 0) {
+		return v, nil
+	}
+	return text, nil
+}
+
+// value converts the text compared with field by op to the type of field.
+func (s Schema) value(name string, op Op, text string) (interface{}, error) {
+	field, ok := s[name]
+	if !ok {
+		return nil, errcode.NewInvalidArgument("unknown field in filter: %s", name)
+	}
+	ordered := op != OpEq && op != OpNe
+	if op == OpHas {
+		return nil, errcode.NewInvalidArgument("%s does not support %s", name, op)
+	}
+
+	switch field.Type {
+	case TypeString:
+		return text, nil
+	case TypeInt:
+		v, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, errcode.NewInvalidArgument("%s requires an integer: %s", name, text)
+		}
+		return v, nil
+	case TypeBool:
+		if ordered {
+			return nil, errcode.NewInvalidArgument("%s does not support %s", name, op)
+		}
+		v, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, errcode.NewInvalidArgument("%s requires true or false: %s", name, text)
+		}
+		return v, nil
+	case TypeEnum:
+		if ordered {
+			return nil, errcode.NewInvalidArgument("%s does not support %s", name, op)
+		}
+		v, ok := field.Enum[text]
+		if !ok {
+			return nil, errcode.NewInvalidArgument("unknown value of %s: %s", name, text)
+		}
+		return v, nil
+	case TypeTimestamp:
+		for _, layout := range timestampLayouts {
+			if v, err := time.Parse(layout, text); err == nil {
+				return v, nil
+			}
+		}
+		return nil, errcode.NewInvalidArgument("%s requires a timestamp like \"2006-01-02T15:04:05Z\": %s", name, text)
+	}
+	return nil, errcode.NewInvalidArgument("unsupported field in filter: %s", name)
+}
@@ -0,0 +1,73 @@
+package filter
+
+import (
+	"fmt"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+)
+
+// SQLOptions adapts the compiled SQL to a backend.
+type SQLOptions struct {
+	// Column returns the column of a field of the schema.
+	Column func(field string) string
+	// Placeholder returns the placeholder of the n-th argument, from 1.
+	Placeholder func(n int) string
+	// Value optionally converts the value compared with field to the
+	// representation stored in the column.
+	Value func(field string, v interface{}) interface{}
+	// Map compiles the comparisons of map fields, which have a Key or OpHas,
+	// appending their values to args. A comparison of a missing key or of a
+	// value of another type must be false rather than NULL, so that NOT
+	// matches as in Eval. Schemas without maps leave it nil.
+	Map func(c *Comparison, args []interface{}) (string, []interface{})
+}
+
+// SQL compiles e into a condition for a WHERE clause. The values are appended
+// to args as parameters and never written into the SQL.
+func SQL(e Expr, args []interface{}, opts *SQLOptions) (string, []interface{}, error) {
+	switch e := e.(type) {
+	case nil:
+		return "TRUE", args, nil
+	case *And:
+		return sqlBinary("AND", e.Left, e.Right, args, opts)
+	case *Or:
+		return sqlBinary("OR", e.Left, e.Right, args, opts)
+	case *Not:
+		cond, args, err := SQL(e.Expr, args, opts)
+		if err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("(NOT %s)", cond), args, nil
+	case *Comparison:
+		if e.Key != "" || e.Op == OpHas {
+			if opts.Map == nil {
+				return "", nil, errcode.NewInvalidArgument("filter: %s is not a map", e.Field)
+			}
+			cond, args := opts.Map(e, args)
+			return cond, args, nil
+		}
+		v := e.Value
+		if opts.Value != nil {
+			v = opts.Value(e.Field, v)
+		}
+		args = append(args, v)
+		op := string(e.Op)
+		if e.Op == OpNe {
+			op = "<>"
+		}
+		return fmt.Sprintf("%s %s %s", opts.Column(e.Field), op, opts.Placeholder(len(args))), args, nil
+	}
+	return "", nil, errcode.NewInvalidArgument("filter: unknown expression %T", e)
+}
+
+func sqlBinary(op string, left, right Expr, args []interface{}, opts *SQLOptions) (string, []interface{}, error) {
+	l, args, err := SQL(left, args, opts)
+	if err != nil {
+		return "", nil, err
+	}
+	r, args, err := SQL(right, args, opts)
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("(%s %s %s)", l, op, r), args, nil
+}
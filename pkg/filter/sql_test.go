@@ -0,0 +1,55 @@
+package filter
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQL(t *testing.T) {
+	opts := &SQLOptions{
+		Column:      func(field string) string { return "u." + field },
+		Placeholder: func(n int) string { return fmt.Sprintf("$%d", n) },
+	}
+
+	e, err := Parse(`gender = FEMALE AND (name != "x'; DROP TABLE users; --" OR NOT updated_at < "2026-01-01")`, testSchema)
+	require.NoError(t, err)
+	// one argument is already bound by the caller
+	cond, args, err := SQL(e, []interface{}{"tenant"}, opts)
+	require.NoError(t, err)
+	require.Equal(t, "(u.gender = $2 AND (u.name <> $3 OR (NOT u.updated_at < $4)))", cond)
+	require.Equal(t, []interface{}{"tenant", int64(2), "x'; DROP TABLE users; --", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}, args)
+
+	cond, args, err = SQL(nil, nil, opts)
+	require.NoError(t, err)
+	require.Equal(t, "TRUE", cond)
+	require.Empty(t, args)
+
+	// values are converted to the representation of the column
+	opts.Value = func(field string, v interface{}) interface{} { return fmt.Sprint(v) }
+	e, err = Parse(`age = 3`, testSchema)
+	require.NoError(t, err)
+	_, args, err = SQL(e, nil, opts)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"3"}, args)
+
+	// map comparisons are left to the backend, which must support them
+	e, err = Parse(`labels.team = core`, testSchema)
+	require.NoError(t, err)
+	_, _, err = SQL(e, nil, opts)
+	require.True(t, errcode.IsInvalidArgument(err))
+
+	opts.Map = func(c *Comparison, args []interface{}) (string, []interface{}) {
+		args = append(args, c.Key, c.Value)
+		return fmt.Sprintf("m(%s, %s, $%d, $%d)", c.Field, c.Op, len(args)-1, len(args)), args
+	}
+	e, err = Parse(`labels.team = core OR labels:beta`, testSchema)
+	require.NoError(t, err)
+	cond, args, err = SQL(e, nil, opts)
+	require.NoError(t, err)
+	require.Equal(t, "(m(labels, =, $1, $2) OR m(labels, :, $3, $4))", cond)
+	require.Equal(t, []interface{}{"team", "core", "", "beta"}, args)
+}
@@ -0,0 +1,77 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/auth"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const authorizationHeader = "authorization"
+
+type AuthInterceptor struct {
+	authenticator auth.Authenticator
+	public        map[string]bool
+}
+
+// NewAuthInterceptor returns an interceptor that requires a bearer token on
+// every call except the given full method names, e.g.
+// "/e_architecture.api.EArchitecture/GetUser".
+func NewAuthInterceptor(a auth.Authenticator, publicMethods ...string) *AuthInterceptor {
+	public := make(map[string]bool, len(publicMethods))
+	for _, m := range publicMethods {
+		public[m] = true
+	}
+	return &AuthInterceptor{authenticator: a, public: public}
+}
+
+func (i *AuthInterceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := i.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (i *AuthInterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := i.authenticate(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, wrapServerStream(ss, ctx))
+	}
+}
+
+func (i *AuthInterceptor) authenticate(ctx context.Context, fullMethod string) (context.Context, error) {
+	token, err := bearerToken(ctx)
+	if err != nil {
+		if i.public[fullMethod] {
+			return ctx, nil
+		}
+		return nil, err
+	}
+	p, err := i.authenticator.Authenticate(ctx, token)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return auth.NewContext(ctx, p), nil
+}
+
+func bearerToken(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(authorizationHeader)
+	if len(values) == 0 {
+		return "", errcode.NewUnauthenticated("missing %s metadata", authorizationHeader)
+	}
+	scheme, token, ok := strings.Cut(values[0], " ")
+	if !ok || !strings.EqualFold(scheme, "bearer") || token == "" {
+		return "", errcode.NewUnauthenticated("%s metadata is not a bearer token", authorizationHeader)
+	}
+	return token, nil
+}
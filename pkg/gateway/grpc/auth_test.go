@@ -0,0 +1,52 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/auth"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthInterceptor_Unary(t *testing.T) {
+	const (
+		method       = "/e_architecture.api.EArchitecture/GetUser"
+		publicMethod = "/e_architecture.api.EArchitecture/CreateUser"
+	)
+	interceptor := NewAuthInterceptor(auth.NewAPIKey([]*auth.APIKeyEntry{{Key: "key", Subject: "user"}}), publicMethod).Unary()
+
+	tests := []struct {
+		name        string
+		method      string
+		md          metadata.MD
+		wantCode    codes.Code
+		wantSubject string
+	}{
+		{name: "valid", method: method, md: metadata.Pairs("authorization", "Bearer key"), wantCode: codes.OK, wantSubject: "user"},
+		{name: "lower case scheme", method: method, md: metadata.Pairs("authorization", "bearer key"), wantCode: codes.OK, wantSubject: "user"},
+		{name: "missing", method: method, md: metadata.MD{}, wantCode: codes.Unauthenticated},
+		{name: "not bearer", method: method, md: metadata.Pairs("authorization", "Basic key"), wantCode: codes.Unauthenticated},
+		{name: "invalid", method: method, md: metadata.Pairs("authorization", "Bearer other"), wantCode: codes.Unauthenticated},
+		{name: "public anonymous", method: publicMethod, md: metadata.MD{}, wantCode: codes.OK},
+		{name: "public invalid", method: publicMethod, md: metadata.Pairs("authorization", "Bearer other"), wantCode: codes.Unauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+			var subject string
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				if p, ok := auth.FromContext(ctx); ok {
+					subject = p.Subject
+				}
+				return "ok", nil
+			}
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			require.Equal(t, tt.wantCode, status.Code(err))
+			require.Equal(t, tt.wantSubject, subject)
+		})
+	}
+}
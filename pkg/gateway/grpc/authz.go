@@ -0,0 +1,38 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/authz"
+	"google.golang.org/grpc"
+)
+
+// AuthzInterceptor enforces the authorization policy on every call. It must
+// run after AuthInterceptor so that the principal is available in the context.
+type AuthzInterceptor struct {
+	engine *authz.Engine
+}
+
+func NewAuthzInterceptor(engine *authz.Engine) *AuthzInterceptor {
+	return &AuthzInterceptor{engine: engine}
+}
+
+func (i *AuthzInterceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := i.engine.Authorize(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (i *AuthzInterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := i.engine.Authorize(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, wrapServerStream(ss, ctx))
+	}
+}
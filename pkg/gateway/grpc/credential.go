@@ -0,0 +1,49 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/proto/api"
+	"github.com/SakataAtsuki/e-architecture/pkg/usecase"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+)
+
+func (s *Service) SetPassword(ctx context.Context, in *api.SetPasswordRequest) (*api.SetPasswordResponse, error) {
+	req := &usecase.SetPasswordRequest{
+		ID:       in.Id,
+		Password: in.Password,
+	}
+	if _, err := s.uc.SetPassword(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.SetPasswordResponse{}, nil
+}
+
+func (s *Service) VerifyPassword(ctx context.Context, in *api.VerifyPasswordRequest) (*api.VerifyPasswordResponse, error) {
+	req := &usecase.VerifyPasswordRequest{
+		ID:       in.Id,
+		Password: in.Password,
+	}
+	resp, err := s.uc.VerifyPassword(ctx, req)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.VerifyPasswordResponse{Valid: resp.Valid}, nil
+}
+
+func (s *Service) Login(ctx context.Context, in *api.LoginRequest) (*api.LoginResponse, error) {
+	req := &usecase.LoginRequest{
+		ID:       in.Id,
+		Email:    in.Email,
+		Password: in.Password,
+	}
+	resp, err := s.uc.Login(ctx, req)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.LoginResponse{
+		Token:     resp.Token,
+		ExpiresAt: resp.ExpiresAt.Unix(),
+		User:      resp.User.Proto(),
+	}, nil
+}
@@ -0,0 +1,55 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/auth"
+	"github.com/SakataAtsuki/e-architecture/pkg/proto/api"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/memory"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/usecase"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_Login(t *testing.T) {
+	secret := []byte("secret")
+	issuer, err := auth.NewIssuer(&auth.IssuerConfig{HS256Secret: secret})
+	require.NoError(t, err)
+	users := memory.NewUser()
+	s := New(usecase.New(&usecase.Config{
+		DB:        &repository.Database{User: users, Credential: memory.NewCredential(users)},
+		Passwords: auth.NewPasswords(auth.Argon2Params{Time: 1, Memory: 64, Threads: 1}),
+		Tokens:    issuer,
+	}))
+	ctx := tenant.NewContext(context.Background(), tenant.Default)
+	_, err = s.CreateUser(ctx, &api.CreateUserRequest{User: &api.User{Id: "1", Name: "alice", Email: "alice@example.com"}})
+	require.NoError(t, err)
+
+	_, err = s.SetPassword(ctx, &api.SetPasswordRequest{Id: "1", Password: "short"})
+	require.True(t, errcode.IsInvalidArgument(err), err)
+	_, err = s.SetPassword(ctx, &api.SetPasswordRequest{Id: "1", Password: "correct horse battery"})
+	require.NoError(t, err)
+
+	verified, err := s.VerifyPassword(ctx, &api.VerifyPasswordRequest{Id: "1", Password: "wrong password"})
+	require.NoError(t, err)
+	require.False(t, verified.Valid)
+
+	resp, err := s.Login(ctx, &api.LoginRequest{Email: "alice@example.com", Password: "correct horse battery"})
+	require.NoError(t, err)
+	require.Equal(t, "1", resp.User.Id)
+	require.NotZero(t, resp.ExpiresAt)
+
+	// the token authenticates the calls of the user
+	jwt, err := auth.NewJWT(&auth.JWTConfig{HS256Secret: secret})
+	require.NoError(t, err)
+	p, err := jwt.Authenticate(ctx, resp.Token)
+	require.NoError(t, err)
+	require.Equal(t, "1", p.Subject)
+	require.Equal(t, tenant.Default, p.Tenant)
+
+	_, err = s.Login(ctx, &api.LoginRequest{Id: "1", Password: "wrong password"})
+	require.True(t, errcode.IsUnauthenticated(err), err)
+}
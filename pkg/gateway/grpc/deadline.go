@@ -0,0 +1,84 @@
+package grpc
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"google.golang.org/grpc"
+	"gopkg.in/yaml.v3"
+)
+
+// Deadline bounds how long a call may run. Default applies when the client
+// sets no deadline and Max caps the deadline set by the client. Zero values
+// leave the deadline as it is.
+type Deadline struct {
+	Default time.Duration `yaml:"default"`
+	Max     time.Duration `yaml:"max"`
+}
+
+// DeadlineConfig holds the deadlines applied to each method.
+//
+//	default: {default: 5s, max: 30s}
+//	methods:
+//	  /e_architecture.api.EArchitecture/GetUser: {default: 1s, max: 5s}
+type DeadlineConfig struct {
+	// Default applies to methods without their own deadline.
+	Default Deadline            `yaml:"default"`
+	Methods map[string]Deadline `yaml:"methods"`
+}
+
+// DefaultDeadlineConfig is used when no deadline configuration is given.
+func DefaultDeadlineConfig() *DeadlineConfig {
+	return &DeadlineConfig{Default: Deadline{Default: 10 * time.Second, Max: 60 * time.Second}}
+}
+
+func LoadDeadlineConfig(path string) (*DeadlineConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	cfg := &DeadlineConfig{}
+	if err := yaml.Unmarshal(b, cfg); err != nil {
+		return nil, errcode.New(err)
+	}
+	return cfg, nil
+}
+
+func (c *DeadlineConfig) deadline(fullMethod string) Deadline {
+	if d, ok := c.Methods[fullMethod]; ok {
+		return d
+	}
+	return c.Default
+}
+
+// DeadlineUnaryServerInterceptor applies the configured deadline of the method
+// to the context so that handlers and the queries they run are canceled once
+// it passes.
+func DeadlineUnaryServerInterceptor(cfg *DeadlineConfig) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, cancel := withDeadline(ctx, cfg.deadline(info.FullMethod), time.Now())
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+func DeadlineStreamServerInterceptor(cfg *DeadlineConfig) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, cancel := withDeadline(ss.Context(), cfg.deadline(info.FullMethod), time.Now())
+		defer cancel()
+		return handler(srv, wrapServerStream(ss, ctx))
+	}
+}
+
+func withDeadline(ctx context.Context, d Deadline, now time.Time) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	switch {
+	case !ok && d.Default > 0:
+		return context.WithDeadline(ctx, now.Add(d.Default))
+	case !ok && d.Max > 0, ok && d.Max > 0 && deadline.After(now.Add(d.Max)):
+		return context.WithDeadline(ctx, now.Add(d.Max))
+	}
+	return ctx, func() {}
+}
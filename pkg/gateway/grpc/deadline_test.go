@@ -0,0 +1,83 @@
+package grpc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWithDeadline(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		deadline time.Duration
+		d        Deadline
+		want     time.Duration
+	}{
+		{name: "default", d: Deadline{Default: time.Second, Max: time.Minute}, want: time.Second},
+		{name: "max without default", d: Deadline{Max: time.Minute}, want: time.Minute},
+		{name: "client deadline within max", deadline: 5 * time.Second, d: Deadline{Default: time.Second, Max: time.Minute}, want: 5 * time.Second},
+		{name: "client deadline capped", deadline: time.Hour, d: Deadline{Default: time.Second, Max: time.Minute}, want: time.Minute},
+		{name: "client deadline without max", deadline: time.Hour, d: Deadline{Default: time.Second}, want: time.Hour},
+		{name: "unbounded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.deadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithDeadline(ctx, now.Add(tt.deadline))
+				defer cancel()
+			}
+			ctx, cancel := withDeadline(ctx, tt.d, now)
+			defer cancel()
+			deadline, ok := ctx.Deadline()
+			if tt.want == 0 {
+				require.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			require.Equal(t, now.Add(tt.want), deadline)
+		})
+	}
+}
+
+func TestDeadlineUnaryServerInterceptor(t *testing.T) {
+	const method = "/e_architecture.api.EArchitecture/GetUser"
+	interceptor := DeadlineUnaryServerInterceptor(&DeadlineConfig{
+		Default: Deadline{Default: time.Minute},
+		Methods: map[string]Deadline{method: {Default: 10 * time.Millisecond}},
+	})
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		<-ctx.Done()
+		return nil, errcode.New(ctx.Err())
+	}
+
+	_, err := ErrorUnaryServerInterceptor()(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		})
+	require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
+
+func TestLoadDeadlineConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deadline.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+default: {default: 5s, max: 30s}
+methods:
+  /e_architecture.api.EArchitecture/ListUsers: {default: 1m, max: 2m}
+`), 0o600))
+
+	cfg, err := LoadDeadlineConfig(path)
+	require.NoError(t, err)
+	require.Equal(t, Deadline{Default: 5 * time.Second, Max: 30 * time.Second}, cfg.deadline("/e_architecture.api.EArchitecture/GetUser"))
+	require.Equal(t, Deadline{Default: time.Minute, Max: 2 * time.Minute}, cfg.deadline("/e_architecture.api.EArchitecture/ListUsers"))
+}
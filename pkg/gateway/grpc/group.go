@@ -0,0 +1,136 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/proto/api"
+	"github.com/SakataAtsuki/e-architecture/pkg/usecase"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+)
+
+func (s *Service) CreateGroup(ctx context.Context, in *api.CreateGroupRequest) (*api.CreateGroupResponse, error) {
+	if in.GetGroup() == nil {
+		return nil, errcode.NewInvalidArgument("group is required")
+	}
+	req := &usecase.CreateGroupRequest{
+		Group: &entity.Group{
+			ID:          in.Group.Id,
+			Name:        in.Group.Name,
+			Description: in.Group.Description,
+		},
+		OwnerID: in.OwnerId,
+	}
+	resp, err := s.uc.CreateGroup(ctx, req)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.CreateGroupResponse{Group: resp.Group.Proto()}, nil
+}
+
+func (s *Service) GetGroup(ctx context.Context, in *api.GetGroupRequest) (*api.GetGroupResponse, error) {
+	req := &usecase.GetGroupRequest{
+		ID: in.Id,
+	}
+	resp, err := s.uc.GetGroup(ctx, req)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.GetGroupResponse{Group: resp.Group.Proto()}, nil
+}
+
+func (s *Service) ListGroups(ctx context.Context, in *api.ListGroupsRequest) (*api.ListGroupsResponse, error) {
+	req := &usecase.ListGroupsRequest{
+		PageSize:  int(in.PageSize),
+		PageToken: in.PageToken,
+	}
+	resp, err := s.uc.ListGroups(ctx, req)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.ListGroupsResponse{Groups: resp.Groups.Proto(), NextPageToken: resp.NextPageToken}, nil
+}
+
+func (s *Service) UpdateGroup(ctx context.Context, in *api.UpdateGroupRequest) (*api.UpdateGroupResponse, error) {
+	if in.GetGroup() == nil {
+		return nil, errcode.NewInvalidArgument("group is required")
+	}
+	req := &usecase.UpdateGroupRequest{
+		Group: &entity.Group{
+			ID:          in.Group.Id,
+			Name:        in.Group.Name,
+			Description: in.Group.Description,
+		},
+	}
+	resp, err := s.uc.UpdateGroup(ctx, req)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.UpdateGroupResponse{Group: resp.Group.Proto()}, nil
+}
+
+func (s *Service) DeleteGroup(ctx context.Context, in *api.DeleteGroupRequest) (*api.DeleteGroupResponse, error) {
+	req := &usecase.DeleteGroupRequest{
+		ID: in.Id,
+	}
+	if err := s.uc.DeleteGroup(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.DeleteGroupResponse{}, nil
+}
+
+func (s *Service) AddMember(ctx context.Context, in *api.AddMemberRequest) (*api.AddMemberResponse, error) {
+	req := &usecase.AddMemberRequest{
+		Member: &entity.GroupMember{
+			GroupID: in.GroupId,
+			UserID:  in.UserId,
+			Role:    entity.GroupRole(in.Role),
+		},
+	}
+	resp, err := s.uc.AddMember(ctx, req)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.AddMemberResponse{Member: resp.Member.Proto()}, nil
+}
+
+func (s *Service) RemoveMember(ctx context.Context, in *api.RemoveMemberRequest) (*api.RemoveMemberResponse, error) {
+	req := &usecase.RemoveMemberRequest{
+		GroupID: in.GroupId,
+		UserID:  in.UserId,
+	}
+	if err := s.uc.RemoveMember(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.RemoveMemberResponse{}, nil
+}
+
+func (s *Service) ListMembers(ctx context.Context, in *api.ListMembersRequest) (*api.ListMembersResponse, error) {
+	req := &usecase.ListMembersRequest{
+		GroupID:   in.GroupId,
+		PageSize:  int(in.PageSize),
+		PageToken: in.PageToken,
+	}
+	resp, err := s.uc.ListMembers(ctx, req)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.ListMembersResponse{Members: resp.Members.Proto(), NextPageToken: resp.NextPageToken}, nil
+}
+
+func (s *Service) ListUserGroups(ctx context.Context, in *api.ListUserGroupsRequest) (*api.ListUserGroupsResponse, error) {
+	req := &usecase.ListUserGroupsRequest{
+		UserID:    in.UserId,
+		PageSize:  int(in.PageSize),
+		PageToken: in.PageToken,
+	}
+	resp, err := s.uc.ListUserGroups(ctx, req)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	groups := make([]*api.UserGroup, 0, len(resp.Groups))
+	for _, g := range resp.Groups {
+		groups = append(groups, &api.UserGroup{Group: g.Group.Proto(), Role: api.GroupRole(g.Role)})
+	}
+	return &api.ListUserGroupsResponse{Groups: groups, NextPageToken: resp.NextPageToken}, nil
+}
@@ -0,0 +1,65 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/proto/api"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/memory"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/usecase"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestService_Group(t *testing.T) {
+	users := memory.NewUser()
+	s := New(usecase.New(&usecase.Config{
+		DB: &repository.Database{User: users, Group: memory.NewGroup(users)},
+	}))
+	ctx := tenant.NewContext(context.Background(), tenant.Default)
+	for _, id := range []string{"alice", "bob"} {
+		_, err := s.CreateUser(ctx, &api.CreateUserRequest{User: &api.User{Id: id, Name: id}})
+		require.NoError(t, err)
+	}
+
+	_, err := s.CreateGroup(ctx, &api.CreateGroupRequest{})
+	require.True(t, errcode.IsInvalidArgument(err), err)
+	created, err := s.CreateGroup(ctx, &api.CreateGroupRequest{Group: &api.Group{Id: "core", Name: "core"}, OwnerId: "alice"})
+	require.NoError(t, err)
+	require.NotZero(t, created.Group.CreatedAt)
+
+	added, err := s.AddMember(ctx, &api.AddMemberRequest{GroupId: "core", UserId: "bob", Role: api.GroupRole_ADMIN})
+	require.NoError(t, err)
+	require.Equal(t, api.GroupRole_ADMIN, added.Member.Role)
+	// members must exist
+	_, err = s.AddMember(ctx, &api.AddMemberRequest{GroupId: "core", UserId: "carol"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(errcode.NewGrpcError(err)))
+
+	members, err := s.ListMembers(ctx, &api.ListMembersRequest{GroupId: "core", PageSize: 1})
+	require.NoError(t, err)
+	require.Len(t, members.Members, 1)
+	require.Equal(t, "alice", members.Members[0].UserId)
+	require.Equal(t, api.GroupRole_OWNER, members.Members[0].Role)
+	members, err = s.ListMembers(ctx, &api.ListMembersRequest{GroupId: "core", PageSize: 1, PageToken: members.NextPageToken})
+	require.NoError(t, err)
+	require.Equal(t, "bob", members.Members[0].UserId)
+	require.Empty(t, members.NextPageToken)
+
+	groups, err := s.ListUserGroups(ctx, &api.ListUserGroupsRequest{UserId: "bob"})
+	require.NoError(t, err)
+	require.Len(t, groups.Groups, 1)
+	require.Equal(t, "core", groups.Groups[0].Group.Id)
+	require.Equal(t, api.GroupRole_ADMIN, groups.Groups[0].Role)
+
+	_, err = s.RemoveMember(ctx, &api.RemoveMemberRequest{GroupId: "core", UserId: "bob"})
+	require.NoError(t, err)
+	_, err = s.DeleteGroup(ctx, &api.DeleteGroupRequest{Id: "core"})
+	require.NoError(t, err)
+	listed, err := s.ListGroups(ctx, &api.ListGroupsRequest{})
+	require.NoError(t, err)
+	require.Empty(t, listed.Groups)
+}
@@ -0,0 +1,45 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"google.golang.org/grpc"
+)
+
+// serverStream overrides the context of a grpc.ServerStream so that stream
+// interceptors can pass values down to the handler.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func wrapServerStream(ss grpc.ServerStream, ctx context.Context) grpc.ServerStream {
+	return &serverStream{ServerStream: ss, ctx: ctx}
+}
+
+// ErrorUnaryServerInterceptor converts errcode errors returned by handlers into
+// gRPC status errors. It must be the outermost interceptor so that the others
+// still see the errcode errors.
+func ErrorUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return nil, errcode.NewGrpcError(err)
+		}
+		return resp, nil
+	}
+}
+
+func ErrorStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, ss); err != nil {
+			return errcode.NewGrpcError(err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,69 @@
+package grpc
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/log"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/requestid"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// LoggingUnaryServerInterceptor logs every call with its method, duration,
+// status code, peer and request ID. The method and request ID are also
+// attached to the context so that logs of usecases and repositories carry them.
+func LoggingUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx = withCallFields(ctx, info.FullMethod)
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(ctx, time.Since(start), err)
+		return resp, err
+	}
+}
+
+func LoggingStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := withCallFields(ss.Context(), info.FullMethod)
+		start := time.Now()
+		err := handler(srv, wrapServerStream(ss, ctx))
+		logCall(ctx, time.Since(start), err)
+		return err
+	}
+}
+
+func withCallFields(ctx context.Context, fullMethod string) context.Context {
+	args := []any{"method", fullMethod}
+	if id, ok := requestid.FromContext(ctx); ok {
+		args = append(args, "request_id", id)
+	}
+	if sc := oteltrace.SpanContextFromContext(ctx); sc.IsValid() {
+		args = append(args, "trace_id", sc.TraceID().String())
+	}
+	return log.With(ctx, args...)
+}
+
+func logCall(ctx context.Context, duration time.Duration, err error) {
+	code := status.Code(err)
+	attrs := []any{
+		slog.String("grpc_code", code.String()),
+		slog.Duration("duration", duration),
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		attrs = append(attrs, slog.String("peer", p.Addr.String()))
+	}
+	switch {
+	case err == nil:
+		log.Info(ctx, "finished call", attrs...)
+	case errcode.IsServerError(err) || code == codes.Internal || code == codes.Unknown:
+		log.Error(ctx, "finished call", append(attrs, log.Err(err))...)
+	default:
+		log.Warn(ctx, "finished call", append(attrs, log.Err(err))...)
+	}
+}
@@ -0,0 +1,54 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/log"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/requestid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestLoggingUnaryServerInterceptor(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := log.New(&buf, log.FormatJSON, "info")
+	require.NoError(t, err)
+	prev := slog.Default()
+	log.SetDefault(logger)
+	t.Cleanup(func() { log.SetDefault(prev) })
+
+	interceptor := LoggingUnaryServerInterceptor()
+	ctx := requestid.NewContext(context.Background(), "req-1")
+	info := &grpc.UnaryServerInfo{FullMethod: "/e_architecture.api.EArchitecture/GetUser"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		log.Info(ctx, "in handler")
+		return nil, errcode.NewNotFound("user not found")
+	}
+	_, err = interceptor(ctx, nil, info, handler)
+	require.True(t, errcode.IsNotfound(err))
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+	for _, line := range lines {
+		var got map[string]interface{}
+		require.NoError(t, json.Unmarshal(line, &got))
+		require.Equal(t, info.FullMethod, got["method"])
+		require.Equal(t, "req-1", got["request_id"])
+	}
+
+	var got struct {
+		Level    string            `json:"level"`
+		GrpcCode string            `json:"grpc_code"`
+		Error    map[string]string `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(lines[1], &got))
+	require.Equal(t, "WARN", got.Level)
+	require.Equal(t, "NotFound", got.GrpcCode)
+	require.Equal(t, "Not found", got.Error["code"])
+	require.NotEmpty(t, got.Error["stack"])
+}
@@ -0,0 +1,27 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/metrics"
+	"google.golang.org/grpc"
+)
+
+func MetricsUnaryServerInterceptor(m *metrics.Metrics) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		m.RPCDuration.WithLabelValues(info.FullMethod, metrics.Code(err)).Observe(time.Since(start).Seconds())
+		return resp, err
+	}
+}
+
+func MetricsStreamServerInterceptor(m *metrics.Metrics) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		m.RPCDuration.WithLabelValues(info.FullMethod, metrics.Code(err)).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
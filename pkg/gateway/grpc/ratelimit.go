@@ -0,0 +1,183 @@
+package grpc
+
+import (
+	"context"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/auth"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	retryAfterTrailer = "retry-after"
+	// buckets idle for bucketIdleTTL are dropped to bound the memory, but
+	// only once they have had the time to refill, as new buckets start full.
+	bucketIdleTTL = 10 * time.Minute
+)
+
+// Quota is a token bucket refilled with Rate tokens per second up to Burst.
+// A zero Rate disables the limit.
+type Quota struct {
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+}
+
+// RateLimitConfig holds the quotas applied to each client, identified by the
+// authenticated principal or else the peer IP.
+//
+//	default: {rate: 10, burst: 20}
+//	methods:
+//	  /e_architecture.api.EArchitecture/ListUsers: {rate: 1, burst: 5}
+type RateLimitConfig struct {
+	// Default applies to methods without their own quota.
+	Default Quota            `yaml:"default"`
+	Methods map[string]Quota `yaml:"methods"`
+}
+
+func LoadRateLimitConfig(path string) (*RateLimitConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	cfg := &RateLimitConfig{}
+	if err := yaml.Unmarshal(b, cfg); err != nil {
+		return nil, errcode.New(err)
+	}
+	return cfg, nil
+}
+
+func (c *RateLimitConfig) quota(fullMethod string) Quota {
+	if q, ok := c.Methods[fullMethod]; ok {
+		return q
+	}
+	return c.Default
+}
+
+type bucketKey struct {
+	client string
+	method string
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+	// refill is how long the empty bucket takes to fill up.
+	refill time.Duration
+}
+
+type RateLimitInterceptor struct {
+	cfg *RateLimitConfig
+	now func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[bucketKey]*bucket
+	lastSweep time.Time
+}
+
+// NewRateLimitInterceptor returns an interceptor that rejects calls exceeding
+// the quota with CodeResourceExhausted and a retry-after trailer in seconds.
+// It must run after AuthInterceptor to limit per principal.
+func NewRateLimitInterceptor(cfg *RateLimitConfig) *RateLimitInterceptor {
+	return &RateLimitInterceptor{
+		cfg:     cfg,
+		now:     time.Now,
+		buckets: map[bucketKey]*bucket{},
+	}
+}
+
+func (i *RateLimitInterceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if retryAfter, ok := i.allow(ctx, info.FullMethod); !ok {
+			_ = grpc.SetTrailer(ctx, retryAfterMD(retryAfter))
+			return nil, errcode.NewResourceExhausted("rate limit exceeded for %s", info.FullMethod)
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (i *RateLimitInterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if retryAfter, ok := i.allow(ss.Context(), info.FullMethod); !ok {
+			ss.SetTrailer(retryAfterMD(retryAfter))
+			return errcode.NewResourceExhausted("rate limit exceeded for %s", info.FullMethod)
+		}
+		return handler(srv, ss)
+	}
+}
+
+// allow takes a token from the bucket of the caller. When the bucket is empty
+// it returns how long the caller should wait.
+func (i *RateLimitInterceptor) allow(ctx context.Context, fullMethod string) (time.Duration, bool) {
+	q := i.cfg.quota(fullMethod)
+	if q.Rate <= 0 {
+		return 0, true
+	}
+	now := i.now()
+	key := bucketKey{client: clientKey(ctx), method: fullMethod}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.sweep(now)
+	b, ok := i.buckets[key]
+	if !ok {
+		burst := q.Burst
+		if burst < 1 {
+			burst = 1
+		}
+		b = &bucket{
+			limiter: rate.NewLimiter(rate.Limit(q.Rate), burst),
+			refill:  time.Duration(float64(burst) / q.Rate * float64(time.Second)),
+		}
+		i.buckets[key] = b
+	}
+	b.lastSeen = now
+
+	r := b.limiter.ReserveN(now, 1)
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return delay, false
+	}
+	return 0, true
+}
+
+func (i *RateLimitInterceptor) sweep(now time.Time) {
+	if now.Sub(i.lastSweep) < bucketIdleTTL {
+		return
+	}
+	for key, b := range i.buckets {
+		if idle := now.Sub(b.lastSeen); idle >= bucketIdleTTL && idle >= b.refill {
+			delete(i.buckets, key)
+		}
+	}
+	i.lastSweep = now
+}
+
+func clientKey(ctx context.Context) string {
+	if p, ok := auth.FromContext(ctx); ok {
+		return "principal:" + p.Tenant + "/" + p.Subject
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		host, _, err := net.SplitHostPort(p.Addr.String())
+		if err != nil {
+			host = p.Addr.String()
+		}
+		return "ip:" + host
+	}
+	return "anonymous"
+}
+
+func retryAfterMD(d time.Duration) metadata.MD {
+	seconds := int64(math.Ceil(d.Seconds()))
+	return metadata.Pairs(retryAfterTrailer, strconv.FormatInt(seconds, 10))
+}
@@ -0,0 +1,110 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/auth"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestRateLimitInterceptor(t *testing.T) {
+	const (
+		method  = "/e_architecture.api.EArchitecture/GetUser"
+		limited = "/e_architecture.api.EArchitecture/ListUsers"
+	)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	i := NewRateLimitInterceptor(&RateLimitConfig{
+		Default: Quota{Rate: 1, Burst: 2},
+		Methods: map[string]Quota{limited: {Rate: 0.1, Burst: 1}},
+	})
+	i.now = func() time.Time { return now }
+
+	alice := auth.NewContext(context.Background(), &auth.Principal{Subject: "alice"})
+	bob := auth.NewContext(context.Background(), &auth.Principal{Subject: "bob"})
+
+	// burst, then rejected until a token is refilled
+	for n := 0; n < 2; n++ {
+		_, ok := i.allow(alice, method)
+		require.True(t, ok)
+	}
+	retryAfter, ok := i.allow(alice, method)
+	require.False(t, ok)
+	require.Equal(t, time.Second, retryAfter)
+
+	// other clients and methods have their own buckets
+	_, ok = i.allow(bob, method)
+	require.True(t, ok)
+	_, ok = i.allow(alice, limited)
+	require.True(t, ok)
+	retryAfter, ok = i.allow(alice, limited)
+	require.False(t, ok)
+	require.Equal(t, 10*time.Second, retryAfter)
+
+	now = now.Add(time.Second)
+	_, ok = i.allow(alice, method)
+	require.True(t, ok)
+
+	// idle buckets are dropped
+	now = now.Add(bucketIdleTTL)
+	_, ok = i.allow(bob, method)
+	require.True(t, ok)
+	require.Len(t, i.buckets, 1)
+}
+
+func TestRateLimitInterceptor_SlowRefill(t *testing.T) {
+	const method = "/e_architecture.api.EArchitecture/GetUser"
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	// an hour to refill
+	i := NewRateLimitInterceptor(&RateLimitConfig{Default: Quota{Rate: 1.0 / 60, Burst: 60}})
+	i.now = func() time.Time { return now }
+	alice := auth.NewContext(context.Background(), &auth.Principal{Subject: "alice"})
+
+	for n := 0; n < 60; n++ {
+		_, ok := i.allow(alice, method)
+		require.True(t, ok)
+	}
+
+	// the bucket outlives bucketIdleTTL while it is not full again
+	now = now.Add(bucketIdleTTL)
+	for n := 0; n < 10; n++ {
+		_, ok := i.allow(alice, method)
+		require.True(t, ok)
+	}
+	_, ok := i.allow(alice, method)
+	require.False(t, ok)
+
+	now = now.Add(2 * time.Hour)
+	_, ok = i.allow(context.Background(), method)
+	require.True(t, ok)
+	require.Len(t, i.buckets, 1)
+}
+
+func TestRateLimitInterceptor_Unary(t *testing.T) {
+	interceptor := NewRateLimitInterceptor(&RateLimitConfig{Default: Quota{Rate: 1, Burst: 1}}).Unary()
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1234}})
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/e_architecture.api.EArchitecture/GetUser"}
+
+	_, err := interceptor(ctx, nil, info, handler)
+	require.NoError(t, err)
+	_, err = interceptor(ctx, nil, info, handler)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// the port does not distinguish clients
+	ctx = peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 5678}})
+	_, err = interceptor(ctx, nil, info, handler)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestRetryAfterMD(t *testing.T) {
+	require.Equal(t, []string{"2"}, retryAfterMD(1500*time.Millisecond).Get("retry-after"))
+}
@@ -0,0 +1,45 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/log"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/metrics"
+	"google.golang.org/grpc"
+)
+
+// RecoveryUnaryServerInterceptor converts a panic in the handler into a
+// CodeInternal error carrying the stack trace of the panic, logs it and counts
+// it when m is not nil.
+func RecoveryUnaryServerInterceptor(m *metrics.Metrics) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(ctx, m, info.FullMethod, r)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+func RecoveryStreamServerInterceptor(m *metrics.Metrics) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(ss.Context(), m, info.FullMethod, r)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+func recovered(ctx context.Context, m *metrics.Metrics, fullMethod string, r interface{}) error {
+	// created in the deferred call, so the stack still contains the panicking frames
+	err := errcode.NewInternal("panic: %v", r)
+	log.Error(ctx, "recovered from panic", log.Err(err))
+	if m != nil {
+		m.Panics.WithLabelValues(fullMethod).Inc()
+	}
+	return err
+}
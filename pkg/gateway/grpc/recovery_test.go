@@ -0,0 +1,51 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+type panickingStream struct {
+	grpc.ServerStream
+}
+
+func (panickingStream) Context() context.Context {
+	return context.Background()
+}
+
+func TestRecoveryUnaryServerInterceptor(t *testing.T) {
+	m := metrics.New()
+	info := &grpc.UnaryServerInfo{FullMethod: "/e_architecture.api.EArchitecture/CreateUser"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		var user *struct{ ID string }
+		return user.ID, nil
+	}
+
+	_, err := RecoveryUnaryServerInterceptor(m)(context.Background(), nil, info, handler)
+	require.True(t, errcode.IsInternal(err))
+	var e *errcode.Error
+	require.ErrorAs(t, err, &e)
+	require.True(t, strings.Contains(e.Stack(), "TestRecoveryUnaryServerInterceptor"), e.Stack())
+	require.Equal(t, 1.0, testutil.ToFloat64(m.Panics.WithLabelValues(info.FullMethod)))
+
+	// without metrics
+	_, err = RecoveryUnaryServerInterceptor(nil)(context.Background(), nil, info, handler)
+	require.True(t, errcode.IsInternal(err))
+}
+
+func TestRecoveryStreamServerInterceptor(t *testing.T) {
+	info := &grpc.StreamServerInfo{FullMethod: "/e_architecture.api.EArchitecture/Watch"}
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		panic("boom")
+	}
+	err := RecoveryStreamServerInterceptor(nil)(nil, panickingStream{}, info, handler)
+	require.True(t, errcode.IsInternal(err))
+	require.Contains(t, err.Error(), "panic: boom")
+}
@@ -0,0 +1,94 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/proto/api"
+	"github.com/SakataAtsuki/e-architecture/pkg/usecase"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+)
+
+func (s *Service) Follow(ctx context.Context, in *api.FollowRequest) (*api.FollowResponse, error) {
+	req := &usecase.FollowRequest{
+		UserID:   in.UserId,
+		TargetID: in.TargetId,
+	}
+	resp, err := s.uc.Follow(ctx, req)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.FollowResponse{Relationship: resp.Relationship.Proto()}, nil
+}
+
+func (s *Service) Unfollow(ctx context.Context, in *api.UnfollowRequest) (*api.UnfollowResponse, error) {
+	req := &usecase.UnfollowRequest{
+		UserID:   in.UserId,
+		TargetID: in.TargetId,
+	}
+	if err := s.uc.Unfollow(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.UnfollowResponse{}, nil
+}
+
+func (s *Service) Block(ctx context.Context, in *api.BlockRequest) (*api.BlockResponse, error) {
+	req := &usecase.BlockRequest{
+		UserID:   in.UserId,
+		TargetID: in.TargetId,
+	}
+	resp, err := s.uc.Block(ctx, req)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.BlockResponse{Relationship: resp.Relationship.Proto()}, nil
+}
+
+func (s *Service) Unblock(ctx context.Context, in *api.UnblockRequest) (*api.UnblockResponse, error) {
+	req := &usecase.UnblockRequest{
+		UserID:   in.UserId,
+		TargetID: in.TargetId,
+	}
+	if err := s.uc.Unblock(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.UnblockResponse{}, nil
+}
+
+func (s *Service) ListFollowers(ctx context.Context, in *api.ListFollowersRequest) (*api.ListFollowersResponse, error) {
+	req := &usecase.ListRelationshipsRequest{
+		UserID:    in.UserId,
+		PageSize:  int(in.PageSize),
+		PageToken: in.PageToken,
+	}
+	resp, err := s.uc.ListFollowers(ctx, req)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.ListFollowersResponse{Relationships: resp.Relationships.Proto(), NextPageToken: resp.NextPageToken}, nil
+}
+
+func (s *Service) ListFollowing(ctx context.Context, in *api.ListFollowingRequest) (*api.ListFollowingResponse, error) {
+	req := &usecase.ListRelationshipsRequest{
+		UserID:    in.UserId,
+		PageSize:  int(in.PageSize),
+		PageToken: in.PageToken,
+	}
+	resp, err := s.uc.ListFollowing(ctx, req)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.ListFollowingResponse{Relationships: resp.Relationships.Proto(), NextPageToken: resp.NextPageToken}, nil
+}
+
+func (s *Service) ListMutualFollows(ctx context.Context, in *api.ListMutualFollowsRequest) (*api.ListMutualFollowsResponse, error) {
+	req := &usecase.ListRelationshipsRequest{
+		UserID:    in.UserId,
+		PageSize:  int(in.PageSize),
+		PageToken: in.PageToken,
+	}
+	resp, err := s.uc.ListMutualFollows(ctx, req)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.ListMutualFollowsResponse{Relationships: resp.Relationships.Proto(), NextPageToken: resp.NextPageToken}, nil
+}
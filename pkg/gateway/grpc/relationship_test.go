@@ -0,0 +1,65 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/proto/api"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/memory"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/usecase"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestService_Relationship(t *testing.T) {
+	users := memory.NewUser()
+	s := New(usecase.New(&usecase.Config{
+		DB: &repository.Database{User: users, Relationship: memory.NewRelationship(users)},
+	}))
+	ctx := tenant.NewContext(context.Background(), tenant.Default)
+	for _, id := range []string{"alice", "bob", "carol"} {
+		_, err := s.CreateUser(ctx, &api.CreateUserRequest{User: &api.User{Id: id, Name: id}})
+		require.NoError(t, err)
+	}
+
+	followed, err := s.Follow(ctx, &api.FollowRequest{UserId: "alice", TargetId: "bob"})
+	require.NoError(t, err)
+	require.Equal(t, api.RelationshipKind_FOLLOW, followed.Relationship.Kind)
+	require.NotZero(t, followed.Relationship.CreatedAt)
+	_, err = s.Follow(ctx, &api.FollowRequest{UserId: "bob", TargetId: "alice"})
+	require.NoError(t, err)
+	_, err = s.Follow(ctx, &api.FollowRequest{UserId: "carol", TargetId: "alice"})
+	require.NoError(t, err)
+
+	followers, err := s.ListFollowers(ctx, &api.ListFollowersRequest{UserId: "alice", PageSize: 1})
+	require.NoError(t, err)
+	require.Len(t, followers.Relationships, 1)
+	require.Equal(t, "bob", followers.Relationships[0].UserId)
+	followers, err = s.ListFollowers(ctx, &api.ListFollowersRequest{UserId: "alice", PageSize: 1, PageToken: followers.NextPageToken})
+	require.NoError(t, err)
+	require.Equal(t, "carol", followers.Relationships[0].UserId)
+	require.Empty(t, followers.NextPageToken)
+	mutual, err := s.ListMutualFollows(ctx, &api.ListMutualFollowsRequest{UserId: "alice"})
+	require.NoError(t, err)
+	require.Len(t, mutual.Relationships, 1)
+	require.Equal(t, "bob", mutual.Relationships[0].TargetId)
+
+	// blocked users cannot follow
+	blocked, err := s.Block(ctx, &api.BlockRequest{UserId: "alice", TargetId: "carol"})
+	require.NoError(t, err)
+	require.Equal(t, api.RelationshipKind_BLOCK, blocked.Relationship.Kind)
+	_, err = s.Follow(ctx, &api.FollowRequest{UserId: "carol", TargetId: "alice"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(errcode.NewGrpcError(err)))
+	_, err = s.Unblock(ctx, &api.UnblockRequest{UserId: "alice", TargetId: "carol"})
+	require.NoError(t, err)
+
+	_, err = s.Unfollow(ctx, &api.UnfollowRequest{UserId: "alice", TargetId: "bob"})
+	require.NoError(t, err)
+	following, err := s.ListFollowing(ctx, &api.ListFollowingRequest{UserId: "alice"})
+	require.NoError(t, err)
+	require.Empty(t, following.Relationships)
+}
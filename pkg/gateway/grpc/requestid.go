@@ -0,0 +1,48 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/requestid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const requestIDHeader = "x-request-id"
+
+// RequestIDUnaryServerInterceptor adopts the x-request-id of the request or
+// generates one, stores it in the context, returns it in the response header
+// and attaches it to errcode errors. It must run inside the error interceptor
+// and before the logging interceptor.
+func RequestIDUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		id := incomingRequestID(ctx)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDHeader, id))
+		resp, err := handler(requestid.NewContext(ctx, id), req)
+		if err != nil {
+			return nil, errcode.WithRequestID(err, id)
+		}
+		return resp, nil
+	}
+}
+
+func RequestIDStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		id := incomingRequestID(ss.Context())
+		_ = ss.SetHeader(metadata.Pairs(requestIDHeader, id))
+		err := handler(srv, wrapServerStream(ss, requestid.NewContext(ss.Context(), id)))
+		if err != nil {
+			return errcode.WithRequestID(err, id)
+		}
+		return nil
+	}
+}
+
+func incomingRequestID(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(requestIDHeader); len(values) > 0 && requestid.Valid(values[0]) {
+		return values[0]
+	}
+	return requestid.New()
+}
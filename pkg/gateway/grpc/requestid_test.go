@@ -0,0 +1,48 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/requestid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestRequestIDUnaryServerInterceptor(t *testing.T) {
+	interceptor := RequestIDUnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/e_architecture.api.EArchitecture/GetUser"}
+
+	tests := []struct {
+		name   string
+		md     metadata.MD
+		wantID string
+	}{
+		{name: "given", md: metadata.Pairs("x-request-id", "req-1"), wantID: "req-1"},
+		{name: "generated", md: metadata.MD{}},
+		{name: "invalid", md: metadata.Pairs("x-request-id", "req 1\n")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+			var got string
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				got, _ = requestid.FromContext(ctx)
+				return nil, errcode.NewNotFound("user not found")
+			}
+			_, err := interceptor(ctx, nil, info, handler)
+
+			require.True(t, requestid.Valid(got))
+			if tt.wantID != "" {
+				require.Equal(t, tt.wantID, got)
+			}
+			st := status.Convert(err)
+			require.Len(t, st.Details(), 1)
+			require.Equal(t, got, st.Details()[0].(*errdetails.RequestInfo).GetRequestId())
+		})
+	}
+}
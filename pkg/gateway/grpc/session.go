@@ -0,0 +1,63 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/proto/api"
+	"github.com/SakataAtsuki/e-architecture/pkg/usecase"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+)
+
+func (s *Service) CreateSession(ctx context.Context, in *api.CreateSessionRequest) (*api.CreateSessionResponse, error) {
+	req := &usecase.CreateSessionRequest{
+		UserID: in.UserId,
+	}
+	resp, err := s.uc.CreateSession(ctx, req)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.CreateSessionResponse{
+		Session:      resp.Session.Proto(),
+		AccessToken:  resp.AccessToken,
+		ExpiresAt:    resp.ExpiresAt.Unix(),
+		RefreshToken: resp.RefreshToken,
+	}, nil
+}
+
+func (s *Service) RefreshSession(ctx context.Context, in *api.RefreshSessionRequest) (*api.RefreshSessionResponse, error) {
+	req := &usecase.RefreshSessionRequest{
+		RefreshToken: in.RefreshToken,
+	}
+	resp, err := s.uc.RefreshSession(ctx, req)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.RefreshSessionResponse{
+		Session:      resp.Session.Proto(),
+		AccessToken:  resp.AccessToken,
+		ExpiresAt:    resp.ExpiresAt.Unix(),
+		RefreshToken: resp.RefreshToken,
+	}, nil
+}
+
+func (s *Service) ListSessions(ctx context.Context, in *api.ListSessionsRequest) (*api.ListSessionsResponse, error) {
+	req := &usecase.ListSessionsRequest{
+		UserID: in.UserId,
+	}
+	resp, err := s.uc.ListSessions(ctx, req)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.ListSessionsResponse{Sessions: resp.Sessions.Proto()}, nil
+}
+
+func (s *Service) RevokeSession(ctx context.Context, in *api.RevokeSessionRequest) (*api.RevokeSessionResponse, error) {
+	req := &usecase.RevokeSessionRequest{
+		ID: in.Id,
+	}
+	resp, err := s.uc.RevokeSession(ctx, req)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.RevokeSessionResponse{Session: resp.Session.Proto()}, nil
+}
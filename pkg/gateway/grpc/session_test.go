@@ -0,0 +1,54 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/auth"
+	"github.com/SakataAtsuki/e-architecture/pkg/proto/api"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/memory"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/usecase"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_Session(t *testing.T) {
+	issuer, err := auth.NewIssuer(&auth.IssuerConfig{HS256Secret: []byte("secret")})
+	require.NoError(t, err)
+	users := memory.NewUser()
+	s := New(usecase.New(&usecase.Config{
+		DB:     &repository.Database{User: users, Session: memory.NewSession(users)},
+		Tokens: issuer,
+	}))
+	ctx := auth.NewContext(tenant.NewContext(context.Background(), tenant.Default), &auth.Principal{Subject: "1"})
+	_, err = s.CreateUser(ctx, &api.CreateUserRequest{User: &api.User{Id: "1", Name: "alice"}})
+	require.NoError(t, err)
+
+	created, err := s.CreateSession(ctx, &api.CreateSessionRequest{UserId: "1"})
+	require.NoError(t, err)
+	require.Equal(t, "1", created.Session.UserId)
+	require.NotEmpty(t, created.AccessToken)
+	require.NotZero(t, created.ExpiresAt)
+	require.Zero(t, created.Session.RevokedAt)
+
+	refreshed, err := s.RefreshSession(ctx, &api.RefreshSessionRequest{RefreshToken: created.RefreshToken})
+	require.NoError(t, err)
+	require.Equal(t, created.Session.Id, refreshed.Session.Id)
+	require.NotEqual(t, created.RefreshToken, refreshed.RefreshToken)
+
+	revoked, err := s.RevokeSession(ctx, &api.RevokeSessionRequest{Id: created.Session.Id})
+	require.NoError(t, err)
+	require.NotZero(t, revoked.Session.RevokedAt)
+	_, err = s.RefreshSession(ctx, &api.RefreshSessionRequest{RefreshToken: refreshed.RefreshToken})
+	require.True(t, errcode.IsUnauthenticated(err), err)
+
+	listed, err := s.ListSessions(ctx, &api.ListSessionsRequest{UserId: "1"})
+	require.NoError(t, err)
+	require.Len(t, listed.Sessions, 1)
+	require.Equal(t, revoked.Session, listed.Sessions[0])
+
+	_, err = s.ListSessions(ctx, &api.ListSessionsRequest{})
+	require.True(t, errcode.IsInvalidArgument(err), err)
+}
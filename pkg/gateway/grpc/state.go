@@ -0,0 +1,64 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/proto/api"
+	"github.com/SakataAtsuki/e-architecture/pkg/usecase"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+)
+
+func (s *Service) SendVerificationEmail(ctx context.Context, in *api.SendVerificationEmailRequest) (*api.SendVerificationEmailResponse, error) {
+	req := &usecase.SendVerificationEmailRequest{
+		ID: in.Id,
+	}
+	resp, err := s.uc.SendVerificationEmail(ctx, req)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.SendVerificationEmailResponse{ExpiresAt: resp.ExpiresAt.Unix()}, nil
+}
+
+func (s *Service) VerifyEmail(ctx context.Context, in *api.VerifyEmailRequest) (*api.VerifyEmailResponse, error) {
+	req := &usecase.VerifyEmailRequest{
+		Token: in.Token,
+	}
+	resp, err := s.uc.VerifyEmail(ctx, req)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.VerifyEmailResponse{User: resp.User.Proto()}, nil
+}
+
+func (s *Service) ActivateUser(ctx context.Context, in *api.ActivateUserRequest) (*api.ActivateUserResponse, error) {
+	req := &usecase.ActivateUserRequest{
+		ID: in.Id,
+	}
+	resp, err := s.uc.ActivateUser(ctx, req)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.ActivateUserResponse{User: resp.User.Proto()}, nil
+}
+
+func (s *Service) SuspendUser(ctx context.Context, in *api.SuspendUserRequest) (*api.SuspendUserResponse, error) {
+	req := &usecase.SuspendUserRequest{
+		ID: in.Id,
+	}
+	resp, err := s.uc.SuspendUser(ctx, req)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.SuspendUserResponse{User: resp.User.Proto()}, nil
+}
+
+func (s *Service) DeactivateUser(ctx context.Context, in *api.DeactivateUserRequest) (*api.DeactivateUserResponse, error) {
+	req := &usecase.DeactivateUserRequest{
+		ID: in.Id,
+	}
+	resp, err := s.uc.DeactivateUser(ctx, req)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.DeactivateUserResponse{User: resp.User.Proto()}, nil
+}
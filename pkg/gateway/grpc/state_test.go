@@ -0,0 +1,70 @@
+package grpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/authz"
+	"github.com/SakataAtsuki/e-architecture/pkg/notify"
+	"github.com/SakataAtsuki/e-architecture/pkg/proto/api"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/memory"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/usecase"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestService_UserState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.jsonl")
+	users := memory.NewUser()
+	engine, err := authz.New(&authz.Policy{Default: authz.EffectAllow})
+	require.NoError(t, err)
+	s := New(usecase.New(&usecase.Config{
+		DB:         &repository.Database{User: users, Verification: memory.NewVerification(users)},
+		Authorizer: engine,
+		Notifier:   notify.NewFile(path),
+	}))
+	ctx, err := engine.Authorize(tenant.NewContext(context.Background(), tenant.Default), "/e_architecture.api.EArchitecture/SuspendUser")
+	require.NoError(t, err)
+	created, err := s.CreateUser(ctx, &api.CreateUserRequest{User: &api.User{Id: "1", Name: "alice", Email: "alice@example.com"}})
+	require.NoError(t, err)
+	require.Equal(t, api.UserState_PENDING, created.User.State)
+
+	sent, err := s.SendVerificationEmail(ctx, &api.SendVerificationEmailRequest{Id: "1"})
+	require.NoError(t, err)
+	require.NotZero(t, sent.ExpiresAt)
+
+	// the token is delivered through the file
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	m := &notify.Message{}
+	line := bufio.NewScanner(bytes.NewReader(b))
+	require.True(t, line.Scan())
+	require.NoError(t, json.Unmarshal(line.Bytes(), m))
+	verified, err := s.VerifyEmail(ctx, &api.VerifyEmailRequest{Token: m.Data["token"]})
+	require.NoError(t, err)
+	require.Equal(t, api.UserState_ACTIVE, verified.User.State)
+
+	suspended, err := s.SuspendUser(ctx, &api.SuspendUserRequest{Id: "1"})
+	require.NoError(t, err)
+	require.Equal(t, api.UserState_SUSPENDED, suspended.User.State)
+	deactivated, err := s.DeactivateUser(ctx, &api.DeactivateUserRequest{Id: "1"})
+	require.NoError(t, err)
+	require.Equal(t, api.UserState_DEACTIVATED, deactivated.User.State)
+	activated, err := s.ActivateUser(ctx, &api.ActivateUserRequest{Id: "1"})
+	require.NoError(t, err)
+	require.Equal(t, api.UserState_ACTIVE, activated.User.State)
+
+	// invalid transitions are failed preconditions
+	_, err = s.ActivateUser(ctx, &api.ActivateUserRequest{Id: "1"})
+	require.Equal(t, errcode.CodeFailedPrecondition, errcode.NewCode(err), err)
+	require.Equal(t, codes.FailedPrecondition, status.Code(errcode.NewGrpcError(err)))
+}
@@ -0,0 +1,60 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/auth"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const tenantHeader = "x-tenant-id"
+
+// TenantUnaryServerInterceptor resolves the tenant of the call and stores it in
+// the context. The tenant of an authenticated principal takes precedence;
+// the x-tenant-id metadata may only repeat it. Principals that are not pinned
+// to a tenant select it with the metadata. It must run after AuthInterceptor.
+func TenantUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := resolveTenant(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func TenantStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := resolveTenant(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, wrapServerStream(ss, ctx))
+	}
+}
+
+func resolveTenant(ctx context.Context) (context.Context, error) {
+	var requested string
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(tenantHeader); len(values) > 0 {
+		requested = values[0]
+	}
+
+	id := requested
+	if p, ok := auth.FromContext(ctx); ok && p.Tenant != "" {
+		if requested != "" && requested != p.Tenant {
+			return nil, errcode.NewForbidden("%s does not belong to tenant %s", p.Subject, requested)
+		}
+		id = p.Tenant
+	}
+	if id == "" {
+		return nil, errcode.NewInvalidArgument("missing %s metadata", tenantHeader)
+	}
+	if err := tenant.Validate(id); err != nil {
+		return nil, err
+	}
+	return tenant.NewContext(ctx, id), nil
+}
@@ -0,0 +1,50 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/auth"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestTenantUnaryServerInterceptor(t *testing.T) {
+	interceptor := TenantUnaryServerInterceptor()
+
+	tests := []struct {
+		name       string
+		principal  *auth.Principal
+		md         metadata.MD
+		wantCode   codes.Code
+		wantTenant string
+	}{
+		{name: "metadata", md: metadata.Pairs("x-tenant-id", "acme"), wantCode: codes.OK, wantTenant: "acme"},
+		{name: "principal", principal: &auth.Principal{Tenant: "acme"}, md: metadata.MD{}, wantCode: codes.OK, wantTenant: "acme"},
+		{name: "principal and same metadata", principal: &auth.Principal{Tenant: "acme"}, md: metadata.Pairs("x-tenant-id", "acme"), wantCode: codes.OK, wantTenant: "acme"},
+		{name: "principal and other metadata", principal: &auth.Principal{Tenant: "acme"}, md: metadata.Pairs("x-tenant-id", "other"), wantCode: codes.PermissionDenied},
+		{name: "unpinned principal", principal: &auth.Principal{}, md: metadata.Pairs("x-tenant-id", "other"), wantCode: codes.OK, wantTenant: "other"},
+		{name: "missing", md: metadata.MD{}, wantCode: codes.InvalidArgument},
+		{name: "invalid", md: metadata.Pairs("x-tenant-id", "Acme Corp"), wantCode: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+			if tt.principal != nil {
+				ctx = auth.NewContext(ctx, tt.principal)
+			}
+			var got string
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				got, _ = tenant.FromContext(ctx)
+				return "ok", nil
+			}
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+			require.Equal(t, tt.wantCode, status.Code(err))
+			require.Equal(t, tt.wantTenant, got)
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestTraceUnaryServerInterceptor(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	md := metadata.Pairs("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	info := &grpc.UnaryServerInfo{FullMethod: "/e_architecture.api.EArchitecture/GetUser"}
+
+	var inHandler oteltrace.SpanContext
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		inHandler = oteltrace.SpanContextFromContext(ctx)
+		return nil, errcode.NewNotFound("user not found")
+	}
+	_, err := TraceUnaryServerInterceptor()(ctx, nil, info, handler)
+	require.True(t, errcode.IsNotfound(err))
+
+	require.Equal(t, traceID, inHandler.TraceID().String())
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, info.FullMethod, spans[0].Name())
+	require.Equal(t, oteltrace.SpanKindServer, spans[0].SpanKind())
+	require.Equal(t, traceID, spans[0].Parent().TraceID().String())
+}
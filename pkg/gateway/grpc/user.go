@@ -2,18 +2,30 @@ package grpc
 
 import (
 	"context"
+	"sort"
+	"time"
 
 	"github.com/SakataAtsuki/e-architecture/pkg/entity"
 	"github.com/SakataAtsuki/e-architecture/pkg/proto/api"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
 	"github.com/SakataAtsuki/e-architecture/pkg/usecase"
 	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/textsearch"
 )
 
 func (s *Service) CreateUser(ctx context.Context, in *api.CreateUserRequest) (*api.CreateUserResponse, error) {
+	if in.GetUser() == nil {
+		return nil, errcode.NewInvalidArgument("user is required")
+	}
 	req := &usecase.CreateUserRequest{
 		User: &entity.User{
-			ID:   in.User.Id,
-			Name: in.User.Name,
+			ID:         in.User.Id,
+			Name:       in.User.Name,
+			Gender:     entity.Gender(in.User.Gender),
+			Email:      in.User.Email,
+			Locale:     in.User.Locale,
+			Timezone:   in.User.Timezone,
+			Attributes: entity.AttributesFromProto(in.User.Attributes),
 		},
 	}
 	resp, err := s.uc.CreateUser(ctx, req)
@@ -33,3 +45,119 @@ func (s *Service) GetUser(ctx context.Context, in *api.GetUserRequest) (*api.Get
 	}
 	return &api.GetUserResponse{User: resp.User.Proto()}, nil
 }
+
+func (s *Service) ListUsers(ctx context.Context, in *api.ListUsersRequest) (*api.ListUsersResponse, error) {
+	req := &usecase.ListUsersRequest{
+		Name:      in.Name,
+		OrderBy:   in.OrderBy,
+		Limit:     int(in.Limit),
+		Filter:    in.Filter,
+		PageToken: in.PageToken,
+	}
+	resp, err := s.uc.ListUsers(ctx, req)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.ListUsersResponse{Users: resp.Users.Proto(), NextPageToken: resp.NextPageToken}, nil
+}
+
+func (s *Service) UpdateUser(ctx context.Context, in *api.UpdateUserRequest) (*api.UpdateUserResponse, error) {
+	if in.GetUser() == nil {
+		return nil, errcode.NewInvalidArgument("user is required")
+	}
+	req := &usecase.UpdateUserRequest{
+		User: &entity.User{
+			ID:         in.User.Id,
+			Name:       in.User.Name,
+			Gender:     entity.Gender(in.User.Gender),
+			Email:      in.User.Email,
+			Locale:     in.User.Locale,
+			Timezone:   in.User.Timezone,
+			Attributes: entity.AttributesFromProto(in.User.Attributes),
+		},
+		UpdateMask: in.GetUpdateMask().GetPaths(),
+	}
+	resp, err := s.uc.UpdateUser(ctx, req)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.UpdateUserResponse{User: resp.User.Proto()}, nil
+}
+
+func (s *Service) DeleteUser(ctx context.Context, in *api.DeleteUserRequest) (*api.DeleteUserResponse, error) {
+	req := &usecase.DeleteUserRequest{
+		ID: in.Id,
+	}
+	if err := s.uc.DeleteUser(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	return &api.DeleteUserResponse{}, nil
+}
+
+func (s *Service) SearchUsers(ctx context.Context, in *api.SearchUsersRequest) (*api.SearchUsersResponse, error) {
+	var mode textsearch.Mode
+	switch in.Mode {
+	case api.SearchMode_SEARCH_MODE_SUBSTRING:
+		mode = textsearch.ModeSubstring
+	case api.SearchMode_SEARCH_MODE_PREFIX:
+		mode = textsearch.ModePrefix
+	case api.SearchMode_SEARCH_MODE_FUZZY:
+		mode = textsearch.ModeFuzzy
+	default:
+		return nil, errcode.NewInvalidArgument("unknown search mode: %v", in.Mode)
+	}
+	req := &usecase.SearchUsersRequest{
+		Query:     in.Query,
+		Mode:      mode,
+		PageSize:  int(in.PageSize),
+		PageToken: in.PageToken,
+	}
+	resp, err := s.uc.SearchUsers(ctx, req)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	results := make([]*api.SearchUsersResult, 0, len(resp.Users))
+	for _, u := range resp.Users {
+		results = append(results, &api.SearchUsersResult{User: u.User.Proto(), Score: u.Score})
+	}
+	return &api.SearchUsersResponse{Results: results, NextPageToken: resp.NextPageToken}, nil
+}
+
+func (s *Service) GetUserStats(ctx context.Context, in *api.GetUserStatsRequest) (*api.GetUserStatsResponse, error) {
+	var bucket repository.Bucket
+	switch in.Bucket {
+	case api.StatsBucket_STATS_BUCKET_DAY:
+		bucket = repository.BucketDay
+	case api.StatsBucket_STATS_BUCKET_WEEK:
+		bucket = repository.BucketWeek
+	case api.StatsBucket_STATS_BUCKET_MONTH:
+		bucket = repository.BucketMonth
+	default:
+		return nil, errcode.NewInvalidArgument("unknown stats bucket: %v", in.Bucket)
+	}
+	req := &usecase.GetUserStatsRequest{
+		Filter: in.Filter,
+		Bucket: bucket,
+	}
+	if in.From != 0 {
+		req.From = time.Unix(in.From, 0)
+	}
+	if in.To != 0 {
+		req.To = time.Unix(in.To, 0)
+	}
+	resp, err := s.uc.GetUserStats(ctx, req)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	// every gender is listed, in the order of their values
+	genders := make([]*api.GenderCount, 0, len(api.Gender_name))
+	for v := range api.Gender_name {
+		genders = append(genders, &api.GenderCount{Gender: api.Gender(v), Count: resp.ByGender[entity.Gender(v)]})
+	}
+	sort.Slice(genders, func(i, j int) bool { return genders[i].Gender < genders[j].Gender })
+	signups := make([]*api.SignupCount, 0, len(resp.Signups))
+	for _, c := range resp.Signups {
+		signups = append(signups, &api.SignupCount{Start: c.Start.Unix(), Count: c.Count})
+	}
+	return &api.GetUserStatsResponse{Total: resp.Total, ByGender: genders, Signups: signups}, nil
+}
@@ -0,0 +1,120 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/proto/api"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/memory"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/usecase"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func TestService_NilMessages(t *testing.T) {
+	s := New(usecase.New(&usecase.Config{DB: &repository.Database{User: memory.NewUser()}}))
+	ctx := tenant.NewContext(context.Background(), tenant.Default)
+
+	_, err := s.CreateUser(ctx, &api.CreateUserRequest{})
+	require.True(t, errcode.IsInvalidArgument(err))
+	_, err = s.UpdateUser(ctx, &api.UpdateUserRequest{})
+	require.True(t, errcode.IsInvalidArgument(err))
+	_, err = s.GetUser(ctx, &api.GetUserRequest{})
+	require.True(t, errcode.IsInvalidArgument(err))
+	_, err = s.DeleteUser(ctx, &api.DeleteUserRequest{})
+	require.True(t, errcode.IsInvalidArgument(err))
+}
+
+func TestService_UserProfile(t *testing.T) {
+	s := New(usecase.New(&usecase.Config{DB: &repository.Database{User: memory.NewUser()}}))
+	ctx := tenant.NewContext(context.Background(), tenant.Default)
+
+	resp, err := s.CreateUser(ctx, &api.CreateUserRequest{User: &api.User{
+		Id:       "1",
+		Name:     "alice",
+		Email:    "alice@example.com",
+		Locale:   "ja-JP",
+		Timezone: "Asia/Tokyo",
+		Attributes: map[string]*api.AttributeValue{
+			"team":  {Kind: &api.AttributeValue_StringValue{StringValue: "core"}},
+			"level": {Kind: &api.AttributeValue_IntValue{IntValue: 3}},
+			"ratio": {Kind: &api.AttributeValue_DoubleValue{DoubleValue: 1}},
+			"beta":  {Kind: &api.AttributeValue_BoolValue{BoolValue: true}},
+		},
+	}})
+	require.NoError(t, err)
+	got := resp.User
+	require.Equal(t, "alice@example.com", got.Email)
+	require.Equal(t, "ja-JP", got.Locale)
+	require.Equal(t, "Asia/Tokyo", got.Timezone)
+	require.NotZero(t, got.CreatedAt)
+	require.Equal(t, "core", got.Attributes["team"].GetStringValue())
+	require.EqualValues(t, 3, got.Attributes["level"].GetIntValue())
+	require.EqualValues(t, 1, got.Attributes["ratio"].GetDoubleValue())
+	require.True(t, got.Attributes["beta"].GetBoolValue())
+
+	listed, err := s.ListUsers(ctx, &api.ListUsersRequest{Filter: `attributes.level > 2 AND attributes:beta`})
+	require.NoError(t, err)
+	require.Len(t, listed.Users, 1)
+
+	// the fields outside of the mask are kept
+	updated, err := s.UpdateUser(ctx, &api.UpdateUserRequest{
+		User:       &api.User{Id: "1", Timezone: "UTC"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"timezone"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "UTC", updated.User.Timezone)
+	require.Equal(t, "alice@example.com", updated.User.Email)
+	require.Len(t, updated.User.Attributes, 4)
+
+	// a value without a kind
+	_, err = s.CreateUser(ctx, &api.CreateUserRequest{User: &api.User{
+		Id:         "2",
+		Name:       "bob",
+		Attributes: map[string]*api.AttributeValue{"team": {}},
+	}})
+	require.True(t, errcode.IsInvalidArgument(err), err)
+}
+
+func TestService_SearchUsers(t *testing.T) {
+	s := New(usecase.New(&usecase.Config{DB: &repository.Database{User: memory.NewUser()}}))
+	ctx := tenant.NewContext(context.Background(), tenant.Default)
+	for _, u := range []*api.User{{Id: "1", Name: "ヤマダ タロウ"}, {Id: "2", Name: "Alice"}} {
+		_, err := s.CreateUser(ctx, &api.CreateUserRequest{User: u})
+		require.NoError(t, err)
+	}
+
+	resp, err := s.SearchUsers(ctx, &api.SearchUsersRequest{Query: "ﾔﾏﾀﾞ", Mode: api.SearchMode_SEARCH_MODE_PREFIX})
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 1)
+	require.Equal(t, "1", resp.Results[0].User.Id)
+	require.Empty(t, resp.NextPageToken)
+
+	_, err = s.SearchUsers(ctx, &api.SearchUsersRequest{Query: "alice", Mode: api.SearchMode(99)})
+	require.True(t, errcode.IsInvalidArgument(err))
+}
+
+func TestService_GetUserStats(t *testing.T) {
+	s := New(usecase.New(&usecase.Config{DB: &repository.Database{User: memory.NewUser()}}))
+	ctx := tenant.NewContext(context.Background(), tenant.Default)
+	_, err := s.CreateUser(ctx, &api.CreateUserRequest{User: &api.User{Id: "1", Name: "alice", Gender: api.Gender_FEMALE}})
+	require.NoError(t, err)
+
+	resp, err := s.GetUserStats(ctx, &api.GetUserStatsRequest{Bucket: api.StatsBucket_STATS_BUCKET_WEEK})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, resp.Total)
+	// every gender is listed
+	require.Equal(t, []*api.GenderCount{
+		{Gender: api.Gender_GENDER_OTHER},
+		{Gender: api.Gender_MALE},
+		{Gender: api.Gender_FEMALE, Count: 1},
+	}, resp.ByGender)
+	require.NotEmpty(t, resp.Signups)
+	require.EqualValues(t, 1, resp.Signups[len(resp.Signups)-1].Count)
+
+	_, err = s.GetUserStats(ctx, &api.GetUserStatsRequest{Bucket: api.StatsBucket(99)})
+	require.True(t, errcode.IsInvalidArgument(err))
+}
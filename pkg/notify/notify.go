@@ -0,0 +1,73 @@
+// Package notify delivers messages to users, such as the tokens of email
+// verifications. The implementations here write messages locally, so that
+// flows needing them can be run and tested offline.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/log"
+)
+
+// KindVerifyEmail asks the user to verify its email with the token in the
+// data of the message.
+const KindVerifyEmail = "verify_email"
+
+type Message struct {
+	Kind   string `json:"kind"`
+	Tenant string `json:"tenant"`
+	UserID string `json:"user_id"`
+	// To is the address to deliver to.
+	To   string            `json:"to"`
+	Data map[string]string `json:"data,omitempty"`
+}
+
+// Log logs messages at the info level. As it logs their data, including
+// tokens, it is meant for development.
+type Log struct{}
+
+func NewLog() *Log {
+	return &Log{}
+}
+
+func (*Log) Notify(ctx context.Context, m *Message) error {
+	log.Info(ctx, "notification", "kind", m.Kind, "tenant", m.Tenant, "user", m.UserID, "to", m.To, "data", m.Data)
+	return nil
+}
+
+// File appends messages to a file as JSON lines.
+type File struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFile returns a File writing to path, which is created with mode 0600
+// if it does not exist.
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+func (f *File) Notify(ctx context.Context, m *Message) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return errcode.New(err)
+	}
+	b = append(b, '\n')
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return errcode.New(err)
+	}
+	if _, err := file.Write(b); err != nil {
+		file.Close()
+		return errcode.New(err)
+	}
+	return errcode.New(file.Close())
+}
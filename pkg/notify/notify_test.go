@@ -0,0 +1,41 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile_Notify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.jsonl")
+	f := NewFile(path)
+	messages := []*Message{
+		{Kind: KindVerifyEmail, Tenant: "acme", UserID: "1", To: "alice@example.com", Data: map[string]string{"token": "t1"}},
+		{Kind: KindVerifyEmail, Tenant: "acme", UserID: "2", To: "bob@example.com"},
+	}
+	for _, m := range messages {
+		require.NoError(t, f.Notify(context.Background(), m))
+	}
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	info, err := file.Stat()
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	var got []*Message
+	s := bufio.NewScanner(file)
+	for s.Scan() {
+		m := &Message{}
+		require.NoError(t, json.Unmarshal(s.Bytes(), m))
+		got = append(got, m)
+	}
+	require.NoError(t, s.Err())
+	require.Equal(t, messages, got)
+}
@@ -0,0 +1,236 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.1
+// 	protoc        (unknown)
+// source: api/relationship.proto
+
+package api
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+//
+// * 関係の種類 (2 人の間で同じ向きの関係は 1 つ)
+//
+type RelationshipKind int32
+
+const (
+	RelationshipKind_FOLLOW RelationshipKind = 0 // フォロー
+	RelationshipKind_BLOCK  RelationshipKind = 1 // ブロック (互いのフォローを解除し, フォローできなくする)
+)
+
+// Enum value maps for RelationshipKind.
+var (
+	RelationshipKind_name = map[int32]string{
+		0: "FOLLOW",
+		1: "BLOCK",
+	}
+	RelationshipKind_value = map[string]int32{
+		"FOLLOW": 0,
+		"BLOCK":  1,
+	}
+)
+
+func (x RelationshipKind) Enum() *RelationshipKind {
+	p := new(RelationshipKind)
+	*p = x
+	return p
+}
+
+func (x RelationshipKind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (RelationshipKind) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_relationship_proto_enumTypes[0].Descriptor()
+}
+
+func (RelationshipKind) Type() protoreflect.EnumType {
+	return &file_api_relationship_proto_enumTypes[0]
+}
+
+func (x RelationshipKind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use RelationshipKind.Descriptor instead.
+func (RelationshipKind) EnumDescriptor() ([]byte, []int) {
+	return file_api_relationship_proto_rawDescGZIP(), []int{0}
+}
+
+//
+// * ユーザー間の関係 (user_id から target_id へ)
+//
+type Relationship struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	UserId    string           `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id"`                         // フォロー・ブロックしたユーザー ID
+	TargetId  string           `protobuf:"bytes,2,opt,name=target_id,json=targetId,proto3" json:"target_id"`                   // フォロー・ブロックされたユーザー ID
+	Kind      RelationshipKind `protobuf:"varint,3,opt,name=kind,proto3,enum=e_architecture.api.RelationshipKind" json:"kind"` // 種類
+	CreatedAt int64            `protobuf:"varint,4,opt,name=created_at,json=createdAt,proto3" json:"created_at"`               // 作成日時 (UNIX 秒)
+}
+
+func (x *Relationship) Reset() {
+	*x = Relationship{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_relationship_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Relationship) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Relationship) ProtoMessage() {}
+
+func (x *Relationship) ProtoReflect() protoreflect.Message {
+	mi := &file_api_relationship_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Relationship.ProtoReflect.Descriptor instead.
+func (*Relationship) Descriptor() ([]byte, []int) {
+	return file_api_relationship_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Relationship) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *Relationship) GetTargetId() string {
+	if x != nil {
+		return x.TargetId
+	}
+	return ""
+}
+
+func (x *Relationship) GetKind() RelationshipKind {
+	if x != nil {
+		return x.Kind
+	}
+	return RelationshipKind_FOLLOW
+}
+
+func (x *Relationship) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+var File_api_relationship_proto protoreflect.FileDescriptor
+
+var file_api_relationship_proto_rawDesc = []byte{
+	0x0a, 0x16, 0x61, 0x70, 0x69, 0x2f, 0x72, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x68,
+	0x69, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x12, 0x65, 0x5f, 0x61, 0x72, 0x63, 0x68,
+	0x69, 0x74, 0x65, 0x63, 0x74, 0x75, 0x72, 0x65, 0x2e, 0x61, 0x70, 0x69, 0x22, 0x9d, 0x01, 0x0a,
+	0x0c, 0x52, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x68, 0x69, 0x70, 0x12, 0x17, 0x0a,
+	0x07, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
+	0x75, 0x73, 0x65, 0x72, 0x49, 0x64, 0x12, 0x1b, 0x0a, 0x09, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74,
+	0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x74, 0x61, 0x72, 0x67, 0x65,
+	0x74, 0x49, 0x64, 0x12, 0x38, 0x0a, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x0e, 0x32, 0x24, 0x2e, 0x65, 0x5f, 0x61, 0x72, 0x63, 0x68, 0x69, 0x74, 0x65, 0x63, 0x74, 0x75,
+	0x72, 0x65, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x52, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73,
+	0x68, 0x69, 0x70, 0x4b, 0x69, 0x6e, 0x64, 0x52, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x12, 0x1d, 0x0a,
+	0x0a, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x09, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x41, 0x74, 0x2a, 0x29, 0x0a, 0x10,
+	0x52, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x68, 0x69, 0x70, 0x4b, 0x69, 0x6e, 0x64,
+	0x12, 0x0a, 0x0a, 0x06, 0x46, 0x4f, 0x4c, 0x4c, 0x4f, 0x57, 0x10, 0x00, 0x12, 0x09, 0x0a, 0x05,
+	0x42, 0x4c, 0x4f, 0x43, 0x4b, 0x10, 0x01, 0x42, 0x36, 0x5a, 0x34, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x53, 0x61, 0x6b, 0x61, 0x74, 0x61, 0x41, 0x74, 0x73, 0x75,
+	0x6b, 0x69, 0x2f, 0x65, 0x2d, 0x61, 0x72, 0x63, 0x68, 0x69, 0x74, 0x65, 0x63, 0x74, 0x75, 0x72,
+	0x65, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x61, 0x70, 0x69, 0x62,
+	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_api_relationship_proto_rawDescOnce sync.Once
+	file_api_relationship_proto_rawDescData = file_api_relationship_proto_rawDesc
+)
+
+func file_api_relationship_proto_rawDescGZIP() []byte {
+	file_api_relationship_proto_rawDescOnce.Do(func() {
+		file_api_relationship_proto_rawDescData = protoimpl.X.CompressGZIP(file_api_relationship_proto_rawDescData)
+	})
+	return file_api_relationship_proto_rawDescData
+}
+
+var file_api_relationship_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_api_relationship_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_api_relationship_proto_goTypes = []interface{}{
+	(RelationshipKind)(0), // 0: e_architecture.api.RelationshipKind
+	(*Relationship)(nil),  // 1: e_architecture.api.Relationship
+}
+var file_api_relationship_proto_depIdxs = []int32{
+	0, // 0: e_architecture.api.Relationship.kind:type_name -> e_architecture.api.RelationshipKind
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_api_relationship_proto_init() }
+func file_api_relationship_proto_init() {
+	if File_api_relationship_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_api_relationship_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Relationship); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_api_relationship_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_api_relationship_proto_goTypes,
+		DependencyIndexes: file_api_relationship_proto_depIdxs,
+		EnumInfos:         file_api_relationship_proto_enumTypes,
+		MessageInfos:      file_api_relationship_proto_msgTypes,
+	}.Build()
+	File_api_relationship_proto = out.File
+	file_api_relationship_proto_rawDesc = nil
+	file_api_relationship_proto_goTypes = nil
+	file_api_relationship_proto_depIdxs = nil
+}
@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewLRU(2)
+	c.now = func() time.Time { return now }
+
+	require.NoError(t, c.Set(ctx, "a", []byte("1"), time.Minute))
+	require.NoError(t, c.Set(ctx, "b", []byte("2"), time.Second))
+	// a becomes the most recently used, so b is evicted
+	_, ok, _ := c.Get(ctx, "a")
+	require.True(t, ok)
+	require.NoError(t, c.Set(ctx, "c", []byte("3"), time.Minute))
+	_, ok, _ = c.Get(ctx, "b")
+	require.False(t, ok)
+
+	v, ok, err := c.Get(ctx, "c")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []byte("3"), v)
+
+	now = now.Add(time.Minute)
+	_, ok, _ = c.Get(ctx, "a")
+	require.False(t, ok)
+	require.Empty(t, c.entries["a"])
+
+	require.NoError(t, c.Set(ctx, "a", []byte("1"), time.Minute))
+	require.NoError(t, c.Delete(ctx, "a"))
+	_, ok, _ = c.Get(ctx, "a")
+	require.False(t, ok)
+}
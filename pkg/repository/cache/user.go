@@ -0,0 +1,301 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/filter"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/log"
+	"golang.org/x/sync/singleflight"
+)
+
+var _ repository.User = (*User)(nil)
+
+type Config struct {
+	Store Store
+	// TTL is how long a user is cached.
+	TTL time.Duration
+	// NegativeTTL is how long the absence of a user is cached. Zero disables
+	// negative caching.
+	NegativeTTL time.Duration
+	// StatsTTL is how long the results of Stats are cached. They are not
+	// invalidated by writes. Zero disables their caching.
+	StatsTTL time.Duration
+}
+
+// User caches the users read with Get, and optionally the results of Stats,
+// from the wrapped repository.User.
+// Writes through this User invalidate the cached entry; writes by other
+// processes are seen once the entry expires, or right away by the reads with
+// a repository.Latest context.
+type User struct {
+	next  repository.User
+	cfg   *Config
+	group singleflight.Group
+
+	mu sync.Mutex
+	// gen is incremented by each invalidation, so that loads which started
+	// before it do not cache what they read.
+	gen uint64
+}
+
+func NewUser(next repository.User, cfg *Config) *User {
+	return &User{next: next, cfg: cfg}
+}
+
+// entry is the cached value. A nil User records that the user does not exist.
+type entry struct {
+	User *entity.User `json:"user"`
+}
+
+func (u *User) Create(ctx context.Context, v *entity.User) (*entity.User, error) {
+	user, err := u.next.Create(ctx, v)
+	if err != nil {
+		return nil, err
+	}
+	// drop a cached not found
+	u.invalidate(ctx, v.ID)
+	return user, nil
+}
+
+func (u *User) Get(ctx context.Context, id string) (*entity.User, error) {
+	key, err := userKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if repository.IsLatest(ctx) {
+		return u.next.Get(ctx, id)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, errcode.New(err)
+	}
+	e := &entry{}
+	if u.lookup(ctx, key, e) {
+		if e.User == nil {
+			return nil, errcode.NewNotFound("user not found: %s", id)
+		}
+		return e.User, nil
+	}
+
+	// concurrent misses share a single read of the wrapped repository, which
+	// therefore must not be canceled when the caller that started it gives up
+	ch := u.group.DoChan(key, func() (interface{}, error) {
+		loadCtx, cancel := detach(ctx)
+		defer cancel()
+		return u.load(loadCtx, key, id)
+	})
+	select {
+	case <-ctx.Done():
+		return nil, errcode.New(ctx.Err())
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		// callers sharing the result must not share the user
+		user := *res.Val.(*entity.User)
+		user.Attributes = user.Attributes.Clone()
+		return &user, nil
+	}
+}
+
+// GetByEmail is not cached: it authenticates logins, which must see changes
+// of emails right away.
+func (u *User) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
+	return u.next.GetByEmail(ctx, email)
+}
+
+func (u *User) List(ctx context.Context, params *repository.ListUsersParams) (entity.Users, error) {
+	return u.next.List(ctx, params)
+}
+
+func (u *User) Search(ctx context.Context, params *repository.SearchUsersParams) ([]*repository.ScoredUser, error) {
+	return u.next.Search(ctx, params)
+}
+
+func (u *User) Stats(ctx context.Context, params *repository.UserStatsParams) (*repository.UserStats, error) {
+	if u.cfg.StatsTTL <= 0 {
+		return u.next.Stats(ctx, params)
+	}
+	key, err := statsKey(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, errcode.New(err)
+	}
+	stats := &repository.UserStats{}
+	if u.lookup(ctx, key, stats) {
+		return stats, nil
+	}
+
+	// aggregations are expensive, so concurrent misses share one as in Get
+	ch := u.group.DoChan(key, func() (interface{}, error) {
+		loadCtx, cancel := detach(ctx)
+		defer cancel()
+		stats, err := u.next.Stats(loadCtx, params)
+		if err != nil {
+			return nil, err
+		}
+		u.store(loadCtx, key, stats, u.cfg.StatsTTL)
+		return stats, nil
+	})
+	select {
+	case <-ctx.Done():
+		return nil, errcode.New(ctx.Err())
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return copyStats(res.Val.(*repository.UserStats)), nil
+	}
+}
+
+func (u *User) Update(ctx context.Context, id string, update func(*entity.User) bool) (*entity.User, error) {
+	user, err := u.next.Update(ctx, id, update)
+	u.invalidate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (u *User) Delete(ctx context.Context, id string) error {
+	err := u.next.Delete(ctx, id)
+	u.invalidate(ctx, id)
+	return err
+}
+
+func (u *User) load(ctx context.Context, key, id string) (*entity.User, error) {
+	u.mu.Lock()
+	gen := u.gen
+	u.mu.Unlock()
+
+	user, err := u.next.Get(ctx, id)
+	switch {
+	case errcode.IsNotfound(err):
+		if u.cfg.NegativeTTL > 0 {
+			u.storeLoaded(ctx, gen, key, &entry{}, u.cfg.NegativeTTL)
+		}
+		return nil, err
+	case err != nil:
+		return nil, err
+	}
+	u.storeLoaded(ctx, gen, key, &entry{User: user}, u.cfg.TTL)
+	return user, nil
+}
+
+// storeLoaded stores v read by a load that started at gen, unless the user
+// was invalidated since then, in which case v may be older than the write
+// that invalidated it.
+func (u *User) storeLoaded(ctx context.Context, gen uint64, key string, v interface{}, ttl time.Duration) {
+	// held while storing so that an invalidation either sees the entry to
+	// delete or makes the load skip it
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.gen != gen {
+		return
+	}
+	u.store(ctx, key, v, ttl)
+}
+
+// lookup decodes the cached value of key into v and reports whether it was
+// found. Failures of the store are logged and treated as misses so that the
+// cache never makes reads fail.
+func (u *User) lookup(ctx context.Context, key string, v interface{}) bool {
+	b, ok, err := u.cfg.Store.Get(ctx, key)
+	if err != nil {
+		log.Warn(ctx, "failed to read user cache", "key", key, log.Err(err))
+		return false
+	}
+	if !ok {
+		return false
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		log.Warn(ctx, "failed to decode user cache", "key", key, log.Err(errcode.New(err)))
+		return false
+	}
+	return true
+}
+
+func (u *User) store(ctx context.Context, key string, v interface{}, ttl time.Duration) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		log.Warn(ctx, "failed to encode user cache", "key", key, log.Err(errcode.New(err)))
+		return
+	}
+	if err := u.cfg.Store.Set(ctx, key, b, ttl); err != nil {
+		log.Warn(ctx, "failed to write user cache", "key", key, log.Err(err))
+	}
+}
+
+func (u *User) invalidate(ctx context.Context, id string) {
+	key, err := userKey(ctx, id)
+	if err != nil {
+		return
+	}
+	u.mu.Lock()
+	u.gen++
+	u.mu.Unlock()
+	// callers from now on must not join a load that may have read the user
+	// before the write
+	u.group.Forget(key)
+	if err := u.cfg.Store.Delete(ctx, key); err != nil {
+		log.Error(ctx, "failed to invalidate user cache", "key", key, log.Err(err))
+	}
+}
+
+// detach returns a context with the values and the deadline of ctx that is
+// not canceled with ctx.
+func detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached := context.WithoutCancel(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(detached, deadline)
+	}
+	return detached, func() {}
+}
+
+func userKey(ctx context.Context, id string) (string, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return "", errcode.New(err)
+	}
+	return "user:" + tenantID + ":" + id, nil
+}
+
+// statsKey identifies the results of Stats for params. The filter is hashed
+// to bound the length of the key.
+func statsKey(ctx context.Context, params *repository.UserStatsParams) (string, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return "", errcode.New(err)
+	}
+	h := sha256.Sum256([]byte(fmt.Sprintf("%d|%d|%d|%s",
+		params.Bucket, params.From.UnixNano(), params.To.UnixNano(), filter.String(params.Filter))))
+	return "user-stats:" + tenantID + ":" + hex.EncodeToString(h[:]), nil
+}
+
+// copyStats copies stats for callers sharing a result.
+func copyStats(stats *repository.UserStats) *repository.UserStats {
+	ret := &repository.UserStats{
+		Total:    stats.Total,
+		ByGender: map[entity.Gender]int64{},
+		Signups:  make([]*repository.SignupCount, 0, len(stats.Signups)),
+	}
+	for gender, n := range stats.ByGender {
+		ret.ByGender[gender] = n
+	}
+	for _, c := range stats.Signups {
+		signup := *c
+		ret.Signups = append(ret.Signups, &signup)
+	}
+	return ret
+}
@@ -0,0 +1,262 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/filter"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/memory"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/repositorytest"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+// countingUser counts the calls to Get and Stats and blocks those to Get,
+// once they have read the user, until release is closed, if set.
+type countingUser struct {
+	repository.User
+	gets    atomic.Int32
+	stats   atomic.Int32
+	release chan struct{}
+}
+
+func (u *countingUser) Stats(ctx context.Context, params *repository.UserStatsParams) (*repository.UserStats, error) {
+	u.stats.Add(1)
+	return u.User.Stats(ctx, params)
+}
+
+func (u *countingUser) Get(ctx context.Context, id string) (*entity.User, error) {
+	u.gets.Add(1)
+	user, err := u.User.Get(ctx, id)
+	if u.release != nil {
+		<-u.release
+	}
+	return user, err
+}
+
+func stores(t *testing.T) map[string]Store {
+	s := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return map[string]Store{
+		"lru":   NewLRU(100),
+		"redis": NewRedis(client, "test:"),
+	}
+}
+
+func TestUser(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			backend := &countingUser{User: memory.NewUser()}
+			repo := NewUser(backend, &Config{Store: store, TTL: time.Minute, NegativeTTL: time.Minute})
+			ctx := tenant.NewContext(context.Background(), "acme")
+
+			// not found is cached until the user is created
+			for n := 0; n < 2; n++ {
+				_, err := repo.Get(ctx, "id")
+				require.True(t, errcode.IsNotfound(err))
+			}
+			require.EqualValues(t, 1, backend.gets.Load())
+
+			created, err := repo.Create(ctx, &entity.User{ID: "id", Name: "name"})
+			require.NoError(t, err)
+			for n := 0; n < 2; n++ {
+				got, err := repo.Get(ctx, "id")
+				require.NoError(t, err)
+				require.Equal(t, created, got)
+			}
+			require.EqualValues(t, 2, backend.gets.Load())
+
+			// entries are per tenant
+			_, err = repo.Get(tenant.NewContext(context.Background(), "other"), "id")
+			require.True(t, errcode.IsNotfound(err))
+			require.EqualValues(t, 3, backend.gets.Load())
+
+			_, err = repo.Update(ctx, "id", func(u *entity.User) bool { u.Name = "changed"; return true })
+			require.NoError(t, err)
+			got, err := repo.Get(ctx, "id")
+			require.NoError(t, err)
+			require.Equal(t, "changed", got.Name)
+			require.EqualValues(t, 4, backend.gets.Load())
+
+			// reads of the latest state bypass the cache
+			_, err = repo.Get(repository.Latest(ctx), "id")
+			require.NoError(t, err)
+			require.EqualValues(t, 5, backend.gets.Load())
+
+			require.NoError(t, repo.Delete(ctx, "id"))
+			_, err = repo.Get(ctx, "id")
+			require.True(t, errcode.IsNotfound(err))
+			require.EqualValues(t, 6, backend.gets.Load())
+		})
+	}
+}
+
+func TestUser_CoalescesMisses(t *testing.T) {
+	backend := &countingUser{User: memory.NewUser(), release: make(chan struct{})}
+	repo := NewUser(backend, &Config{Store: NewLRU(100), TTL: time.Minute})
+	ctx := tenant.NewContext(context.Background(), "acme")
+	_, err := backend.Create(ctx, &entity.User{ID: "id", Name: "name"})
+	require.NoError(t, err)
+
+	const callers = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for n := 0; n < callers; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.Get(ctx, "id")
+			errs <- err
+		}()
+	}
+	// let every caller reach the cache before the read completes
+	require.Eventually(t, func() bool { return backend.gets.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(backend.release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.EqualValues(t, 1, backend.gets.Load())
+}
+
+// TestUser_CoalescedErrors runs coalesced callers that fail with the same
+// error through the request ID of the gateway, which must not race under
+// -race nor leak the ID of one caller to the other.
+func TestUser_CoalescedErrors(t *testing.T) {
+	backend := &countingUser{User: memory.NewUser(), release: make(chan struct{})}
+	repo := NewUser(backend, &Config{Store: NewLRU(100), TTL: time.Minute})
+	ctx := tenant.NewContext(context.Background(), "acme")
+
+	ids := []string{"req-1", "req-2"}
+	var wg sync.WaitGroup
+	got := make([]string, len(ids))
+	for n, id := range ids {
+		wg.Add(1)
+		go func(n int, id string) {
+			defer wg.Done()
+			_, err := repo.Get(ctx, "missing")
+			var e *errcode.Error
+			if errors.As(errcode.WithRequestID(err, id), &e) {
+				got[n] = e.RequestID()
+			}
+		}(n, id)
+	}
+	require.Eventually(t, func() bool { return backend.gets.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(backend.release)
+	wg.Wait()
+
+	require.Equal(t, ids, got)
+	require.EqualValues(t, 1, backend.gets.Load())
+}
+
+// TestUser_InvalidatedLoad updates a user while a load of it is in flight,
+// which must not cache what it read before the update.
+func TestUser_InvalidatedLoad(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			backend := &countingUser{User: memory.NewUser()}
+			repo := NewUser(backend, &Config{Store: store, TTL: time.Minute})
+			ctx := tenant.NewContext(context.Background(), "acme")
+			_, err := repo.Create(ctx, &entity.User{ID: "id", Name: "old"})
+			require.NoError(t, err)
+
+			backend.release = make(chan struct{})
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				_, _ = repo.Get(ctx, "id")
+			}()
+			require.Eventually(t, func() bool { return backend.gets.Load() == 1 }, time.Second, time.Millisecond)
+
+			_, err = repo.Update(ctx, "id", func(u *entity.User) bool {
+				u.Name = "new"
+				return true
+			})
+			require.NoError(t, err)
+			close(backend.release)
+			<-done
+
+			got, err := repo.Get(ctx, "id")
+			require.NoError(t, err)
+			require.Equal(t, "new", got.Name)
+		})
+	}
+}
+
+func TestUser_Stats(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			backend := &countingUser{User: memory.NewUser()}
+			repo := NewUser(backend, &Config{Store: store, TTL: time.Minute, StatsTTL: time.Minute})
+			ctx := repositorytest.TenantContext(t)
+			_, err := repo.Create(ctx, &entity.User{ID: "id", Name: "name", Gender: entity.GenderFemale})
+			require.NoError(t, err)
+
+			from := time.Now().Add(-time.Hour)
+			params := &repository.UserStatsParams{From: from, To: from.Add(2 * time.Hour)}
+			want, err := repo.Stats(ctx, params)
+			require.NoError(t, err)
+			require.EqualValues(t, 1, want.Total)
+			got, err := repo.Stats(ctx, params)
+			require.NoError(t, err)
+			require.Equal(t, want, got)
+			require.EqualValues(t, 1, backend.stats.Load())
+
+			// results are not invalidated by writes
+			_, err = repo.Create(ctx, &entity.User{ID: "id2", Name: "name2"})
+			require.NoError(t, err)
+			got, err = repo.Stats(ctx, params)
+			require.NoError(t, err)
+			require.EqualValues(t, 1, got.Total)
+			require.EqualValues(t, 1, backend.stats.Load())
+
+			// other parameters and tenants have entries of their own
+			e, err := filter.Parse(`gender = FEMALE`, repository.UserFilterSchema)
+			require.NoError(t, err)
+			got, err = repo.Stats(ctx, &repository.UserStatsParams{Filter: e, From: params.From, To: params.To})
+			require.NoError(t, err)
+			require.EqualValues(t, 1, got.Total)
+			got, err = repo.Stats(ctx, &repository.UserStatsParams{From: params.From, To: params.To, Bucket: repository.BucketWeek})
+			require.NoError(t, err)
+			require.EqualValues(t, 2, got.Total)
+			got, err = repo.Stats(repositorytest.TenantContext(t), params)
+			require.NoError(t, err)
+			require.Zero(t, got.Total)
+			require.EqualValues(t, 4, backend.stats.Load())
+		})
+	}
+
+	// without StatsTTL every call reaches the backend
+	backend := &countingUser{User: memory.NewUser()}
+	repo := NewUser(backend, &Config{Store: NewLRU(100), TTL: time.Minute})
+	ctx := repositorytest.TenantContext(t)
+	for n := 0; n < 2; n++ {
+		_, err := repo.Stats(ctx, &repository.UserStatsParams{})
+		require.NoError(t, err)
+	}
+	require.EqualValues(t, 2, backend.stats.Load())
+}
+
+func TestUser_Contract(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			repositorytest.TestUser(t, func(t *testing.T) repository.User {
+				return NewUser(memory.NewUser(), &Config{Store: store, TTL: time.Minute, NegativeTTL: time.Minute, StatsTTL: time.Minute})
+			})
+		})
+	}
+}
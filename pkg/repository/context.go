@@ -0,0 +1,19 @@
+package repository
+
+import "context"
+
+type latestKey struct{}
+
+// Latest returns a context whose reads observe every committed write, by
+// bypassing caches and replicas. It is for the checks that must not pass on
+// stale data, such as whether a user may sign in.
+func Latest(ctx context.Context) context.Context {
+	return context.WithValue(ctx, latestKey{}, true)
+}
+
+// IsLatest reports whether the reads with ctx must observe every committed
+// write.
+func IsLatest(ctx context.Context) bool {
+	latest, _ := ctx.Value(latestKey{}).(bool)
+	return latest
+}
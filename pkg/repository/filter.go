@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/filter"
+	"github.com/SakataAtsuki/e-architecture/pkg/proto/api"
+)
+
+// UserFilterSchema lists the fields of entity.User that filters and orders
+// may refer to, named as in the API.
+var UserFilterSchema = filter.Schema{
+	"id":         {Type: filter.TypeString, Sortable: true},
+	"name":       {Type: filter.TypeString, Sortable: true},
+	"gender":     {Type: filter.TypeEnum, Enum: enumValues(api.Gender_value), Sortable: true},
+	"created_at": {Type: filter.TypeTimestamp, Sortable: true},
+	"updated_at": {Type: filter.TypeTimestamp, Sortable: true},
+	"email":      {Type: filter.TypeString, Sortable: true},
+	"locale":     {Type: filter.TypeString, Sortable: true},
+	"timezone":   {Type: filter.TypeString, Sortable: true},
+	"attributes": {Type: filter.TypeMap},
+	"state":      {Type: filter.TypeEnum, Enum: enumValues(api.UserState_value), Sortable: true},
+}
+
+// UserOrderTiebreak is the unique field that ends every order of users.
+const UserOrderTiebreak = "id"
+
+// UserFilterValue returns the field of u named as in UserFilterSchema, for
+// filter.Eval.
+func UserFilterValue(u *entity.User, field string) interface{} {
+	switch field {
+	case "id":
+		return u.ID
+	case "name":
+		return u.Name
+	case "gender":
+		return int64(u.Gender)
+	case "created_at":
+		return u.CreatedAt
+	case "updated_at":
+		return u.UpdatedAt
+	case "email":
+		return u.Email
+	case "locale":
+		return u.Locale
+	case "timezone":
+		return u.Timezone
+	case "attributes":
+		return map[string]interface{}(u.Attributes)
+	case "state":
+		return int64(u.State)
+	}
+	return nil
+}
+
+func enumValues(values map[string]int32) map[string]int64 {
+	ret := make(map[string]int64, len(values))
+	for name, v := range values {
+		ret[name] = int64(v)
+	}
+	return ret
+}
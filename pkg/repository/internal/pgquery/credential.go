@@ -0,0 +1,48 @@
+package pgquery
+
+import (
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+)
+
+// UserByEmail selects the user of $2 by email regardless of case, which the
+// unique index on lower(email) serves.
+const UserByEmail = "SELECT " + UserColumns + " FROM users WHERE tenant_id = $1 AND lower(email) = lower($2) AND email <> ''"
+
+// CredentialColumns are the columns read by ScanCredential.
+const CredentialColumns = "user_id, password_hash, failed_attempts, locked_until, updated_at"
+
+// SetCredential replaces the password of $2 and clears its failed attempts
+// and lockout. The columns follow CredentialColumns.
+const SetCredential = "INSERT INTO credentials(tenant_id, user_id, password_hash) VALUES ($1, $2, $3) " +
+	"ON CONFLICT (tenant_id, user_id) DO UPDATE SET password_hash = EXCLUDED.password_hash, failed_attempts = 0, locked_until = NULL, updated_at = now() " +
+	"RETURNING " + CredentialColumns
+
+// ScanCredential scans a row of CredentialColumns.
+func ScanCredential(s Scanner) (*entity.Credential, error) {
+	credential := &entity.Credential{}
+	var lockedUntil *time.Time
+	var updatedAt time.Time
+	if err := s.Scan(&credential.UserID, &credential.PasswordHash, &credential.FailedAttempts, &lockedUntil, &updatedAt); err != nil {
+		return nil, err
+	}
+	if lockedUntil != nil {
+		credential.LockedUntil = lockedUntil.UTC()
+	}
+	credential.UpdatedAt = updatedAt.UTC()
+	return credential, nil
+}
+
+// UpdateCredential writes the mutable fields of credential to the credential
+// of userID and returns the new updated_at. A zero LockedUntil is stored as
+// NULL.
+func UpdateCredential(tenantID, userID string, credential *entity.Credential) (string, []interface{}) {
+	var lockedUntil *time.Time
+	if !credential.LockedUntil.IsZero() {
+		lockedUntil = &credential.LockedUntil
+	}
+	return "UPDATE credentials SET password_hash = $3, failed_attempts = $4, locked_until = $5, updated_at = now() " +
+			"WHERE tenant_id = $1 AND user_id = $2 RETURNING updated_at",
+		[]interface{}{tenantID, userID, credential.PasswordHash, credential.FailedAttempts, lockedUntil}
+}
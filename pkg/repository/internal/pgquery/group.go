@@ -0,0 +1,93 @@
+package pgquery
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+)
+
+// GroupColumns are the columns read by ScanGroup.
+const GroupColumns = "id, name, description, created_at, updated_at"
+
+// GroupMemberColumns are the columns read by ScanGroupMember.
+const GroupMemberColumns = "group_id, user_id, role, created_at"
+
+const (
+	// InsertGroup inserts a group; created_at and updated_at take their
+	// defaults.
+	InsertGroup = "INSERT INTO groups(tenant_id, id, name, description) VALUES ($1, $2, $3, $4) RETURNING " + GroupColumns
+	// UpdateGroup writes the mutable fields of the group $2 and returns the
+	// new updated_at.
+	UpdateGroup = "UPDATE groups SET name = $3, description = $4, updated_at = now() WHERE tenant_id = $1 AND id = $2 RETURNING updated_at"
+	DeleteGroup = "DELETE FROM groups WHERE tenant_id = $1 AND id = $2"
+	GroupExists = "SELECT 1 FROM groups WHERE tenant_id = $1 AND id = $2"
+	// LockGroup holds the group $2 against a concurrent delete until the end
+	// of the transaction.
+	LockGroup         = GroupExists + " FOR SHARE"
+	UserExists        = "SELECT 1 FROM users WHERE tenant_id = $1 AND id = $2"
+	InsertGroupMember = "INSERT INTO group_members(tenant_id, group_id, user_id, role) VALUES ($1, $2, $3, $4) RETURNING " + GroupMemberColumns
+	GetGroupMember    = "SELECT " + GroupMemberColumns + " FROM group_members WHERE tenant_id = $1 AND group_id = $2 AND user_id = $3"
+	// DeleteGroupMember returns the role of the removed member.
+	DeleteGroupMember = "DELETE FROM group_members WHERE tenant_id = $1 AND group_id = $2 AND user_id = $3 RETURNING role"
+	// LockGroupOwners holds the group $2 against concurrent removals of its
+	// members, so that they do not remove its last owners at once.
+	LockGroupOwners  = GroupExists + " FOR UPDATE"
+	CountGroupOwners = "SELECT count(*) FROM group_members WHERE tenant_id = $1 AND group_id = $2 AND role = $3"
+)
+
+func ListGroups(tenantID string, params *repository.ListGroupsParams) (string, []interface{}) {
+	query, args := "SELECT "+GroupColumns+" FROM groups WHERE tenant_id = $1", []interface{}{tenantID}
+	return pageByID(query, args, "id", params.After, params.Limit)
+}
+
+func ListGroupMembers(tenantID string, params *repository.ListGroupMembersParams) (string, []interface{}) {
+	query, args := "SELECT "+GroupMemberColumns+" FROM group_members WHERE tenant_id = $1 AND group_id = $2", []interface{}{tenantID, params.GroupID}
+	return pageByID(query, args, "user_id", params.After, params.Limit)
+}
+
+// ListUserGroups selects GroupColumns followed by the role of the user.
+func ListUserGroups(tenantID string, params *repository.ListUserGroupsParams) (string, []interface{}) {
+	query := "SELECT g.id, g.name, g.description, g.created_at, g.updated_at, m.role FROM group_members m " +
+		"JOIN groups g ON g.tenant_id = m.tenant_id AND g.id = m.group_id WHERE m.tenant_id = $1 AND m.user_id = $2"
+	return pageByID(query, []interface{}{tenantID, params.UserID}, "m.group_id", params.After, params.Limit)
+}
+
+// pageByID orders query by the ID column and keeps the IDs after after.
+func pageByID(query string, args []interface{}, column, after string, limit int) (string, []interface{}) {
+	if after != "" {
+		args = append(args, after)
+		query += fmt.Sprintf(" AND %s > %s", column, placeholder(len(args)))
+	}
+	query += " ORDER BY " + column
+	if limit > 0 {
+		args = append(args, limit)
+		query += " LIMIT " + placeholder(len(args))
+	}
+	return query, args
+}
+
+// ScanGroup scans a row starting with GroupColumns, followed by extra.
+func ScanGroup(s Scanner, extra ...interface{}) (*entity.Group, error) {
+	group := &entity.Group{}
+	var createdAt, updatedAt time.Time
+	dest := append([]interface{}{&group.ID, &group.Name, &group.Description, &createdAt, &updatedAt}, extra...)
+	if err := s.Scan(dest...); err != nil {
+		return nil, err
+	}
+	group.CreatedAt = createdAt.UTC()
+	group.UpdatedAt = updatedAt.UTC()
+	return group, nil
+}
+
+// ScanGroupMember scans a row of GroupMemberColumns.
+func ScanGroupMember(s Scanner) (*entity.GroupMember, error) {
+	member := &entity.GroupMember{}
+	var createdAt time.Time
+	if err := s.Scan(&member.GroupID, &member.UserID, &member.Role, &createdAt); err != nil {
+		return nil, err
+	}
+	member.CreatedAt = createdAt.UTC()
+	return member, nil
+}
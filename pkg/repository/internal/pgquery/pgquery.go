@@ -0,0 +1,218 @@
+// Package pgquery builds the Postgres queries shared by the lib/pq and pgx
+// backends.
+package pgquery
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/filter"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/textsearch"
+)
+
+// UserColumns are the columns read by ScanUser.
+const UserColumns = "id, name, gender, email, locale, timezone, attributes, state, created_at, updated_at"
+
+// userFilterColumns maps the fields of repository.UserFilterSchema to columns.
+var userFilterColumns = map[string]string{
+	"id":         "id",
+	"name":       "name",
+	"gender":     "gender",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+	"email":      "email",
+	"locale":     "locale",
+	"timezone":   "timezone",
+	"attributes": "attributes",
+	"state":      "state",
+}
+
+// userFilterOptions compile filters of repository.UserFilterSchema.
+var userFilterOptions = &filter.SQLOptions{
+	Column:      userColumn,
+	Placeholder: placeholder,
+	Map:         userAttribute,
+}
+
+type Scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// ScanUser scans a row starting with UserColumns, followed by extra.
+func ScanUser(s Scanner, extra ...interface{}) (*entity.User, error) {
+	user := &entity.User{}
+	var attributes []byte
+	var createdAt, updatedAt time.Time
+	dest := append([]interface{}{&user.ID, &user.Name, &user.Gender, &user.Email, &user.Locale, &user.Timezone, &attributes, &user.State, &createdAt, &updatedAt}, extra...)
+	if err := s.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(attributes, &user.Attributes); err != nil {
+		return nil, err
+	}
+	user.CreatedAt = createdAt.UTC()
+	user.UpdatedAt = updatedAt.UTC()
+	return user, nil
+}
+
+// InsertUser inserts v into the tenant. created_at and updated_at take their
+// defaults.
+func InsertUser(tenantID string, v *entity.User) (string, []interface{}, error) {
+	attributes, err := json.Marshal(v.Attributes)
+	if err != nil {
+		return "", nil, err
+	}
+	return "INSERT INTO users(tenant_id, id, name, name_search, gender, email, locale, timezone, attributes, state) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)",
+		[]interface{}{tenantID, v.ID, v.Name, textsearch.Normalize(v.Name), v.Gender, v.Email, v.Locale, v.Timezone, string(attributes), v.State}, nil
+}
+
+// UpdateUser writes the mutable fields of user to the user id and returns the
+// new updated_at.
+func UpdateUser(tenantID, id string, user *entity.User) (string, []interface{}, error) {
+	attributes, err := json.Marshal(user.Attributes)
+	if err != nil {
+		return "", nil, err
+	}
+	return "UPDATE users SET name = $3, name_search = $4, gender = $5, email = $6, locale = $7, timezone = $8, attributes = $9, state = $10, updated_at = now() " +
+			"WHERE tenant_id = $1 AND id = $2 RETURNING updated_at",
+		[]interface{}{tenantID, id, user.Name, textsearch.Normalize(user.Name), user.Gender, user.Email, user.Locale, user.Timezone, string(attributes), user.State}, nil
+}
+
+func ListUsers(tenantID string, params *repository.ListUsersParams) (string, []interface{}, error) {
+	query, args := "SELECT "+UserColumns+" FROM users WHERE tenant_id = $1", []interface{}{tenantID}
+	if params.Name != "" {
+		args = append(args, params.Name)
+		query += fmt.Sprintf(" AND name = $%d", len(args))
+	}
+	if params.Filter != nil {
+		cond, filterArgs, err := filter.SQL(params.Filter, args, userFilterOptions)
+		if err != nil {
+			return "", nil, err
+		}
+		query += " AND " + cond
+		args = filterArgs
+	}
+	query += " ORDER BY " + filter.OrderSQL(params.Order(), userColumn)
+	if params.Limit > 0 {
+		args = append(args, params.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	return query, args, nil
+}
+
+// SearchUsers matches names with the trigram index on name_search. The score
+// follows UserColumns.
+func SearchUsers(tenantID string, params *repository.SearchUsersParams) (string, []interface{}) {
+	query := "SELECT " + UserColumns + ", similarity(name_search, $2)::float8 AS score FROM users WHERE tenant_id = $1"
+	args := []interface{}{tenantID, params.Query}
+	switch params.Mode {
+	case textsearch.ModePrefix:
+		args = append(args, textsearch.EscapeLike(params.Query)+"%")
+		query += fmt.Sprintf(" AND name_search LIKE $%d", len(args))
+	case textsearch.ModeFuzzy:
+		// uses pg_trgm.similarity_threshold, which defaults to textsearch.SimilarityThreshold
+		query += " AND name_search % $2"
+	default:
+		args = append(args, "%"+textsearch.EscapeLike(params.Query)+"%")
+		query += fmt.Sprintf(" AND name_search LIKE $%d", len(args))
+	}
+	query += " ORDER BY score DESC, id"
+	if params.Limit > 0 {
+		args = append(args, params.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	args = append(args, params.Offset)
+	query += fmt.Sprintf(" OFFSET $%d", len(args))
+	return query, args
+}
+
+// UserStats aggregates the users in a single scan: the rows of the gender
+// grouping set come first, then the sign-up buckets. The rows are read with
+// AddUserStatsRow.
+func UserStats(tenantID string, params *repository.UserStatsParams) (string, []interface{}, error) {
+	args := []interface{}{tenantID, params.Bucket.Unit(), params.From, params.To}
+	where := "tenant_id = $1"
+	if params.Filter != nil {
+		cond, filterArgs, err := filter.SQL(params.Filter, args, userFilterOptions)
+		if err != nil {
+			return "", nil, err
+		}
+		where += " AND " + cond
+		args = filterArgs
+	}
+	query := "SELECT GROUPING(gender), gender, bucket, count(*) FROM (" +
+		"SELECT gender, CASE WHEN created_at >= $3 AND created_at < $4 THEN date_trunc($2, created_at, 'UTC') END AS bucket " +
+		"FROM users WHERE " + where +
+		") AS u GROUP BY GROUPING SETS ((gender), (bucket)) ORDER BY 1, bucket"
+	return query, args, nil
+}
+
+// AddUserStatsRow adds a row of the UserStats query to stats.
+func AddUserStatsRow(s Scanner, stats *repository.UserStats) error {
+	var grouping int
+	var gender *int64
+	var bucket *time.Time
+	var n int64
+	if err := s.Scan(&grouping, &gender, &bucket, &n); err != nil {
+		return err
+	}
+	switch {
+	case grouping == 0:
+		stats.Total += n
+		stats.ByGender[entity.Gender(*gender)] += n
+	case bucket != nil:
+		// users created out of the range have no bucket
+		stats.Signups = append(stats.Signups, &repository.SignupCount{Start: bucket.UTC(), Count: n})
+	}
+	return nil
+}
+
+// userColumn returns the column of a field of repository.UserFilterSchema.
+// Only the columns in userFilterColumns are ever written into queries.
+func userColumn(field string) string {
+	return userFilterColumns[field]
+}
+
+// userAttribute compiles the comparisons of attributes. Equality is JSON
+// containment, which the GIN index on attributes serves; the other operators
+// compare JSON values of the same type.
+func userAttribute(c *filter.Comparison, args []interface{}) (string, []interface{}) {
+	column := userColumn(c.Field)
+	if c.Op == filter.OpHas {
+		args = append(args, c.Value)
+		return fmt.Sprintf("%s ? $%d", column, len(args)), args
+	}
+	if c.Op == filter.OpEq {
+		// numbers are contained regardless of their scale, as 1 = 1.0 in Eval
+		v, _ := json.Marshal(map[string]interface{}{c.Key: c.Value})
+		args = append(args, string(v))
+		return fmt.Sprintf("%s @> $%d::jsonb", column, len(args)), args
+	}
+	v, _ := json.Marshal(c.Value)
+	args = append(args, c.Key, string(v))
+	key, value := len(args)-1, len(args)
+	op := string(c.Op)
+	if c.Op == filter.OpNe {
+		op = "<>"
+	}
+	return fmt.Sprintf("COALESCE(jsonb_typeof(%[1]s -> $%[2]d::text) = '%[3]s' AND %[1]s -> $%[2]d::text %[4]s $%[5]d::jsonb, FALSE)",
+		column, key, jsonType(c.Value), op, value), args
+}
+
+// jsonType returns the jsonb_typeof of the values of filter comparisons.
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case int64, float64:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return "string"
+}
+
+func placeholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
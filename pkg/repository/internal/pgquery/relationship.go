@@ -0,0 +1,57 @@
+package pgquery
+
+import (
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+)
+
+// RelationshipColumns are the columns read by ScanRelationship.
+const RelationshipColumns = "user_id, target_id, kind, created_at"
+
+const (
+	// LockRelationshipPair serializes the writes between the users $2 and $3
+	// in either direction until the end of the transaction, so that a follow
+	// does not slip past a concurrent block. Collisions of the hash only make
+	// unrelated pairs wait.
+	LockRelationshipPair = "SELECT pg_advisory_xact_lock(hashtext($1::text || '/' || LEAST($2::text, $3::text) || '/' || GREATEST($2::text, $3::text)))"
+	// RelationshipsBetween selects user_id and kind of the relationships
+	// between the users $2 and $3 in either direction.
+	RelationshipsBetween = "SELECT user_id, kind FROM relationships WHERE tenant_id = $1 AND ((user_id = $2 AND target_id = $3) OR (user_id = $3 AND target_id = $2))"
+	// InsertRelationship inserts a relationship; created_at takes its default.
+	InsertRelationship = "INSERT INTO relationships(tenant_id, user_id, target_id, kind) VALUES ($1, $2, $3, $4) RETURNING " + RelationshipColumns
+	DeleteRelationship = "DELETE FROM relationships WHERE tenant_id = $1 AND user_id = $2 AND target_id = $3 AND kind = $4"
+	// DeleteFollowsBetween deletes the follows between the users $2 and $3 in
+	// either direction.
+	DeleteFollowsBetween = "DELETE FROM relationships WHERE tenant_id = $1 AND kind = 0 AND ((user_id = $2 AND target_id = $3) OR (user_id = $3 AND target_id = $2))"
+)
+
+func ListFollowers(tenantID string, params *repository.ListRelationshipsParams) (string, []interface{}) {
+	query := "SELECT " + RelationshipColumns + " FROM relationships WHERE tenant_id = $1 AND target_id = $2 AND kind = 0"
+	return pageByID(query, []interface{}{tenantID, params.UserID}, "user_id", params.After, params.Limit)
+}
+
+func ListFollowing(tenantID string, params *repository.ListRelationshipsParams) (string, []interface{}) {
+	query := "SELECT " + RelationshipColumns + " FROM relationships WHERE tenant_id = $1 AND user_id = $2 AND kind = 0"
+	return pageByID(query, []interface{}{tenantID, params.UserID}, "target_id", params.After, params.Limit)
+}
+
+// ListMutualFollows selects the follows by the user that are followed back.
+func ListMutualFollows(tenantID string, params *repository.ListRelationshipsParams) (string, []interface{}) {
+	query := "SELECT r.user_id, r.target_id, r.kind, r.created_at FROM relationships r " +
+		"JOIN relationships b ON b.tenant_id = r.tenant_id AND b.user_id = r.target_id AND b.target_id = r.user_id AND b.kind = 0 " +
+		"WHERE r.tenant_id = $1 AND r.user_id = $2 AND r.kind = 0"
+	return pageByID(query, []interface{}{tenantID, params.UserID}, "r.target_id", params.After, params.Limit)
+}
+
+// ScanRelationship scans a row of RelationshipColumns.
+func ScanRelationship(s Scanner) (*entity.Relationship, error) {
+	rel := &entity.Relationship{}
+	var createdAt time.Time
+	if err := s.Scan(&rel.UserID, &rel.TargetID, &rel.Kind, &createdAt); err != nil {
+		return nil, err
+	}
+	rel.CreatedAt = createdAt.UTC()
+	return rel, nil
+}
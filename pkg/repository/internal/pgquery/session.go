@@ -0,0 +1,45 @@
+package pgquery
+
+import (
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+)
+
+// SessionColumns are the columns read by ScanSession.
+const SessionColumns = "id, user_id, created_at, refreshed_at, expires_at, revoked_at, reuse_detected"
+
+const (
+	InsertSession = "INSERT INTO sessions(tenant_id, id, user_id, created_at, refreshed_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6) " +
+		"RETURNING " + SessionColumns
+	InsertRefreshToken = "INSERT INTO refresh_tokens(tenant_id, token_hash, session_id, created_at) VALUES ($1, $2, $3, $4)"
+	// GetRefreshToken locks the token of $2 and returns its session and
+	// whether it has been used.
+	GetRefreshToken = "SELECT session_id, used_at IS NOT NULL FROM refresh_tokens WHERE tenant_id = $1 AND token_hash = $2 FOR UPDATE"
+	UseRefreshToken = "UPDATE refresh_tokens SET used_at = $3 WHERE tenant_id = $1 AND token_hash = $2"
+	RefreshSession  = "UPDATE sessions SET refreshed_at = $3 WHERE tenant_id = $1 AND id = $2"
+	// RevokeSession revokes the session $2 at $3 unless it is revoked already.
+	RevokeSession = "UPDATE sessions SET revoked_at = COALESCE(revoked_at, $3), reuse_detected = reuse_detected OR (revoked_at IS NULL AND $4) " +
+		"WHERE tenant_id = $1 AND id = $2 RETURNING " + SessionColumns
+	ListSessions = "SELECT " + SessionColumns + " FROM sessions WHERE tenant_id = $1 AND user_id = $2 ORDER BY created_at DESC, id"
+	// DeleteExpiredSessions deletes in batches so that a backlog does not
+	// hold many locks at once. The refresh tokens cascade.
+	DeleteExpiredSessions = "DELETE FROM sessions WHERE (tenant_id, id) IN (SELECT tenant_id, id FROM sessions WHERE expires_at < $1 LIMIT $2)"
+)
+
+// ScanSession scans a row of SessionColumns.
+func ScanSession(s Scanner) (*entity.Session, error) {
+	session := &entity.Session{}
+	var createdAt, refreshedAt, expiresAt time.Time
+	var revokedAt *time.Time
+	if err := s.Scan(&session.ID, &session.UserID, &createdAt, &refreshedAt, &expiresAt, &revokedAt, &session.ReuseDetected); err != nil {
+		return nil, err
+	}
+	session.CreatedAt = createdAt.UTC()
+	session.RefreshedAt = refreshedAt.UTC()
+	session.ExpiresAt = expiresAt.UTC()
+	if revokedAt != nil {
+		session.RevokedAt = revokedAt.UTC()
+	}
+	return session, nil
+}
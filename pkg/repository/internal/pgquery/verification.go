@@ -0,0 +1,32 @@
+package pgquery
+
+import (
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+)
+
+// VerificationColumns are the columns read by ScanVerification.
+const VerificationColumns = "user_id, email, created_at, expires_at"
+
+// SetVerification replaces the verification of the user $2 with the token
+// $3. The columns follow VerificationColumns.
+const SetVerification = "INSERT INTO email_verifications(tenant_id, user_id, token_hash, email, created_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6) " +
+	"ON CONFLICT (tenant_id, user_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, email = EXCLUDED.email, created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at " +
+	"RETURNING " + VerificationColumns
+
+// ConsumeVerification deletes the verification of the token $2 and returns
+// its VerificationColumns.
+const ConsumeVerification = "DELETE FROM email_verifications WHERE tenant_id = $1 AND token_hash = $2 RETURNING " + VerificationColumns
+
+// ScanVerification scans a row of VerificationColumns.
+func ScanVerification(s Scanner) (*entity.Verification, error) {
+	v := &entity.Verification{}
+	var createdAt, expiresAt time.Time
+	if err := s.Scan(&v.UserID, &v.Email, &createdAt, &expiresAt); err != nil {
+		return nil, err
+	}
+	v.CreatedAt = createdAt.UTC()
+	v.ExpiresAt = expiresAt.UTC()
+	return v, nil
+}
@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+)
+
+var _ repository.Credential = (*Credential)(nil)
+
+// Credential is an in-process implementation of repository.Credential for the
+// users of a User.
+type Credential struct {
+	users *User
+}
+
+func NewCredential(users *User) *Credential {
+	return &Credential{users: users}
+}
+
+func (c *Credential) Get(ctx context.Context, userID string) (*entity.Credential, error) {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.users.mu.RLock()
+	defer c.users.mu.RUnlock()
+
+	credential, ok := c.users.credentials[tenantID][userID]
+	if !ok {
+		return nil, errcode.NewNotFound("password not set for user: %s", userID)
+	}
+	return copyCredential(credential), nil
+}
+
+func (c *Credential) Set(ctx context.Context, userID, passwordHash string) (*entity.Credential, error) {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.users.mu.Lock()
+	defer c.users.mu.Unlock()
+
+	if _, ok := c.users.tenants[tenantID][userID]; !ok {
+		return nil, errcode.NewNotFound("user not found: %s", userID)
+	}
+	credentials, ok := c.users.credentials[tenantID]
+	if !ok {
+		credentials = map[string]*entity.Credential{}
+		c.users.credentials[tenantID] = credentials
+	}
+	credential := &entity.Credential{
+		UserID:       userID,
+		PasswordHash: passwordHash,
+		UpdatedAt:    now(),
+	}
+	credentials[userID] = credential
+	return copyCredential(credential), nil
+}
+
+func (c *Credential) Update(ctx context.Context, userID string, update func(*entity.Credential) bool) (*entity.Credential, error) {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.users.mu.Lock()
+	defer c.users.mu.Unlock()
+
+	current, ok := c.users.credentials[tenantID][userID]
+	if !ok {
+		return nil, errcode.NewNotFound("password not set for user: %s", userID)
+	}
+	credential := copyCredential(current)
+	if !update(credential) {
+		return credential, nil
+	}
+	credential.UserID = userID
+	credential.LockedUntil = truncate(credential.LockedUntil)
+	credential.UpdatedAt = now()
+	c.users.credentials[tenantID][userID] = copyCredential(credential)
+	return credential, nil
+}
+
+func copyCredential(c *entity.Credential) *entity.Credential {
+	credential := *c
+	return &credential
+}
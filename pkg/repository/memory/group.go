@@ -0,0 +1,289 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+)
+
+var _ repository.Group = (*Group)(nil)
+
+// Group is an in-process implementation of repository.Group for the users of
+// a User.
+type Group struct {
+	users *User
+}
+
+func NewGroup(users *User) *Group {
+	return &Group{users: users}
+}
+
+func (g *Group) Create(ctx context.Context, v *entity.Group, ownerID string) (*entity.Group, error) {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	g.users.mu.Lock()
+	defer g.users.mu.Unlock()
+
+	groups, ok := g.users.groups[tenantID]
+	if !ok {
+		groups = map[string]*entity.Group{}
+		g.users.groups[tenantID] = groups
+	}
+	if _, ok := groups[v.ID]; ok {
+		return nil, errcode.NewAlreadyExists("group already exists: %s", v.ID)
+	}
+	if ownerID != "" {
+		if _, ok := g.users.tenants[tenantID][ownerID]; !ok {
+			return nil, errcode.NewFailedPrecondition("owner does not exist: %s", ownerID)
+		}
+	}
+	group := *v
+	group.CreatedAt = now()
+	group.UpdatedAt = group.CreatedAt
+	groups[v.ID] = &group
+	if ownerID != "" {
+		g.users.addMember(tenantID, &entity.GroupMember{GroupID: v.ID, UserID: ownerID, Role: entity.GroupRoleOwner})
+	}
+	ret := group
+	return &ret, nil
+}
+
+func (g *Group) Get(ctx context.Context, id string) (*entity.Group, error) {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	g.users.mu.RLock()
+	defer g.users.mu.RUnlock()
+
+	group, ok := g.users.groups[tenantID][id]
+	if !ok {
+		return nil, errcode.NewNotFound("group not found: %s", id)
+	}
+	ret := *group
+	return &ret, nil
+}
+
+func (g *Group) List(ctx context.Context, params *repository.ListGroupsParams) (entity.Groups, error) {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	g.users.mu.RLock()
+	defer g.users.mu.RUnlock()
+
+	ids := make([]string, 0, len(g.users.groups[tenantID]))
+	for id := range g.users.groups[tenantID] {
+		ids = append(ids, id)
+	}
+	ids = pageByID(ids, params.After, params.Limit)
+	groups := make(entity.Groups, 0, len(ids))
+	for _, id := range ids {
+		group := *g.users.groups[tenantID][id]
+		groups = append(groups, &group)
+	}
+	return groups, nil
+}
+
+func (g *Group) Update(ctx context.Context, id string, update func(*entity.Group) bool) (*entity.Group, error) {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	g.users.mu.Lock()
+	defer g.users.mu.Unlock()
+
+	stored, ok := g.users.groups[tenantID][id]
+	if !ok {
+		return nil, errcode.NewNotFound("group not found: %s", id)
+	}
+	group := *stored
+	if !update(&group) {
+		return &group, nil
+	}
+	group.ID = id
+	group.CreatedAt = stored.CreatedAt
+	group.UpdatedAt = now()
+	*stored = group
+	return &group, nil
+}
+
+func (g *Group) Delete(ctx context.Context, id string) error {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	g.users.mu.Lock()
+	defer g.users.mu.Unlock()
+
+	if _, ok := g.users.groups[tenantID][id]; !ok {
+		return errcode.NewNotFound("group not found: %s", id)
+	}
+	delete(g.users.groups[tenantID], id)
+	delete(g.users.members[tenantID], id)
+	return nil
+}
+
+func (g *Group) AddMember(ctx context.Context, m *entity.GroupMember) (*entity.GroupMember, error) {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	g.users.mu.Lock()
+	defer g.users.mu.Unlock()
+
+	if _, ok := g.users.groups[tenantID][m.GroupID]; !ok {
+		return nil, errcode.NewNotFound("group not found: %s", m.GroupID)
+	}
+	if _, ok := g.users.tenants[tenantID][m.UserID]; !ok {
+		return nil, errcode.NewFailedPrecondition("user does not exist: %s", m.UserID)
+	}
+	if _, ok := g.users.members[tenantID][m.GroupID][m.UserID]; ok {
+		return nil, errcode.NewAlreadyExists("%s is a member of group %s already", m.UserID, m.GroupID)
+	}
+	member := g.users.addMember(tenantID, m)
+	ret := *member
+	return &ret, nil
+}
+
+func (g *Group) GetMember(ctx context.Context, groupID, userID string) (*entity.GroupMember, error) {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	g.users.mu.RLock()
+	defer g.users.mu.RUnlock()
+
+	member, ok := g.users.members[tenantID][groupID][userID]
+	if !ok {
+		return nil, errcode.NewNotFound("%s is not a member of group %s", userID, groupID)
+	}
+	ret := *member
+	return &ret, nil
+}
+
+func (g *Group) RemoveMember(ctx context.Context, groupID, userID string) error {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	g.users.mu.Lock()
+	defer g.users.mu.Unlock()
+
+	members := g.users.members[tenantID][groupID]
+	member, ok := members[userID]
+	if !ok {
+		return errcode.NewNotFound("%s is not a member of group %s", userID, groupID)
+	}
+	if member.Role == entity.GroupRoleOwner {
+		owners := 0
+		for _, m := range members {
+			if m.Role == entity.GroupRoleOwner {
+				owners++
+			}
+		}
+		if owners == 1 {
+			return errcode.NewFailedPrecondition("%s is the last owner of group %s", userID, groupID)
+		}
+	}
+	delete(members, userID)
+	return nil
+}
+
+func (g *Group) ListMembers(ctx context.Context, params *repository.ListGroupMembersParams) (entity.GroupMembers, error) {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	g.users.mu.RLock()
+	defer g.users.mu.RUnlock()
+
+	if _, ok := g.users.groups[tenantID][params.GroupID]; !ok {
+		return nil, errcode.NewNotFound("group not found: %s", params.GroupID)
+	}
+	members := g.users.members[tenantID][params.GroupID]
+	ids := make([]string, 0, len(members))
+	for id := range members {
+		ids = append(ids, id)
+	}
+	ids = pageByID(ids, params.After, params.Limit)
+	ret := make(entity.GroupMembers, 0, len(ids))
+	for _, id := range ids {
+		member := *members[id]
+		ret = append(ret, &member)
+	}
+	return ret, nil
+}
+
+func (g *Group) ListUserGroups(ctx context.Context, params *repository.ListUserGroupsParams) ([]*repository.UserGroup, error) {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	g.users.mu.RLock()
+	defer g.users.mu.RUnlock()
+
+	if _, ok := g.users.tenants[tenantID][params.UserID]; !ok {
+		return nil, errcode.NewNotFound("user not found: %s", params.UserID)
+	}
+	roles := map[string]entity.GroupRole{}
+	var ids []string
+	for groupID, members := range g.users.members[tenantID] {
+		if member, ok := members[params.UserID]; ok {
+			roles[groupID] = member.Role
+			ids = append(ids, groupID)
+		}
+	}
+	ids = pageByID(ids, params.After, params.Limit)
+	ret := make([]*repository.UserGroup, 0, len(ids))
+	for _, id := range ids {
+		group := *g.users.groups[tenantID][id]
+		ret = append(ret, &repository.UserGroup{Group: &group, Role: roles[id]})
+	}
+	return ret, nil
+}
+
+func (u *User) addMember(tenantID string, m *entity.GroupMember) *entity.GroupMember {
+	groups, ok := u.members[tenantID]
+	if !ok {
+		groups = map[string]map[string]*entity.GroupMember{}
+		u.members[tenantID] = groups
+	}
+	members, ok := groups[m.GroupID]
+	if !ok {
+		members = map[string]*entity.GroupMember{}
+		groups[m.GroupID] = members
+	}
+	member := *m
+	member.CreatedAt = now()
+	members[m.UserID] = &member
+	return &member
+}
+
+// pageByID sorts ids and returns those after after, up to limit of them.
+func pageByID(ids []string, after string, limit int) []string {
+	sort.Strings(ids)
+	ids = ids[sort.SearchStrings(ids, after):]
+	if len(ids) > 0 && ids[0] == after {
+		ids = ids[1:]
+	}
+	if limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids
+}
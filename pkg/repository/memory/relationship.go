@@ -0,0 +1,201 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+)
+
+var _ repository.Relationship = (*Relationship)(nil)
+
+// Relationship is an in-process implementation of repository.Relationship
+// for the users of a User.
+type Relationship struct {
+	users *User
+}
+
+func NewRelationship(users *User) *Relationship {
+	return &Relationship{users: users}
+}
+
+func (r *Relationship) Follow(ctx context.Context, userID, targetID string) (*entity.Relationship, error) {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if userID == targetID {
+		return nil, errcode.NewInvalidArgument("user cannot follow itself: %s", userID)
+	}
+
+	r.users.mu.Lock()
+	defer r.users.mu.Unlock()
+
+	if err := r.users.checkRelated(tenantID, userID, targetID); err != nil {
+		return nil, err
+	}
+	if r.users.relationship(tenantID, userID, targetID, entity.RelationshipKindBlock) != nil ||
+		r.users.relationship(tenantID, targetID, userID, entity.RelationshipKindBlock) != nil {
+		return nil, errcode.NewFailedPrecondition("relationship between %s and %s is blocked", userID, targetID)
+	}
+	if r.users.relationship(tenantID, userID, targetID, entity.RelationshipKindFollow) != nil {
+		return nil, errcode.NewAlreadyExists("%s follows %s already", userID, targetID)
+	}
+	return r.users.relate(tenantID, userID, targetID, entity.RelationshipKindFollow), nil
+}
+
+func (r *Relationship) Unfollow(ctx context.Context, userID, targetID string) error {
+	return r.delete(ctx, userID, targetID, entity.RelationshipKindFollow)
+}
+
+func (r *Relationship) Block(ctx context.Context, userID, targetID string) (*entity.Relationship, error) {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if userID == targetID {
+		return nil, errcode.NewInvalidArgument("user cannot block itself: %s", userID)
+	}
+
+	r.users.mu.Lock()
+	defer r.users.mu.Unlock()
+
+	if err := r.users.checkRelated(tenantID, userID, targetID); err != nil {
+		return nil, err
+	}
+	if r.users.relationship(tenantID, userID, targetID, entity.RelationshipKindBlock) != nil {
+		return nil, errcode.NewAlreadyExists("%s blocks %s already", userID, targetID)
+	}
+	if r.users.relationship(tenantID, targetID, userID, entity.RelationshipKindFollow) != nil {
+		delete(r.users.relationships[tenantID][targetID], userID)
+	}
+	return r.users.relate(tenantID, userID, targetID, entity.RelationshipKindBlock), nil
+}
+
+func (r *Relationship) Unblock(ctx context.Context, userID, targetID string) error {
+	return r.delete(ctx, userID, targetID, entity.RelationshipKindBlock)
+}
+
+func (r *Relationship) delete(ctx context.Context, userID, targetID string, kind entity.RelationshipKind) error {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	r.users.mu.Lock()
+	defer r.users.mu.Unlock()
+
+	if r.users.relationship(tenantID, userID, targetID, kind) == nil {
+		return errcode.NewNotFound("%s relationship not found: %s -> %s", kind, userID, targetID)
+	}
+	delete(r.users.relationships[tenantID][userID], targetID)
+	return nil
+}
+
+func (r *Relationship) ListFollowers(ctx context.Context, params *repository.ListRelationshipsParams) (entity.Relationships, error) {
+	return r.list(ctx, params, func(rels map[string]map[string]*entity.Relationship) map[string]*entity.Relationship {
+		followers := map[string]*entity.Relationship{}
+		for userID, targets := range rels {
+			if rel, ok := targets[params.UserID]; ok && rel.Kind == entity.RelationshipKindFollow {
+				followers[userID] = rel
+			}
+		}
+		return followers
+	})
+}
+
+func (r *Relationship) ListFollowing(ctx context.Context, params *repository.ListRelationshipsParams) (entity.Relationships, error) {
+	return r.list(ctx, params, func(rels map[string]map[string]*entity.Relationship) map[string]*entity.Relationship {
+		following := map[string]*entity.Relationship{}
+		for targetID, rel := range rels[params.UserID] {
+			if rel.Kind == entity.RelationshipKindFollow {
+				following[targetID] = rel
+			}
+		}
+		return following
+	})
+}
+
+func (r *Relationship) ListMutualFollows(ctx context.Context, params *repository.ListRelationshipsParams) (entity.Relationships, error) {
+	return r.list(ctx, params, func(rels map[string]map[string]*entity.Relationship) map[string]*entity.Relationship {
+		mutual := map[string]*entity.Relationship{}
+		for targetID, rel := range rels[params.UserID] {
+			back, ok := rels[targetID][params.UserID]
+			if rel.Kind == entity.RelationshipKindFollow && ok && back.Kind == entity.RelationshipKindFollow {
+				mutual[targetID] = rel
+			}
+		}
+		return mutual
+	})
+}
+
+// list pages the relationships returned by match, which keys them by the ID
+// of the other user.
+func (r *Relationship) list(ctx context.Context, params *repository.ListRelationshipsParams, match func(map[string]map[string]*entity.Relationship) map[string]*entity.Relationship) (entity.Relationships, error) {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r.users.mu.RLock()
+	defer r.users.mu.RUnlock()
+
+	if _, ok := r.users.tenants[tenantID][params.UserID]; !ok {
+		return nil, errcode.NewNotFound("user not found: %s", params.UserID)
+	}
+	matched := match(r.users.relationships[tenantID])
+	ids := make([]string, 0, len(matched))
+	for id := range matched {
+		ids = append(ids, id)
+	}
+	ids = pageByID(ids, params.After, params.Limit)
+	ret := make(entity.Relationships, 0, len(ids))
+	for _, id := range ids {
+		rel := *matched[id]
+		ret = append(ret, &rel)
+	}
+	return ret, nil
+}
+
+// checkRelated returns NotFound when the user does not exist and
+// FailedPrecondition when the target does not, like the foreign keys of the
+// databases.
+func (u *User) checkRelated(tenantID, userID, targetID string) error {
+	if _, ok := u.tenants[tenantID][userID]; !ok {
+		return errcode.NewNotFound("user not found: %s", userID)
+	}
+	if _, ok := u.tenants[tenantID][targetID]; !ok {
+		return errcode.NewFailedPrecondition("target does not exist: %s", targetID)
+	}
+	return nil
+}
+
+// relationship returns the relationship of the kind from the user to the
+// target, or nil.
+func (u *User) relationship(tenantID, userID, targetID string, kind entity.RelationshipKind) *entity.Relationship {
+	rel, ok := u.relationships[tenantID][userID][targetID]
+	if !ok || rel.Kind != kind {
+		return nil
+	}
+	return rel
+}
+
+// relate stores the relationship from the user to the target, replacing the
+// one there is.
+func (u *User) relate(tenantID, userID, targetID string, kind entity.RelationshipKind) *entity.Relationship {
+	users, ok := u.relationships[tenantID]
+	if !ok {
+		users = map[string]map[string]*entity.Relationship{}
+		u.relationships[tenantID] = users
+	}
+	targets, ok := users[userID]
+	if !ok {
+		targets = map[string]*entity.Relationship{}
+		users[userID] = targets
+	}
+	rel := &entity.Relationship{UserID: userID, TargetID: targetID, Kind: kind, CreatedAt: now()}
+	targets[targetID] = rel
+	ret := *rel
+	return &ret
+}
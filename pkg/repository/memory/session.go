@@ -0,0 +1,206 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+)
+
+var _ repository.Session = (*Session)(nil)
+
+// Session is an in-process implementation of repository.Session for the
+// users of a User.
+type Session struct {
+	users *User
+}
+
+func NewSession(users *User) *Session {
+	return &Session{users: users}
+}
+
+// refreshToken is a refresh token of a session by its hash.
+type refreshToken struct {
+	sessionID string
+	used      bool
+}
+
+func (s *Session) Create(ctx context.Context, v *entity.Session, tokenHash string) (*entity.Session, error) {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.users.mu.Lock()
+	defer s.users.mu.Unlock()
+
+	if _, ok := s.users.tenants[tenantID][v.UserID]; !ok {
+		return nil, errcode.NewNotFound("user not found: %s", v.UserID)
+	}
+	sessions, ok := s.users.sessions[tenantID]
+	if !ok {
+		sessions = map[string]*entity.Session{}
+		s.users.sessions[tenantID] = sessions
+	}
+	tokens, ok := s.users.refreshTokens[tenantID]
+	if !ok {
+		tokens = map[string]*refreshToken{}
+		s.users.refreshTokens[tenantID] = tokens
+	}
+	if _, ok := sessions[v.ID]; ok {
+		return nil, errcode.NewAlreadyExists("session already exists: %s", v.ID)
+	}
+	if _, ok := tokens[tokenHash]; ok {
+		return nil, errcode.NewAlreadyExists("refresh token already exists")
+	}
+	session := copySession(v)
+	session.CreatedAt = truncate(session.CreatedAt)
+	session.RefreshedAt = truncate(session.RefreshedAt)
+	session.ExpiresAt = truncate(session.ExpiresAt)
+	session.RevokedAt = truncate(session.RevokedAt)
+	sessions[v.ID] = session
+	tokens[tokenHash] = &refreshToken{sessionID: v.ID}
+	return copySession(session), nil
+}
+
+func (s *Session) Get(ctx context.Context, id string) (*entity.Session, error) {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.users.mu.RLock()
+	defer s.users.mu.RUnlock()
+
+	session, ok := s.users.sessions[tenantID][id]
+	if !ok {
+		return nil, errcode.NewNotFound("session not found: %s", id)
+	}
+	return copySession(session), nil
+}
+
+func (s *Session) List(ctx context.Context, params *repository.ListSessionsParams) (entity.Sessions, error) {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.users.mu.RLock()
+	defer s.users.mu.RUnlock()
+
+	sessions := entity.Sessions{}
+	for _, session := range s.users.sessions[tenantID] {
+		if session.UserID == params.UserID {
+			sessions = append(sessions, copySession(session))
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].CreatedAt.Equal(sessions[j].CreatedAt) {
+			return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+		}
+		return sessions[i].ID < sessions[j].ID
+	})
+	if params.Limit > 0 && len(sessions) > params.Limit {
+		sessions = sessions[:params.Limit]
+	}
+	return sessions, nil
+}
+
+func (s *Session) Rotate(ctx context.Context, tokenHash, newHash string, now time.Time) (*entity.Session, error) {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.users.mu.Lock()
+	defer s.users.mu.Unlock()
+
+	tokens := s.users.refreshTokens[tenantID]
+	token, ok := tokens[tokenHash]
+	if !ok {
+		return nil, errcode.NewNotFound("refresh token not found")
+	}
+	session := s.users.sessions[tenantID][token.sessionID]
+	now = truncate(now)
+	switch {
+	case token.used:
+		if session.RevokedAt.IsZero() {
+			session.RevokedAt = now
+			session.ReuseDetected = true
+		}
+	case session.Active(now):
+		if _, ok := tokens[newHash]; ok {
+			return nil, errcode.NewAlreadyExists("refresh token already exists")
+		}
+		token.used = true
+		tokens[newHash] = &refreshToken{sessionID: session.ID}
+		session.RefreshedAt = now
+	}
+	return copySession(session), nil
+}
+
+func (s *Session) Revoke(ctx context.Context, id string, now time.Time) (*entity.Session, error) {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.users.mu.Lock()
+	defer s.users.mu.Unlock()
+
+	session, ok := s.users.sessions[tenantID][id]
+	if !ok {
+		return nil, errcode.NewNotFound("session not found: %s", id)
+	}
+	if session.RevokedAt.IsZero() {
+		session.RevokedAt = truncate(now)
+	}
+	return copySession(session), nil
+}
+
+func (s *Session) DeleteExpired(ctx context.Context, before time.Time, limit int) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, errcode.New(err)
+	}
+
+	s.users.mu.Lock()
+	defer s.users.mu.Unlock()
+
+	var n int64
+	for tenantID, sessions := range s.users.sessions {
+		for id, session := range sessions {
+			if n >= int64(limit) {
+				return n, nil
+			}
+			if session.ExpiresAt.Before(before) {
+				s.users.deleteSession(tenantID, id)
+				n++
+			}
+		}
+	}
+	return n, nil
+}
+
+// deleteSession deletes the session and its refresh tokens. The caller holds
+// the write lock.
+func (u *User) deleteSession(tenantID, id string) {
+	delete(u.sessions[tenantID], id)
+	for hash, token := range u.refreshTokens[tenantID] {
+		if token.sessionID == id {
+			delete(u.refreshTokens[tenantID], hash)
+		}
+	}
+}
+
+// truncate returns t in UTC at the precision of the SQL backends.
+func truncate(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
+
+func copySession(s *entity.Session) *entity.Session {
+	session := *s
+	return &session
+}
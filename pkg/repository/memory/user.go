@@ -0,0 +1,275 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/filter"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+)
+
+var _ repository.User = (*User)(nil)
+
+// User is an in-process implementation of repository.User for tests and
+// single-process deployments. Rows are partitioned by tenant.
+type User struct {
+	mu      sync.RWMutex
+	tenants map[string]map[string]*entity.User
+	// credentials, sessions, verifications, groups and relationships are
+	// those of Credential, Session, Verification, Group and Relationship, kept
+	// here so that they are deleted with their users.
+	credentials   map[string]map[string]*entity.Credential
+	sessions      map[string]map[string]*entity.Session
+	refreshTokens map[string]map[string]*refreshToken
+	verifications map[string]map[string]*verification
+	groups        map[string]map[string]*entity.Group
+	// members are by group and then by user.
+	members map[string]map[string]map[string]*entity.GroupMember
+	// relationships are by user and then by target.
+	relationships map[string]map[string]map[string]*entity.Relationship
+}
+
+func NewUser() *User {
+	return &User{
+		tenants:       map[string]map[string]*entity.User{},
+		credentials:   map[string]map[string]*entity.Credential{},
+		sessions:      map[string]map[string]*entity.Session{},
+		refreshTokens: map[string]map[string]*refreshToken{},
+		verifications: map[string]map[string]*verification{},
+		groups:        map[string]map[string]*entity.Group{},
+		members:       map[string]map[string]map[string]*entity.GroupMember{},
+		relationships: map[string]map[string]map[string]*entity.Relationship{},
+	}
+}
+
+func (u *User) Create(ctx context.Context, v *entity.User) (*entity.User, error) {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	users, ok := u.tenants[tenantID]
+	if !ok {
+		users = map[string]*entity.User{}
+		u.tenants[tenantID] = users
+	}
+	if _, ok := users[v.ID]; ok {
+		return nil, errcode.NewAlreadyExists("user already exists: %s", v.ID)
+	}
+	if err := checkUnique(users, v); err != nil {
+		return nil, err
+	}
+	user := copyUser(v)
+	user.CreatedAt = now()
+	user.UpdatedAt = user.CreatedAt
+	users[v.ID] = user
+	return copyUser(user), nil
+}
+
+func (u *User) Get(ctx context.Context, id string) (*entity.User, error) {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	user, ok := u.tenants[tenantID][id]
+	if !ok {
+		return nil, errcode.NewNotFound("user not found: %s", id)
+	}
+	return copyUser(user), nil
+}
+
+func (u *User) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	if email != "" {
+		for _, user := range u.tenants[tenantID] {
+			if strings.EqualFold(user.Email, email) {
+				return copyUser(user), nil
+			}
+		}
+	}
+	return nil, errcode.NewNotFound("user not found: %s", email)
+}
+
+func (u *User) List(ctx context.Context, params *repository.ListUsersParams) (entity.Users, error) {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	users := entity.Users{}
+	for _, user := range u.tenants[tenantID] {
+		if params.Name != "" && user.Name != params.Name {
+			continue
+		}
+		if !filter.Eval(params.Filter, fields(user)) {
+			continue
+		}
+		users = append(users, copyUser(user))
+	}
+	order := params.Order()
+	sort.Slice(users, func(i, j int) bool { return order.Compare(fields(users[i]), fields(users[j])) < 0 })
+	if params.Limit > 0 && len(users) > params.Limit {
+		users = users[:params.Limit]
+	}
+	return users, nil
+}
+
+func (u *User) Search(ctx context.Context, params *repository.SearchUsersParams) ([]*repository.ScoredUser, error) {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	users := entity.Users{}
+	for _, user := range u.tenants[tenantID] {
+		users = append(users, copyUser(user))
+	}
+	return repository.SearchUsers(users, params), nil
+}
+
+func (u *User) Stats(ctx context.Context, params *repository.UserStatsParams) (*repository.UserStats, error) {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	users := entity.Users{}
+	for _, user := range u.tenants[tenantID] {
+		users = append(users, user)
+	}
+	return repository.CountUsers(users, params), nil
+}
+
+func (u *User) Update(ctx context.Context, id string, update func(*entity.User) bool) (*entity.User, error) {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	users := u.tenants[tenantID]
+	current, ok := users[id]
+	if !ok {
+		return nil, errcode.NewNotFound("user not found: %s", id)
+	}
+	user := copyUser(current)
+	if !update(user) {
+		return user, nil
+	}
+	// the primary key and the creation time are immutable, as in the postgres
+	// backend
+	user.ID = id
+	user.CreatedAt = current.CreatedAt
+	user.UpdatedAt = now()
+	if err := checkUnique(users, user); err != nil {
+		return nil, err
+	}
+	users[id] = copyUser(user)
+	return user, nil
+}
+
+func (u *User) Delete(ctx context.Context, id string) error {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if _, ok := u.tenants[tenantID][id]; !ok {
+		return errcode.NewNotFound("user not found: %s", id)
+	}
+	delete(u.tenants[tenantID], id)
+	delete(u.credentials[tenantID], id)
+	delete(u.verifications[tenantID], id)
+	for _, members := range u.members[tenantID] {
+		delete(members, id)
+	}
+	delete(u.relationships[tenantID], id)
+	for _, rels := range u.relationships[tenantID] {
+		delete(rels, id)
+	}
+	for _, session := range u.sessions[tenantID] {
+		if session.UserID == id {
+			u.deleteSession(tenantID, session.ID)
+		}
+	}
+	return nil
+}
+
+// scope returns the tenant of ctx. Like a database, it fails once ctx is done.
+func scope(ctx context.Context) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", errcode.New(err)
+	}
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return "", errcode.New(err)
+	}
+	return tenantID, nil
+}
+
+// fields returns the fields of u for filter.Eval and filter.Order.Compare.
+func fields(u *entity.User) func(field string) interface{} {
+	return func(field string) interface{} { return repository.UserFilterValue(u, field) }
+}
+
+// checkUnique enforces the unique constraints of the SQL backends on the
+// users other than v.
+func checkUnique(users map[string]*entity.User, v *entity.User) error {
+	for _, user := range users {
+		if user.ID == v.ID {
+			continue
+		}
+		if user.Name == v.Name {
+			return errcode.NewAlreadyExists("user name already exists: %s", v.Name)
+		}
+		if v.Email != "" && strings.EqualFold(user.Email, v.Email) {
+			return errcode.NewAlreadyExists("user email already exists: %s", v.Email)
+		}
+	}
+	return nil
+}
+
+// now returns the current time at the precision of the SQL backends.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+func copyUser(u *entity.User) *entity.User {
+	user := *u
+	user.Attributes = u.Attributes.Clone()
+	return &user
+}
@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/repositorytest"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUser_TenantIsolation(t *testing.T) {
+	repo := NewUser()
+	acme := tenant.NewContext(context.Background(), "acme")
+	other := tenant.NewContext(context.Background(), "other")
+
+	_, err := repo.Create(acme, &entity.User{ID: "id", Name: "name"})
+	require.NoError(t, err)
+
+	// names and ids are unique per tenant
+	_, err = repo.Create(acme, &entity.User{ID: "id2", Name: "name"})
+	require.True(t, errcode.IsAlreadyExists(err))
+	_, err = repo.Create(other, &entity.User{ID: "id", Name: "name"})
+	require.NoError(t, err)
+	require.NoError(t, repo.Delete(other, "id"))
+
+	// another tenant cannot observe the user
+	_, err = repo.Get(other, "id")
+	require.True(t, errcode.IsNotfound(err))
+	users, err := repo.List(other, &repository.ListUsersParams{})
+	require.NoError(t, err)
+	require.Empty(t, users)
+	_, err = repo.Update(other, "id", func(u *entity.User) bool { u.Name = "changed"; return true })
+	require.True(t, errcode.IsNotfound(err))
+	require.True(t, errcode.IsNotfound(repo.Delete(other, "id")))
+
+	got, err := repo.Get(acme, "id")
+	require.NoError(t, err)
+	require.Equal(t, "name", got.Name)
+
+	// queries without a tenant are rejected
+	_, err = repo.Get(context.Background(), "id")
+	require.True(t, errcode.IsInvalidArgument(err))
+}
+
+func TestUser_Contract(t *testing.T) {
+	repositorytest.TestUser(t, func(t *testing.T) repository.User {
+		return NewUser()
+	})
+}
+
+func TestCredential_Contract(t *testing.T) {
+	repositorytest.TestCredential(t, func(t *testing.T) (repository.User, repository.Credential) {
+		users := NewUser()
+		return users, NewCredential(users)
+	})
+}
+
+func TestSession_Contract(t *testing.T) {
+	repositorytest.TestSession(t, func(t *testing.T) (repository.User, repository.Session) {
+		users := NewUser()
+		return users, NewSession(users)
+	})
+}
+
+func TestVerification_Contract(t *testing.T) {
+	repositorytest.TestVerification(t, func(t *testing.T) (repository.User, repository.Verification) {
+		users := NewUser()
+		return users, NewVerification(users)
+	})
+}
+
+func TestGroup_Contract(t *testing.T) {
+	repositorytest.TestGroup(t, func(t *testing.T) (repository.User, repository.Group) {
+		users := NewUser()
+		return users, NewGroup(users)
+	})
+}
+
+func TestRelationship_Contract(t *testing.T) {
+	repositorytest.TestRelationship(t, func(t *testing.T) (repository.User, repository.Relationship) {
+		users := NewUser()
+		return users, NewRelationship(users)
+	})
+}
@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+)
+
+var _ repository.Verification = (*Verification)(nil)
+
+// Verification is an in-process implementation of repository.Verification
+// for the users of a User.
+type Verification struct {
+	users *User
+}
+
+func NewVerification(users *User) *Verification {
+	return &Verification{users: users}
+}
+
+// verification is a stored verification with the hash of its token.
+type verification struct {
+	tokenHash string
+	v         entity.Verification
+}
+
+func (v *Verification) Create(ctx context.Context, in *entity.Verification, tokenHash string) (*entity.Verification, error) {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	v.users.mu.Lock()
+	defer v.users.mu.Unlock()
+
+	if _, ok := v.users.tenants[tenantID][in.UserID]; !ok {
+		return nil, errcode.NewNotFound("user not found: %s", in.UserID)
+	}
+	verifications, ok := v.users.verifications[tenantID]
+	if !ok {
+		verifications = map[string]*verification{}
+		v.users.verifications[tenantID] = verifications
+	}
+	for userID, stored := range verifications {
+		if stored.tokenHash == tokenHash && userID != in.UserID {
+			return nil, errcode.NewAlreadyExists("verification token already exists")
+		}
+	}
+	stored := &verification{tokenHash: tokenHash, v: *in}
+	stored.v.CreatedAt = truncate(in.CreatedAt)
+	stored.v.ExpiresAt = truncate(in.ExpiresAt)
+	verifications[in.UserID] = stored
+	ret := stored.v
+	return &ret, nil
+}
+
+func (v *Verification) Consume(ctx context.Context, tokenHash string) (*entity.Verification, error) {
+	tenantID, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	v.users.mu.Lock()
+	defer v.users.mu.Unlock()
+
+	for userID, stored := range v.users.verifications[tenantID] {
+		if stored.tokenHash == tokenHash {
+			delete(v.users.verifications[tenantID], userID)
+			ret := stored.v
+			return &ret, nil
+		}
+	}
+	return nil, errcode.NewNotFound("verification token not found")
+}
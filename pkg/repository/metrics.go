@@ -0,0 +1,363 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/metrics"
+)
+
+var _ User = (*metricsUser)(nil)
+
+// metricsUser records the duration of each call to the wrapped User by
+// operation and error code.
+type metricsUser struct {
+	next User
+	m    *metrics.Metrics
+}
+
+func WithUserMetrics(next User, m *metrics.Metrics) User {
+	return &metricsUser{next: next, m: m}
+}
+
+func (u *metricsUser) observe(op string, start time.Time, err error) {
+	u.m.RepositoryDuration.WithLabelValues("user", op, metrics.Code(err)).Observe(time.Since(start).Seconds())
+}
+
+func (u *metricsUser) Create(ctx context.Context, v *entity.User) (*entity.User, error) {
+	start := time.Now()
+	user, err := u.next.Create(ctx, v)
+	u.observe("Create", start, err)
+	return user, err
+}
+
+func (u *metricsUser) Get(ctx context.Context, id string) (*entity.User, error) {
+	start := time.Now()
+	user, err := u.next.Get(ctx, id)
+	u.observe("Get", start, err)
+	return user, err
+}
+
+func (u *metricsUser) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
+	start := time.Now()
+	user, err := u.next.GetByEmail(ctx, email)
+	u.observe("GetByEmail", start, err)
+	return user, err
+}
+
+func (u *metricsUser) List(ctx context.Context, params *ListUsersParams) (entity.Users, error) {
+	start := time.Now()
+	users, err := u.next.List(ctx, params)
+	u.observe("List", start, err)
+	return users, err
+}
+
+func (u *metricsUser) Update(ctx context.Context, id string, update func(*entity.User) bool) (*entity.User, error) {
+	start := time.Now()
+	user, err := u.next.Update(ctx, id, update)
+	u.observe("Update", start, err)
+	return user, err
+}
+
+func (u *metricsUser) Delete(ctx context.Context, id string) error {
+	start := time.Now()
+	err := u.next.Delete(ctx, id)
+	u.observe("Delete", start, err)
+	return err
+}
+
+func (u *metricsUser) Search(ctx context.Context, params *SearchUsersParams) ([]*ScoredUser, error) {
+	start := time.Now()
+	users, err := u.next.Search(ctx, params)
+	u.observe("Search", start, err)
+	return users, err
+}
+
+func (u *metricsUser) Stats(ctx context.Context, params *UserStatsParams) (*UserStats, error) {
+	start := time.Now()
+	stats, err := u.next.Stats(ctx, params)
+	u.observe("Stats", start, err)
+	return stats, err
+}
+
+var _ Credential = (*metricsCredential)(nil)
+
+// metricsCredential records the duration of each call to the wrapped
+// Credential like metricsUser.
+type metricsCredential struct {
+	next Credential
+	m    *metrics.Metrics
+}
+
+func WithCredentialMetrics(next Credential, m *metrics.Metrics) Credential {
+	return &metricsCredential{next: next, m: m}
+}
+
+func (c *metricsCredential) observe(op string, start time.Time, err error) {
+	c.m.RepositoryDuration.WithLabelValues("credential", op, metrics.Code(err)).Observe(time.Since(start).Seconds())
+}
+
+func (c *metricsCredential) Get(ctx context.Context, userID string) (*entity.Credential, error) {
+	start := time.Now()
+	cred, err := c.next.Get(ctx, userID)
+	c.observe("Get", start, err)
+	return cred, err
+}
+
+func (c *metricsCredential) Set(ctx context.Context, userID, passwordHash string) (*entity.Credential, error) {
+	start := time.Now()
+	cred, err := c.next.Set(ctx, userID, passwordHash)
+	c.observe("Set", start, err)
+	return cred, err
+}
+
+func (c *metricsCredential) Update(ctx context.Context, userID string, update func(*entity.Credential) bool) (*entity.Credential, error) {
+	start := time.Now()
+	cred, err := c.next.Update(ctx, userID, update)
+	c.observe("Update", start, err)
+	return cred, err
+}
+
+var _ Session = (*metricsSession)(nil)
+
+// metricsSession records the duration of each call to the wrapped Session
+// like metricsUser.
+type metricsSession struct {
+	next Session
+	m    *metrics.Metrics
+}
+
+func WithSessionMetrics(next Session, m *metrics.Metrics) Session {
+	return &metricsSession{next: next, m: m}
+}
+
+func (s *metricsSession) observe(op string, start time.Time, err error) {
+	s.m.RepositoryDuration.WithLabelValues("session", op, metrics.Code(err)).Observe(time.Since(start).Seconds())
+}
+
+func (s *metricsSession) Create(ctx context.Context, v *entity.Session, tokenHash string) (*entity.Session, error) {
+	start := time.Now()
+	session, err := s.next.Create(ctx, v, tokenHash)
+	s.observe("Create", start, err)
+	return session, err
+}
+
+func (s *metricsSession) Get(ctx context.Context, id string) (*entity.Session, error) {
+	start := time.Now()
+	session, err := s.next.Get(ctx, id)
+	s.observe("Get", start, err)
+	return session, err
+}
+
+func (s *metricsSession) List(ctx context.Context, params *ListSessionsParams) (entity.Sessions, error) {
+	start := time.Now()
+	sessions, err := s.next.List(ctx, params)
+	s.observe("List", start, err)
+	return sessions, err
+}
+
+func (s *metricsSession) Rotate(ctx context.Context, tokenHash, newHash string, now time.Time) (*entity.Session, error) {
+	start := time.Now()
+	session, err := s.next.Rotate(ctx, tokenHash, newHash, now)
+	s.observe("Rotate", start, err)
+	return session, err
+}
+
+func (s *metricsSession) Revoke(ctx context.Context, id string, now time.Time) (*entity.Session, error) {
+	start := time.Now()
+	session, err := s.next.Revoke(ctx, id, now)
+	s.observe("Revoke", start, err)
+	return session, err
+}
+
+func (s *metricsSession) DeleteExpired(ctx context.Context, before time.Time, limit int) (int64, error) {
+	start := time.Now()
+	n, err := s.next.DeleteExpired(ctx, before, limit)
+	s.observe("DeleteExpired", start, err)
+	return n, err
+}
+
+var _ Verification = (*metricsVerification)(nil)
+
+// metricsVerification records the duration of each call to the wrapped
+// Verification like metricsUser.
+type metricsVerification struct {
+	next Verification
+	m    *metrics.Metrics
+}
+
+func WithVerificationMetrics(next Verification, m *metrics.Metrics) Verification {
+	return &metricsVerification{next: next, m: m}
+}
+
+func (v *metricsVerification) observe(op string, start time.Time, err error) {
+	v.m.RepositoryDuration.WithLabelValues("verification", op, metrics.Code(err)).Observe(time.Since(start).Seconds())
+}
+
+func (v *metricsVerification) Create(ctx context.Context, verification *entity.Verification, tokenHash string) (*entity.Verification, error) {
+	start := time.Now()
+	ret, err := v.next.Create(ctx, verification, tokenHash)
+	v.observe("Create", start, err)
+	return ret, err
+}
+
+func (v *metricsVerification) Consume(ctx context.Context, tokenHash string) (*entity.Verification, error) {
+	start := time.Now()
+	ret, err := v.next.Consume(ctx, tokenHash)
+	v.observe("Consume", start, err)
+	return ret, err
+}
+
+var _ Group = (*metricsGroup)(nil)
+
+// metricsGroup records the duration of each call to the wrapped Group like
+// metricsUser.
+type metricsGroup struct {
+	next Group
+	m    *metrics.Metrics
+}
+
+func WithGroupMetrics(next Group, m *metrics.Metrics) Group {
+	return &metricsGroup{next: next, m: m}
+}
+
+func (g *metricsGroup) observe(op string, start time.Time, err error) {
+	g.m.RepositoryDuration.WithLabelValues("group", op, metrics.Code(err)).Observe(time.Since(start).Seconds())
+}
+
+func (g *metricsGroup) Create(ctx context.Context, v *entity.Group, ownerID string) (*entity.Group, error) {
+	start := time.Now()
+	group, err := g.next.Create(ctx, v, ownerID)
+	g.observe("Create", start, err)
+	return group, err
+}
+
+func (g *metricsGroup) Get(ctx context.Context, id string) (*entity.Group, error) {
+	start := time.Now()
+	group, err := g.next.Get(ctx, id)
+	g.observe("Get", start, err)
+	return group, err
+}
+
+func (g *metricsGroup) List(ctx context.Context, params *ListGroupsParams) (entity.Groups, error) {
+	start := time.Now()
+	groups, err := g.next.List(ctx, params)
+	g.observe("List", start, err)
+	return groups, err
+}
+
+func (g *metricsGroup) Update(ctx context.Context, id string, update func(*entity.Group) bool) (*entity.Group, error) {
+	start := time.Now()
+	group, err := g.next.Update(ctx, id, update)
+	g.observe("Update", start, err)
+	return group, err
+}
+
+func (g *metricsGroup) Delete(ctx context.Context, id string) error {
+	start := time.Now()
+	err := g.next.Delete(ctx, id)
+	g.observe("Delete", start, err)
+	return err
+}
+
+func (g *metricsGroup) AddMember(ctx context.Context, m *entity.GroupMember) (*entity.GroupMember, error) {
+	start := time.Now()
+	member, err := g.next.AddMember(ctx, m)
+	g.observe("AddMember", start, err)
+	return member, err
+}
+
+func (g *metricsGroup) GetMember(ctx context.Context, groupID, userID string) (*entity.GroupMember, error) {
+	start := time.Now()
+	member, err := g.next.GetMember(ctx, groupID, userID)
+	g.observe("GetMember", start, err)
+	return member, err
+}
+
+func (g *metricsGroup) RemoveMember(ctx context.Context, groupID, userID string) error {
+	start := time.Now()
+	err := g.next.RemoveMember(ctx, groupID, userID)
+	g.observe("RemoveMember", start, err)
+	return err
+}
+
+func (g *metricsGroup) ListMembers(ctx context.Context, params *ListGroupMembersParams) (entity.GroupMembers, error) {
+	start := time.Now()
+	members, err := g.next.ListMembers(ctx, params)
+	g.observe("ListMembers", start, err)
+	return members, err
+}
+
+func (g *metricsGroup) ListUserGroups(ctx context.Context, params *ListUserGroupsParams) ([]*UserGroup, error) {
+	start := time.Now()
+	groups, err := g.next.ListUserGroups(ctx, params)
+	g.observe("ListUserGroups", start, err)
+	return groups, err
+}
+
+var _ Relationship = (*metricsRelationship)(nil)
+
+// metricsRelationship records the duration of each call to the wrapped
+// Relationship like metricsUser.
+type metricsRelationship struct {
+	next Relationship
+	m    *metrics.Metrics
+}
+
+func WithRelationshipMetrics(next Relationship, m *metrics.Metrics) Relationship {
+	return &metricsRelationship{next: next, m: m}
+}
+
+func (r *metricsRelationship) observe(op string, start time.Time, err error) {
+	r.m.RepositoryDuration.WithLabelValues("relationship", op, metrics.Code(err)).Observe(time.Since(start).Seconds())
+}
+
+func (r *metricsRelationship) Follow(ctx context.Context, userID, targetID string) (*entity.Relationship, error) {
+	start := time.Now()
+	rel, err := r.next.Follow(ctx, userID, targetID)
+	r.observe("Follow", start, err)
+	return rel, err
+}
+
+func (r *metricsRelationship) Unfollow(ctx context.Context, userID, targetID string) error {
+	start := time.Now()
+	err := r.next.Unfollow(ctx, userID, targetID)
+	r.observe("Unfollow", start, err)
+	return err
+}
+
+func (r *metricsRelationship) Block(ctx context.Context, userID, targetID string) (*entity.Relationship, error) {
+	start := time.Now()
+	rel, err := r.next.Block(ctx, userID, targetID)
+	r.observe("Block", start, err)
+	return rel, err
+}
+
+func (r *metricsRelationship) Unblock(ctx context.Context, userID, targetID string) error {
+	start := time.Now()
+	err := r.next.Unblock(ctx, userID, targetID)
+	r.observe("Unblock", start, err)
+	return err
+}
+
+func (r *metricsRelationship) ListFollowers(ctx context.Context, params *ListRelationshipsParams) (entity.Relationships, error) {
+	start := time.Now()
+	rels, err := r.next.ListFollowers(ctx, params)
+	r.observe("ListFollowers", start, err)
+	return rels, err
+}
+
+func (r *metricsRelationship) ListFollowing(ctx context.Context, params *ListRelationshipsParams) (entity.Relationships, error) {
+	start := time.Now()
+	rels, err := r.next.ListFollowing(ctx, params)
+	r.observe("ListFollowing", start, err)
+	return rels, err
+}
+
+func (r *metricsRelationship) ListMutualFollows(ctx context.Context, params *ListRelationshipsParams) (entity.Relationships, error) {
+	start := time.Now()
+	rels, err := r.next.ListMutualFollows(ctx, params)
+	r.observe("ListMutualFollows", start, err)
+	return rels, err
+}
@@ -0,0 +1,38 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/memory"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithUserMetrics(t *testing.T) {
+	m := metrics.New()
+	repo := repository.WithUserMetrics(memory.NewUser(), m)
+	ctx := tenant.NewContext(context.Background(), tenant.Default)
+
+	_, err := repo.Create(ctx, &entity.User{ID: "id", Name: "name"})
+	require.NoError(t, err)
+	_, err = repo.Get(ctx, "id")
+	require.NoError(t, err)
+	_, err = repo.Get(ctx, "missing")
+	require.Error(t, err)
+
+	require.Equal(t, 3, testutil.CollectAndCount(m.RepositoryDuration))
+	for _, code := range []string{metrics.CodeOK, "Not found"} {
+		observer, err := m.RepositoryDuration.GetMetricWithLabelValues("user", "Get", code)
+		require.NoError(t, err)
+		got := &dto.Metric{}
+		require.NoError(t, observer.(prometheus.Metric).Write(got))
+		require.Equal(t, uint64(1), got.GetHistogram().GetSampleCount())
+	}
+}
@@ -0,0 +1,117 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/internal/pgquery"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/jackc/pgx/v5"
+)
+
+var _ repository.Credential = (*Credential)(nil)
+
+// Credential stores credentials in the database of a User.
+type Credential struct {
+	u *User
+}
+
+func NewCredential(u *User) *Credential {
+	return &Credential{u: u}
+}
+
+func (c *Credential) Get(ctx context.Context, userID string) (*entity.Credential, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var credential *entity.Credential
+	err = c.u.withReadTx(ctx, "GetCredential", func(tx *txn) error {
+		credential, err = getCredential(tx, tenantID, userID, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+func (c *Credential) Set(ctx context.Context, userID, passwordHash string) (*entity.Credential, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var credential *entity.Credential
+	err = c.u.withTx(ctx, "SetCredential", func(tx *txn) error {
+		if err := lockUser(tx, tenantID, userID); err != nil {
+			return err
+		}
+		credential, err = pgquery.ScanCredential(tx.QueryRow(pgquery.SetCredential, tenantID, userID, passwordHash))
+		return newError(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+func (c *Credential) Update(ctx context.Context, userID string, update func(*entity.Credential) bool) (*entity.Credential, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var credential *entity.Credential
+	err = c.u.withTx(ctx, "UpdateCredential", func(tx *txn) error {
+		credential, err = getCredential(tx, tenantID, userID, true)
+		if err != nil {
+			return err
+		}
+		if !update(credential) {
+			return nil
+		}
+		credential.LockedUntil = truncate(credential.LockedUntil)
+		query, args := pgquery.UpdateCredential(tenantID, userID, credential)
+		if err := tx.QueryRow(query, args...).Scan(&credential.UpdatedAt); err != nil {
+			return newError(err)
+		}
+		credential.UserID = userID
+		credential.UpdatedAt = credential.UpdatedAt.UTC()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+func getCredential(tx *txn, tenantID, userID string, forUpdate bool) (*entity.Credential, error) {
+	query := "SELECT " + pgquery.CredentialColumns + " FROM credentials WHERE tenant_id = $1 AND user_id = $2"
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	credential, err := pgquery.ScanCredential(tx.QueryRow(query, tenantID, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errcode.NewNotFound("password not set for user: %s", userID)
+	}
+	if err != nil {
+		return nil, newError(err)
+	}
+	return credential, nil
+}
+
+// lockUser holds the user against a concurrent delete until the end of the
+// transaction.
+func lockUser(tx *txn, tenantID, id string) error {
+	var one int
+	err := tx.QueryRow("SELECT 1 FROM users WHERE tenant_id = $1 AND id = $2 FOR SHARE", tenantID, id).Scan(&one)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errcode.NewNotFound("user not found: %s", id)
+	}
+	return newError(err)
+}
@@ -0,0 +1,49 @@
+package pgx
+
+import (
+	"errors"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	foreignKeyViolation  = "23503"
+	uniqueViolation      = "23505"
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+	queryCanceled        = "57014"
+)
+
+// newError classifies postgres errors the same way as the lib/pq backend
+// before falling back to errcode.New.
+func newError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case uniqueViolation:
+			return errcode.NewAlreadyExists("%w", err)
+		case foreignKeyViolation:
+			// a row refers to another that does not exist
+			return errcode.NewFailedPrecondition("%w", err)
+		case serializationFailure, deadlockDetected:
+			return errcode.NewAborted("%w", err)
+		case queryCanceled:
+			// raised when statement_timeout elapses
+			return errcode.NewDeadlineExceeded("%w", err)
+		}
+	}
+	return errcode.New(err)
+}
+
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == serializationFailure || pgErr.Code == deadlockDetected
+}
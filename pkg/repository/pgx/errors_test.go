@@ -0,0 +1,35 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want errcode.Code
+	}{
+		{name: "unique violation", err: &pgconn.PgError{Code: uniqueViolation}, want: errcode.CodeAlreadyExists},
+		{name: "foreign key violation", err: &pgconn.PgError{Code: foreignKeyViolation}, want: errcode.CodeFailedPrecondition},
+		{name: "serialization failure", err: &pgconn.PgError{Code: serializationFailure}, want: errcode.CodeAborted},
+		{name: "deadlock", err: &pgconn.PgError{Code: deadlockDetected}, want: errcode.CodeAborted},
+		{name: "statement timeout", err: &pgconn.PgError{Code: queryCanceled}, want: errcode.CodeDeadlineExceeded},
+		{name: "context canceled", err: context.Canceled, want: errcode.CodeCancelled},
+		{name: "other", err: errors.New("error"), want: errcode.CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var e *errcode.Error
+			require.ErrorAs(t, newError(tt.err), &e)
+			require.Equal(t, tt.want, e.Code)
+		})
+	}
+	require.NoError(t, newError(nil))
+}
@@ -0,0 +1,307 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/internal/pgquery"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/jackc/pgx/v5"
+)
+
+var _ repository.Group = (*Group)(nil)
+
+// Group stores groups in the database of a User.
+type Group struct {
+	u *User
+}
+
+func NewGroup(u *User) *Group {
+	return &Group{u: u}
+}
+
+func (g *Group) Create(ctx context.Context, v *entity.Group, ownerID string) (*entity.Group, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var group *entity.Group
+	err = g.u.withTx(ctx, "CreateGroup", func(tx *txn) error {
+		group, err = pgquery.ScanGroup(tx.QueryRow(pgquery.InsertGroup, tenantID, v.ID, v.Name, v.Description))
+		if err != nil {
+			return newError(err)
+		}
+		if ownerID == "" {
+			return nil
+		}
+		_, err = tx.Exec(pgquery.InsertGroupMember, tenantID, v.ID, ownerID, entity.GroupRoleOwner)
+		return newError(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (g *Group) Get(ctx context.Context, id string) (*entity.Group, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var group *entity.Group
+	err = g.u.withReadTx(ctx, "GetGroup", func(tx *txn) error {
+		group, err = getGroup(tx, tenantID, id, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (g *Group) List(ctx context.Context, params *repository.ListGroupsParams) (entity.Groups, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	query, args := pgquery.ListGroups(tenantID, params)
+	var groups entity.Groups
+	err = g.u.withReadTx(ctx, "ListGroups", func(tx *txn) error {
+		rows, err := tx.Query(query, args...)
+		if err != nil {
+			return newError(err)
+		}
+		defer rows.Close()
+
+		groups = entity.Groups{}
+		for rows.Next() {
+			group, err := pgquery.ScanGroup(rows)
+			if err != nil {
+				return newError(err)
+			}
+			groups = append(groups, group)
+		}
+		return newError(rows.Err())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (g *Group) Update(ctx context.Context, id string, update func(*entity.Group) bool) (*entity.Group, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var group *entity.Group
+	err = g.u.withTx(ctx, "UpdateGroup", func(tx *txn) error {
+		group, err = getGroup(tx, tenantID, id, true)
+		if err != nil {
+			return err
+		}
+		if !update(group) {
+			return nil
+		}
+		if err := tx.QueryRow(pgquery.UpdateGroup, tenantID, id, group.Name, group.Description).Scan(&group.UpdatedAt); err != nil {
+			return newError(err)
+		}
+		group.UpdatedAt = group.UpdatedAt.UTC()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (g *Group) Delete(ctx context.Context, id string) error {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return errcode.New(err)
+	}
+
+	return g.u.withTx(ctx, "DeleteGroup", func(tx *txn) error {
+		tag, err := tx.Exec(pgquery.DeleteGroup, tenantID, id)
+		if err != nil {
+			return newError(err)
+		}
+		if tag.RowsAffected() == 0 {
+			return errcode.NewNotFound("group not found: %s", id)
+		}
+		return nil
+	})
+}
+
+func (g *Group) AddMember(ctx context.Context, m *entity.GroupMember) (*entity.GroupMember, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var member *entity.GroupMember
+	err = g.u.withTx(ctx, "AddMember", func(tx *txn) error {
+		if err := requireRow(tx, pgquery.LockGroup, tenantID, m.GroupID, "group"); err != nil {
+			return err
+		}
+		member, err = pgquery.ScanGroupMember(tx.QueryRow(pgquery.InsertGroupMember, tenantID, m.GroupID, m.UserID, m.Role))
+		return newError(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (g *Group) GetMember(ctx context.Context, groupID, userID string) (*entity.GroupMember, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var member *entity.GroupMember
+	err = g.u.withReadTx(ctx, "GetMember", func(tx *txn) error {
+		member, err = pgquery.ScanGroupMember(tx.QueryRow(pgquery.GetGroupMember, tenantID, groupID, userID))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errcode.NewNotFound("%s is not a member of group %s", userID, groupID)
+		}
+		return newError(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (g *Group) RemoveMember(ctx context.Context, groupID, userID string) error {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return errcode.New(err)
+	}
+
+	return g.u.withTx(ctx, "RemoveMember", func(tx *txn) error {
+		if _, err := tx.Exec(pgquery.LockGroupOwners, tenantID, groupID); err != nil {
+			return newError(err)
+		}
+		var role entity.GroupRole
+		err := tx.QueryRow(pgquery.DeleteGroupMember, tenantID, groupID, userID).Scan(&role)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errcode.NewNotFound("%s is not a member of group %s", userID, groupID)
+		}
+		if err != nil {
+			return newError(err)
+		}
+		if role != entity.GroupRoleOwner {
+			return nil
+		}
+		var owners int
+		if err := tx.QueryRow(pgquery.CountGroupOwners, tenantID, groupID, entity.GroupRoleOwner).Scan(&owners); err != nil {
+			return newError(err)
+		}
+		if owners == 0 {
+			return errcode.NewFailedPrecondition("%s is the last owner of group %s", userID, groupID)
+		}
+		return nil
+	})
+}
+
+func (g *Group) ListMembers(ctx context.Context, params *repository.ListGroupMembersParams) (entity.GroupMembers, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	query, args := pgquery.ListGroupMembers(tenantID, params)
+	var members entity.GroupMembers
+	err = g.u.withReadTx(ctx, "ListMembers", func(tx *txn) error {
+		if err := requireRow(tx, pgquery.GroupExists, tenantID, params.GroupID, "group"); err != nil {
+			return err
+		}
+		rows, err := tx.Query(query, args...)
+		if err != nil {
+			return newError(err)
+		}
+		defer rows.Close()
+
+		members = entity.GroupMembers{}
+		for rows.Next() {
+			member, err := pgquery.ScanGroupMember(rows)
+			if err != nil {
+				return newError(err)
+			}
+			members = append(members, member)
+		}
+		return newError(rows.Err())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (g *Group) ListUserGroups(ctx context.Context, params *repository.ListUserGroupsParams) ([]*repository.UserGroup, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	query, args := pgquery.ListUserGroups(tenantID, params)
+	var groups []*repository.UserGroup
+	err = g.u.withReadTx(ctx, "ListUserGroups", func(tx *txn) error {
+		if err := requireRow(tx, pgquery.UserExists, tenantID, params.UserID, "user"); err != nil {
+			return err
+		}
+		rows, err := tx.Query(query, args...)
+		if err != nil {
+			return newError(err)
+		}
+		defer rows.Close()
+
+		groups = []*repository.UserGroup{}
+		for rows.Next() {
+			ug := &repository.UserGroup{}
+			if ug.Group, err = pgquery.ScanGroup(rows, &ug.Role); err != nil {
+				return newError(err)
+			}
+			groups = append(groups, ug)
+		}
+		return newError(rows.Err())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func getGroup(tx *txn, tenantID, id string, forUpdate bool) (*entity.Group, error) {
+	query := "SELECT " + pgquery.GroupColumns + " FROM groups WHERE tenant_id = $1 AND id = $2"
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	group, err := pgquery.ScanGroup(tx.QueryRow(query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errcode.NewNotFound("group not found: %s", id)
+	}
+	if err != nil {
+		return nil, newError(err)
+	}
+	return group, nil
+}
+
+// requireRow returns NotFound for the kind of row when query, one of the
+// existence checks of pgquery, selects nothing.
+func requireRow(tx *txn, query, tenantID, id, kind string) error {
+	var one int
+	err := tx.QueryRow(query, tenantID, id).Scan(&one)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errcode.NewNotFound("%s not found: %s", kind, id)
+	}
+	return newError(err)
+}
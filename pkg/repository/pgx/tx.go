@@ -0,0 +1,190 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/trace"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const defaultMaxTxRetries = 3
+
+type Option func(*User)
+
+// WithMaxTxRetries sets how many times a transaction aborted by a
+// serialization failure or a deadlock is retried.
+func WithMaxTxRetries(n int) Option {
+	return func(u *User) {
+		u.maxTxRetries = n
+	}
+}
+
+// WithTxRetryObserver registers f to be called with the operation name before
+// each transaction retry, e.g. to count retries.
+func WithTxRetryObserver(f func(op string)) Option {
+	return func(u *User) {
+		u.onTxRetry = f
+	}
+}
+
+// txn is a transaction that records a span for each statement.
+type txn struct {
+	tx  pgx.Tx
+	ctx context.Context
+}
+
+func (t *txn) Exec(query string, args ...interface{}) (pgconn.CommandTag, error) {
+	ctx, span := startSpan(t.ctx, "postgres.Exec", query)
+	tag, err := t.tx.Exec(ctx, query, args...)
+	trace.End(span, newError(err))
+	return tag, err
+}
+
+func (t *txn) Query(query string, args ...interface{}) (pgx.Rows, error) {
+	ctx, span := startSpan(t.ctx, "postgres.Query", query)
+	rows, err := t.tx.Query(ctx, query, args...)
+	trace.End(span, newError(err))
+	return rows, err
+}
+
+// QueryRow defers the query until Scan, so the span ends there.
+func (t *txn) QueryRow(query string, args ...interface{}) pgx.Row {
+	ctx, span := startSpan(t.ctx, "postgres.QueryRow", query)
+	return &row{Row: t.tx.QueryRow(ctx, query, args...), span: span}
+}
+
+// SendBatch sends the queued statements in a single round trip. The span ends
+// when the results are closed.
+func (t *txn) SendBatch(b *pgx.Batch) pgx.BatchResults {
+	ctx, span := startSpan(t.ctx, "postgres.Batch", "BATCH")
+	span.SetAttributes(attribute.Int("db.batch_size", b.Len()))
+	return &batchResults{BatchResults: t.tx.SendBatch(ctx, b), span: span}
+}
+
+type row struct {
+	pgx.Row
+	span oteltrace.Span
+}
+
+func (r *row) Scan(dest ...interface{}) error {
+	err := r.Row.Scan(dest...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// a missing row is an answer rather than a failure
+		trace.End(r.span, nil)
+	} else {
+		trace.End(r.span, newError(err))
+	}
+	return err
+}
+
+type batchResults struct {
+	pgx.BatchResults
+	span   oteltrace.Span
+	closed bool
+}
+
+func (b *batchResults) Close() error {
+	err := b.BatchResults.Close()
+	if !b.closed {
+		b.closed = true
+		trace.End(b.span, newError(err))
+	}
+	return err
+}
+
+func (t *txn) CopyFrom(table pgx.Identifier, columns []string, src pgx.CopyFromSource) (int64, error) {
+	ctx, span := startSpan(t.ctx, "postgres.CopyFrom", "COPY "+table.Sanitize())
+	n, err := t.tx.CopyFrom(ctx, table, columns, src)
+	trace.End(span, newError(err))
+	return n, err
+}
+
+// withTx runs f in a transaction and commits it when f succeeds. Transactions
+// aborted by the server because of concurrent ones are retried from the start.
+func (u *User) withTx(ctx context.Context, op string, f func(tx *txn) error) error {
+	return u.retryTx(ctx, op, pgx.TxOptions{}, f)
+}
+
+// withReadTx is withTx for reads, in a read-only transaction.
+func (u *User) withReadTx(ctx context.Context, op string, f func(tx *txn) error) error {
+	return u.retryTx(ctx, op, pgx.TxOptions{AccessMode: pgx.ReadOnly}, f)
+}
+
+func (u *User) retryTx(ctx context.Context, op string, opts pgx.TxOptions, f func(tx *txn) error) error {
+	ctx, span := trace.Start(ctx, "postgres.User."+op)
+	for attempt := 0; ; attempt++ {
+		err := runTx(ctx, u.pool, opts, f)
+		if err != nil && ctx.Err() != nil {
+			// the statement was canceled because the caller gave up
+			err = errcode.New(ctx.Err())
+		}
+		if err == nil || !isRetryable(err) || attempt >= u.maxTxRetries {
+			span.SetAttributes(attribute.Int("db.tx_retries", attempt))
+			trace.End(span, err)
+			return err
+		}
+		if u.onTxRetry != nil {
+			u.onTxRetry(op)
+		}
+	}
+}
+
+func runTx(ctx context.Context, pool *pgxpool.Pool, opts pgx.TxOptions, f func(tx *txn) error) (err error) {
+	_, span := startSpan(ctx, "postgres.Begin", "BEGIN")
+	tx, err := pool.BeginTx(ctx, opts)
+	if err != nil {
+		err = newError(err)
+		trace.End(span, err)
+		return err
+	}
+	trace.End(span, nil)
+
+	defer func() {
+		if err != nil {
+			// the context may be done already
+			tx.Rollback(context.WithoutCancel(ctx))
+			return
+		}
+		_, span := startSpan(ctx, "postgres.Commit", "COMMIT")
+		if cErr := tx.Commit(ctx); cErr != nil {
+			err = newError(cErr)
+		}
+		trace.End(span, err)
+	}()
+	t := &txn{tx: tx, ctx: ctx}
+	if err := setStatementTimeout(t); err != nil {
+		return err
+	}
+	return f(t)
+}
+
+// setStatementTimeout bounds the statements of the transaction by the deadline
+// of the context, so that the server stops working on them even if the
+// cancel request of the driver does not reach it.
+func setStatementTimeout(tx *txn) error {
+	deadline, ok := tx.ctx.Deadline()
+	if !ok {
+		return nil
+	}
+	ms := time.Until(deadline).Milliseconds()
+	if ms < 1 {
+		return errcode.New(context.DeadlineExceeded)
+	}
+	_, err := tx.Exec("SELECT set_config('statement_timeout', $1, true)", strconv.FormatInt(ms, 10))
+	return newError(err)
+}
+
+func startSpan(ctx context.Context, name, statement string) (context.Context, oteltrace.Span) {
+	return trace.Start(ctx, name,
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.statement", statement),
+	)
+}
@@ -0,0 +1,53 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type fakeRow struct {
+	err error
+}
+
+func (r fakeRow) Scan(dest ...interface{}) error {
+	return r.err
+}
+
+func TestRow_Scan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{name: "ok", err: nil, want: codes.Unset},
+		{name: "no rows", err: pgx.ErrNoRows, want: codes.Unset},
+		{name: "error", err: errors.New("connection reset"), want: codes.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, span := startSpan(context.Background(), "postgres.QueryRow", "SELECT 1")
+			n := len(recorder.Ended())
+			r := &row{Row: fakeRow{err: tt.err}, span: span}
+			// the span is open until the row is read
+			require.Len(t, recorder.Ended(), n)
+
+			require.Equal(t, tt.err, r.Scan())
+			spans := recorder.Ended()
+			require.Len(t, spans, n+1)
+			require.Equal(t, tt.want, spans[n].Status().Code)
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/internal/pgquery"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var _ repository.User = (*User)(nil)
+
+// User is a repository.User backed by pgx. It behaves like postgres.User
+// and additionally supports bulk creation with COPY.
+type User struct {
+	pool         *pgxpool.Pool
+	maxTxRetries int
+	onTxRetry    func(op string)
+}
+
+func NewUser(pool *pgxpool.Pool, opts ...Option) *User {
+	u := &User{pool: pool, maxTxRetries: defaultMaxTxRetries}
+	for _, opt := range opts {
+		opt(u)
+	}
+	return u
+}
+
+func (u *User) Create(ctx context.Context, v *entity.User) (*entity.User, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var user *entity.User
+	err = u.withTx(ctx, "Create", func(tx *txn) error {
+		query, args, err := pgquery.InsertUser(tenantID, v)
+		if err != nil {
+			return errcode.New(err)
+		}
+		// insert and read back in a single round trip
+		b := &pgx.Batch{}
+		b.Queue(query, args...)
+		b.Queue("SELECT "+pgquery.UserColumns+" FROM users WHERE tenant_id = $1 AND id = $2", tenantID, v.ID)
+		results := tx.SendBatch(b)
+		defer results.Close()
+
+		if _, err := results.Exec(); err != nil {
+			return newError(err)
+		}
+		user, err = pgquery.ScanUser(results.QueryRow())
+		if err != nil {
+			return newError(err)
+		}
+		return newError(results.Close())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// BulkCreate inserts users with COPY. Either all of them are created or none.
+func (u *User) BulkCreate(ctx context.Context, users entity.Users) (int64, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return 0, errcode.New(err)
+	}
+
+	var n int64
+	err = u.withTx(ctx, "BulkCreate", func(tx *txn) error {
+		columns := []string{"tenant_id", "id", "name", "name_search", "gender", "email", "locale", "timezone", "attributes", "state"}
+		n, err = tx.CopyFrom(pgx.Identifier{"users"}, columns,
+			pgx.CopyFromSlice(len(users), func(i int) ([]interface{}, error) {
+				// the same values as a single insert
+				_, args, err := pgquery.InsertUser(tenantID, users[i])
+				return args, err
+			}))
+		return newError(err)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (u *User) Get(ctx context.Context, id string) (*entity.User, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var user *entity.User
+	err = u.withReadTx(ctx, "Get", func(tx *txn) error {
+		user, err = getUser(tx, tenantID, id, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (u *User) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var user *entity.User
+	err = u.withReadTx(ctx, "GetByEmail", func(tx *txn) error {
+		user, err = pgquery.ScanUser(tx.QueryRow(pgquery.UserByEmail, tenantID, email))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errcode.NewNotFound("user not found: %s", email)
+		}
+		return newError(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (u *User) List(ctx context.Context, params *repository.ListUsersParams) (entity.Users, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	query, args, err := pgquery.ListUsers(tenantID, params)
+	if err != nil {
+		return nil, err
+	}
+	var users entity.Users
+	err = u.withReadTx(ctx, "List", func(tx *txn) error {
+		rows, err := tx.Query(query, args...)
+		if err != nil {
+			return newError(err)
+		}
+		users, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (*entity.User, error) {
+			return pgquery.ScanUser(row)
+		})
+		if err != nil {
+			return newError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (u *User) Search(ctx context.Context, params *repository.SearchUsersParams) ([]*repository.ScoredUser, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	query, args := pgquery.SearchUsers(tenantID, params)
+	var users []*repository.ScoredUser
+	err = u.withReadTx(ctx, "Search", func(tx *txn) error {
+		rows, err := tx.Query(query, args...)
+		if err != nil {
+			return newError(err)
+		}
+		users, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (*repository.ScoredUser, error) {
+			var score float64
+			user, err := pgquery.ScanUser(row, &score)
+			return &repository.ScoredUser{User: user, Score: score}, err
+		})
+		return newError(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (u *User) Stats(ctx context.Context, params *repository.UserStatsParams) (*repository.UserStats, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	query, args, err := pgquery.UserStats(tenantID, params)
+	if err != nil {
+		return nil, err
+	}
+	var stats *repository.UserStats
+	err = u.withReadTx(ctx, "Stats", func(tx *txn) error {
+		rows, err := tx.Query(query, args...)
+		if err != nil {
+			return newError(err)
+		}
+		defer rows.Close()
+
+		stats = &repository.UserStats{ByGender: map[entity.Gender]int64{}, Signups: []*repository.SignupCount{}}
+		for rows.Next() {
+			if err := pgquery.AddUserStatsRow(rows, stats); err != nil {
+				return newError(err)
+			}
+		}
+		return newError(rows.Err())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (u *User) Update(ctx context.Context, id string, update func(*entity.User) bool) (*entity.User, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var user *entity.User
+	err = u.withTx(ctx, "Update", func(tx *txn) error {
+		user, err = getUser(tx, tenantID, id, true)
+		if err != nil {
+			return err
+		}
+		if !update(user) {
+			return nil
+		}
+		query, args, err := pgquery.UpdateUser(tenantID, id, user)
+		if err != nil {
+			return errcode.New(err)
+		}
+		if err := tx.QueryRow(query, args...).Scan(&user.UpdatedAt); err != nil {
+			return newError(err)
+		}
+		user.UpdatedAt = user.UpdatedAt.UTC()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (u *User) Delete(ctx context.Context, id string) error {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return errcode.New(err)
+	}
+
+	return u.withTx(ctx, "Delete", func(tx *txn) error {
+		tag, err := tx.Exec("DELETE FROM users WHERE tenant_id = $1 AND id = $2", tenantID, id)
+		if err != nil {
+			return newError(err)
+		}
+		if tag.RowsAffected() == 0 {
+			return errcode.NewNotFound("user not found: %s", id)
+		}
+		return nil
+	})
+}
+
+func getUser(tx *txn, tenantID, id string, forUpdate bool) (*entity.User, error) {
+	query := "SELECT " + pgquery.UserColumns + " FROM users WHERE tenant_id = $1 AND id = $2"
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	user, err := pgquery.ScanUser(tx.QueryRow(query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errcode.NewNotFound("user not found: %s", id)
+	}
+	if err != nil {
+		return nil, newError(err)
+	}
+	return user, nil
+}
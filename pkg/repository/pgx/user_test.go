@@ -0,0 +1,43 @@
+package pgx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/repositorytest"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUser_Contract(t *testing.T) {
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, repositorytest.PostgresDSN(t))
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+	require.NoError(t, pool.Ping(ctx))
+
+	repositorytest.TestUser(t, func(t *testing.T) repository.User {
+		return NewUser(pool)
+	})
+	repositorytest.TestCredential(t, func(t *testing.T) (repository.User, repository.Credential) {
+		users := NewUser(pool)
+		return users, NewCredential(users)
+	})
+	repositorytest.TestSession(t, func(t *testing.T) (repository.User, repository.Session) {
+		users := NewUser(pool)
+		return users, NewSession(users)
+	})
+	repositorytest.TestVerification(t, func(t *testing.T) (repository.User, repository.Verification) {
+		users := NewUser(pool)
+		return users, NewVerification(users)
+	})
+	repositorytest.TestGroup(t, func(t *testing.T) (repository.User, repository.Group) {
+		users := NewUser(pool)
+		return users, NewGroup(users)
+	})
+	repositorytest.TestRelationship(t, func(t *testing.T) (repository.User, repository.Relationship) {
+		users := NewUser(pool)
+		return users, NewRelationship(users)
+	})
+}
@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"errors"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/lib/pq"
+)
+
+// https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	foreignKeyViolation  pq.ErrorCode = "23503"
+	uniqueViolation      pq.ErrorCode = "23505"
+	serializationFailure pq.ErrorCode = "40001"
+	deadlockDetected     pq.ErrorCode = "40P01"
+	queryCanceled        pq.ErrorCode = "57014"
+)
+
+// newError classifies postgres errors into errcode before falling back to errcode.New.
+func newError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case uniqueViolation:
+			return errcode.NewAlreadyExists("%w", err)
+		case foreignKeyViolation:
+			// a row refers to another that does not exist
+			return errcode.NewFailedPrecondition("%w", err)
+		case serializationFailure, deadlockDetected:
+			return errcode.NewAborted("%w", err)
+		case queryCanceled:
+			// raised when statement_timeout elapses
+			return errcode.NewDeadlineExceeded("%w", err)
+		}
+	}
+	return errcode.New(err)
+}
@@ -0,0 +1,188 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/internal/pgquery"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+)
+
+var _ repository.Relationship = (*Relationship)(nil)
+
+// Relationship stores follows and blocks in the database of a User.
+type Relationship struct {
+	u *User
+}
+
+func NewRelationship(u *User) *Relationship {
+	return &Relationship{u: u}
+}
+
+func (r *Relationship) Follow(ctx context.Context, userID, targetID string) (*entity.Relationship, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	if userID == targetID {
+		return nil, errcode.NewInvalidArgument("user cannot follow itself: %s", userID)
+	}
+
+	var rel *entity.Relationship
+	err = r.u.withTx(ctx, "Follow", func(tx *txn) error {
+		kinds, err := lockRelationships(tx, tenantID, userID, targetID)
+		if err != nil {
+			return err
+		}
+		for _, kind := range kinds {
+			if kind == entity.RelationshipKindBlock {
+				return errcode.NewFailedPrecondition("relationship between %s and %s is blocked", userID, targetID)
+			}
+		}
+		if _, ok := kinds[userID]; ok {
+			return errcode.NewAlreadyExists("%s follows %s already", userID, targetID)
+		}
+		rel, err = pgquery.ScanRelationship(tx.QueryRow(pgquery.InsertRelationship, tenantID, userID, targetID, entity.RelationshipKindFollow))
+		return newError(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rel, nil
+}
+
+func (r *Relationship) Unfollow(ctx context.Context, userID, targetID string) error {
+	return r.delete(ctx, "Unfollow", userID, targetID, entity.RelationshipKindFollow)
+}
+
+func (r *Relationship) Block(ctx context.Context, userID, targetID string) (*entity.Relationship, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	if userID == targetID {
+		return nil, errcode.NewInvalidArgument("user cannot block itself: %s", userID)
+	}
+
+	var rel *entity.Relationship
+	err = r.u.withTx(ctx, "Block", func(tx *txn) error {
+		kinds, err := lockRelationships(tx, tenantID, userID, targetID)
+		if err != nil {
+			return err
+		}
+		if kind, ok := kinds[userID]; ok && kind == entity.RelationshipKindBlock {
+			return errcode.NewAlreadyExists("%s blocks %s already", userID, targetID)
+		}
+		if _, err := tx.Exec(pgquery.DeleteFollowsBetween, tenantID, userID, targetID); err != nil {
+			return newError(err)
+		}
+		rel, err = pgquery.ScanRelationship(tx.QueryRow(pgquery.InsertRelationship, tenantID, userID, targetID, entity.RelationshipKindBlock))
+		return newError(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rel, nil
+}
+
+func (r *Relationship) Unblock(ctx context.Context, userID, targetID string) error {
+	return r.delete(ctx, "Unblock", userID, targetID, entity.RelationshipKindBlock)
+}
+
+func (r *Relationship) delete(ctx context.Context, op, userID, targetID string, kind entity.RelationshipKind) error {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return errcode.New(err)
+	}
+
+	return r.u.withTx(ctx, op, func(tx *txn) error {
+		result, err := tx.Exec(pgquery.DeleteRelationship, tenantID, userID, targetID, kind)
+		if err != nil {
+			return newError(err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return newError(err)
+		}
+		if n == 0 {
+			return errcode.NewNotFound("%s relationship not found: %s -> %s", kind, userID, targetID)
+		}
+		return nil
+	})
+}
+
+func (r *Relationship) ListFollowers(ctx context.Context, params *repository.ListRelationshipsParams) (entity.Relationships, error) {
+	return r.list(ctx, "ListFollowers", params, pgquery.ListFollowers)
+}
+
+func (r *Relationship) ListFollowing(ctx context.Context, params *repository.ListRelationshipsParams) (entity.Relationships, error) {
+	return r.list(ctx, "ListFollowing", params, pgquery.ListFollowing)
+}
+
+func (r *Relationship) ListMutualFollows(ctx context.Context, params *repository.ListRelationshipsParams) (entity.Relationships, error) {
+	return r.list(ctx, "ListMutualFollows", params, pgquery.ListMutualFollows)
+}
+
+func (r *Relationship) list(ctx context.Context, op string, params *repository.ListRelationshipsParams, build func(string, *repository.ListRelationshipsParams) (string, []interface{})) (entity.Relationships, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	query, args := build(tenantID, params)
+	var rels entity.Relationships
+	err = r.u.withReadTx(ctx, op, func(tx *txn) error {
+		if err := requireRow(tx, pgquery.UserExists, tenantID, params.UserID, "user"); err != nil {
+			return err
+		}
+		rows, err := tx.Query(query, args...)
+		if err != nil {
+			return newError(err)
+		}
+		defer rows.Close()
+
+		rels = entity.Relationships{}
+		for rows.Next() {
+			rel, err := pgquery.ScanRelationship(rows)
+			if err != nil {
+				return newError(err)
+			}
+			rels = append(rels, rel)
+		}
+		return newError(rows.Err())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rels, nil
+}
+
+// lockRelationships holds the user and the pair of users, and returns the
+// kinds of the relationships between them by the ID of the user they are
+// from. The target is left to the foreign keys.
+func lockRelationships(tx *txn, tenantID, userID, targetID string) (map[string]entity.RelationshipKind, error) {
+	if err := lockUser(tx, tenantID, userID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(pgquery.LockRelationshipPair, tenantID, userID, targetID); err != nil {
+		return nil, newError(err)
+	}
+	rows, err := tx.Query(pgquery.RelationshipsBetween, tenantID, userID, targetID)
+	if err != nil {
+		return nil, newError(err)
+	}
+	defer rows.Close()
+
+	kinds := map[string]entity.RelationshipKind{}
+	for rows.Next() {
+		var id string
+		var kind entity.RelationshipKind
+		if err := rows.Scan(&id, &kind); err != nil {
+			return nil, newError(err)
+		}
+		kinds[id] = kind
+	}
+	return kinds, newError(rows.Err())
+}
@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/log"
+	"github.com/lib/pq"
+)
+
+const defaultMaxReplicaLag = 5 * time.Second
+
+// WithReplicas routes Get and List to the given read replicas. Reads fall back
+// to the primary while no replica is healthy.
+func WithReplicas(dbs ...*sql.DB) Option {
+	return func(u *User) {
+		for _, db := range dbs {
+			r := &replica{db: db}
+			r.healthy.Store(true)
+			u.replicas = append(u.replicas, r)
+		}
+	}
+}
+
+// WithMaxReplicaLag sets how far behind the primary a replica may replay
+// before reads stop being routed to it.
+func WithMaxReplicaLag(d time.Duration) Option {
+	return func(u *User) {
+		u.maxReplicaLag = d
+	}
+}
+
+type replica struct {
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+type primaryHintKey struct{}
+
+// UsePrimary returns a context whose reads are served by the primary.
+func UsePrimary(ctx context.Context) context.Context {
+	hint := &atomic.Bool{}
+	hint.Store(true)
+	return context.WithValue(ctx, primaryHintKey{}, hint)
+}
+
+// ReadYourWrites returns a context whose reads are served by the primary once
+// a write with it has succeeded, so that they observe that write even if the
+// replicas have not replayed it yet.
+func ReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryHintKey{}, &atomic.Bool{})
+}
+
+func usePrimary(ctx context.Context) bool {
+	if repository.IsLatest(ctx) {
+		return true
+	}
+	hint, ok := ctx.Value(primaryHintKey{}).(*atomic.Bool)
+	return ok && hint.Load()
+}
+
+func markWritten(ctx context.Context) {
+	if hint, ok := ctx.Value(primaryHintKey{}).(*atomic.Bool); ok {
+		hint.Store(true)
+	}
+}
+
+// MonitorReplicas checks the health and replication lag of the replicas every
+// interval until ctx is done.
+func (u *User) MonitorReplicas(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		u.checkReplicas(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (u *User) checkReplicas(ctx context.Context) {
+	for i, r := range u.replicas {
+		lag, err := replicaLag(ctx, r.db)
+		healthy := err == nil && lag <= u.maxReplicaLag
+		if was := r.healthy.Swap(healthy); was != healthy {
+			log.Warn(ctx, "replica health changed", "replica", i, "healthy", healthy, "lag", lag, log.Err(err))
+		}
+	}
+}
+
+// replicaLag returns how old the last transaction replayed by the replica is.
+// A replica that has replayed everything it received reports no lag.
+func replicaLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	const query = `SELECT CASE
+		WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+	END`
+	var seconds float64
+	if err := db.QueryRowContext(ctx, query).Scan(&seconds); err != nil {
+		return 0, newError(err)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// pickReplica returns the next healthy replica in turn, or nil when reads
+// must go to the primary.
+func (u *User) pickReplica(ctx context.Context) *replica {
+	if len(u.replicas) == 0 || usePrimary(ctx) {
+		return nil
+	}
+	start := u.nextReplica.Add(1)
+	for i := range u.replicas {
+		r := u.replicas[(int(start)+i)%len(u.replicas)]
+		if r.healthy.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+// isConnError reports whether err was caused by the connection rather than
+// by the statement, in which case another server may succeed. Errors it does
+// not recognize are not.
+func isConnError(err error) bool {
+	if err == nil || errcode.IsCancelled(err) || errcode.IsDeadlineExceeded(err) {
+		return false
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		// connection exceptions and operator interventions such as a shutdown
+		return pqErr.Code.Class() == "08" || pqErr.Code == "57P01" || pqErr.Code == "57P02" || pqErr.Code == "57P03"
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUser_pickReplica(t *testing.T) {
+	open := func() *sql.DB {
+		db, err := sql.Open("postgres", "postgres://localhost/test")
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+	primary, r1, r2 := open(), open(), open()
+	u := NewUser(primary, WithReplicas(r1, r2))
+	ctx := context.Background()
+
+	// round robin over the replicas
+	picked := map[*sql.DB]int{}
+	for n := 0; n < 4; n++ {
+		picked[u.pickReplica(ctx).db]++
+	}
+	require.Equal(t, map[*sql.DB]int{r1: 2, r2: 2}, picked)
+
+	// unhealthy replicas are skipped until none is left
+	u.replicas[0].healthy.Store(false)
+	for n := 0; n < 2; n++ {
+		require.Equal(t, r2, u.pickReplica(ctx).db)
+	}
+	u.replicas[1].healthy.Store(false)
+	require.Nil(t, u.pickReplica(ctx))
+	u.replicas[0].healthy.Store(true)
+	u.replicas[1].healthy.Store(true)
+
+	// hints
+	require.Nil(t, u.pickReplica(UsePrimary(ctx)))
+	require.Nil(t, u.pickReplica(repository.Latest(ctx)))
+	ryw := ReadYourWrites(ctx)
+	require.NotNil(t, u.pickReplica(ryw))
+	markWritten(ryw)
+	require.Nil(t, u.pickReplica(ryw))
+
+	require.Nil(t, NewUser(primary).pickReplica(ctx))
+}
+
+func TestIsConnError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil},
+		{name: "connection exception", err: newError(&pq.Error{Code: "08006"}), want: true},
+		{name: "admin shutdown", err: newError(&pq.Error{Code: "57P01"}), want: true},
+		{name: "bad conn", err: newError(driver.ErrBadConn), want: true},
+		{name: "eof", err: newError(io.ErrUnexpectedEOF), want: true},
+		{name: "network", err: newError(&net.OpError{Op: "dial", Err: errors.New("refused")}), want: true},
+		{name: "statement", err: newError(&pq.Error{Code: "42P01"})},
+		{name: "not found", err: newError(sql.ErrNoRows)},
+		{name: "canceled", err: newError(context.Canceled)},
+		{name: "unknown", err: newError(errors.New("unexpected"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, isConnError(tt.err))
+		})
+	}
+}
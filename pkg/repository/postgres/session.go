@@ -0,0 +1,203 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/internal/pgquery"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+)
+
+var _ repository.Session = (*Session)(nil)
+
+// Session stores sessions in the database of a User.
+type Session struct {
+	u *User
+}
+
+func NewSession(u *User) *Session {
+	return &Session{u: u}
+}
+
+func (s *Session) Create(ctx context.Context, v *entity.Session, tokenHash string) (*entity.Session, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var session *entity.Session
+	err = s.u.withTx(ctx, "CreateSession", func(tx *txn) error {
+		if err := lockUser(tx, tenantID, v.UserID); err != nil {
+			return err
+		}
+		session, err = pgquery.ScanSession(tx.QueryRow(pgquery.InsertSession,
+			tenantID, v.ID, v.UserID, truncate(v.CreatedAt), truncate(v.RefreshedAt), truncate(v.ExpiresAt)))
+		if err != nil {
+			return newError(err)
+		}
+		_, err = tx.Exec(pgquery.InsertRefreshToken, tenantID, tokenHash, v.ID, truncate(v.CreatedAt))
+		return newError(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *Session) Get(ctx context.Context, id string) (*entity.Session, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var session *entity.Session
+	err = s.u.withReadTx(ctx, "GetSession", func(tx *txn) error {
+		session, err = getSession(tx, tenantID, id, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *Session) List(ctx context.Context, params *repository.ListSessionsParams) (entity.Sessions, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	query, args := pgquery.ListSessions, []interface{}{tenantID, params.UserID}
+	if params.Limit > 0 {
+		query += " LIMIT $3"
+		args = append(args, params.Limit)
+	}
+	var sessions entity.Sessions
+	err = s.u.withReadTx(ctx, "ListSessions", func(tx *txn) error {
+		rows, err := tx.Query(query, args...)
+		if err != nil {
+			return newError(err)
+		}
+		defer rows.Close()
+
+		sessions = entity.Sessions{}
+		for rows.Next() {
+			session, err := pgquery.ScanSession(rows)
+			if err != nil {
+				return newError(err)
+			}
+			sessions = append(sessions, session)
+		}
+		return newError(rows.Err())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (s *Session) Rotate(ctx context.Context, tokenHash, newHash string, now time.Time) (*entity.Session, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	now = truncate(now)
+	var session *entity.Session
+	err = s.u.withTx(ctx, "RotateSession", func(tx *txn) error {
+		var sessionID string
+		var used bool
+		err := tx.QueryRow(pgquery.GetRefreshToken, tenantID, tokenHash).Scan(&sessionID, &used)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errcode.NewNotFound("refresh token not found")
+		}
+		if err != nil {
+			return newError(err)
+		}
+		if used {
+			session, err = pgquery.ScanSession(tx.QueryRow(pgquery.RevokeSession, tenantID, sessionID, now, true))
+			return newError(err)
+		}
+		session, err = getSession(tx, tenantID, sessionID, true)
+		if err != nil || !session.Active(now) {
+			return err
+		}
+		if _, err := tx.Exec(pgquery.UseRefreshToken, tenantID, tokenHash, now); err != nil {
+			return newError(err)
+		}
+		if _, err := tx.Exec(pgquery.InsertRefreshToken, tenantID, newHash, sessionID, now); err != nil {
+			return newError(err)
+		}
+		if _, err := tx.Exec(pgquery.RefreshSession, tenantID, sessionID, now); err != nil {
+			return newError(err)
+		}
+		session.RefreshedAt = now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *Session) Revoke(ctx context.Context, id string, now time.Time) (*entity.Session, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var session *entity.Session
+	err = s.u.withTx(ctx, "RevokeSession", func(tx *txn) error {
+		session, err = pgquery.ScanSession(tx.QueryRow(pgquery.RevokeSession, tenantID, id, truncate(now), false))
+		if errors.Is(err, sql.ErrNoRows) {
+			return errcode.NewNotFound("session not found: %s", id)
+		}
+		return newError(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *Session) DeleteExpired(ctx context.Context, before time.Time, limit int) (int64, error) {
+	var n int64
+	err := s.u.withTx(ctx, "DeleteExpiredSessions", func(tx *txn) error {
+		result, err := tx.Exec(pgquery.DeleteExpiredSessions, before, limit)
+		if err != nil {
+			return newError(err)
+		}
+		n, err = result.RowsAffected()
+		return newError(err)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func getSession(tx *txn, tenantID, id string, forUpdate bool) (*entity.Session, error) {
+	query := "SELECT " + pgquery.SessionColumns + " FROM sessions WHERE tenant_id = $1 AND id = $2"
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	session, err := pgquery.ScanSession(tx.QueryRow(query, tenantID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errcode.NewNotFound("session not found: %s", id)
+	}
+	if err != nil {
+		return nil, newError(err)
+	}
+	return session, nil
+}
+
+// truncate returns t at the precision of the columns, which would round it
+// otherwise.
+func truncate(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
@@ -0,0 +1,215 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/internal/pgquery"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/log"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/trace"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const defaultMaxTxRetries = 3
+
+type Option func(*User)
+
+// WithMaxTxRetries sets how many times a transaction aborted by a
+// serialization failure or a deadlock is retried.
+func WithMaxTxRetries(n int) Option {
+	return func(u *User) {
+		u.maxTxRetries = n
+	}
+}
+
+// WithTxRetryObserver registers f to be called with the operation name before
+// each transaction retry, e.g. to count retries.
+func WithTxRetryObserver(f func(op string)) Option {
+	return func(u *User) {
+		u.onTxRetry = f
+	}
+}
+
+// txn is a transaction that records a span for each statement.
+type txn struct {
+	tx  *sql.Tx
+	ctx context.Context
+}
+
+func (t *txn) Exec(query string, args ...interface{}) (sql.Result, error) {
+	_, span := startSpan(t.ctx, "postgres.Exec", query)
+	result, err := t.tx.ExecContext(t.ctx, query, args...)
+	trace.End(span, newError(err))
+	return result, err
+}
+
+// Query reads the rows as they are scanned, so the span ends when they are
+// closed.
+func (t *txn) Query(query string, args ...interface{}) (*rows, error) {
+	_, span := startSpan(t.ctx, "postgres.Query", query)
+	r, err := t.tx.QueryContext(t.ctx, query, args...)
+	if err != nil {
+		trace.End(span, newError(err))
+		return nil, err
+	}
+	return &rows{Rows: r, span: span}, nil
+}
+
+// QueryRow defers the error of the query until Scan, so the span ends there.
+func (t *txn) QueryRow(query string, args ...interface{}) *row {
+	_, span := startSpan(t.ctx, "postgres.QueryRow", query)
+	return &row{Scanner: t.tx.QueryRowContext(t.ctx, query, args...), span: span}
+}
+
+type row struct {
+	pgquery.Scanner
+	span oteltrace.Span
+}
+
+func (r *row) Scan(dest ...interface{}) error {
+	err := r.Scanner.Scan(dest...)
+	if errors.Is(err, sql.ErrNoRows) {
+		// a missing row is an answer rather than a failure
+		trace.End(r.span, nil)
+	} else {
+		trace.End(r.span, newError(err))
+	}
+	return err
+}
+
+type rows struct {
+	*sql.Rows
+	span   oteltrace.Span
+	closed bool
+}
+
+func (r *rows) Close() error {
+	err := r.Rows.Close()
+	if !r.closed {
+		r.closed = true
+		spanErr := err
+		if spanErr == nil {
+			spanErr = r.Rows.Err()
+		}
+		trace.End(r.span, newError(spanErr))
+	}
+	return err
+}
+
+// withTx runs f in a transaction on the primary and commits it when f
+// succeeds. Transactions aborted by the server because of concurrent ones are
+// retried from the start.
+func (u *User) withTx(ctx context.Context, op string, f func(tx *txn) error) error {
+	ctx, span := trace.Start(ctx, "postgres.User."+op)
+	err := u.retryTx(ctx, op, u.db, nil, f)
+	if err == nil {
+		markWritten(ctx)
+	}
+	trace.End(span, err)
+	return err
+}
+
+// withReadTx runs f in a read-only transaction on a replica, or on the primary
+// when no replica can serve it.
+func (u *User) withReadTx(ctx context.Context, op string, f func(tx *txn) error) error {
+	ctx, span := trace.Start(ctx, "postgres.User."+op)
+	r := u.pickReplica(ctx)
+	if r == nil {
+		err := u.retryTx(ctx, op, u.db, nil, f)
+		trace.End(span, err)
+		return err
+	}
+
+	span.SetAttributes(attribute.Bool("db.replica", true))
+	err := u.retryTx(ctx, op, r.db, &sql.TxOptions{ReadOnly: true}, f)
+	if isConnError(err) {
+		log.Warn(ctx, "replica failed, reading from primary", log.Err(err))
+		r.healthy.Store(false)
+		span.SetAttributes(attribute.Bool("db.replica", false))
+		err = u.retryTx(ctx, op, u.db, nil, f)
+	}
+	trace.End(span, err)
+	return err
+}
+
+func (u *User) retryTx(ctx context.Context, op string, db *sql.DB, opts *sql.TxOptions, f func(tx *txn) error) error {
+	for attempt := 0; ; attempt++ {
+		err := runTx(ctx, db, opts, f)
+		if err != nil && ctx.Err() != nil {
+			// the statement was canceled because the caller gave up
+			err = errcode.New(ctx.Err())
+		}
+		if err == nil || !isRetryable(err) || attempt >= u.maxTxRetries {
+			oteltrace.SpanFromContext(ctx).SetAttributes(attribute.Int("db.tx_retries", attempt))
+			return err
+		}
+		if u.onTxRetry != nil {
+			u.onTxRetry(op)
+		}
+	}
+}
+
+func runTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, f func(tx *txn) error) (err error) {
+	_, span := startSpan(ctx, "postgres.Begin", "BEGIN")
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		err = newError(err)
+		trace.End(span, err)
+		return err
+	}
+	trace.End(span, nil)
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		_, span := startSpan(ctx, "postgres.Commit", "COMMIT")
+		if cErr := tx.Commit(); cErr != nil {
+			err = newError(cErr)
+		}
+		trace.End(span, err)
+	}()
+	t := &txn{tx: tx, ctx: ctx}
+	if err := setStatementTimeout(t); err != nil {
+		return err
+	}
+	return f(t)
+}
+
+// setStatementTimeout bounds the statements of the transaction by the deadline
+// of the context, so that the server stops working on them even if the
+// cancel request of the driver does not reach it.
+func setStatementTimeout(tx *txn) error {
+	deadline, ok := tx.ctx.Deadline()
+	if !ok {
+		return nil
+	}
+	ms := time.Until(deadline).Milliseconds()
+	if ms < 1 {
+		return errcode.New(context.DeadlineExceeded)
+	}
+	_, err := tx.Exec("SELECT set_config('statement_timeout', $1, true)", strconv.FormatInt(ms, 10))
+	return newError(err)
+}
+
+func startSpan(ctx context.Context, name, statement string) (context.Context, oteltrace.Span) {
+	return trace.Start(ctx, name,
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.statement", statement),
+	)
+}
+
+func isRetryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == serializationFailure || pqErr.Code == deadlockDetected
+}
@@ -3,9 +3,14 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"sync/atomic"
+	"time"
 
 	"github.com/SakataAtsuki/e-architecture/pkg/entity"
 	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/internal/pgquery"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
 	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
 	_ "github.com/lib/pq"
 )
@@ -13,86 +18,250 @@ import (
 var _ repository.User = (*User)(nil)
 
 type User struct {
-	db *sql.DB
+	db           *sql.DB
+	maxTxRetries int
+	onTxRetry    func(op string)
+
+	replicas      []*replica
+	nextReplica   atomic.Uint32
+	maxReplicaLag time.Duration
 }
 
-func NewUser(db *sql.DB) *User {
-	return &User{db: db}
+// NewUser returns a User that writes to the primary db.
+func NewUser(db *sql.DB, opts ...Option) *User {
+	u := &User{db: db, maxTxRetries: defaultMaxTxRetries, maxReplicaLag: defaultMaxReplicaLag}
+	for _, opt := range opts {
+		opt(u)
+	}
+	return u
 }
 
 func (u *User) Create(ctx context.Context, v *entity.User) (*entity.User, error) {
-	tx, err := u.db.Begin()
+	tenantID, err := tenant.Require(ctx)
 	if err != nil {
 		return nil, errcode.New(err)
 	}
-	defer func() {
-		switch err {
-		case nil:
-			err = tx.Commit()
-		default:
-			tx.Rollback()
+
+	var user *entity.User
+	err = u.withTx(ctx, "Create", func(tx *txn) error {
+		query, args, err := pgquery.InsertUser(tenantID, v)
+		if err != nil {
+			return errcode.New(err)
+		}
+		if _, err := tx.Exec(query, args...); err != nil {
+			return newError(err)
 		}
-	}()
+		user, err = getUser(tx, tenantID, v.ID, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
 
-	_, err = tx.Exec("INSERT INTO users(id, name) VALUES ($1, $2)", v.ID, v.Name)
+func (u *User) Get(ctx context.Context, id string) (*entity.User, error) {
+	tenantID, err := tenant.Require(ctx)
 	if err != nil {
 		return nil, errcode.New(err)
 	}
 
-	rows, err := tx.Query("SELECT id, name FROM users WHERE id = $1", v.ID)
+	var user *entity.User
+	err = u.withReadTx(ctx, "Get", func(tx *txn) error {
+		user, err = getUser(tx, tenantID, id, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (u *User) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
+	tenantID, err := tenant.Require(ctx)
 	if err != nil {
 		return nil, errcode.New(err)
 	}
-	defer rows.Close()
 
-	user := &entity.User{}
-	for rows.Next() {
-		err := rows.Scan(&user.ID, &user.Name)
-		if err != nil {
-			return nil, errcode.New(err)
+	var user *entity.User
+	err = u.withReadTx(ctx, "GetByEmail", func(tx *txn) error {
+		user, err = pgquery.ScanUser(tx.QueryRow(pgquery.UserByEmail, tenantID, email))
+		if errors.Is(err, sql.ErrNoRows) {
+			return errcode.NewNotFound("user not found: %s", email)
 		}
+		return newError(err)
+	})
+	if err != nil {
+		return nil, err
 	}
+	return user, nil
+}
 
-	err = rows.Err()
+func (u *User) List(ctx context.Context, params *repository.ListUsersParams) (entity.Users, error) {
+	tenantID, err := tenant.Require(ctx)
 	if err != nil {
 		return nil, errcode.New(err)
 	}
 
-	return user, nil
+	query, args, err := pgquery.ListUsers(tenantID, params)
+	if err != nil {
+		return nil, err
+	}
+	var users entity.Users
+	err = u.withReadTx(ctx, "List", func(tx *txn) error {
+		rows, err := tx.Query(query, args...)
+		if err != nil {
+			return newError(err)
+		}
+		defer rows.Close()
+
+		users = entity.Users{}
+		for rows.Next() {
+			user, err := pgquery.ScanUser(rows)
+			if err != nil {
+				return newError(err)
+			}
+			users = append(users, user)
+		}
+		if err := rows.Err(); err != nil {
+			return newError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
 }
 
-func (u *User) Get(ctx context.Context, id string) (*entity.User, error) {
-	tx, err := u.db.Begin()
+func (u *User) Search(ctx context.Context, params *repository.SearchUsersParams) ([]*repository.ScoredUser, error) {
+	tenantID, err := tenant.Require(ctx)
 	if err != nil {
 		return nil, errcode.New(err)
 	}
-	defer func() {
-		switch err {
-		case nil:
-			err = tx.Commit()
-		default:
-			tx.Rollback()
+
+	query, args := pgquery.SearchUsers(tenantID, params)
+	var users []*repository.ScoredUser
+	err = u.withReadTx(ctx, "Search", func(tx *txn) error {
+		rows, err := tx.Query(query, args...)
+		if err != nil {
+			return newError(err)
+		}
+		defer rows.Close()
+
+		users = []*repository.ScoredUser{}
+		for rows.Next() {
+			var score float64
+			user, err := pgquery.ScanUser(rows, &score)
+			if err != nil {
+				return newError(err)
+			}
+			users = append(users, &repository.ScoredUser{User: user, Score: score})
 		}
-	}()
+		return newError(rows.Err())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
 
-	rows, err := tx.Query("SELECT id, name FROM users WHERE id = $1", id)
+func (u *User) Stats(ctx context.Context, params *repository.UserStatsParams) (*repository.UserStats, error) {
+	tenantID, err := tenant.Require(ctx)
 	if err != nil {
 		return nil, errcode.New(err)
 	}
-	defer rows.Close()
 
-	user := &entity.User{}
-	for rows.Next() {
-		err := rows.Scan(&user.ID, &user.Name)
+	query, args, err := pgquery.UserStats(tenantID, params)
+	if err != nil {
+		return nil, err
+	}
+	var stats *repository.UserStats
+	err = u.withReadTx(ctx, "Stats", func(tx *txn) error {
+		rows, err := tx.Query(query, args...)
 		if err != nil {
-			return nil, errcode.New(err)
+			return newError(err)
 		}
+		defer rows.Close()
+
+		stats = &repository.UserStats{ByGender: map[entity.Gender]int64{}, Signups: []*repository.SignupCount{}}
+		for rows.Next() {
+			if err := pgquery.AddUserStatsRow(rows, stats); err != nil {
+				return newError(err)
+			}
+		}
+		return newError(rows.Err())
+	})
+	if err != nil {
+		return nil, err
 	}
+	return stats, nil
+}
 
-	err = rows.Err()
+func (u *User) Update(ctx context.Context, id string, update func(*entity.User) bool) (*entity.User, error) {
+	tenantID, err := tenant.Require(ctx)
 	if err != nil {
 		return nil, errcode.New(err)
 	}
 
+	var user *entity.User
+	err = u.withTx(ctx, "Update", func(tx *txn) error {
+		user, err = getUser(tx, tenantID, id, true)
+		if err != nil {
+			return err
+		}
+		if !update(user) {
+			return nil
+		}
+		query, args, err := pgquery.UpdateUser(tenantID, id, user)
+		if err != nil {
+			return errcode.New(err)
+		}
+		if err := tx.QueryRow(query, args...).Scan(&user.UpdatedAt); err != nil {
+			return newError(err)
+		}
+		user.UpdatedAt = user.UpdatedAt.UTC()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (u *User) Delete(ctx context.Context, id string) error {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return errcode.New(err)
+	}
+
+	return u.withTx(ctx, "Delete", func(tx *txn) error {
+		result, err := tx.Exec("DELETE FROM users WHERE tenant_id = $1 AND id = $2", tenantID, id)
+		if err != nil {
+			return newError(err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return newError(err)
+		}
+		if n == 0 {
+			return errcode.NewNotFound("user not found: %s", id)
+		}
+		return nil
+	})
+}
+
+func getUser(tx *txn, tenantID, id string, forUpdate bool) (*entity.User, error) {
+	query := "SELECT " + pgquery.UserColumns + " FROM users WHERE tenant_id = $1 AND id = $2"
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	user, err := pgquery.ScanUser(tx.QueryRow(query, tenantID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errcode.NewNotFound("user not found: %s", id)
+	}
+	if err != nil {
+		return nil, newError(err)
+	}
 	return user, nil
 }
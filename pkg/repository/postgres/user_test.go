@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/repositorytest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUser_Contract(t *testing.T) {
+	db, err := sql.Open("postgres", repositorytest.PostgresDSN(t))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, db.Ping())
+
+	repositorytest.TestUser(t, func(t *testing.T) repository.User {
+		return NewUser(db)
+	})
+	repositorytest.TestCredential(t, func(t *testing.T) (repository.User, repository.Credential) {
+		users := NewUser(db)
+		return users, NewCredential(users)
+	})
+	repositorytest.TestSession(t, func(t *testing.T) (repository.User, repository.Session) {
+		users := NewUser(db)
+		return users, NewSession(users)
+	})
+	repositorytest.TestVerification(t, func(t *testing.T) (repository.User, repository.Verification) {
+		users := NewUser(db)
+		return users, NewVerification(users)
+	})
+	repositorytest.TestGroup(t, func(t *testing.T) (repository.User, repository.Group) {
+		users := NewUser(db)
+		return users, NewGroup(users)
+	})
+	repositorytest.TestRelationship(t, func(t *testing.T) (repository.User, repository.Relationship) {
+		users := NewUser(db)
+		return users, NewRelationship(users)
+	})
+}
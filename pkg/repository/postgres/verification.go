@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/internal/pgquery"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+)
+
+var _ repository.Verification = (*Verification)(nil)
+
+// Verification stores email verifications in the database of a User.
+type Verification struct {
+	u *User
+}
+
+func NewVerification(u *User) *Verification {
+	return &Verification{u: u}
+}
+
+func (v *Verification) Create(ctx context.Context, in *entity.Verification, tokenHash string) (*entity.Verification, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var verification *entity.Verification
+	err = v.u.withTx(ctx, "CreateVerification", func(tx *txn) error {
+		if err := lockUser(tx, tenantID, in.UserID); err != nil {
+			return err
+		}
+		verification, err = pgquery.ScanVerification(tx.QueryRow(pgquery.SetVerification,
+			tenantID, in.UserID, tokenHash, in.Email, truncate(in.CreatedAt), truncate(in.ExpiresAt)))
+		return newError(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return verification, nil
+}
+
+func (v *Verification) Consume(ctx context.Context, tokenHash string) (*entity.Verification, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var verification *entity.Verification
+	err = v.u.withTx(ctx, "ConsumeVerification", func(tx *txn) error {
+		verification, err = pgquery.ScanVerification(tx.QueryRow(pgquery.ConsumeVerification, tenantID, tokenHash))
+		if errors.Is(err, sql.ErrNoRows) {
+			return errcode.NewNotFound("verification token not found")
+		}
+		return newError(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return verification, nil
+}
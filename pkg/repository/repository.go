@@ -2,24 +2,212 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/filter"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/textsearch"
 )
 
 type Database struct {
-	User User
+	User         User
+	Credential   Credential
+	Session      Session
+	Verification Verification
+	Group        Group
+	Relationship Relationship
 }
 
 type User interface {
 	Create(ctx context.Context, v *entity.User) (*entity.User, error)
 	Get(ctx context.Context, id string) (*entity.User, error)
-	// List(ctx context.Context, params *ListUsersParams) (entity.Users, error)
-	// Update(ctx context.Context, id string, update func(*entity.User) bool) (*entity.User, error)
-	// Delete(ctx context.Context, id string) error
+	// GetByEmail returns the user whose email equals email regardless of case.
+	GetByEmail(ctx context.Context, email string) (*entity.User, error)
+	List(ctx context.Context, params *ListUsersParams) (entity.Users, error)
+	Update(ctx context.Context, id string, update func(*entity.User) bool) (*entity.User, error)
+	Delete(ctx context.Context, id string) error
+	// Search returns the users whose name matches the query, the most relevant
+	// first and then by ID.
+	Search(ctx context.Context, params *SearchUsersParams) ([]*ScoredUser, error)
+	// Stats aggregates the users matching the filter of params.
+	Stats(ctx context.Context, params *UserStatsParams) (*UserStats, error)
 }
 
-// type ListUsersParams struct {
-// 	Name    string
-// 	OrderBy string
-// 	Limit   int
-// }
+// Credential stores the passwords of the users in the same database, so that
+// they are deleted with their users.
+type Credential interface {
+	// Get returns the credential of the user, or NotFound when the user has no
+	// password.
+	Get(ctx context.Context, userID string) (*entity.Credential, error)
+	// Set stores the password hash of the user and clears its failed attempts
+	// and lockout. It returns NotFound when the user does not exist.
+	Set(ctx context.Context, userID, passwordHash string) (*entity.Credential, error)
+	// Update calls update with the credential of the user, holding it against
+	// concurrent updates, and stores it when update returns true.
+	Update(ctx context.Context, userID string, update func(*entity.Credential) bool) (*entity.Credential, error)
+}
+
+// Session stores sessions and the hashes of their refresh tokens in the
+// database of the users, so that they are deleted with their users.
+type Session interface {
+	// Create stores v with its first refresh token. It returns NotFound when
+	// the user does not exist.
+	Create(ctx context.Context, v *entity.Session, tokenHash string) (*entity.Session, error)
+	Get(ctx context.Context, id string) (*entity.Session, error)
+	// List returns the sessions of the user, the newest first.
+	List(ctx context.Context, params *ListSessionsParams) (entity.Sessions, error)
+	// Rotate replaces the refresh token tokenHash with newHash and sets
+	// RefreshedAt to now, if its session is active at now. A token that has
+	// already been replaced revokes its session at now with ReuseDetected.
+	// It returns the session as it is after the call, or NotFound when the
+	// token is unknown.
+	Rotate(ctx context.Context, tokenHash, newHash string, now time.Time) (*entity.Session, error)
+	// Revoke revokes the session at now unless it is revoked already.
+	Revoke(ctx context.Context, id string, now time.Time) (*entity.Session, error)
+	// DeleteExpired deletes up to limit sessions that expired before before,
+	// with their refresh tokens. Unlike the other methods it spans every
+	// tenant, for the background sweeper.
+	DeleteExpired(ctx context.Context, before time.Time, limit int) (int64, error)
+}
+
+// Verification stores the outstanding email verifications of the users by
+// the hashes of their tokens, at most one per user, in the database of the
+// users so that they are deleted with their users.
+type Verification interface {
+	// Create stores v, replacing the outstanding verification of the user. It
+	// returns NotFound when the user does not exist.
+	Create(ctx context.Context, v *entity.Verification, tokenHash string) (*entity.Verification, error)
+	// Consume deletes the verification of the token and returns it, so that a
+	// token is used at most once. It returns NotFound when the token is
+	// unknown.
+	Consume(ctx context.Context, tokenHash string) (*entity.Verification, error)
+}
+
+// Group stores groups and their members in the database of the users, so
+// that memberships are deleted with their users and their groups. Members
+// must be existing users: adding another one is a FailedPrecondition.
+type Group interface {
+	// Create stores v and, unless ownerID is empty, adds that user as its
+	// owner. It returns FailedPrecondition when the owner does not exist.
+	Create(ctx context.Context, v *entity.Group, ownerID string) (*entity.Group, error)
+	Get(ctx context.Context, id string) (*entity.Group, error)
+	// List returns the groups by ID.
+	List(ctx context.Context, params *ListGroupsParams) (entity.Groups, error)
+	Update(ctx context.Context, id string, update func(*entity.Group) bool) (*entity.Group, error)
+	// Delete deletes the group with its members.
+	Delete(ctx context.Context, id string) error
+	// AddMember returns NotFound when the group does not exist,
+	// FailedPrecondition when the user does not exist and AlreadyExists when
+	// the user is a member already.
+	AddMember(ctx context.Context, m *entity.GroupMember) (*entity.GroupMember, error)
+	// GetMember returns NotFound when the user is not a member of the group.
+	GetMember(ctx context.Context, groupID, userID string) (*entity.GroupMember, error)
+	// RemoveMember returns NotFound when the user is not a member of the
+	// group and FailedPrecondition when it is the last owner of the group.
+	RemoveMember(ctx context.Context, groupID, userID string) error
+	// ListMembers returns the members of the group by user ID, or NotFound
+	// when the group does not exist.
+	ListMembers(ctx context.Context, params *ListGroupMembersParams) (entity.GroupMembers, error)
+	// ListUserGroups returns the groups of the user by group ID, or NotFound
+	// when the user does not exist.
+	ListUserGroups(ctx context.Context, params *ListUserGroupsParams) ([]*UserGroup, error)
+}
+
+// Relationship stores the follows and blocks between the users in their
+// database, so that they are deleted with either user. Targets must be
+// existing users: relating to another one is a FailedPrecondition.
+type Relationship interface {
+	// Follow makes the user follow the target. It returns NotFound when the
+	// user does not exist, FailedPrecondition when either of them blocks the
+	// other and AlreadyExists when the user follows the target already.
+	Follow(ctx context.Context, userID, targetID string) (*entity.Relationship, error)
+	// Unfollow returns NotFound when the user does not follow the target.
+	Unfollow(ctx context.Context, userID, targetID string) error
+	// Block makes the user block the target and removes the follows between
+	// them. It returns NotFound when the user does not exist and
+	// AlreadyExists when the user blocks the target already.
+	Block(ctx context.Context, userID, targetID string) (*entity.Relationship, error)
+	// Unblock returns NotFound when the user does not block the target.
+	Unblock(ctx context.Context, userID, targetID string) error
+	// ListFollowers returns the follows of the user by follower ID, or
+	// NotFound when the user does not exist.
+	ListFollowers(ctx context.Context, params *ListRelationshipsParams) (entity.Relationships, error)
+	// ListFollowing returns the follows by the user by target ID, or NotFound
+	// when the user does not exist.
+	ListFollowing(ctx context.Context, params *ListRelationshipsParams) (entity.Relationships, error)
+	// ListMutualFollows returns the follows by the user of users who follow
+	// it back, by target ID, or NotFound when the user does not exist.
+	ListMutualFollows(ctx context.Context, params *ListRelationshipsParams) (entity.Relationships, error)
+}
+
+type ListSessionsParams struct {
+	UserID string
+	Limit  int
+}
+
+// ListGroupsParams and the other group lists page by ID: After excludes
+// the IDs up to and including it.
+type ListGroupsParams struct {
+	After string
+	Limit int
+}
+
+type ListGroupMembersParams struct {
+	GroupID string
+	// After is a user ID.
+	After string
+	Limit int
+}
+
+type ListUserGroupsParams struct {
+	UserID string
+	// After is a group ID.
+	After string
+	Limit int
+}
+
+// ListRelationshipsParams pages by the ID of the other user of each
+// relationship like the group lists.
+type ListRelationshipsParams struct {
+	UserID string
+	After  string
+	Limit  int
+}
+
+// UserGroup is a group with the role of the user in it.
+type UserGroup struct {
+	Group *entity.Group
+	Role  entity.GroupRole
+}
+
+type ListUsersParams struct {
+	Name string
+	// Filter is checked against UserFilterSchema. Nil matches every user.
+	Filter filter.Expr
+	// OrderBy is checked against UserFilterSchema and ends with
+	// UserOrderTiebreak. Empty orders by ID.
+	OrderBy filter.Order
+	Limit   int
+}
+
+// Order returns OrderBy, or the order by ID when it is empty.
+func (p *ListUsersParams) Order() filter.Order {
+	if len(p.OrderBy) == 0 {
+		return filter.Order{{Field: UserOrderTiebreak}}
+	}
+	return p.OrderBy
+}
+
+type SearchUsersParams struct {
+	// Query is normalized with textsearch.Normalize.
+	Query  string
+	Mode   textsearch.Mode
+	Offset int
+	Limit  int
+}
+
+type ScoredUser struct {
+	User *entity.User
+	// Score is the relevance of the user between 0 and 1.
+	Score float64
+}
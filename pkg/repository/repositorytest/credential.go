@@ -0,0 +1,158 @@
+package repositorytest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCredential runs the suite against the repository.Credential returned by
+// newCredential with the repository.User of its users, which is called once
+// per subtest.
+func TestCredential(t *testing.T, newCredential func(t *testing.T) (repository.User, repository.Credential)) {
+	tests := []struct {
+		name string
+		f    func(t *testing.T, users repository.User, repo repository.Credential)
+	}{
+		{name: "SetGet", f: testCredentialSetGet},
+		{name: "NotFound", f: testCredentialNotFound},
+		{name: "Update", f: testCredentialUpdate},
+		{name: "DeleteUser", f: testCredentialDeleteUser},
+		{name: "TenantIsolation", f: testCredentialTenantIsolation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, repo := newCredential(t)
+			tt.f(t, users, repo)
+		})
+	}
+}
+
+func testCredentialSetGet(t *testing.T, users repository.User, repo repository.Credential) {
+	ctx := TenantContext(t)
+	_, err := users.Create(ctx, &entity.User{ID: "id", Name: "name"})
+	require.NoError(t, err)
+
+	set, err := repo.Set(ctx, "id", "hash")
+	require.NoError(t, err)
+	require.Equal(t, "id", set.UserID)
+	require.Equal(t, "hash", set.PasswordHash)
+	require.Zero(t, set.FailedAttempts)
+	require.True(t, set.LockedUntil.IsZero())
+	require.False(t, set.UpdatedAt.IsZero())
+	require.Equal(t, time.UTC, set.UpdatedAt.Location())
+
+	got, err := repo.Get(ctx, "id")
+	require.NoError(t, err)
+	require.Equal(t, set, got)
+
+	// setting again clears the failed attempts and the lockout
+	_, err = repo.Update(ctx, "id", func(c *entity.Credential) bool {
+		c.FailedAttempts = 3
+		c.LockedUntil = time.Now().Add(time.Hour)
+		return true
+	})
+	require.NoError(t, err)
+	set, err = repo.Set(ctx, "id", "new-hash")
+	require.NoError(t, err)
+	got, err = repo.Get(ctx, "id")
+	require.NoError(t, err)
+	require.Equal(t, set, got)
+	require.Equal(t, "new-hash", got.PasswordHash)
+	require.Zero(t, got.FailedAttempts)
+	require.True(t, got.LockedUntil.IsZero())
+}
+
+func testCredentialNotFound(t *testing.T, users repository.User, repo repository.Credential) {
+	ctx := TenantContext(t)
+
+	// the user does not exist
+	_, err := repo.Set(ctx, "missing", "hash")
+	require.True(t, errcode.IsNotfound(err), err)
+
+	// the user has no password
+	_, err = users.Create(ctx, &entity.User{ID: "id", Name: "name"})
+	require.NoError(t, err)
+	_, err = repo.Get(ctx, "id")
+	require.True(t, errcode.IsNotfound(err), err)
+	_, err = repo.Update(ctx, "id", func(c *entity.Credential) bool { return true })
+	require.True(t, errcode.IsNotfound(err), err)
+}
+
+func testCredentialUpdate(t *testing.T, users repository.User, repo repository.Credential) {
+	ctx := TenantContext(t)
+	_, err := users.Create(ctx, &entity.User{ID: "id", Name: "name"})
+	require.NoError(t, err)
+	set, err := repo.Set(ctx, "id", "hash")
+	require.NoError(t, err)
+
+	lockedUntil := time.Now().Add(time.Hour).UTC().Truncate(time.Microsecond)
+	var current entity.Credential
+	got, err := repo.Update(ctx, "id", func(c *entity.Credential) bool {
+		current = *c
+		c.PasswordHash = "rehashed"
+		c.FailedAttempts = 2
+		c.LockedUntil = lockedUntil
+		return true
+	})
+	require.NoError(t, err)
+	require.Equal(t, set, &current)
+	require.Equal(t, "rehashed", got.PasswordHash)
+	require.Equal(t, 2, got.FailedAttempts)
+	require.Equal(t, lockedUntil, got.LockedUntil)
+	require.False(t, got.UpdatedAt.Before(set.UpdatedAt))
+	updated := got
+
+	// changes are discarded when update returns false
+	_, err = repo.Update(ctx, "id", func(c *entity.Credential) bool {
+		c.FailedAttempts = 10
+		return false
+	})
+	require.NoError(t, err)
+	got, err = repo.Get(ctx, "id")
+	require.NoError(t, err)
+	require.Equal(t, updated, got)
+
+	// a zero LockedUntil ends the lockout
+	got, err = repo.Update(ctx, "id", func(c *entity.Credential) bool {
+		c.LockedUntil = time.Time{}
+		return true
+	})
+	require.NoError(t, err)
+	require.True(t, got.LockedUntil.IsZero())
+	got, err = repo.Get(ctx, "id")
+	require.NoError(t, err)
+	require.True(t, got.LockedUntil.IsZero())
+}
+
+func testCredentialDeleteUser(t *testing.T, users repository.User, repo repository.Credential) {
+	ctx := TenantContext(t)
+	_, err := users.Create(ctx, &entity.User{ID: "id", Name: "name"})
+	require.NoError(t, err)
+	_, err = repo.Set(ctx, "id", "hash")
+	require.NoError(t, err)
+
+	// the password is deleted with the user and not inherited by a new one
+	require.NoError(t, users.Delete(ctx, "id"))
+	_, err = users.Create(ctx, &entity.User{ID: "id", Name: "name"})
+	require.NoError(t, err)
+	_, err = repo.Get(ctx, "id")
+	require.True(t, errcode.IsNotfound(err), err)
+}
+
+func testCredentialTenantIsolation(t *testing.T, users repository.User, repo repository.Credential) {
+	ctx, other := TenantContext(t), TenantContext(t)
+	_, err := users.Create(ctx, &entity.User{ID: "id", Name: "name"})
+	require.NoError(t, err)
+	_, err = repo.Set(ctx, "id", "hash")
+	require.NoError(t, err)
+
+	_, err = repo.Get(other, "id")
+	require.True(t, errcode.IsNotfound(err), err)
+	_, err = repo.Set(other, "id", "hash")
+	require.True(t, errcode.IsNotfound(err), err)
+}
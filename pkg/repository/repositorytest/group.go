@@ -0,0 +1,322 @@
+package repositorytest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGroup runs the suite against the repository.Group returned by newGroup
+// with the repository.User of its users, which is called once per subtest.
+func TestGroup(t *testing.T, newGroup func(t *testing.T) (repository.User, repository.Group)) {
+	tests := []struct {
+		name string
+		f    func(t *testing.T, users repository.User, repo repository.Group)
+	}{
+		{name: "CreateGet", f: testGroupCreateGet},
+		{name: "CreateOwner", f: testGroupCreateOwner},
+		{name: "List", f: testGroupList},
+		{name: "Update", f: testGroupUpdate},
+		{name: "Delete", f: testGroupDelete},
+		{name: "Members", f: testGroupMembers},
+		{name: "ListMembers", f: testGroupListMembers},
+		{name: "ListUserGroups", f: testGroupListUserGroups},
+		{name: "DeleteUser", f: testGroupDeleteUser},
+		{name: "TenantIsolation", f: testGroupTenantIsolation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, repo := newGroup(t)
+			tt.f(t, users, repo)
+		})
+	}
+}
+
+func testGroupCreateGet(t *testing.T, users repository.User, repo repository.Group) {
+	ctx := TenantContext(t)
+	before := time.Now().Add(-time.Second)
+	created, err := repo.Create(ctx, &entity.Group{ID: "id", Name: "name", Description: "description"}, "")
+	require.NoError(t, err)
+	require.Equal(t, "name", created.Name)
+	require.Equal(t, "description", created.Description)
+	require.True(t, created.CreatedAt.After(before), created.CreatedAt)
+	require.Equal(t, created.CreatedAt, created.UpdatedAt)
+	require.Equal(t, time.UTC, created.CreatedAt.Location())
+
+	got, err := repo.Get(ctx, "id")
+	require.NoError(t, err)
+	require.Equal(t, created, got)
+
+	_, err = repo.Create(ctx, &entity.Group{ID: "id", Name: "other"}, "")
+	require.True(t, errcode.IsAlreadyExists(err), err)
+	_, err = repo.Get(ctx, "missing")
+	require.True(t, errcode.IsNotfound(err), err)
+
+	// a group without an owner has no members
+	members, err := repo.ListMembers(ctx, &repository.ListGroupMembersParams{GroupID: "id"})
+	require.NoError(t, err)
+	require.Empty(t, members)
+}
+
+func testGroupCreateOwner(t *testing.T, users repository.User, repo repository.Group) {
+	ctx := TenantContext(t)
+	_, err := users.Create(ctx, &entity.User{ID: "owner", Name: "owner"})
+	require.NoError(t, err)
+
+	_, err = repo.Create(ctx, &entity.Group{ID: "id", Name: "name"}, "owner")
+	require.NoError(t, err)
+	member, err := repo.GetMember(ctx, "id", "owner")
+	require.NoError(t, err)
+	require.Equal(t, entity.GroupRoleOwner, member.Role)
+
+	// the group is not created without its owner
+	_, err = repo.Create(ctx, &entity.Group{ID: "orphan", Name: "name"}, "missing")
+	require.True(t, errcode.IsFailedPrecondition(err), err)
+	_, err = repo.Get(ctx, "orphan")
+	require.True(t, errcode.IsNotfound(err), err)
+}
+
+func testGroupList(t *testing.T, users repository.User, repo repository.Group) {
+	ctx := TenantContext(t)
+	for _, id := range []string{"c", "a", "b"} {
+		_, err := repo.Create(ctx, &entity.Group{ID: id, Name: id}, "")
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		name   string
+		params *repository.ListGroupsParams
+		want   []string
+	}{
+		{name: "all", params: &repository.ListGroupsParams{}, want: []string{"a", "b", "c"}},
+		{name: "limit", params: &repository.ListGroupsParams{Limit: 2}, want: []string{"a", "b"}},
+		{name: "after", params: &repository.ListGroupsParams{After: "a", Limit: 2}, want: []string{"b", "c"}},
+		{name: "after missing", params: &repository.ListGroupsParams{After: "aa"}, want: []string{"b", "c"}},
+		{name: "after last", params: &repository.ListGroupsParams{After: "c"}, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups, err := repo.List(ctx, tt.params)
+			require.NoError(t, err)
+			ids := []string{}
+			for _, g := range groups {
+				ids = append(ids, g.ID)
+			}
+			require.Equal(t, tt.want, ids)
+		})
+	}
+}
+
+func testGroupUpdate(t *testing.T, users repository.User, repo repository.Group) {
+	ctx := TenantContext(t)
+	created, err := repo.Create(ctx, &entity.Group{ID: "id", Name: "name"}, "")
+	require.NoError(t, err)
+
+	unchanged, err := repo.Update(ctx, "id", func(g *entity.Group) bool { return false })
+	require.NoError(t, err)
+	require.Equal(t, created, unchanged)
+
+	updated, err := repo.Update(ctx, "id", func(g *entity.Group) bool {
+		g.Name = "changed"
+		g.Description = "description"
+		return true
+	})
+	require.NoError(t, err)
+	require.Equal(t, "changed", updated.Name)
+	require.Equal(t, created.CreatedAt, updated.CreatedAt)
+	require.False(t, updated.UpdatedAt.Before(created.UpdatedAt))
+
+	got, err := repo.Get(ctx, "id")
+	require.NoError(t, err)
+	require.Equal(t, updated, got)
+
+	_, err = repo.Update(ctx, "missing", func(g *entity.Group) bool { return true })
+	require.True(t, errcode.IsNotfound(err), err)
+}
+
+func testGroupDelete(t *testing.T, users repository.User, repo repository.Group) {
+	ctx := TenantContext(t)
+	_, err := users.Create(ctx, &entity.User{ID: "user", Name: "user"})
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, &entity.Group{ID: "id", Name: "name"}, "user")
+	require.NoError(t, err)
+
+	// the members go with the group
+	require.NoError(t, repo.Delete(ctx, "id"))
+	_, err = repo.Get(ctx, "id")
+	require.True(t, errcode.IsNotfound(err), err)
+	groups, err := repo.ListUserGroups(ctx, &repository.ListUserGroupsParams{UserID: "user"})
+	require.NoError(t, err)
+	require.Empty(t, groups)
+	require.True(t, errcode.IsNotfound(repo.Delete(ctx, "id")))
+
+	// a group of the same ID starts over
+	_, err = repo.Create(ctx, &entity.Group{ID: "id", Name: "name"}, "")
+	require.NoError(t, err)
+	_, err = repo.GetMember(ctx, "id", "user")
+	require.True(t, errcode.IsNotfound(err), err)
+}
+
+func testGroupMembers(t *testing.T, users repository.User, repo repository.Group) {
+	ctx := TenantContext(t)
+	_, err := users.Create(ctx, &entity.User{ID: "user", Name: "user"})
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, &entity.Group{ID: "id", Name: "name"}, "")
+	require.NoError(t, err)
+
+	before := time.Now().Add(-time.Second)
+	added, err := repo.AddMember(ctx, &entity.GroupMember{GroupID: "id", UserID: "user", Role: entity.GroupRoleAdmin})
+	require.NoError(t, err)
+	require.Equal(t, "id", added.GroupID)
+	require.Equal(t, "user", added.UserID)
+	require.Equal(t, entity.GroupRoleAdmin, added.Role)
+	require.True(t, added.CreatedAt.After(before), added.CreatedAt)
+	got, err := repo.GetMember(ctx, "id", "user")
+	require.NoError(t, err)
+	require.Equal(t, added, got)
+
+	_, err = repo.AddMember(ctx, &entity.GroupMember{GroupID: "id", UserID: "user"})
+	require.True(t, errcode.IsAlreadyExists(err), err)
+	// members must be existing users of existing groups
+	_, err = repo.AddMember(ctx, &entity.GroupMember{GroupID: "id", UserID: "missing"})
+	require.True(t, errcode.IsFailedPrecondition(err), err)
+	_, err = repo.AddMember(ctx, &entity.GroupMember{GroupID: "missing", UserID: "user"})
+	require.True(t, errcode.IsNotfound(err), err)
+
+	require.NoError(t, repo.RemoveMember(ctx, "id", "user"))
+	_, err = repo.GetMember(ctx, "id", "user")
+	require.True(t, errcode.IsNotfound(err), err)
+	require.True(t, errcode.IsNotfound(repo.RemoveMember(ctx, "id", "user")))
+
+	// the last owner stays
+	_, err = users.Create(ctx, &entity.User{ID: "owner", Name: "owner"})
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, &entity.Group{ID: "owned", Name: "owned"}, "owner")
+	require.NoError(t, err)
+	err = repo.RemoveMember(ctx, "owned", "owner")
+	require.True(t, errcode.IsFailedPrecondition(err), err)
+	_, err = repo.AddMember(ctx, &entity.GroupMember{GroupID: "owned", UserID: "user", Role: entity.GroupRoleOwner})
+	require.NoError(t, err)
+	require.NoError(t, repo.RemoveMember(ctx, "owned", "owner"))
+	err = repo.RemoveMember(ctx, "owned", "user")
+	require.True(t, errcode.IsFailedPrecondition(err), err)
+}
+
+func testGroupListMembers(t *testing.T, users repository.User, repo repository.Group) {
+	ctx := TenantContext(t)
+	_, err := repo.Create(ctx, &entity.Group{ID: "id", Name: "name"}, "")
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, &entity.Group{ID: "other", Name: "other"}, "")
+	require.NoError(t, err)
+	for _, id := range []string{"c", "a", "b"} {
+		_, err := users.Create(ctx, &entity.User{ID: id, Name: id})
+		require.NoError(t, err)
+		_, err = repo.AddMember(ctx, &entity.GroupMember{GroupID: "id", UserID: id})
+		require.NoError(t, err)
+	}
+	_, err = repo.AddMember(ctx, &entity.GroupMember{GroupID: "other", UserID: "a"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		params *repository.ListGroupMembersParams
+		want   []string
+	}{
+		{name: "all", params: &repository.ListGroupMembersParams{GroupID: "id"}, want: []string{"a", "b", "c"}},
+		{name: "page", params: &repository.ListGroupMembersParams{GroupID: "id", After: "a", Limit: 1}, want: []string{"b"}},
+		{name: "other", params: &repository.ListGroupMembersParams{GroupID: "other"}, want: []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			members, err := repo.ListMembers(ctx, tt.params)
+			require.NoError(t, err)
+			ids := []string{}
+			for _, m := range members {
+				require.Equal(t, tt.params.GroupID, m.GroupID)
+				ids = append(ids, m.UserID)
+			}
+			require.Equal(t, tt.want, ids)
+		})
+	}
+
+	_, err = repo.ListMembers(ctx, &repository.ListGroupMembersParams{GroupID: "missing"})
+	require.True(t, errcode.IsNotfound(err), err)
+}
+
+func testGroupListUserGroups(t *testing.T, users repository.User, repo repository.Group) {
+	ctx := TenantContext(t)
+	_, err := users.Create(ctx, &entity.User{ID: "user", Name: "user"})
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, &entity.Group{ID: "b", Name: "b"}, "user")
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, &entity.Group{ID: "a", Name: "a"}, "")
+	require.NoError(t, err)
+	_, err = repo.AddMember(ctx, &entity.GroupMember{GroupID: "a", UserID: "user"})
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, &entity.Group{ID: "c", Name: "c"}, "")
+	require.NoError(t, err)
+
+	groups, err := repo.ListUserGroups(ctx, &repository.ListUserGroupsParams{UserID: "user"})
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	require.Equal(t, "a", groups[0].Group.ID)
+	require.Equal(t, entity.GroupRoleMember, groups[0].Role)
+	require.Equal(t, "b", groups[1].Group.ID)
+	require.Equal(t, entity.GroupRoleOwner, groups[1].Role)
+	got, err := repo.Get(ctx, "b")
+	require.NoError(t, err)
+	require.Equal(t, got, groups[1].Group)
+
+	groups, err = repo.ListUserGroups(ctx, &repository.ListUserGroupsParams{UserID: "user", After: "a", Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	require.Equal(t, "b", groups[0].Group.ID)
+
+	_, err = repo.ListUserGroups(ctx, &repository.ListUserGroupsParams{UserID: "missing"})
+	require.True(t, errcode.IsNotfound(err), err)
+}
+
+func testGroupDeleteUser(t *testing.T, users repository.User, repo repository.Group) {
+	ctx := TenantContext(t)
+	_, err := users.Create(ctx, &entity.User{ID: "user", Name: "user"})
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, &entity.Group{ID: "id", Name: "name"}, "user")
+	require.NoError(t, err)
+
+	// the memberships go with the user, the group stays
+	require.NoError(t, users.Delete(ctx, "user"))
+	_, err = repo.Get(ctx, "id")
+	require.NoError(t, err)
+	members, err := repo.ListMembers(ctx, &repository.ListGroupMembersParams{GroupID: "id"})
+	require.NoError(t, err)
+	require.Empty(t, members)
+}
+
+func testGroupTenantIsolation(t *testing.T, users repository.User, repo repository.Group) {
+	ctx, other := TenantContext(t), TenantContext(t)
+	_, err := users.Create(ctx, &entity.User{ID: "user", Name: "user"})
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, &entity.Group{ID: "id", Name: "name"}, "user")
+	require.NoError(t, err)
+
+	_, err = repo.Get(other, "id")
+	require.True(t, errcode.IsNotfound(err), err)
+	groups, err := repo.List(other, &repository.ListGroupsParams{})
+	require.NoError(t, err)
+	require.Empty(t, groups)
+	_, err = repo.GetMember(other, "id", "user")
+	require.True(t, errcode.IsNotfound(err), err)
+	_, err = repo.AddMember(other, &entity.GroupMember{GroupID: "id", UserID: "user"})
+	require.True(t, errcode.IsNotfound(err), err)
+	require.True(t, errcode.IsNotfound(repo.Delete(other, "id")))
+
+	// the same IDs are free in the other tenant, but its users are not
+	_, err = repo.Create(other, &entity.Group{ID: "id", Name: "name"}, "user")
+	require.True(t, errcode.IsFailedPrecondition(err), err)
+	_, err = repo.Create(other, &entity.Group{ID: "id", Name: "name"}, "")
+	require.NoError(t, err)
+}
@@ -0,0 +1,310 @@
+package repositorytest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRelationship runs the suite against the repository.Relationship
+// returned by newRelationship with the repository.User of its users, which
+// is called once per subtest.
+func TestRelationship(t *testing.T, newRelationship func(t *testing.T) (repository.User, repository.Relationship)) {
+	tests := []struct {
+		name string
+		f    func(t *testing.T, users repository.User, repo repository.Relationship)
+	}{
+		{name: "Follow", f: testRelationshipFollow},
+		{name: "Block", f: testRelationshipBlock},
+		{name: "BlockRace", f: testRelationshipBlockRace},
+		{name: "ListFollowers", f: testRelationshipListFollowers},
+		{name: "ListFollowing", f: testRelationshipListFollowing},
+		{name: "ListMutualFollows", f: testRelationshipListMutualFollows},
+		{name: "DeleteUser", f: testRelationshipDeleteUser},
+		{name: "TenantIsolation", f: testRelationshipTenantIsolation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, repo := newRelationship(t)
+			tt.f(t, users, repo)
+		})
+	}
+}
+
+func createUsers(t *testing.T, ctx context.Context, users repository.User, ids ...string) {
+	t.Helper()
+	for _, id := range ids {
+		_, err := users.Create(ctx, &entity.User{ID: id, Name: id})
+		require.NoError(t, err)
+	}
+}
+
+// relatedIDs returns the IDs of the other users of rels, the targets unless
+// followers.
+func relatedIDs(rels entity.Relationships, followers bool) []string {
+	ids := []string{}
+	for _, rel := range rels {
+		if followers {
+			ids = append(ids, rel.UserID)
+		} else {
+			ids = append(ids, rel.TargetID)
+		}
+	}
+	return ids
+}
+
+func testRelationshipFollow(t *testing.T, users repository.User, repo repository.Relationship) {
+	ctx := TenantContext(t)
+	createUsers(t, ctx, users, "a", "b")
+
+	before := time.Now().Add(-time.Second)
+	rel, err := repo.Follow(ctx, "a", "b")
+	require.NoError(t, err)
+	require.Equal(t, "a", rel.UserID)
+	require.Equal(t, "b", rel.TargetID)
+	require.Equal(t, entity.RelationshipKindFollow, rel.Kind)
+	require.True(t, rel.CreatedAt.After(before), rel.CreatedAt)
+	require.Equal(t, time.UTC, rel.CreatedAt.Location())
+
+	following, err := repo.ListFollowing(ctx, &repository.ListRelationshipsParams{UserID: "a"})
+	require.NoError(t, err)
+	require.Equal(t, entity.Relationships{rel}, following)
+
+	_, err = repo.Follow(ctx, "a", "b")
+	require.True(t, errcode.IsAlreadyExists(err), err)
+	_, err = repo.Follow(ctx, "a", "a")
+	require.True(t, errcode.IsInvalidArgument(err), err)
+	_, err = repo.Follow(ctx, "missing", "b")
+	require.True(t, errcode.IsNotfound(err), err)
+	_, err = repo.Follow(ctx, "a", "missing")
+	require.True(t, errcode.IsFailedPrecondition(err), err)
+
+	require.NoError(t, repo.Unfollow(ctx, "a", "b"))
+	require.True(t, errcode.IsNotfound(repo.Unfollow(ctx, "a", "b")))
+	following, err = repo.ListFollowing(ctx, &repository.ListRelationshipsParams{UserID: "a"})
+	require.NoError(t, err)
+	require.Empty(t, following)
+}
+
+func testRelationshipBlock(t *testing.T, users repository.User, repo repository.Relationship) {
+	ctx := TenantContext(t)
+	createUsers(t, ctx, users, "a", "b")
+	_, err := repo.Follow(ctx, "a", "b")
+	require.NoError(t, err)
+	_, err = repo.Follow(ctx, "b", "a")
+	require.NoError(t, err)
+
+	// a block removes the follows in both directions
+	rel, err := repo.Block(ctx, "a", "b")
+	require.NoError(t, err)
+	require.Equal(t, entity.RelationshipKindBlock, rel.Kind)
+	for _, id := range []string{"a", "b"} {
+		following, err := repo.ListFollowing(ctx, &repository.ListRelationshipsParams{UserID: id})
+		require.NoError(t, err)
+		require.Empty(t, following)
+		followers, err := repo.ListFollowers(ctx, &repository.ListRelationshipsParams{UserID: id})
+		require.NoError(t, err)
+		require.Empty(t, followers)
+	}
+	_, err = repo.Block(ctx, "a", "b")
+	require.True(t, errcode.IsAlreadyExists(err), err)
+	_, err = repo.Block(ctx, "a", "a")
+	require.True(t, errcode.IsInvalidArgument(err), err)
+	_, err = repo.Block(ctx, "a", "missing")
+	require.True(t, errcode.IsFailedPrecondition(err), err)
+
+	// neither of them can follow the other
+	_, err = repo.Follow(ctx, "a", "b")
+	require.True(t, errcode.IsFailedPrecondition(err), err)
+	_, err = repo.Follow(ctx, "b", "a")
+	require.True(t, errcode.IsFailedPrecondition(err), err)
+	require.True(t, errcode.IsNotfound(repo.Unfollow(ctx, "a", "b")))
+
+	// the blocked user can block back, and both blocks must go
+	_, err = repo.Block(ctx, "b", "a")
+	require.NoError(t, err)
+	require.NoError(t, repo.Unblock(ctx, "a", "b"))
+	require.True(t, errcode.IsNotfound(repo.Unblock(ctx, "a", "b")))
+	_, err = repo.Follow(ctx, "a", "b")
+	require.True(t, errcode.IsFailedPrecondition(err), err)
+	require.NoError(t, repo.Unblock(ctx, "b", "a"))
+	_, err = repo.Follow(ctx, "a", "b")
+	require.NoError(t, err)
+}
+
+func testRelationshipBlockRace(t *testing.T, users repository.User, repo repository.Relationship) {
+	ctx := TenantContext(t)
+	createUsers(t, ctx, users, "a", "b")
+
+	// however a follow and a block interleave, the follow does not outlive
+	// the block
+	for i := 0; i < 10; i++ {
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, _ = repo.Follow(ctx, "a", "b")
+		}()
+		go func() {
+			defer wg.Done()
+			_, _ = repo.Block(ctx, "b", "a")
+		}()
+		wg.Wait()
+
+		followers, err := repo.ListFollowers(ctx, &repository.ListRelationshipsParams{UserID: "b"})
+		require.NoError(t, err)
+		require.Empty(t, followers)
+		require.NoError(t, repo.Unblock(ctx, "b", "a"))
+	}
+}
+
+func testRelationshipListFollowers(t *testing.T, users repository.User, repo repository.Relationship) {
+	ctx := TenantContext(t)
+	createUsers(t, ctx, users, "user", "c", "a", "b", "d")
+	for _, id := range []string{"c", "a", "b"} {
+		_, err := repo.Follow(ctx, id, "user")
+		require.NoError(t, err)
+	}
+	_, err := repo.Follow(ctx, "user", "d")
+	require.NoError(t, err)
+	_, err = repo.Block(ctx, "d", "user")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		params *repository.ListRelationshipsParams
+		want   []string
+	}{
+		{name: "all", params: &repository.ListRelationshipsParams{UserID: "user"}, want: []string{"a", "b", "c"}},
+		{name: "limit", params: &repository.ListRelationshipsParams{UserID: "user", Limit: 2}, want: []string{"a", "b"}},
+		{name: "after", params: &repository.ListRelationshipsParams{UserID: "user", After: "a", Limit: 2}, want: []string{"b", "c"}},
+		{name: "after last", params: &repository.ListRelationshipsParams{UserID: "user", After: "c"}, want: []string{}},
+		// blocks are neither followers nor follows
+		{name: "blocked", params: &repository.ListRelationshipsParams{UserID: "d"}, want: []string{}},
+		{name: "missing user", params: &repository.ListRelationshipsParams{UserID: "missing"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rels, err := repo.ListFollowers(ctx, tt.params)
+			if tt.want == nil {
+				require.True(t, errcode.IsNotfound(err), err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, relatedIDs(rels, true))
+		})
+	}
+}
+
+func testRelationshipListFollowing(t *testing.T, users repository.User, repo repository.Relationship) {
+	ctx := TenantContext(t)
+	createUsers(t, ctx, users, "user", "c", "a", "b", "d")
+	for _, id := range []string{"c", "a", "b"} {
+		_, err := repo.Follow(ctx, "user", id)
+		require.NoError(t, err)
+	}
+	_, err := repo.Follow(ctx, "d", "user")
+	require.NoError(t, err)
+	_, err = repo.Block(ctx, "user", "d")
+	require.NoError(t, err)
+
+	rels, err := repo.ListFollowing(ctx, &repository.ListRelationshipsParams{UserID: "user"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b", "c"}, relatedIDs(rels, false))
+	for _, rel := range rels {
+		require.Equal(t, "user", rel.UserID)
+	}
+	rels, err = repo.ListFollowing(ctx, &repository.ListRelationshipsParams{UserID: "user", After: "a", Limit: 1})
+	require.NoError(t, err)
+	require.Equal(t, []string{"b"}, relatedIDs(rels, false))
+	rels, err = repo.ListFollowing(ctx, &repository.ListRelationshipsParams{UserID: "d"})
+	require.NoError(t, err)
+	require.Empty(t, rels)
+
+	_, err = repo.ListFollowing(ctx, &repository.ListRelationshipsParams{UserID: "missing"})
+	require.True(t, errcode.IsNotfound(err), err)
+}
+
+func testRelationshipListMutualFollows(t *testing.T, users repository.User, repo repository.Relationship) {
+	ctx := TenantContext(t)
+	createUsers(t, ctx, users, "user", "a", "b", "c", "d")
+	// a and c follow back, b does not, d only follows
+	for _, id := range []string{"a", "b", "c"} {
+		_, err := repo.Follow(ctx, "user", id)
+		require.NoError(t, err)
+	}
+	for _, id := range []string{"a", "c", "d"} {
+		_, err := repo.Follow(ctx, id, "user")
+		require.NoError(t, err)
+	}
+
+	rels, err := repo.ListMutualFollows(ctx, &repository.ListRelationshipsParams{UserID: "user"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "c"}, relatedIDs(rels, false))
+	require.Equal(t, "user", rels[0].UserID)
+	require.Equal(t, entity.RelationshipKindFollow, rels[0].Kind)
+	rels, err = repo.ListMutualFollows(ctx, &repository.ListRelationshipsParams{UserID: "user", After: "a", Limit: 1})
+	require.NoError(t, err)
+	require.Equal(t, []string{"c"}, relatedIDs(rels, false))
+
+	// mutual follows are symmetric
+	rels, err = repo.ListMutualFollows(ctx, &repository.ListRelationshipsParams{UserID: "a"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"user"}, relatedIDs(rels, false))
+
+	require.NoError(t, repo.Unfollow(ctx, "c", "user"))
+	rels, err = repo.ListMutualFollows(ctx, &repository.ListRelationshipsParams{UserID: "user"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a"}, relatedIDs(rels, false))
+
+	_, err = repo.ListMutualFollows(ctx, &repository.ListRelationshipsParams{UserID: "missing"})
+	require.True(t, errcode.IsNotfound(err), err)
+}
+
+func testRelationshipDeleteUser(t *testing.T, users repository.User, repo repository.Relationship) {
+	ctx := TenantContext(t)
+	createUsers(t, ctx, users, "a", "b", "c")
+	_, err := repo.Follow(ctx, "a", "b")
+	require.NoError(t, err)
+	_, err = repo.Follow(ctx, "b", "a")
+	require.NoError(t, err)
+	_, err = repo.Block(ctx, "c", "a")
+	require.NoError(t, err)
+
+	// the relationships go with the user in either direction
+	require.NoError(t, users.Delete(ctx, "a"))
+	rels, err := repo.ListFollowers(ctx, &repository.ListRelationshipsParams{UserID: "b"})
+	require.NoError(t, err)
+	require.Empty(t, rels)
+	rels, err = repo.ListFollowing(ctx, &repository.ListRelationshipsParams{UserID: "b"})
+	require.NoError(t, err)
+	require.Empty(t, rels)
+	require.True(t, errcode.IsNotfound(repo.Unblock(ctx, "c", "a")))
+}
+
+func testRelationshipTenantIsolation(t *testing.T, users repository.User, repo repository.Relationship) {
+	ctx, other := TenantContext(t), TenantContext(t)
+	createUsers(t, ctx, users, "a", "b")
+	_, err := repo.Follow(ctx, "a", "b")
+	require.NoError(t, err)
+
+	_, err = repo.ListFollowers(other, &repository.ListRelationshipsParams{UserID: "b"})
+	require.True(t, errcode.IsNotfound(err), err)
+	_, err = repo.Follow(other, "a", "b")
+	require.True(t, errcode.IsNotfound(err), err)
+	require.True(t, errcode.IsNotfound(repo.Unfollow(other, "a", "b")))
+
+	// the same IDs are free in the other tenant
+	createUsers(t, other, users, "a", "b")
+	_, err = repo.Block(other, "b", "a")
+	require.NoError(t, err)
+	followers, err := repo.ListFollowers(ctx, &repository.ListRelationshipsParams{UserID: "b"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a"}, relatedIDs(followers, true))
+}
@@ -0,0 +1,279 @@
+package repositorytest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSession runs the suite against the repository.Session returned by
+// newSession with the repository.User of its users, which is called once per
+// subtest.
+func TestSession(t *testing.T, newSession func(t *testing.T) (repository.User, repository.Session)) {
+	tests := []struct {
+		name string
+		f    func(t *testing.T, users repository.User, repo repository.Session)
+	}{
+		{name: "CreateGet", f: testSessionCreateGet},
+		{name: "List", f: testSessionList},
+		{name: "Rotate", f: testSessionRotate},
+		{name: "RotateReuse", f: testSessionRotateReuse},
+		{name: "RotateInactive", f: testSessionRotateInactive},
+		{name: "Revoke", f: testSessionRevoke},
+		{name: "DeleteExpired", f: testSessionDeleteExpired},
+		{name: "DeleteUser", f: testSessionDeleteUser},
+		{name: "TenantIsolation", f: testSessionTenantIsolation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, repo := newSession(t)
+			tt.f(t, users, repo)
+		})
+	}
+}
+
+// newSession returns a session of the user created at now and expiring an
+// hour later.
+func newSession(id, userID string, now time.Time) *entity.Session {
+	now = now.UTC().Truncate(time.Microsecond)
+	return &entity.Session{ID: id, UserID: userID, CreatedAt: now, RefreshedAt: now, ExpiresAt: now.Add(time.Hour)}
+}
+
+func testSessionCreateGet(t *testing.T, users repository.User, repo repository.Session) {
+	ctx := TenantContext(t)
+	_, err := users.Create(ctx, &entity.User{ID: "user", Name: "name"})
+	require.NoError(t, err)
+
+	v := newSession("id", "user", time.Now())
+	created, err := repo.Create(ctx, v, "hash")
+	require.NoError(t, err)
+	require.Equal(t, v, created)
+	require.Equal(t, time.UTC, created.CreatedAt.Location())
+	require.True(t, created.RevokedAt.IsZero())
+
+	got, err := repo.Get(ctx, "id")
+	require.NoError(t, err)
+	require.Equal(t, created, got)
+
+	_, err = repo.Create(ctx, newSession("id", "user", time.Now()), "other")
+	require.True(t, errcode.IsAlreadyExists(err), err)
+	_, err = repo.Create(ctx, newSession("missing-user", "missing", time.Now()), "missing")
+	require.True(t, errcode.IsNotfound(err), err)
+	_, err = repo.Get(ctx, "missing")
+	require.True(t, errcode.IsNotfound(err), err)
+}
+
+func testSessionList(t *testing.T, users repository.User, repo repository.Session) {
+	ctx := TenantContext(t)
+	for _, id := range []string{"user", "other"} {
+		_, err := users.Create(ctx, &entity.User{ID: id, Name: id})
+		require.NoError(t, err)
+	}
+	now := time.Now()
+	for i, id := range []string{"a", "b", "c"} {
+		_, err := repo.Create(ctx, newSession(id, "user", now.Add(time.Duration(i)*time.Second)), id)
+		require.NoError(t, err)
+	}
+	_, err := repo.Create(ctx, newSession("d", "other", now), "d")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		params *repository.ListSessionsParams
+		want   []string
+	}{
+		{name: "newest first", params: &repository.ListSessionsParams{UserID: "user"}, want: []string{"c", "b", "a"}},
+		{name: "limit", params: &repository.ListSessionsParams{UserID: "user", Limit: 2}, want: []string{"c", "b"}},
+		{name: "other user", params: &repository.ListSessionsParams{UserID: "other"}, want: []string{"d"}},
+		{name: "no sessions", params: &repository.ListSessionsParams{UserID: "missing"}, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.List(ctx, tt.params)
+			require.NoError(t, err)
+			ids := []string{}
+			for _, session := range got {
+				ids = append(ids, session.ID)
+			}
+			require.Equal(t, tt.want, ids)
+		})
+	}
+}
+
+func testSessionRotate(t *testing.T, users repository.User, repo repository.Session) {
+	ctx := TenantContext(t)
+	_, err := users.Create(ctx, &entity.User{ID: "user", Name: "name"})
+	require.NoError(t, err)
+	created, err := repo.Create(ctx, newSession("id", "user", time.Now()), "first")
+	require.NoError(t, err)
+
+	now := created.CreatedAt.Add(time.Minute)
+	got, err := repo.Rotate(ctx, "first", "second", now)
+	require.NoError(t, err)
+	require.Equal(t, now, got.RefreshedAt)
+	require.True(t, got.Active(now))
+	require.Equal(t, created.ExpiresAt, got.ExpiresAt)
+
+	// the new token rotates in turn
+	now = now.Add(time.Minute)
+	got, err = repo.Rotate(ctx, "second", "third", now)
+	require.NoError(t, err)
+	require.Equal(t, now, got.RefreshedAt)
+
+	stored, err := repo.Get(ctx, "id")
+	require.NoError(t, err)
+	require.Equal(t, got, stored)
+
+	_, err = repo.Rotate(ctx, "unknown", "fourth", now)
+	require.True(t, errcode.IsNotfound(err), err)
+}
+
+func testSessionRotateReuse(t *testing.T, users repository.User, repo repository.Session) {
+	ctx := TenantContext(t)
+	_, err := users.Create(ctx, &entity.User{ID: "user", Name: "name"})
+	require.NoError(t, err)
+	created, err := repo.Create(ctx, newSession("id", "user", time.Now()), "first")
+	require.NoError(t, err)
+	now := created.CreatedAt.Add(time.Minute)
+	_, err = repo.Rotate(ctx, "first", "second", now)
+	require.NoError(t, err)
+
+	// replaying the replaced token revokes the session
+	got, err := repo.Rotate(ctx, "first", "stolen", now.Add(time.Minute))
+	require.NoError(t, err)
+	require.True(t, got.ReuseDetected)
+	require.Equal(t, now.Add(time.Minute), got.RevokedAt)
+	require.False(t, got.Active(now.Add(time.Minute)))
+
+	// and with it every token of the family
+	got, err = repo.Rotate(ctx, "second", "third", now.Add(2*time.Minute))
+	require.NoError(t, err)
+	require.False(t, got.Active(now.Add(2*time.Minute)))
+	require.Equal(t, now.Add(time.Minute), got.RevokedAt)
+	_, err = repo.Rotate(ctx, "stolen", "fourth", now.Add(2*time.Minute))
+	require.True(t, errcode.IsNotfound(err), err)
+}
+
+func testSessionRotateInactive(t *testing.T, users repository.User, repo repository.Session) {
+	ctx := TenantContext(t)
+	_, err := users.Create(ctx, &entity.User{ID: "user", Name: "name"})
+	require.NoError(t, err)
+	created, err := repo.Create(ctx, newSession("id", "user", time.Now()), "first")
+	require.NoError(t, err)
+
+	// an expired session is returned as is and its token stays unused
+	expired := created.ExpiresAt.Add(time.Second)
+	got, err := repo.Rotate(ctx, "first", "second", expired)
+	require.NoError(t, err)
+	require.Equal(t, created, got)
+	_, err = repo.Rotate(ctx, "second", "third", expired)
+	require.True(t, errcode.IsNotfound(err), err)
+
+	// so is a revoked one, without reuse being detected
+	now := created.CreatedAt.Add(time.Minute)
+	_, err = repo.Revoke(ctx, "id", now)
+	require.NoError(t, err)
+	got, err = repo.Rotate(ctx, "first", "second", now)
+	require.NoError(t, err)
+	require.False(t, got.ReuseDetected)
+	require.Equal(t, created.RefreshedAt, got.RefreshedAt)
+}
+
+func testSessionRevoke(t *testing.T, users repository.User, repo repository.Session) {
+	ctx := TenantContext(t)
+	_, err := users.Create(ctx, &entity.User{ID: "user", Name: "name"})
+	require.NoError(t, err)
+	created, err := repo.Create(ctx, newSession("id", "user", time.Now()), "first")
+	require.NoError(t, err)
+
+	now := created.CreatedAt.Add(time.Minute)
+	got, err := repo.Revoke(ctx, "id", now)
+	require.NoError(t, err)
+	require.Equal(t, now, got.RevokedAt)
+	require.False(t, got.ReuseDetected)
+	require.False(t, got.Active(now))
+
+	// revoking again keeps the first revocation
+	got, err = repo.Revoke(ctx, "id", now.Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, now, got.RevokedAt)
+
+	_, err = repo.Revoke(ctx, "missing", now)
+	require.True(t, errcode.IsNotfound(err), err)
+}
+
+func testSessionDeleteExpired(t *testing.T, users repository.User, repo repository.Session) {
+	ctx, other := TenantContext(t), TenantContext(t)
+	now := time.Now()
+	for _, ctx := range []context.Context{ctx, other} {
+		_, err := users.Create(ctx, &entity.User{ID: "user", Name: "name"})
+		require.NoError(t, err)
+		for i, id := range []string{"a", "b", "c"} {
+			_, err := repo.Create(ctx, newSession(id, "user", now.Add(time.Duration(i)*time.Hour)), id)
+			require.NoError(t, err)
+		}
+	}
+
+	// a and b of both tenants have expired. The database may hold the expired
+	// sessions of other tests as well, so the sweep runs until it is done.
+	before := now.Add(2*time.Hour + time.Minute)
+	n, err := repo.DeleteExpired(ctx, before, 3)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, n)
+	total := n
+	for n > 0 {
+		n, err = repo.DeleteExpired(ctx, before, 3)
+		require.NoError(t, err)
+		total += n
+	}
+	require.GreaterOrEqual(t, total, int64(4))
+
+	for _, ctx := range []context.Context{ctx, other} {
+		got, err := repo.List(ctx, &repository.ListSessionsParams{UserID: "user"})
+		require.NoError(t, err)
+		require.Len(t, got, 1)
+		require.Equal(t, "c", got[0].ID)
+		// the tokens are deleted with their sessions
+		_, err = repo.Rotate(ctx, "a", "new", now)
+		require.True(t, errcode.IsNotfound(err), err)
+	}
+}
+
+func testSessionDeleteUser(t *testing.T, users repository.User, repo repository.Session) {
+	ctx := TenantContext(t)
+	_, err := users.Create(ctx, &entity.User{ID: "user", Name: "name"})
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, newSession("id", "user", time.Now()), "first")
+	require.NoError(t, err)
+
+	require.NoError(t, users.Delete(ctx, "user"))
+	_, err = repo.Get(ctx, "id")
+	require.True(t, errcode.IsNotfound(err), err)
+	_, err = repo.Rotate(ctx, "first", "second", time.Now())
+	require.True(t, errcode.IsNotfound(err), err)
+}
+
+func testSessionTenantIsolation(t *testing.T, users repository.User, repo repository.Session) {
+	ctx, other := TenantContext(t), TenantContext(t)
+	_, err := users.Create(ctx, &entity.User{ID: "user", Name: "name"})
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, newSession("id", "user", time.Now()), "first")
+	require.NoError(t, err)
+
+	_, err = repo.Get(other, "id")
+	require.True(t, errcode.IsNotfound(err), err)
+	_, err = repo.Rotate(other, "first", "second", time.Now())
+	require.True(t, errcode.IsNotfound(err), err)
+	_, err = repo.Revoke(other, "id", time.Now())
+	require.True(t, errcode.IsNotfound(err), err)
+	got, err := repo.List(other, &repository.ListSessionsParams{UserID: "user"})
+	require.NoError(t, err)
+	require.Empty(t, got)
+	_, err = repo.Create(other, newSession("id", "user", time.Now()), "other")
+	require.True(t, errcode.IsNotfound(err), err)
+}
@@ -0,0 +1,654 @@
+// Package repositorytest provides the behavior every repository backend must
+// share, as a suite to run from the tests of each backend.
+package repositorytest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/filter"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/requestid"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/textsearch"
+	"github.com/stretchr/testify/require"
+)
+
+// PostgresDSNEnv names the environment variable holding the DSN of a
+// migrated Postgres database to run the suite against.
+const PostgresDSNEnv = "TEST_POSTGRES_DSN"
+
+// PostgresDSN returns the DSN in PostgresDSNEnv, skipping the test when it is
+// not set.
+func PostgresDSN(t *testing.T) string {
+	t.Helper()
+	dsn := os.Getenv(PostgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", PostgresDSNEnv)
+	}
+	return dsn
+}
+
+// TenantContext returns a context scoped to a new tenant, so that tests sharing
+// a database do not observe each other's rows.
+func TenantContext(t *testing.T) context.Context {
+	t.Helper()
+	return tenant.NewContext(context.Background(), "test-"+requestid.New())
+}
+
+// TestUser runs the suite against the repository.User returned by newUser,
+// which is called once per subtest.
+func TestUser(t *testing.T, newUser func(t *testing.T) repository.User) {
+	tests := []struct {
+		name string
+		f    func(t *testing.T, repo repository.User)
+	}{
+		{name: "CreateGet", f: testCreateGet},
+		{name: "Duplicate", f: testDuplicate},
+		{name: "NotFound", f: testNotFound},
+		{name: "GetByEmail", f: testGetByEmail},
+		{name: "List", f: testList},
+		{name: "Update", f: testUpdate},
+		{name: "Delete", f: testDelete},
+		{name: "TenantIsolation", f: testTenantIsolation},
+		{name: "ConcurrentCreate", f: testConcurrentCreate},
+		{name: "ConcurrentUpdate", f: testConcurrentUpdate},
+		{name: "ContextCancellation", f: testContextCancellation},
+		{name: "Search", f: testSearch},
+		{name: "Filter", f: testFilter},
+		{name: "Order", f: testOrder},
+		{name: "Stats", f: testStats},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.f(t, newUser(t))
+		})
+	}
+}
+
+func testCreateGet(t *testing.T, repo repository.User) {
+	ctx := TenantContext(t)
+	want := &entity.User{
+		ID:       "id",
+		Name:     "name",
+		Gender:   entity.GenderFemale,
+		Email:    "Name@Example.com",
+		Locale:   "ja-JP",
+		Timezone: "Asia/Tokyo",
+		// 1.0 stays a float
+		Attributes: entity.Attributes{"team": "core", "level": int64(3), "ratio": 1.0, "beta": true},
+	}
+
+	created, err := repo.Create(ctx, want)
+	require.NoError(t, err)
+	requireUser(t, want, created)
+	require.Equal(t, created.CreatedAt, created.UpdatedAt)
+
+	got, err := repo.Get(ctx, "id")
+	require.NoError(t, err)
+	require.Equal(t, created, got)
+
+	// the returned user is not shared with the repository
+	got.Name = "changed"
+	got.Attributes["team"] = "changed"
+	got, err = repo.Get(ctx, "id")
+	require.NoError(t, err)
+	require.Equal(t, created, got)
+}
+
+// requireUser asserts that got is want with the times set by the repository.
+func requireUser(t *testing.T, want, got *entity.User) {
+	t.Helper()
+	for _, v := range []time.Time{got.CreatedAt, got.UpdatedAt} {
+		require.False(t, v.IsZero())
+		require.Equal(t, time.UTC, v.Location())
+	}
+	withTimes := *want
+	withTimes.CreatedAt, withTimes.UpdatedAt = got.CreatedAt, got.UpdatedAt
+	require.Equal(t, &withTimes, got)
+}
+
+func testDuplicate(t *testing.T, repo repository.User) {
+	ctx := TenantContext(t)
+	_, err := repo.Create(ctx, &entity.User{ID: "id", Name: "name"})
+	require.NoError(t, err)
+
+	_, err = repo.Create(ctx, &entity.User{ID: "id", Name: "other"})
+	require.True(t, errcode.IsAlreadyExists(err), err)
+	_, err = repo.Create(ctx, &entity.User{ID: "id2", Name: "name"})
+	require.True(t, errcode.IsAlreadyExists(err), err)
+
+	// renaming to a taken name
+	_, err = repo.Create(ctx, &entity.User{ID: "id2", Name: "name2"})
+	require.NoError(t, err)
+	_, err = repo.Update(ctx, "id2", func(u *entity.User) bool { u.Name = "name"; return true })
+	require.True(t, errcode.IsAlreadyExists(err), err)
+
+	// emails are unique regardless of case, and any number of users have none
+	_, err = repo.Create(ctx, &entity.User{ID: "id3", Name: "name3", Email: "Taken@example.com"})
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, &entity.User{ID: "id4", Name: "name4", Email: "taken@EXAMPLE.com"})
+	require.True(t, errcode.IsAlreadyExists(err), err)
+	_, err = repo.Update(ctx, "id2", func(u *entity.User) bool { u.Email = "TAKEN@example.com"; return true })
+	require.True(t, errcode.IsAlreadyExists(err), err)
+	_, err = repo.Update(ctx, "id3", func(u *entity.User) bool { u.Email = "taken@example.com"; return true })
+	require.NoError(t, err)
+}
+
+func testNotFound(t *testing.T, repo repository.User) {
+	ctx := TenantContext(t)
+
+	_, err := repo.Get(ctx, "missing")
+	require.True(t, errcode.IsNotfound(err), err)
+	_, err = repo.Update(ctx, "missing", func(u *entity.User) bool { return true })
+	require.True(t, errcode.IsNotfound(err), err)
+	err = repo.Delete(ctx, "missing")
+	require.True(t, errcode.IsNotfound(err), err)
+}
+
+func testGetByEmail(t *testing.T, repo repository.User) {
+	ctx, other := TenantContext(t), TenantContext(t)
+	created, err := repo.Create(ctx, &entity.User{ID: "id", Name: "name", Email: "Name@Example.com"})
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, &entity.User{ID: "no-email", Name: "no-email"})
+	require.NoError(t, err)
+
+	got, err := repo.GetByEmail(ctx, "name@EXAMPLE.com")
+	require.NoError(t, err)
+	require.Equal(t, created, got)
+
+	for _, email := range []string{"", "other@example.com"} {
+		_, err = repo.GetByEmail(ctx, email)
+		require.True(t, errcode.IsNotfound(err), err)
+	}
+	_, err = repo.GetByEmail(other, "name@example.com")
+	require.True(t, errcode.IsNotfound(err), err)
+}
+
+func testList(t *testing.T, repo repository.User) {
+	ctx := TenantContext(t)
+
+	users, err := repo.List(ctx, &repository.ListUsersParams{})
+	require.NoError(t, err)
+	require.Empty(t, users)
+
+	for _, id := range []string{"c", "a", "b"} {
+		_, err := repo.Create(ctx, &entity.User{ID: id, Name: "name-" + id})
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		name   string
+		params *repository.ListUsersParams
+		want   []string
+	}{
+		{name: "all ordered by id", params: &repository.ListUsersParams{}, want: []string{"a", "b", "c"}},
+		{name: "by name", params: &repository.ListUsersParams{Name: "name-b"}, want: []string{"b"}},
+		{name: "unknown name", params: &repository.ListUsersParams{Name: "unknown"}, want: []string{}},
+		{name: "limit", params: &repository.ListUsersParams{Limit: 2}, want: []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, err := repo.List(ctx, tt.params)
+			require.NoError(t, err)
+			ids := []string{}
+			for _, u := range users {
+				ids = append(ids, u.ID)
+			}
+			require.Equal(t, tt.want, ids)
+		})
+	}
+}
+
+func testUpdate(t *testing.T, repo repository.User) {
+	ctx := TenantContext(t)
+	created, err := repo.Create(ctx, &entity.User{ID: "id", Name: "name"})
+	require.NoError(t, err)
+
+	var current entity.User
+	got, err := repo.Update(ctx, "id", func(u *entity.User) bool {
+		current = *u
+		u.Name = "changed"
+		u.Gender = entity.GenderMale
+		u.Email = "changed@example.com"
+		u.Locale = "en"
+		u.Timezone = "UTC"
+		u.Attributes = entity.Attributes{"team": "core"}
+		u.State = entity.UserStateActive
+		return true
+	})
+	require.NoError(t, err)
+	require.Equal(t, created, &current)
+	requireUser(t, &entity.User{
+		ID:         "id",
+		Name:       "changed",
+		Gender:     entity.GenderMale,
+		Email:      "changed@example.com",
+		Locale:     "en",
+		Timezone:   "UTC",
+		Attributes: entity.Attributes{"team": "core"},
+		State:      entity.UserStateActive,
+	}, got)
+	require.False(t, got.UpdatedAt.Before(created.UpdatedAt))
+	require.Equal(t, created.CreatedAt, got.CreatedAt)
+	updated := got
+
+	// changes are discarded when update returns false
+	got, err = repo.Update(ctx, "id", func(u *entity.User) bool {
+		u.Name = "discarded"
+		return false
+	})
+	require.NoError(t, err)
+	require.Equal(t, "id", got.ID)
+
+	got, err = repo.Get(ctx, "id")
+	require.NoError(t, err)
+	require.Equal(t, updated, got)
+}
+
+func testDelete(t *testing.T, repo repository.User) {
+	ctx := TenantContext(t)
+	_, err := repo.Create(ctx, &entity.User{ID: "id", Name: "name"})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Delete(ctx, "id"))
+	_, err = repo.Get(ctx, "id")
+	require.True(t, errcode.IsNotfound(err), err)
+	require.True(t, errcode.IsNotfound(repo.Delete(ctx, "id")))
+
+	// the id and the name can be reused
+	_, err = repo.Create(ctx, &entity.User{ID: "id", Name: "name"})
+	require.NoError(t, err)
+}
+
+func testTenantIsolation(t *testing.T, repo repository.User) {
+	ctx, other := TenantContext(t), TenantContext(t)
+	_, err := repo.Create(ctx, &entity.User{ID: "id", Name: "name"})
+	require.NoError(t, err)
+
+	// ids and names are unique per tenant
+	_, err = repo.Create(other, &entity.User{ID: "id", Name: "name"})
+	require.NoError(t, err)
+	require.NoError(t, repo.Delete(other, "id"))
+
+	_, err = repo.Get(other, "id")
+	require.True(t, errcode.IsNotfound(err), err)
+	users, err := repo.List(other, &repository.ListUsersParams{})
+	require.NoError(t, err)
+	require.Empty(t, users)
+	_, err = repo.Update(other, "id", func(u *entity.User) bool { u.Name = "changed"; return true })
+	require.True(t, errcode.IsNotfound(err), err)
+
+	got, err := repo.Get(ctx, "id")
+	require.NoError(t, err)
+	requireUser(t, &entity.User{ID: "id", Name: "name"}, got)
+
+	_, err = repo.Get(context.Background(), "id")
+	require.True(t, errcode.IsInvalidArgument(err), err)
+}
+
+func testConcurrentCreate(t *testing.T, repo repository.User) {
+	ctx := TenantContext(t)
+	const n = 10
+
+	// distinct users are all created and the same user exactly once
+	var wg sync.WaitGroup
+	distinctErrs, sameErrs := make(chan error, n), make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			_, err := repo.Create(ctx, &entity.User{ID: fmt.Sprintf("id-%d", i), Name: fmt.Sprintf("name-%d", i)})
+			distinctErrs <- err
+		}(i)
+		go func() {
+			defer wg.Done()
+			_, err := repo.Create(ctx, &entity.User{ID: "same", Name: "same"})
+			sameErrs <- err
+		}()
+	}
+	wg.Wait()
+	close(distinctErrs)
+	close(sameErrs)
+
+	for err := range distinctErrs {
+		require.NoError(t, err)
+	}
+	created := 0
+	for err := range sameErrs {
+		if err == nil {
+			created++
+			continue
+		}
+		require.True(t, errcode.IsAlreadyExists(err), err)
+	}
+	require.Equal(t, 1, created)
+
+	users, err := repo.List(ctx, &repository.ListUsersParams{})
+	require.NoError(t, err)
+	require.Len(t, users, n+1)
+}
+
+func testConcurrentUpdate(t *testing.T, repo repository.User) {
+	ctx := TenantContext(t)
+	_, err := repo.Create(ctx, &entity.User{ID: "id", Name: ""})
+	require.NoError(t, err)
+
+	// updates of the same user are serialized, so none of them is lost
+	const n = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.Update(ctx, "id", func(u *entity.User) bool { u.Name += "x"; return true })
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	got, err := repo.Get(ctx, "id")
+	require.NoError(t, err)
+	require.Len(t, got.Name, n)
+}
+
+func testContextCancellation(t *testing.T, repo repository.User) {
+	ctx := TenantContext(t)
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	_, err := repo.Create(canceled, &entity.User{ID: "id", Name: "name"})
+	require.True(t, errcode.IsCancelled(err), err)
+	_, err = repo.Get(canceled, "id")
+	require.True(t, errcode.IsCancelled(err), err)
+	_, err = repo.List(canceled, &repository.ListUsersParams{})
+	require.True(t, errcode.IsCancelled(err), err)
+
+	// nothing was written
+	_, err = repo.Get(ctx, "id")
+	require.True(t, errcode.IsNotfound(err), err)
+}
+
+func testSearch(t *testing.T, repo repository.User) {
+	ctx := TenantContext(t)
+	for _, u := range []*entity.User{
+		{ID: "1", Name: "ヤマダ タロウ"},
+		{ID: "2", Name: "やまだ はなこ"},
+		{ID: "3", Name: "Alice"},
+		{ID: "4", Name: "Alicia"},
+		{ID: "5", Name: "Bob"},
+		{ID: "6", Name: "100%"},
+	} {
+		_, err := repo.Create(ctx, u)
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		name   string
+		params *repository.SearchUsersParams
+		want   []string
+	}{
+		{
+			name:   "substring across kana",
+			params: &repository.SearchUsersParams{Query: textsearch.Normalize("ﾔﾏﾀﾞ"), Mode: textsearch.ModeSubstring},
+			want:   []string{"1", "2"},
+		},
+		{
+			name:   "substring in the middle",
+			params: &repository.SearchUsersParams{Query: "lic", Mode: textsearch.ModeSubstring},
+			want:   []string{"3", "4"},
+		},
+		{
+			name:   "wildcards are literal",
+			params: &repository.SearchUsersParams{Query: "0%", Mode: textsearch.ModeSubstring},
+			want:   []string{"6"},
+		},
+		{
+			name:   "prefix",
+			params: &repository.SearchUsersParams{Query: "ali", Mode: textsearch.ModePrefix},
+			want:   []string{"3", "4"},
+		},
+		{
+			name:   "prefix does not match in the middle",
+			params: &repository.SearchUsersParams{Query: "lic", Mode: textsearch.ModePrefix},
+			want:   []string{},
+		},
+		{
+			name:   "fuzzy ranked by similarity",
+			params: &repository.SearchUsersParams{Query: "alicia", Mode: textsearch.ModeFuzzy},
+			want:   []string{"4", "3"},
+		},
+		{
+			name:   "paged",
+			params: &repository.SearchUsersParams{Query: "ali", Mode: textsearch.ModePrefix, Offset: 1, Limit: 1},
+			want:   []string{"4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, err := repo.Search(ctx, tt.params)
+			require.NoError(t, err)
+			ids := []string{}
+			for _, u := range users {
+				ids = append(ids, u.User.ID)
+				require.True(t, u.Score >= 0 && u.Score <= 1)
+			}
+			require.Equal(t, tt.want, ids)
+		})
+	}
+
+	users, err := repo.Search(TenantContext(t), &repository.SearchUsersParams{Query: "ali"})
+	require.NoError(t, err)
+	require.Empty(t, users)
+}
+
+func testFilter(t *testing.T, repo repository.User) {
+	ctx := TenantContext(t)
+	for _, u := range []*entity.User{
+		{ID: "1", Name: "alice", Gender: entity.GenderFemale, Attributes: entity.Attributes{"team": "core", "level": int64(3), "beta": true}},
+		{ID: "2", Name: "bob", Gender: entity.GenderMale, Attributes: entity.Attributes{"team": "web", "level": 1.5}, State: entity.UserStateActive},
+		{ID: "3", Name: "carol", Gender: entity.GenderFemale, Attributes: entity.Attributes{"team": "core", "level": "3", "beta": false}},
+		{ID: "4", Name: "dave", State: entity.UserStateSuspended},
+	} {
+		_, err := repo.Create(ctx, u)
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		filter string
+		limit  int
+		want   []string
+	}{
+		{filter: `attributes.team = "core"`, want: []string{"1", "3"}},
+		{filter: `attributes.team != "core"`, want: []string{"2"}},
+		{filter: `NOT attributes.team = "core"`, want: []string{"2", "4"}},
+		{filter: `attributes.level = 3`, want: []string{"1"}},
+		{filter: `attributes.level = "3"`, want: []string{"3"}},
+		{filter: `attributes.level >= 1.5`, want: []string{"1", "2"}},
+		{filter: `attributes.level < 3.0`, want: []string{"2"}},
+		{filter: `attributes.beta = true`, want: []string{"1"}},
+		{filter: `attributes.beta != true`, want: []string{"3"}},
+		{filter: `attributes:beta`, want: []string{"1", "3"}},
+		{filter: `NOT attributes:team OR attributes.team > "d"`, want: []string{"2", "4"}},
+		{filter: `gender = FEMALE`, want: []string{"1", "3"}},
+		{filter: `gender != FEMALE`, want: []string{"2", "4"}},
+		{filter: `gender = FEMALE AND name = "carol"`, want: []string{"3"}},
+		{filter: `name = "alice" OR name = "dave"`, want: []string{"1", "4"}},
+		{filter: `NOT gender = MALE name >= "c"`, want: []string{"3", "4"}},
+		{filter: `(gender = MALE OR gender = GENDER_OTHER) AND id > "2"`, want: []string{"4"}},
+		{filter: `updated_at > "2000-01-01" AND gender = FEMALE`, want: []string{"1", "3"}},
+		{filter: `updated_at < "2000-01-01T00:00:00Z"`, want: []string{}},
+		{filter: `gender = FEMALE`, limit: 1, want: []string{"1"}},
+		{filter: `state = ACTIVE`, want: []string{"2"}},
+		{filter: `state != PENDING`, want: []string{"2", "4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			e, err := filter.Parse(tt.filter, repository.UserFilterSchema)
+			require.NoError(t, err)
+			users, err := repo.List(ctx, &repository.ListUsersParams{Filter: e, Limit: tt.limit})
+			require.NoError(t, err)
+			ids := []string{}
+			for _, u := range users {
+				ids = append(ids, u.ID)
+			}
+			require.Equal(t, tt.want, ids)
+		})
+	}
+}
+
+func testOrder(t *testing.T, repo repository.User) {
+	ctx := TenantContext(t)
+	for _, u := range []*entity.User{
+		{ID: "1", Name: "carol", Gender: entity.GenderFemale},
+		{ID: "2", Name: "alice", Gender: entity.GenderFemale},
+		{ID: "3", Name: "bob", Gender: entity.GenderMale},
+		{ID: "4", Name: "dave", Gender: entity.GenderMale},
+		{ID: "5", Name: "erin"},
+	} {
+		_, err := repo.Create(ctx, u)
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		orderBy string
+		want    []string
+	}{
+		{orderBy: "", want: []string{"1", "2", "3", "4", "5"}},
+		{orderBy: "name", want: []string{"2", "3", "1", "4", "5"}},
+		{orderBy: "name desc", want: []string{"5", "4", "1", "3", "2"}},
+		{orderBy: "gender desc, name", want: []string{"2", "1", "3", "4", "5"}},
+		{orderBy: "gender, id desc", want: []string{"5", "4", "3", "2", "1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.orderBy, func(t *testing.T) {
+			order, err := filter.ParseOrder(tt.orderBy, repository.UserFilterSchema, repository.UserOrderTiebreak)
+			require.NoError(t, err)
+			users, err := repo.List(ctx, &repository.ListUsersParams{OrderBy: order})
+			require.NoError(t, err)
+			require.Equal(t, tt.want, userIDs(users))
+
+			// pages resume after the sort key of the last user
+			ids := []string{}
+			var after filter.Expr
+			for {
+				users, err := repo.List(ctx, &repository.ListUsersParams{Filter: after, OrderBy: order, Limit: 2})
+				require.NoError(t, err)
+				if len(users) == 0 {
+					break
+				}
+				ids = append(ids, userIDs(users)...)
+				last := users[len(users)-1]
+				cursor := order.Cursor(repository.UserFilterSchema, func(field string) interface{} { return repository.UserFilterValue(last, field) })
+				after, err = order.After(repository.UserFilterSchema, cursor)
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.want, ids)
+		})
+	}
+}
+
+func testStats(t *testing.T, repo repository.User) {
+	ctx := TenantContext(t)
+	_, err := repo.Create(TenantContext(t), &entity.User{ID: "other-tenant", Name: "other-tenant"})
+	require.NoError(t, err)
+
+	stats, err := repo.Stats(ctx, &repository.UserStatsParams{From: time.Now().Add(-time.Hour), To: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	require.Equal(t, &repository.UserStats{ByGender: map[entity.Gender]int64{}, Signups: []*repository.SignupCount{}}, stats)
+
+	var created entity.Users
+	for i, gender := range []entity.Gender{entity.GenderFemale, entity.GenderMale, entity.GenderFemale, entity.GenderOther, entity.GenderFemale} {
+		user, err := repo.Create(ctx, &entity.User{ID: fmt.Sprintf("id-%d", i), Name: fmt.Sprintf("name-%d", i), Gender: gender})
+		require.NoError(t, err)
+		created = append(created, user)
+	}
+	from, to := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		filter   string
+		bucket   repository.Bucket
+		from, to time.Time
+		total    int64
+		byGender map[entity.Gender]int64
+		signups  int64
+	}{
+		{
+			name:     "all by day",
+			bucket:   repository.BucketDay,
+			from:     from,
+			to:       to,
+			total:    5,
+			byGender: map[entity.Gender]int64{entity.GenderOther: 1, entity.GenderMale: 1, entity.GenderFemale: 3},
+			signups:  5,
+		},
+		{
+			name:     "filtered by week",
+			filter:   `gender = FEMALE`,
+			bucket:   repository.BucketWeek,
+			from:     from,
+			to:       to,
+			total:    3,
+			byGender: map[entity.Gender]int64{entity.GenderFemale: 3},
+			signups:  3,
+		},
+		{
+			name:     "by month",
+			filter:   `gender != FEMALE`,
+			bucket:   repository.BucketMonth,
+			from:     from,
+			to:       to,
+			total:    2,
+			byGender: map[entity.Gender]int64{entity.GenderOther: 1, entity.GenderMale: 1},
+			signups:  2,
+		},
+		{
+			name:     "sign-ups out of range",
+			bucket:   repository.BucketDay,
+			from:     from.AddDate(-1, 0, 0),
+			to:       from.AddDate(0, 0, -1),
+			total:    5,
+			byGender: map[entity.Gender]int64{entity.GenderOther: 1, entity.GenderMale: 1, entity.GenderFemale: 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := filter.Parse(tt.filter, repository.UserFilterSchema)
+			require.NoError(t, err)
+			params := &repository.UserStatsParams{Filter: e, Bucket: tt.bucket, From: tt.from, To: tt.to}
+			stats, err := repo.Stats(ctx, params)
+			require.NoError(t, err)
+			require.Equal(t, tt.total, stats.Total)
+			require.Equal(t, tt.byGender, stats.ByGender)
+
+			// the buckets are those of the creation times returned by Create
+			want := repository.CountUsers(created, params).Signups
+			require.Equal(t, want, stats.Signups)
+			var n int64
+			for _, c := range stats.Signups {
+				n += c.Count
+			}
+			require.Equal(t, tt.signups, n)
+		})
+	}
+}
+
+func userIDs(users entity.Users) []string {
+	ids := []string{}
+	for _, u := range users {
+		ids = append(ids, u.ID)
+	}
+	return ids
+}
@@ -0,0 +1,115 @@
+package repositorytest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+)
+
+// TestVerification runs the suite against the repository.Verification
+// returned by newVerification with the repository.User of its users, which is
+// called once per subtest.
+func TestVerification(t *testing.T, newVerification func(t *testing.T) (repository.User, repository.Verification)) {
+	tests := []struct {
+		name string
+		f    func(t *testing.T, users repository.User, repo repository.Verification)
+	}{
+		{name: "CreateConsume", f: testVerificationCreateConsume},
+		{name: "Replace", f: testVerificationReplace},
+		{name: "NotFound", f: testVerificationNotFound},
+		{name: "DeleteUser", f: testVerificationDeleteUser},
+		{name: "TenantIsolation", f: testVerificationTenantIsolation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, repo := newVerification(t)
+			tt.f(t, users, repo)
+		})
+	}
+}
+
+// newVerification returns a verification of the user sent at now and
+// expiring an hour later.
+func newVerification(userID, email string, now time.Time) *entity.Verification {
+	now = now.UTC().Truncate(time.Microsecond)
+	return &entity.Verification{UserID: userID, Email: email, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+}
+
+func testVerificationCreateConsume(t *testing.T, users repository.User, repo repository.Verification) {
+	ctx := TenantContext(t)
+	_, err := users.Create(ctx, &entity.User{ID: "id", Name: "name", Email: "name@example.com"})
+	require.NoError(t, err)
+
+	v := newVerification("id", "name@example.com", time.Now())
+	created, err := repo.Create(ctx, v, "hash")
+	require.NoError(t, err)
+	require.Equal(t, v, created)
+	require.Equal(t, time.UTC, created.ExpiresAt.Location())
+
+	got, err := repo.Consume(ctx, "hash")
+	require.NoError(t, err)
+	require.Equal(t, created, got)
+
+	// a token is consumed once
+	_, err = repo.Consume(ctx, "hash")
+	require.True(t, errcode.IsNotfound(err), err)
+}
+
+func testVerificationReplace(t *testing.T, users repository.User, repo repository.Verification) {
+	ctx := TenantContext(t)
+	_, err := users.Create(ctx, &entity.User{ID: "id", Name: "name", Email: "name@example.com"})
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, newVerification("id", "name@example.com", time.Now()), "first")
+	require.NoError(t, err)
+
+	// sending again voids the previous token
+	v := newVerification("id", "changed@example.com", time.Now().Add(time.Minute))
+	_, err = repo.Create(ctx, v, "second")
+	require.NoError(t, err)
+	_, err = repo.Consume(ctx, "first")
+	require.True(t, errcode.IsNotfound(err), err)
+	got, err := repo.Consume(ctx, "second")
+	require.NoError(t, err)
+	require.Equal(t, v, got)
+}
+
+func testVerificationNotFound(t *testing.T, users repository.User, repo repository.Verification) {
+	ctx := TenantContext(t)
+	_, err := repo.Create(ctx, newVerification("missing", "missing@example.com", time.Now()), "hash")
+	require.True(t, errcode.IsNotfound(err), err)
+	_, err = repo.Consume(ctx, "unknown")
+	require.True(t, errcode.IsNotfound(err), err)
+}
+
+func testVerificationDeleteUser(t *testing.T, users repository.User, repo repository.Verification) {
+	ctx := TenantContext(t)
+	_, err := users.Create(ctx, &entity.User{ID: "id", Name: "name"})
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, newVerification("id", "name@example.com", time.Now()), "hash")
+	require.NoError(t, err)
+
+	require.NoError(t, users.Delete(ctx, "id"))
+	_, err = repo.Consume(ctx, "hash")
+	require.True(t, errcode.IsNotfound(err), err)
+}
+
+func testVerificationTenantIsolation(t *testing.T, users repository.User, repo repository.Verification) {
+	ctx, other := TenantContext(t), TenantContext(t)
+	_, err := users.Create(ctx, &entity.User{ID: "id", Name: "name"})
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, newVerification("id", "name@example.com", time.Now()), "hash")
+	require.NoError(t, err)
+
+	_, err = repo.Consume(other, "hash")
+	require.True(t, errcode.IsNotfound(err), err)
+	_, err = repo.Create(other, newVerification("id", "name@example.com", time.Now()), "other")
+	require.True(t, errcode.IsNotfound(err), err)
+
+	// the token is still good in its tenant
+	_, err = repo.Consume(ctx, "hash")
+	require.NoError(t, err)
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"sort"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/textsearch"
+)
+
+// SearchUsers searches users in process, for the backends that cannot search
+// natively. It ranks and pages them as Search requires.
+func SearchUsers(users entity.Users, params *SearchUsersParams) []*ScoredUser {
+	matched := []*ScoredUser{}
+	for _, user := range users {
+		score, ok := textsearch.Match(params.Mode, params.Query, textsearch.Normalize(user.Name))
+		if ok {
+			matched = append(matched, &ScoredUser{User: user, Score: score})
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].Score != matched[j].Score {
+			return matched[i].Score > matched[j].Score
+		}
+		return matched[i].User.ID < matched[j].User.ID
+	})
+
+	if params.Offset >= len(matched) {
+		return []*ScoredUser{}
+	}
+	matched = matched[params.Offset:]
+	if params.Limit > 0 && len(matched) > params.Limit {
+		matched = matched[:params.Limit]
+	}
+	return matched
+}
@@ -0,0 +1,117 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+)
+
+var _ repository.Credential = (*Credential)(nil)
+
+// Credential stores credentials in the database of a User.
+type Credential struct {
+	u *User
+}
+
+func NewCredential(u *User) *Credential {
+	return &Credential{u: u}
+}
+
+func (c *Credential) Get(ctx context.Context, userID string) (*entity.Credential, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var credential *entity.Credential
+	err = c.u.withReadTx(ctx, "GetCredential", func(tx *sql.Tx) error {
+		credential, err = getCredential(ctx, tx, tenantID, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+func (c *Credential) Set(ctx context.Context, userID, passwordHash string) (*entity.Credential, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var credential *entity.Credential
+	err = c.u.withTx(ctx, "SetCredential", func(tx *sql.Tx) error {
+		if _, err := getUser(ctx, tx, tenantID, userID); err != nil {
+			return err
+		}
+		credential = &entity.Credential{UserID: userID, PasswordHash: passwordHash, UpdatedAt: now()}
+		_, err := tx.ExecContext(ctx, "INSERT INTO credentials(tenant_id, user_id, password_hash, updated_at) VALUES (?, ?, ?, ?) "+
+			"ON CONFLICT (tenant_id, user_id) DO UPDATE SET password_hash = excluded.password_hash, failed_attempts = 0, locked_until = NULL, updated_at = excluded.updated_at",
+			tenantID, userID, passwordHash, credential.UpdatedAt.UnixMicro())
+		return newError(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+func (c *Credential) Update(ctx context.Context, userID string, update func(*entity.Credential) bool) (*entity.Credential, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var credential *entity.Credential
+	// the transaction holds the write lock, as in User.Update
+	err = c.u.withTx(ctx, "UpdateCredential", func(tx *sql.Tx) error {
+		credential, err = getCredential(ctx, tx, tenantID, userID)
+		if err != nil {
+			return err
+		}
+		if !update(credential) {
+			return nil
+		}
+		credential.UserID = userID
+		credential.LockedUntil = credential.LockedUntil.UTC().Truncate(time.Microsecond)
+		credential.UpdatedAt = now()
+		var lockedUntil *int64
+		if !credential.LockedUntil.IsZero() {
+			micros := credential.LockedUntil.UnixMicro()
+			lockedUntil = &micros
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE credentials SET password_hash = ?, failed_attempts = ?, locked_until = ?, updated_at = ? WHERE tenant_id = ? AND user_id = ?",
+			credential.PasswordHash, credential.FailedAttempts, lockedUntil, credential.UpdatedAt.UnixMicro(), tenantID, userID)
+		return newError(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+func getCredential(ctx context.Context, tx *sql.Tx, tenantID, userID string) (*entity.Credential, error) {
+	credential := &entity.Credential{}
+	var lockedUntil sql.NullInt64
+	var updatedAt int64
+	err := tx.QueryRowContext(ctx, "SELECT user_id, password_hash, failed_attempts, locked_until, updated_at FROM credentials WHERE tenant_id = ? AND user_id = ?", tenantID, userID).
+		Scan(&credential.UserID, &credential.PasswordHash, &credential.FailedAttempts, &lockedUntil, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errcode.NewNotFound("password not set for user: %s", userID)
+	}
+	if err != nil {
+		return nil, newError(err)
+	}
+	if lockedUntil.Valid {
+		credential.LockedUntil = time.UnixMicro(lockedUntil.Int64).UTC()
+	}
+	credential.UpdatedAt = time.UnixMicro(updatedAt).UTC()
+	return credential, nil
+}
@@ -0,0 +1,29 @@
+package sqlite
+
+import (
+	"errors"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// newError classifies SQLite errors like the postgres backend does before
+// falling back to errcode.New.
+func newError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() {
+		case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+			return errcode.NewAlreadyExists("%w", err)
+		case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+			return errcode.NewFailedPrecondition("%w", err)
+		case sqlite3.SQLITE_BUSY:
+			return errcode.NewAborted("%w", err)
+		}
+	}
+	return errcode.New(err)
+}
@@ -0,0 +1,363 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+)
+
+var _ repository.Group = (*Group)(nil)
+
+// Group stores groups in the database of a User. The foreign keys of
+// group_members keep members to existing users.
+type Group struct {
+	u *User
+}
+
+func NewGroup(u *User) *Group {
+	return &Group{u: u}
+}
+
+// groupColumns are the columns read by scanGroup, and groupMemberColumns
+// those read by scanGroupMember.
+const (
+	groupColumns       = "id, name, description, created_at, updated_at"
+	groupMemberColumns = "group_id, user_id, role, created_at"
+)
+
+func (g *Group) Create(ctx context.Context, v *entity.Group, ownerID string) (*entity.Group, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var group *entity.Group
+	err = g.u.withTx(ctx, "CreateGroup", func(tx *sql.Tx) error {
+		createdAt := now().UnixMicro()
+		_, err := tx.ExecContext(ctx, "INSERT INTO groups(tenant_id, id, name, description, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)",
+			tenantID, v.ID, v.Name, v.Description, createdAt, createdAt)
+		if err != nil {
+			return newError(err)
+		}
+		if ownerID != "" {
+			_, err = tx.ExecContext(ctx, "INSERT INTO group_members(tenant_id, group_id, user_id, role, created_at) VALUES (?, ?, ?, ?, ?)",
+				tenantID, v.ID, ownerID, entity.GroupRoleOwner, createdAt)
+			if err != nil {
+				return newError(err)
+			}
+		}
+		group, err = getGroup(ctx, tx, tenantID, v.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (g *Group) Get(ctx context.Context, id string) (*entity.Group, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var group *entity.Group
+	err = g.u.withReadTx(ctx, "GetGroup", func(tx *sql.Tx) error {
+		group, err = getGroup(ctx, tx, tenantID, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (g *Group) List(ctx context.Context, params *repository.ListGroupsParams) (entity.Groups, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	query, args := pageByID("SELECT "+groupColumns+" FROM groups WHERE tenant_id = ?", []interface{}{tenantID}, "id", params.After, params.Limit)
+	var groups entity.Groups
+	err = g.u.withReadTx(ctx, "ListGroups", func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return newError(err)
+		}
+		defer rows.Close()
+
+		groups = entity.Groups{}
+		for rows.Next() {
+			group, err := scanGroup(rows)
+			if err != nil {
+				return newError(err)
+			}
+			groups = append(groups, group)
+		}
+		return newError(rows.Err())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (g *Group) Update(ctx context.Context, id string, update func(*entity.Group) bool) (*entity.Group, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var group *entity.Group
+	// the transaction holds the write lock, as in User.Update
+	err = g.u.withTx(ctx, "UpdateGroup", func(tx *sql.Tx) error {
+		group, err = getGroup(ctx, tx, tenantID, id)
+		if err != nil {
+			return err
+		}
+		if !update(group) {
+			return nil
+		}
+		group.UpdatedAt = now()
+		_, err = tx.ExecContext(ctx, "UPDATE groups SET name = ?, description = ?, updated_at = ? WHERE tenant_id = ? AND id = ?",
+			group.Name, group.Description, group.UpdatedAt.UnixMicro(), tenantID, id)
+		return newError(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (g *Group) Delete(ctx context.Context, id string) error {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return errcode.New(err)
+	}
+
+	return g.u.withTx(ctx, "DeleteGroup", func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, "DELETE FROM groups WHERE tenant_id = ? AND id = ?", tenantID, id)
+		if err != nil {
+			return newError(err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return newError(err)
+		}
+		if n == 0 {
+			return errcode.NewNotFound("group not found: %s", id)
+		}
+		return nil
+	})
+}
+
+func (g *Group) AddMember(ctx context.Context, m *entity.GroupMember) (*entity.GroupMember, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var member *entity.GroupMember
+	err = g.u.withTx(ctx, "AddMember", func(tx *sql.Tx) error {
+		if _, err := getGroup(ctx, tx, tenantID, m.GroupID); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "INSERT INTO group_members(tenant_id, group_id, user_id, role, created_at) VALUES (?, ?, ?, ?, ?)",
+			tenantID, m.GroupID, m.UserID, m.Role, now().UnixMicro())
+		if err != nil {
+			return newError(err)
+		}
+		member, err = getGroupMember(ctx, tx, tenantID, m.GroupID, m.UserID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (g *Group) GetMember(ctx context.Context, groupID, userID string) (*entity.GroupMember, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var member *entity.GroupMember
+	err = g.u.withReadTx(ctx, "GetMember", func(tx *sql.Tx) error {
+		member, err = getGroupMember(ctx, tx, tenantID, groupID, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (g *Group) RemoveMember(ctx context.Context, groupID, userID string) error {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return errcode.New(err)
+	}
+
+	return g.u.withTx(ctx, "RemoveMember", func(tx *sql.Tx) error {
+		member, err := getGroupMember(ctx, tx, tenantID, groupID, userID)
+		if err != nil {
+			return err
+		}
+		if member.Role == entity.GroupRoleOwner {
+			var owners int
+			err := tx.QueryRowContext(ctx, "SELECT count(*) FROM group_members WHERE tenant_id = ? AND group_id = ? AND role = ?",
+				tenantID, groupID, entity.GroupRoleOwner).Scan(&owners)
+			if err != nil {
+				return newError(err)
+			}
+			if owners == 1 {
+				return errcode.NewFailedPrecondition("%s is the last owner of group %s", userID, groupID)
+			}
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM group_members WHERE tenant_id = ? AND group_id = ? AND user_id = ?", tenantID, groupID, userID)
+		return newError(err)
+	})
+}
+
+func (g *Group) ListMembers(ctx context.Context, params *repository.ListGroupMembersParams) (entity.GroupMembers, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	query, args := pageByID("SELECT "+groupMemberColumns+" FROM group_members WHERE tenant_id = ? AND group_id = ?",
+		[]interface{}{tenantID, params.GroupID}, "user_id", params.After, params.Limit)
+	var members entity.GroupMembers
+	err = g.u.withReadTx(ctx, "ListMembers", func(tx *sql.Tx) error {
+		if _, err := getGroup(ctx, tx, tenantID, params.GroupID); err != nil {
+			return err
+		}
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return newError(err)
+		}
+		defer rows.Close()
+
+		members = entity.GroupMembers{}
+		for rows.Next() {
+			member, err := scanGroupMember(rows)
+			if err != nil {
+				return newError(err)
+			}
+			members = append(members, member)
+		}
+		return newError(rows.Err())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (g *Group) ListUserGroups(ctx context.Context, params *repository.ListUserGroupsParams) ([]*repository.UserGroup, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	query, args := pageByID("SELECT g.id, g.name, g.description, g.created_at, g.updated_at, m.role FROM group_members m "+
+		"JOIN groups g ON g.tenant_id = m.tenant_id AND g.id = m.group_id WHERE m.tenant_id = ? AND m.user_id = ?",
+		[]interface{}{tenantID, params.UserID}, "m.group_id", params.After, params.Limit)
+	var groups []*repository.UserGroup
+	err = g.u.withReadTx(ctx, "ListUserGroups", func(tx *sql.Tx) error {
+		if _, err := getUser(ctx, tx, tenantID, params.UserID); err != nil {
+			return err
+		}
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return newError(err)
+		}
+		defer rows.Close()
+
+		groups = []*repository.UserGroup{}
+		for rows.Next() {
+			ug := &repository.UserGroup{}
+			if ug.Group, err = scanGroup(rows, &ug.Role); err != nil {
+				return newError(err)
+			}
+			groups = append(groups, ug)
+		}
+		return newError(rows.Err())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// pageByID orders query by the ID column and keeps the IDs after after.
+func pageByID(query string, args []interface{}, column, after string, limit int) (string, []interface{}) {
+	if after != "" {
+		query += " AND " + column + " > ?"
+		args = append(args, after)
+	}
+	query += " ORDER BY " + column
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+	return query, args
+}
+
+func getGroup(ctx context.Context, tx *sql.Tx, tenantID, id string) (*entity.Group, error) {
+	group, err := scanGroup(tx.QueryRowContext(ctx, "SELECT "+groupColumns+" FROM groups WHERE tenant_id = ? AND id = ?", tenantID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errcode.NewNotFound("group not found: %s", id)
+	}
+	if err != nil {
+		return nil, newError(err)
+	}
+	return group, nil
+}
+
+func getGroupMember(ctx context.Context, tx *sql.Tx, tenantID, groupID, userID string) (*entity.GroupMember, error) {
+	member, err := scanGroupMember(tx.QueryRowContext(ctx, "SELECT "+groupMemberColumns+" FROM group_members WHERE tenant_id = ? AND group_id = ? AND user_id = ?",
+		tenantID, groupID, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errcode.NewNotFound("%s is not a member of group %s", userID, groupID)
+	}
+	if err != nil {
+		return nil, newError(err)
+	}
+	return member, nil
+}
+
+// scanGroup scans a row starting with groupColumns, followed by extra.
+func scanGroup(s interface {
+	Scan(dest ...interface{}) error
+}, extra ...interface{}) (*entity.Group, error) {
+	group := &entity.Group{}
+	var createdAt, updatedAt int64
+	dest := append([]interface{}{&group.ID, &group.Name, &group.Description, &createdAt, &updatedAt}, extra...)
+	if err := s.Scan(dest...); err != nil {
+		return nil, err
+	}
+	group.CreatedAt = time.UnixMicro(createdAt).UTC()
+	group.UpdatedAt = time.UnixMicro(updatedAt).UTC()
+	return group, nil
+}
+
+func scanGroupMember(s interface {
+	Scan(dest ...interface{}) error
+}) (*entity.GroupMember, error) {
+	member := &entity.GroupMember{}
+	var createdAt int64
+	if err := s.Scan(&member.GroupID, &member.UserID, &member.Role, &createdAt); err != nil {
+		return nil, err
+	}
+	member.CreatedAt = time.UnixMicro(createdAt).UTC()
+	return member, nil
+}
@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS users(
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
@@ -0,0 +1,7 @@
+CREATE TABLE users_old(
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+INSERT INTO users_old(id, name) SELECT id, name FROM users;
+DROP TABLE users;
+ALTER TABLE users_old RENAME TO users;
@@ -0,0 +1,11 @@
+-- SQLite cannot alter constraints, so the table is rebuilt.
+CREATE TABLE users_new(
+    tenant_id TEXT NOT NULL,
+    id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    PRIMARY KEY (tenant_id, id),
+    UNIQUE (tenant_id, name)
+);
+INSERT INTO users_new(tenant_id, id, name) SELECT 'default', id, name FROM users;
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
@@ -0,0 +1,4 @@
+-- updated_at is stored as UNIX microseconds since SQLite has no timestamp type.
+ALTER TABLE users ADD COLUMN gender INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN updated_at INTEGER NOT NULL DEFAULT 0;
+UPDATE users SET updated_at = CAST(unixepoch('subsec') * 1000000 AS INTEGER);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS users_tenant_id_created_at_idx;
+ALTER TABLE users DROP COLUMN created_at;
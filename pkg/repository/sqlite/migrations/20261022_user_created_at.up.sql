@@ -0,0 +1,4 @@
+-- created_at is stored as UNIX microseconds like updated_at.
+ALTER TABLE users ADD COLUMN created_at INTEGER NOT NULL DEFAULT 0;
+UPDATE users SET created_at = updated_at;
+CREATE INDEX users_tenant_id_created_at_idx ON users (tenant_id, created_at);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS users_tenant_id_email_key;
+ALTER TABLE users DROP COLUMN attributes;
+ALTER TABLE users DROP COLUMN timezone;
+ALTER TABLE users DROP COLUMN locale;
+ALTER TABLE users DROP COLUMN email;
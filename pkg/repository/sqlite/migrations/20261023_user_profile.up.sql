@@ -0,0 +1,6 @@
+-- attributes is JSON text. lower() folds ASCII only, unlike Postgres.
+ALTER TABLE users ADD COLUMN email TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN locale TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN timezone TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN attributes TEXT NOT NULL DEFAULT '{}';
+CREATE UNIQUE INDEX users_tenant_id_email_key ON users (tenant_id, lower(email)) WHERE email <> '';
@@ -0,0 +1,25 @@
+-- Times are UNIX microseconds; revoked_at and used_at are NULL until set.
+CREATE TABLE sessions(
+    tenant_id TEXT NOT NULL,
+    id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    refreshed_at INTEGER NOT NULL,
+    expires_at INTEGER NOT NULL,
+    revoked_at INTEGER NULL,
+    reuse_detected INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (tenant_id, id),
+    FOREIGN KEY (tenant_id, user_id) REFERENCES users (tenant_id, id) ON DELETE CASCADE
+);
+CREATE INDEX sessions_tenant_id_user_id_idx ON sessions (tenant_id, user_id, created_at DESC);
+CREATE INDEX sessions_expires_at_idx ON sessions (expires_at);
+CREATE TABLE refresh_tokens(
+    tenant_id TEXT NOT NULL,
+    token_hash TEXT NOT NULL,
+    session_id TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    used_at INTEGER NULL,
+    PRIMARY KEY (tenant_id, token_hash),
+    FOREIGN KEY (tenant_id, session_id) REFERENCES sessions (tenant_id, id) ON DELETE CASCADE
+);
+CREATE INDEX refresh_tokens_tenant_id_session_id_idx ON refresh_tokens (tenant_id, session_id);
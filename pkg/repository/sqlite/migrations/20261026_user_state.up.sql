@@ -0,0 +1,15 @@
+-- state follows the values of api.UserState; existing users are active.
+ALTER TABLE users ADD COLUMN state INTEGER NOT NULL DEFAULT 1;
+
+-- Times are UNIX microseconds.
+CREATE TABLE email_verifications(
+    tenant_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    token_hash TEXT NOT NULL,
+    email TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    expires_at INTEGER NOT NULL,
+    PRIMARY KEY (tenant_id, user_id),
+    UNIQUE (tenant_id, token_hash),
+    FOREIGN KEY (tenant_id, user_id) REFERENCES users (tenant_id, id) ON DELETE CASCADE
+);
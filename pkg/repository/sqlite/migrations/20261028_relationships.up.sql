@@ -0,0 +1,13 @@
+-- Times are UNIX microseconds; kind follows the values of api.RelationshipKind.
+CREATE TABLE relationships(
+    tenant_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    target_id TEXT NOT NULL,
+    kind INTEGER NOT NULL DEFAULT 0,
+    created_at INTEGER NOT NULL,
+    PRIMARY KEY (tenant_id, user_id, target_id),
+    CHECK (user_id <> target_id),
+    FOREIGN KEY (tenant_id, user_id) REFERENCES users (tenant_id, id) ON DELETE CASCADE,
+    FOREIGN KEY (tenant_id, target_id) REFERENCES users (tenant_id, id) ON DELETE CASCADE
+);
+CREATE INDEX relationships_tenant_id_target_id_idx ON relationships (tenant_id, target_id, kind, user_id);
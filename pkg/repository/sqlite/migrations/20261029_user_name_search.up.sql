@@ -0,0 +1,4 @@
+-- name_search is the name normalized by textsearch.Normalize, which is
+-- registered as normalize_name. The application maintains it from now on.
+ALTER TABLE users ADD COLUMN name_search TEXT NOT NULL DEFAULT '';
+UPDATE users SET name_search = normalize_name(name);
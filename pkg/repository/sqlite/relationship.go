@@ -0,0 +1,225 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+)
+
+var _ repository.Relationship = (*Relationship)(nil)
+
+// Relationship stores follows and blocks in the database of a User.
+type Relationship struct {
+	u *User
+}
+
+func NewRelationship(u *User) *Relationship {
+	return &Relationship{u: u}
+}
+
+// relationshipColumns are the columns read by scanRelationship.
+const relationshipColumns = "user_id, target_id, kind, created_at"
+
+func (r *Relationship) Follow(ctx context.Context, userID, targetID string) (*entity.Relationship, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	if userID == targetID {
+		return nil, errcode.NewInvalidArgument("user cannot follow itself: %s", userID)
+	}
+
+	var rel *entity.Relationship
+	err = r.u.withTx(ctx, "Follow", func(tx *sql.Tx) error {
+		// the transaction holds the write lock, so no block can come between
+		kinds, err := relationshipsBetween(ctx, tx, tenantID, userID, targetID)
+		if err != nil {
+			return err
+		}
+		for _, kind := range kinds {
+			if kind == entity.RelationshipKindBlock {
+				return errcode.NewFailedPrecondition("relationship between %s and %s is blocked", userID, targetID)
+			}
+		}
+		if _, ok := kinds[userID]; ok {
+			return errcode.NewAlreadyExists("%s follows %s already", userID, targetID)
+		}
+		rel, err = insertRelationship(ctx, tx, tenantID, userID, targetID, entity.RelationshipKindFollow)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rel, nil
+}
+
+func (r *Relationship) Unfollow(ctx context.Context, userID, targetID string) error {
+	return r.delete(ctx, "Unfollow", userID, targetID, entity.RelationshipKindFollow)
+}
+
+func (r *Relationship) Block(ctx context.Context, userID, targetID string) (*entity.Relationship, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	if userID == targetID {
+		return nil, errcode.NewInvalidArgument("user cannot block itself: %s", userID)
+	}
+
+	var rel *entity.Relationship
+	err = r.u.withTx(ctx, "Block", func(tx *sql.Tx) error {
+		kinds, err := relationshipsBetween(ctx, tx, tenantID, userID, targetID)
+		if err != nil {
+			return err
+		}
+		if kind, ok := kinds[userID]; ok && kind == entity.RelationshipKindBlock {
+			return errcode.NewAlreadyExists("%s blocks %s already", userID, targetID)
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM relationships WHERE tenant_id = ? AND kind = ? AND ((user_id = ? AND target_id = ?) OR (user_id = ? AND target_id = ?))",
+			tenantID, entity.RelationshipKindFollow, userID, targetID, targetID, userID)
+		if err != nil {
+			return newError(err)
+		}
+		rel, err = insertRelationship(ctx, tx, tenantID, userID, targetID, entity.RelationshipKindBlock)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rel, nil
+}
+
+func (r *Relationship) Unblock(ctx context.Context, userID, targetID string) error {
+	return r.delete(ctx, "Unblock", userID, targetID, entity.RelationshipKindBlock)
+}
+
+func (r *Relationship) delete(ctx context.Context, op, userID, targetID string, kind entity.RelationshipKind) error {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return errcode.New(err)
+	}
+
+	return r.u.withTx(ctx, op, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, "DELETE FROM relationships WHERE tenant_id = ? AND user_id = ? AND target_id = ? AND kind = ?", tenantID, userID, targetID, kind)
+		if err != nil {
+			return newError(err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return newError(err)
+		}
+		if n == 0 {
+			return errcode.NewNotFound("%s relationship not found: %s -> %s", kind, userID, targetID)
+		}
+		return nil
+	})
+}
+
+func (r *Relationship) ListFollowers(ctx context.Context, params *repository.ListRelationshipsParams) (entity.Relationships, error) {
+	query, args := "SELECT "+relationshipColumns+" FROM relationships WHERE tenant_id = ? AND target_id = ? AND kind = ?", []interface{}{params.UserID, entity.RelationshipKindFollow}
+	query, args = pageByID(query, args, "user_id", params.After, params.Limit)
+	return r.list(ctx, "ListFollowers", params.UserID, query, args)
+}
+
+func (r *Relationship) ListFollowing(ctx context.Context, params *repository.ListRelationshipsParams) (entity.Relationships, error) {
+	query, args := "SELECT "+relationshipColumns+" FROM relationships WHERE tenant_id = ? AND user_id = ? AND kind = ?", []interface{}{params.UserID, entity.RelationshipKindFollow}
+	query, args = pageByID(query, args, "target_id", params.After, params.Limit)
+	return r.list(ctx, "ListFollowing", params.UserID, query, args)
+}
+
+func (r *Relationship) ListMutualFollows(ctx context.Context, params *repository.ListRelationshipsParams) (entity.Relationships, error) {
+	query := "SELECT r.user_id, r.target_id, r.kind, r.created_at FROM relationships r " +
+		"JOIN relationships b ON b.tenant_id = r.tenant_id AND b.user_id = r.target_id AND b.target_id = r.user_id AND b.kind = r.kind " +
+		"WHERE r.tenant_id = ? AND r.user_id = ? AND r.kind = ?"
+	query, args := pageByID(query, []interface{}{params.UserID, entity.RelationshipKindFollow}, "r.target_id", params.After, params.Limit)
+	return r.list(ctx, "ListMutualFollows", params.UserID, query, args)
+}
+
+// list runs query with the tenant prepended to args, after checking that the
+// user exists.
+func (r *Relationship) list(ctx context.Context, op, userID, query string, args []interface{}) (entity.Relationships, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	args = append([]interface{}{tenantID}, args...)
+	var rels entity.Relationships
+	err = r.u.withReadTx(ctx, op, func(tx *sql.Tx) error {
+		if _, err := getUser(ctx, tx, tenantID, userID); err != nil {
+			return err
+		}
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return newError(err)
+		}
+		defer rows.Close()
+
+		rels = entity.Relationships{}
+		for rows.Next() {
+			rel, err := scanRelationship(rows)
+			if err != nil {
+				return newError(err)
+			}
+			rels = append(rels, rel)
+		}
+		return newError(rows.Err())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rels, nil
+}
+
+// relationshipsBetween checks that the user exists and returns the kinds of
+// the relationships between the users by the ID of the user they are from.
+// The target is left to the foreign keys.
+func relationshipsBetween(ctx context.Context, tx *sql.Tx, tenantID, userID, targetID string) (map[string]entity.RelationshipKind, error) {
+	if _, err := getUser(ctx, tx, tenantID, userID); err != nil {
+		return nil, err
+	}
+	rows, err := tx.QueryContext(ctx, "SELECT user_id, kind FROM relationships WHERE tenant_id = ? AND ((user_id = ? AND target_id = ?) OR (user_id = ? AND target_id = ?))",
+		tenantID, userID, targetID, targetID, userID)
+	if err != nil {
+		return nil, newError(err)
+	}
+	defer rows.Close()
+
+	kinds := map[string]entity.RelationshipKind{}
+	for rows.Next() {
+		var id string
+		var kind entity.RelationshipKind
+		if err := rows.Scan(&id, &kind); err != nil {
+			return nil, newError(err)
+		}
+		kinds[id] = kind
+	}
+	return kinds, newError(rows.Err())
+}
+
+func insertRelationship(ctx context.Context, tx *sql.Tx, tenantID, userID, targetID string, kind entity.RelationshipKind) (*entity.Relationship, error) {
+	createdAt := now().UnixMicro()
+	_, err := tx.ExecContext(ctx, "INSERT INTO relationships(tenant_id, user_id, target_id, kind, created_at) VALUES (?, ?, ?, ?, ?)",
+		tenantID, userID, targetID, kind, createdAt)
+	if err != nil {
+		return nil, newError(err)
+	}
+	return &entity.Relationship{UserID: userID, TargetID: targetID, Kind: kind, CreatedAt: time.UnixMicro(createdAt).UTC()}, nil
+}
+
+func scanRelationship(s interface {
+	Scan(dest ...interface{}) error
+}) (*entity.Relationship, error) {
+	rel := &entity.Relationship{}
+	var createdAt int64
+	if err := s.Scan(&rel.UserID, &rel.TargetID, &rel.Kind, &createdAt); err != nil {
+		return nil, err
+	}
+	rel.CreatedAt = time.UnixMicro(createdAt).UTC()
+	return rel, nil
+}
@@ -0,0 +1,242 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+)
+
+var _ repository.Session = (*Session)(nil)
+
+// Session stores sessions in the database of a User.
+type Session struct {
+	u *User
+}
+
+func NewSession(u *User) *Session {
+	return &Session{u: u}
+}
+
+// sessionColumns are the columns read by scanSession.
+const sessionColumns = "id, user_id, created_at, refreshed_at, expires_at, revoked_at, reuse_detected"
+
+func (s *Session) Create(ctx context.Context, v *entity.Session, tokenHash string) (*entity.Session, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var session *entity.Session
+	err = s.u.withTx(ctx, "CreateSession", func(tx *sql.Tx) error {
+		if _, err := getUser(ctx, tx, tenantID, v.UserID); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "INSERT INTO sessions(tenant_id, id, user_id, created_at, refreshed_at, expires_at) VALUES (?, ?, ?, ?, ?, ?)",
+			tenantID, v.ID, v.UserID, v.CreatedAt.UnixMicro(), v.RefreshedAt.UnixMicro(), v.ExpiresAt.UnixMicro())
+		if err != nil {
+			return newError(err)
+		}
+		_, err = tx.ExecContext(ctx, "INSERT INTO refresh_tokens(tenant_id, token_hash, session_id, created_at) VALUES (?, ?, ?, ?)",
+			tenantID, tokenHash, v.ID, v.CreatedAt.UnixMicro())
+		if err != nil {
+			return newError(err)
+		}
+		session, err = getSession(ctx, tx, tenantID, v.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *Session) Get(ctx context.Context, id string) (*entity.Session, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var session *entity.Session
+	err = s.u.withReadTx(ctx, "GetSession", func(tx *sql.Tx) error {
+		session, err = getSession(ctx, tx, tenantID, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *Session) List(ctx context.Context, params *repository.ListSessionsParams) (entity.Sessions, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	query := "SELECT " + sessionColumns + " FROM sessions WHERE tenant_id = ? AND user_id = ? ORDER BY created_at DESC, id"
+	args := []interface{}{tenantID, params.UserID}
+	if params.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, params.Limit)
+	}
+	var sessions entity.Sessions
+	err = s.u.withReadTx(ctx, "ListSessions", func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return newError(err)
+		}
+		defer rows.Close()
+
+		sessions = entity.Sessions{}
+		for rows.Next() {
+			session, err := scanSession(rows)
+			if err != nil {
+				return newError(err)
+			}
+			sessions = append(sessions, session)
+		}
+		return newError(rows.Err())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (s *Session) Rotate(ctx context.Context, tokenHash, newHash string, now time.Time) (*entity.Session, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	micros := now.UnixMicro()
+	var session *entity.Session
+	// the transaction holds the write lock, as in User.Update
+	err = s.u.withTx(ctx, "RotateSession", func(tx *sql.Tx) error {
+		var sessionID string
+		var usedAt sql.NullInt64
+		err := tx.QueryRowContext(ctx, "SELECT session_id, used_at FROM refresh_tokens WHERE tenant_id = ? AND token_hash = ?", tenantID, tokenHash).
+			Scan(&sessionID, &usedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errcode.NewNotFound("refresh token not found")
+		}
+		if err != nil {
+			return newError(err)
+		}
+		if usedAt.Valid {
+			if err := revokeSession(ctx, tx, tenantID, sessionID, micros, true); err != nil {
+				return err
+			}
+			session, err = getSession(ctx, tx, tenantID, sessionID)
+			return err
+		}
+		session, err = getSession(ctx, tx, tenantID, sessionID)
+		if err != nil || !session.Active(now) {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE refresh_tokens SET used_at = ? WHERE tenant_id = ? AND token_hash = ?", micros, tenantID, tokenHash); err != nil {
+			return newError(err)
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO refresh_tokens(tenant_id, token_hash, session_id, created_at) VALUES (?, ?, ?, ?)", tenantID, newHash, sessionID, micros); err != nil {
+			return newError(err)
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE sessions SET refreshed_at = ? WHERE tenant_id = ? AND id = ?", micros, tenantID, sessionID); err != nil {
+			return newError(err)
+		}
+		session.RefreshedAt = time.UnixMicro(micros).UTC()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *Session) Revoke(ctx context.Context, id string, now time.Time) (*entity.Session, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var session *entity.Session
+	err = s.u.withTx(ctx, "RevokeSession", func(tx *sql.Tx) error {
+		if err := revokeSession(ctx, tx, tenantID, id, now.UnixMicro(), false); err != nil {
+			return err
+		}
+		session, err = getSession(ctx, tx, tenantID, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *Session) DeleteExpired(ctx context.Context, before time.Time, limit int) (int64, error) {
+	var n int64
+	err := s.u.withTx(ctx, "DeleteExpiredSessions", func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, "DELETE FROM sessions WHERE (tenant_id, id) IN (SELECT tenant_id, id FROM sessions WHERE expires_at < ? LIMIT ?)",
+			before.UnixMicro(), limit)
+		if err != nil {
+			return newError(err)
+		}
+		n, err = result.RowsAffected()
+		return newError(err)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// revokeSession revokes the session at micros unless it is revoked already.
+func revokeSession(ctx context.Context, tx *sql.Tx, tenantID, id string, micros int64, reuse bool) error {
+	result, err := tx.ExecContext(ctx, "UPDATE sessions SET revoked_at = COALESCE(revoked_at, ?), reuse_detected = reuse_detected OR (revoked_at IS NULL AND ?) WHERE tenant_id = ? AND id = ?",
+		micros, reuse, tenantID, id)
+	if err != nil {
+		return newError(err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return newError(err)
+	}
+	if n == 0 {
+		return errcode.NewNotFound("session not found: %s", id)
+	}
+	return nil
+}
+
+func getSession(ctx context.Context, tx *sql.Tx, tenantID, id string) (*entity.Session, error) {
+	session, err := scanSession(tx.QueryRowContext(ctx, "SELECT "+sessionColumns+" FROM sessions WHERE tenant_id = ? AND id = ?", tenantID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errcode.NewNotFound("session not found: %s", id)
+	}
+	if err != nil {
+		return nil, newError(err)
+	}
+	return session, nil
+}
+
+func scanSession(s interface {
+	Scan(dest ...interface{}) error
+}) (*entity.Session, error) {
+	session := &entity.Session{}
+	var createdAt, refreshedAt, expiresAt int64
+	var revokedAt sql.NullInt64
+	if err := s.Scan(&session.ID, &session.UserID, &createdAt, &refreshedAt, &expiresAt, &revokedAt, &session.ReuseDetected); err != nil {
+		return nil, err
+	}
+	session.CreatedAt = time.UnixMicro(createdAt).UTC()
+	session.RefreshedAt = time.UnixMicro(refreshedAt).UTC()
+	session.ExpiresAt = time.UnixMicro(expiresAt).UTC()
+	if revokedAt.Valid {
+		session.RevokedAt = time.UnixMicro(revokedAt.Int64).UTC()
+	}
+	return session, nil
+}
@@ -0,0 +1,107 @@
+// Package sqlite implements the repositories on an embedded SQLite database
+// for deployments without a Postgres server.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/log"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/textsearch"
+	"modernc.org/sqlite"
+)
+
+// migrations mirror the ones in sql/ for the SQLite dialect.
+//
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// normalize_name lets migrations fill name_search the way the application
+// does.
+func init() {
+	err := sqlite.RegisterDeterministicScalarFunction("normalize_name", 1, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		name, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("normalize_name: %T is not text", args[0])
+		}
+		return textsearch.Normalize(name), nil
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Open opens the database file at path. Transactions take the write lock when
+// they begin, so concurrent ones wait for each other rather than fail, except
+// read-only ones, which are deferred and do not wait for writers.
+func Open(path string) (*sql.DB, error) {
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)&_txlock=immediate"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	if err := db.Ping(); err != nil {
+		return nil, errcode.New(err)
+	}
+	return db, nil
+}
+
+// Migrate applies the migrations that have not been applied to db yet.
+func Migrate(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations(version TEXT PRIMARY KEY)"); err != nil {
+		return newError(err)
+	}
+	files, err := fs.Glob(migrations, "migrations/*.up.sql")
+	if err != nil {
+		return errcode.New(err)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		version := strings.TrimSuffix(strings.TrimPrefix(file, "migrations/"), ".up.sql")
+		if err := migrate(ctx, db, version, file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrate(ctx context.Context, db *sql.DB, version, file string) (err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return newError(err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = newError(tx.Commit())
+	}()
+
+	var applied int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM schema_migrations WHERE version = ?", version).Scan(&applied); err != nil {
+		return newError(err)
+	}
+	if applied > 0 {
+		return nil
+	}
+	b, err := migrations.ReadFile(file)
+	if err != nil {
+		return errcode.New(err)
+	}
+	if _, err := tx.ExecContext(ctx, string(b)); err != nil {
+		return newError(err)
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations(version) VALUES (?)", version); err != nil {
+		return newError(err)
+	}
+	log.Info(ctx, "applied migration", "version", version)
+	return nil
+}
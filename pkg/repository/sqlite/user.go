@@ -0,0 +1,441 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/filter"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/textsearch"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/trace"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var _ repository.User = (*User)(nil)
+
+type User struct {
+	db *sql.DB
+}
+
+// NewUser returns a User on db, which must have been migrated with Migrate.
+func NewUser(db *sql.DB) *User {
+	return &User{db: db}
+}
+
+func (u *User) Create(ctx context.Context, v *entity.User) (*entity.User, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var user *entity.User
+	err = u.withTx(ctx, "Create", func(tx *sql.Tx) error {
+		attributes, err := json.Marshal(v.Attributes)
+		if err != nil {
+			return errcode.New(err)
+		}
+		createdAt := now().UnixMicro()
+		_, err = tx.ExecContext(ctx, "INSERT INTO users(tenant_id, id, name, name_search, gender, email, locale, timezone, attributes, state, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			tenantID, v.ID, v.Name, textsearch.Normalize(v.Name), v.Gender, v.Email, v.Locale, v.Timezone, string(attributes), v.State, createdAt, createdAt)
+		if err != nil {
+			return newError(err)
+		}
+		user, err = getUser(ctx, tx, tenantID, v.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (u *User) Get(ctx context.Context, id string) (*entity.User, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var user *entity.User
+	err = u.withReadTx(ctx, "Get", func(tx *sql.Tx) error {
+		user, err = getUser(ctx, tx, tenantID, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// GetByEmail folds the case of ASCII letters only, as the unique index does.
+func (u *User) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var user *entity.User
+	err = u.withReadTx(ctx, "GetByEmail", func(tx *sql.Tx) error {
+		user, err = scanUser(tx.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE tenant_id = ? AND lower(email) = lower(?) AND email <> ''", tenantID, email))
+		if errors.Is(err, sql.ErrNoRows) {
+			return errcode.NewNotFound("user not found: %s", email)
+		}
+		return newError(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (u *User) List(ctx context.Context, params *repository.ListUsersParams) (entity.Users, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	query, args := "SELECT "+userColumns+" FROM users WHERE tenant_id = ?", []interface{}{tenantID}
+	if params.Name != "" {
+		query += " AND name = ?"
+		args = append(args, params.Name)
+	}
+	if params.Filter != nil {
+		cond, filterArgs, err := filter.SQL(params.Filter, args, filterOptions)
+		if err != nil {
+			return nil, err
+		}
+		query += " AND " + cond
+		args = filterArgs
+	}
+	query += " ORDER BY " + filter.OrderSQL(params.Order(), userColumn)
+	if params.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, params.Limit)
+	}
+
+	var users entity.Users
+	err = u.withReadTx(ctx, "List", func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return newError(err)
+		}
+		defer rows.Close()
+
+		users = entity.Users{}
+		for rows.Next() {
+			user, err := scanUser(rows)
+			if err != nil {
+				return newError(err)
+			}
+			users = append(users, user)
+		}
+		return newError(rows.Err())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// Search matches prefixes and substrings of name_search in SQL and ranks the
+// matches in process since SQLite has no trigram index. Fuzzy searches have
+// nothing to narrow the users with and rank every user of the tenant, so they
+// suit small tenants only.
+func (u *User) Search(ctx context.Context, params *repository.SearchUsersParams) ([]*repository.ScoredUser, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	query, args := "SELECT "+userColumns+" FROM users WHERE tenant_id = ?", []interface{}{tenantID}
+	switch params.Mode {
+	case textsearch.ModePrefix:
+		query += ` AND name_search LIKE ? ESCAPE '\'`
+		args = append(args, textsearch.EscapeLike(params.Query)+"%")
+	case textsearch.ModeFuzzy:
+	default:
+		query += ` AND name_search LIKE ? ESCAPE '\'`
+		args = append(args, "%"+textsearch.EscapeLike(params.Query)+"%")
+	}
+
+	var users entity.Users
+	err = u.withReadTx(ctx, "Search", func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return newError(err)
+		}
+		defer rows.Close()
+
+		users = entity.Users{}
+		for rows.Next() {
+			user, err := scanUser(rows)
+			if err != nil {
+				return newError(err)
+			}
+			users = append(users, user)
+		}
+		return newError(rows.Err())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return repository.SearchUsers(users, params), nil
+}
+
+// bucketDates truncate created_at to the date starting its bucket, as
+// repository.Bucket.Truncate does.
+var bucketDates = map[repository.Bucket]string{
+	repository.BucketDay: "date(created_at / 1000000, 'unixepoch')",
+	// the Sunday ending the week, then its Monday
+	repository.BucketWeek:  "date(created_at / 1000000, 'unixepoch', 'weekday 0', '-6 days')",
+	repository.BucketMonth: "date(created_at / 1000000, 'unixepoch', 'start of month')",
+}
+
+func (u *User) Stats(ctx context.Context, params *repository.UserStatsParams) (*repository.UserStats, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	bucket, ok := bucketDates[params.Bucket]
+	if !ok {
+		return nil, errcode.NewInvalidArgument("unknown bucket: %d", params.Bucket)
+	}
+
+	where, args := "tenant_id = ?", []interface{}{tenantID}
+	if params.Filter != nil {
+		cond, filterArgs, err := filter.SQL(params.Filter, args, filterOptions)
+		if err != nil {
+			return nil, err
+		}
+		where += " AND " + cond
+		args = filterArgs
+	}
+
+	var stats *repository.UserStats
+	// both queries read the same snapshot in the transaction
+	err = u.withReadTx(ctx, "Stats", func(tx *sql.Tx) error {
+		stats = &repository.UserStats{ByGender: map[entity.Gender]int64{}, Signups: []*repository.SignupCount{}}
+		rows, err := tx.QueryContext(ctx, "SELECT gender, count(*) FROM users WHERE "+where+" GROUP BY gender", args...)
+		if err != nil {
+			return newError(err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var gender entity.Gender
+			var n int64
+			if err := rows.Scan(&gender, &n); err != nil {
+				return newError(err)
+			}
+			stats.Total += n
+			stats.ByGender[gender] = n
+		}
+		if err := rows.Err(); err != nil {
+			return newError(err)
+		}
+
+		rows, err = tx.QueryContext(ctx, "SELECT "+bucket+" AS bucket, count(*) FROM users WHERE "+where+
+			" AND created_at >= ? AND created_at < ? GROUP BY bucket ORDER BY bucket",
+			append(args, params.From.UnixMicro(), params.To.UnixMicro())...)
+		if err != nil {
+			return newError(err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var date string
+			var n int64
+			if err := rows.Scan(&date, &n); err != nil {
+				return newError(err)
+			}
+			start, err := time.Parse("2006-01-02", date)
+			if err != nil {
+				return errcode.New(err)
+			}
+			stats.Signups = append(stats.Signups, &repository.SignupCount{Start: start, Count: n})
+		}
+		return newError(rows.Err())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (u *User) Update(ctx context.Context, id string, update func(*entity.User) bool) (*entity.User, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var user *entity.User
+	// the transaction holds the write lock, so no row lock is needed
+	err = u.withTx(ctx, "Update", func(tx *sql.Tx) error {
+		user, err = getUser(ctx, tx, tenantID, id)
+		if err != nil {
+			return err
+		}
+		if !update(user) {
+			return nil
+		}
+		attributes, err := json.Marshal(user.Attributes)
+		if err != nil {
+			return errcode.New(err)
+		}
+		user.UpdatedAt = now()
+		_, err = tx.ExecContext(ctx, "UPDATE users SET name = ?, name_search = ?, gender = ?, email = ?, locale = ?, timezone = ?, attributes = ?, state = ?, updated_at = ? WHERE tenant_id = ? AND id = ?",
+			user.Name, textsearch.Normalize(user.Name), user.Gender, user.Email, user.Locale, user.Timezone, string(attributes), user.State, user.UpdatedAt.UnixMicro(), tenantID, id)
+		return newError(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (u *User) Delete(ctx context.Context, id string) error {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return errcode.New(err)
+	}
+
+	return u.withTx(ctx, "Delete", func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, "DELETE FROM users WHERE tenant_id = ? AND id = ?", tenantID, id)
+		if err != nil {
+			return newError(err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return newError(err)
+		}
+		if n == 0 {
+			return errcode.NewNotFound("user not found: %s", id)
+		}
+		return nil
+	})
+}
+
+// withTx runs f in a transaction and commits it when f succeeds. The
+// transaction takes the write lock when it begins.
+func (u *User) withTx(ctx context.Context, op string, f func(tx *sql.Tx) error) error {
+	return u.runTx(ctx, op, nil, f)
+}
+
+// withReadTx runs f in a read-only transaction, which takes no lock until it
+// reads so that reads do not wait for writes in WAL mode.
+func (u *User) withReadTx(ctx context.Context, op string, f func(tx *sql.Tx) error) error {
+	return u.runTx(ctx, op, &sql.TxOptions{ReadOnly: true}, f)
+}
+
+func (u *User) runTx(ctx context.Context, op string, opts *sql.TxOptions, f func(tx *sql.Tx) error) (err error) {
+	ctx, span := trace.Start(ctx, "sqlite.User."+op, attribute.String("db.system", "sqlite"))
+	defer func() {
+		if err != nil && ctx.Err() != nil {
+			// the statement was interrupted because the caller gave up
+			err = errcode.New(ctx.Err())
+		}
+		trace.End(span, err)
+	}()
+
+	tx, err := u.db.BeginTx(ctx, opts)
+	if err != nil {
+		return newError(err)
+	}
+	if err := f(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return newError(tx.Commit())
+}
+
+func getUser(ctx context.Context, tx *sql.Tx, tenantID, id string) (*entity.User, error) {
+	user, err := scanUser(tx.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE tenant_id = ? AND id = ?", tenantID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errcode.NewNotFound("user not found: %s", id)
+	}
+	if err != nil {
+		return nil, newError(err)
+	}
+	return user, nil
+}
+
+// userColumns are the columns read by scanUser. Times are stored in UNIX
+// microseconds and attributes as JSON text.
+const userColumns = "id, name, gender, email, locale, timezone, attributes, state, created_at, updated_at"
+
+func scanUser(s interface {
+	Scan(dest ...interface{}) error
+}) (*entity.User, error) {
+	user := &entity.User{}
+	var attributes string
+	var createdAt, updatedAt int64
+	if err := s.Scan(&user.ID, &user.Name, &user.Gender, &user.Email, &user.Locale, &user.Timezone, &attributes, &user.State, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(attributes), &user.Attributes); err != nil {
+		return nil, err
+	}
+	user.CreatedAt = time.UnixMicro(createdAt).UTC()
+	user.UpdatedAt = time.UnixMicro(updatedAt).UTC()
+	return user, nil
+}
+
+// filterOptions compile filters of repository.UserFilterSchema.
+var filterOptions = &filter.SQLOptions{
+	Column:      userColumn,
+	Placeholder: func(int) string { return "?" },
+	Value:       filterValue,
+	Map:         userAttribute,
+}
+
+// userColumn returns the column of a field of repository.UserFilterSchema,
+// which are named as the fields. The schema is the allowlist of what reaches
+// the query.
+func userColumn(field string) string {
+	return field
+}
+
+// filterValue converts timestamps in filters to the stored representation.
+func filterValue(_ string, v interface{}) interface{} {
+	if t, ok := v.(time.Time); ok {
+		return t.UnixMicro()
+	}
+	return v
+}
+
+// userAttribute compiles the comparisons of attributes with the JSON
+// functions. json_extract returns booleans as 1 and 0, which is also how bools
+// are bound.
+func userAttribute(c *filter.Comparison, args []interface{}) (string, []interface{}) {
+	if c.Op == filter.OpHas {
+		return "json_type(attributes, ?) IS NOT NULL", append(args, attributePath(c.Value.(string)))
+	}
+	types := "'text'"
+	switch c.Value.(type) {
+	case int64, float64:
+		types = "'integer', 'real'"
+	case bool:
+		types = "'true', 'false'"
+	}
+	op := string(c.Op)
+	if c.Op == filter.OpNe {
+		op = "<>"
+	}
+	path := attributePath(c.Key)
+	return fmt.Sprintf("COALESCE(json_type(attributes, ?) IN (%s) AND json_extract(attributes, ?) %s ?, 0)", types, op),
+		append(args, path, path, c.Value)
+}
+
+// attributePath returns the JSON path of an attribute. Keys are identifiers,
+// so they need no escaping.
+func attributePath(key string) string {
+	return `$."` + key + `"`
+}
+
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
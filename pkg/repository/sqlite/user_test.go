@@ -0,0 +1,117 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/repositorytest"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/textsearch"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUser_Contract(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, Migrate(context.Background(), db))
+
+	repositorytest.TestUser(t, func(t *testing.T) repository.User {
+		return NewUser(db)
+	})
+	repositorytest.TestCredential(t, func(t *testing.T) (repository.User, repository.Credential) {
+		users := NewUser(db)
+		return users, NewCredential(users)
+	})
+	repositorytest.TestSession(t, func(t *testing.T) (repository.User, repository.Session) {
+		users := NewUser(db)
+		return users, NewSession(users)
+	})
+	repositorytest.TestVerification(t, func(t *testing.T) (repository.User, repository.Verification) {
+		users := NewUser(db)
+		return users, NewVerification(users)
+	})
+	repositorytest.TestGroup(t, func(t *testing.T) (repository.User, repository.Group) {
+		users := NewUser(db)
+		return users, NewGroup(users)
+	})
+	repositorytest.TestRelationship(t, func(t *testing.T) (repository.User, repository.Relationship) {
+		users := NewUser(db)
+		return users, NewRelationship(users)
+	})
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	// rows created before multi-tenancy move to the default tenant
+	_, err = db.Exec(string(mustRead(t, "migrations/20230115_user.up.sql")))
+	require.NoError(t, err)
+	_, err = db.Exec("CREATE TABLE schema_migrations(version TEXT PRIMARY KEY)")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO schema_migrations(version) VALUES ('20230115_user')")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO users(id, name) VALUES ('id', 'name')")
+	require.NoError(t, err)
+
+	require.NoError(t, Migrate(ctx, db))
+	// applying again is a no-op
+	require.NoError(t, Migrate(ctx, db))
+
+	got, err := NewUser(db).Get(tenant.NewContext(ctx, tenant.Default), "id")
+	require.NoError(t, err)
+	require.Equal(t, "name", got.Name)
+	require.Equal(t, entity.GenderOther, got.Gender)
+	// existing rows are stamped with the time of the migration
+	require.False(t, got.UpdatedAt.IsZero())
+	require.Empty(t, got.Email)
+	require.Nil(t, got.Attributes)
+	require.Equal(t, entity.UserStateActive, got.State)
+
+	// names are searchable as the application normalizes them
+	_, err = db.Exec(string(mustRead(t, "migrations/20261029_user_name_search.down.sql")))
+	require.NoError(t, err)
+	_, err = db.Exec("UPDATE users SET name = 'ﾔﾏﾀﾞ Name' WHERE id = 'id'")
+	require.NoError(t, err)
+	_, err = db.Exec(string(mustRead(t, "migrations/20261029_user_name_search.up.sql")))
+	require.NoError(t, err)
+	found, err := NewUser(db).Search(tenant.NewContext(ctx, tenant.Default), &repository.SearchUsersParams{Query: textsearch.Normalize("ヤマダ"), Mode: textsearch.ModePrefix})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+}
+
+func TestUser_ReadDuringWrite(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, Migrate(context.Background(), db))
+	users := NewUser(db)
+	ctx := tenant.NewContext(context.Background(), tenant.Default)
+	_, err = users.Create(ctx, &entity.User{ID: "id", Name: "name"})
+	require.NoError(t, err)
+
+	// reads do not wait for the write lock held by another transaction
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	readCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	got, err := users.Get(readCtx, "id")
+	require.NoError(t, err)
+	require.Equal(t, "name", got.Name)
+	_, err = users.List(readCtx, &repository.ListUsersParams{})
+	require.NoError(t, err)
+}
+
+func mustRead(t *testing.T, name string) []byte {
+	b, err := migrations.ReadFile(name)
+	require.NoError(t, err)
+	return b
+}
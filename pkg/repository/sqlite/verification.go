@@ -0,0 +1,83 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+)
+
+var _ repository.Verification = (*Verification)(nil)
+
+// Verification stores email verifications in the database of a User.
+type Verification struct {
+	u *User
+}
+
+func NewVerification(u *User) *Verification {
+	return &Verification{u: u}
+}
+
+// verificationColumns are the columns read by scanVerification.
+const verificationColumns = "user_id, email, created_at, expires_at"
+
+func (v *Verification) Create(ctx context.Context, in *entity.Verification, tokenHash string) (*entity.Verification, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var verification *entity.Verification
+	err = v.u.withTx(ctx, "CreateVerification", func(tx *sql.Tx) error {
+		if _, err := getUser(ctx, tx, tenantID, in.UserID); err != nil {
+			return err
+		}
+		verification, err = scanVerification(tx.QueryRowContext(ctx, "INSERT INTO email_verifications(tenant_id, user_id, token_hash, email, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?) "+
+			"ON CONFLICT (tenant_id, user_id) DO UPDATE SET token_hash = excluded.token_hash, email = excluded.email, created_at = excluded.created_at, expires_at = excluded.expires_at "+
+			"RETURNING "+verificationColumns,
+			tenantID, in.UserID, tokenHash, in.Email, in.CreatedAt.UnixMicro(), in.ExpiresAt.UnixMicro()))
+		return newError(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return verification, nil
+}
+
+func (v *Verification) Consume(ctx context.Context, tokenHash string) (*entity.Verification, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	var verification *entity.Verification
+	err = v.u.withTx(ctx, "ConsumeVerification", func(tx *sql.Tx) error {
+		verification, err = scanVerification(tx.QueryRowContext(ctx, "DELETE FROM email_verifications WHERE tenant_id = ? AND token_hash = ? RETURNING "+verificationColumns, tenantID, tokenHash))
+		if errors.Is(err, sql.ErrNoRows) {
+			return errcode.NewNotFound("verification token not found")
+		}
+		return newError(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return verification, nil
+}
+
+func scanVerification(s interface {
+	Scan(dest ...interface{}) error
+}) (*entity.Verification, error) {
+	v := &entity.Verification{}
+	var createdAt, expiresAt int64
+	if err := s.Scan(&v.UserID, &v.Email, &createdAt, &expiresAt); err != nil {
+		return nil, err
+	}
+	v.CreatedAt = time.UnixMicro(createdAt).UTC()
+	v.ExpiresAt = time.UnixMicro(expiresAt).UTC()
+	return v, nil
+}
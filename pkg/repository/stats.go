@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"sort"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/filter"
+)
+
+// Bucket is the width of the time buckets of sign-up counts. Buckets start at
+// midnight UTC; weeks start on Monday as in Postgres' date_trunc.
+type Bucket int
+
+const (
+	BucketDay Bucket = iota
+	BucketWeek
+	BucketMonth
+)
+
+// Truncate returns the start of the bucket containing t.
+func (b Bucket) Truncate(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch b {
+	case BucketWeek:
+		// Monday is day 0
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case BucketMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return day
+}
+
+// Add returns the start of the n-th bucket after the one starting at start.
+// Negative n goes back.
+func (b Bucket) Add(start time.Time, n int) time.Time {
+	switch b {
+	case BucketWeek:
+		return start.AddDate(0, 0, 7*n)
+	case BucketMonth:
+		return start.AddDate(0, n, 0)
+	}
+	return start.AddDate(0, 0, n)
+}
+
+// Unit returns the unit of Postgres' date_trunc.
+func (b Bucket) Unit() string {
+	switch b {
+	case BucketWeek:
+		return "week"
+	case BucketMonth:
+		return "month"
+	}
+	return "day"
+}
+
+type UserStatsParams struct {
+	// Filter is checked against UserFilterSchema. Nil matches every user.
+	Filter filter.Expr
+	Bucket Bucket
+	// Signups counts the users created in [From, To).
+	From, To time.Time
+}
+
+type UserStats struct {
+	Total int64 `json:"total"`
+	// ByGender has the genders of at least one user.
+	ByGender map[entity.Gender]int64 `json:"by_gender"`
+	// Signups has the buckets of at least one user, the earliest first.
+	Signups []*SignupCount `json:"signups"`
+}
+
+type SignupCount struct {
+	Start time.Time `json:"start"`
+	Count int64     `json:"count"`
+}
+
+// CountUsers aggregates users in process, for the backends that cannot
+// aggregate natively.
+func CountUsers(users entity.Users, params *UserStatsParams) *UserStats {
+	stats := &UserStats{ByGender: map[entity.Gender]int64{}, Signups: []*SignupCount{}}
+	signups := map[time.Time]int64{}
+	for _, user := range users {
+		if !filter.Eval(params.Filter, func(field string) interface{} { return UserFilterValue(user, field) }) {
+			continue
+		}
+		stats.Total++
+		stats.ByGender[user.Gender]++
+		if !user.CreatedAt.Before(params.From) && user.CreatedAt.Before(params.To) {
+			signups[params.Bucket.Truncate(user.CreatedAt)]++
+		}
+	}
+	for start, n := range signups {
+		stats.Signups = append(stats.Signups, &SignupCount{Start: start, Count: n})
+	}
+	sort.Slice(stats.Signups, func(i, j int) bool { return stats.Signups[i].Start.Before(stats.Signups[j].Start) })
+	return stats
+}
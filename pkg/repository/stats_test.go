@@ -0,0 +1,71 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucket(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	tests := []struct {
+		name   string
+		bucket repository.Bucket
+		t      time.Time
+		start  time.Time
+		next   time.Time
+	}{
+		{
+			name:   "day",
+			bucket: repository.BucketDay,
+			t:      time.Date(2026, 3, 4, 23, 59, 59, 0, time.UTC),
+			start:  time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC),
+			next:   time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "day in UTC",
+			bucket: repository.BucketDay,
+			t:      time.Date(2026, 3, 5, 8, 0, 0, 0, tokyo),
+			start:  time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC),
+			next:   time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "week from wednesday",
+			bucket: repository.BucketWeek,
+			t:      time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC),
+			start:  time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+			next:   time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "week from sunday",
+			bucket: repository.BucketWeek,
+			t:      time.Date(2026, 3, 8, 12, 0, 0, 0, time.UTC),
+			start:  time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+			next:   time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "week across a year",
+			bucket: repository.BucketWeek,
+			t:      time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			start:  time.Date(2025, 12, 29, 0, 0, 0, 0, time.UTC),
+			next:   time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "month",
+			bucket: repository.BucketMonth,
+			t:      time.Date(2026, 12, 31, 12, 0, 0, 0, time.UTC),
+			start:  time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC),
+			next:   time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := tt.bucket.Truncate(tt.t)
+			require.Equal(t, tt.start, start)
+			require.Equal(t, tt.next, tt.bucket.Add(start, 1))
+			require.Equal(t, start, tt.bucket.Add(tt.next, -1))
+		})
+	}
+}
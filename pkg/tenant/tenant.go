@@ -0,0 +1,41 @@
+package tenant
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+)
+
+// Default is the tenant that rows created before multi-tenancy belong to.
+const Default = "default"
+
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+func Validate(id string) error {
+	if !idPattern.MatchString(id) {
+		return errcode.NewInvalidArgument("invalid tenant id: %q", id)
+	}
+	return nil
+}
+
+type tenantKey struct{}
+
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(tenantKey{}).(string)
+	return id, ok && id != ""
+}
+
+// Require returns the tenant of ctx. Repositories use it so that a query is
+// never issued without a tenant scope.
+func Require(ctx context.Context) (string, error) {
+	id, ok := FromContext(ctx)
+	if !ok {
+		return "", errcode.NewInvalidArgument("tenant is not specified")
+	}
+	return id, nil
+}
@@ -0,0 +1,39 @@
+package tenant
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want bool
+	}{
+		{name: "default", id: Default, want: true},
+		{name: "with separators", id: "acme-corp_1", want: true},
+		{name: "empty", id: "", want: false},
+		{name: "upper case", id: "Acme", want: false},
+		{name: "leading separator", id: "-acme", want: false},
+		{name: "too long", id: "a123456789012345678901234567890123456789012345678901234567890123", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.id)
+			require.Equal(t, tt.want, err == nil)
+		})
+	}
+}
+
+func TestRequire(t *testing.T) {
+	_, err := Require(context.Background())
+	require.True(t, errcode.IsInvalidArgument(err))
+
+	got, err := Require(NewContext(context.Background(), "acme"))
+	require.NoError(t, err)
+	require.Equal(t, "acme", got)
+}
@@ -0,0 +1,266 @@
+package usecase
+
+import (
+	"context"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/log"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/requestid"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/trace"
+)
+
+const (
+	defaultMaxLoginFailures = 5
+	defaultLockoutDuration  = 15 * time.Minute
+
+	minPasswordLength = 12
+	maxPasswordLength = 128
+)
+
+// PasswordHasher hashes passwords. It is implemented by auth.Passwords.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(hash, password string) (bool, error)
+	// NeedsRehash reports whether hash should be replaced by a new Hash.
+	NeedsRehash(hash string) bool
+}
+
+// TokenIssuer issues the session tokens of Login. It is implemented by
+// auth.Issuer.
+type TokenIssuer interface {
+	Issue(ctx context.Context, subject, tenant string) (string, time.Time, error)
+}
+
+// validPassword is the password policy: 12 to 128 characters mixing letters
+// with other characters.
+func validPassword(password string) bool {
+	if n := utf8.RuneCountInString(password); n < minPasswordLength || n > maxPasswordLength {
+		return false
+	}
+	var letter, other bool
+	for _, r := range password {
+		if unicode.IsLetter(r) {
+			letter = true
+		} else {
+			other = true
+		}
+	}
+	return letter && other
+}
+
+type SetPasswordRequest struct {
+	ID       string `validate:"required"`
+	Password string `validate:"password"`
+}
+
+type SetPasswordResponse struct{}
+
+func (u *UsecaseImpl) SetPassword(ctx context.Context, req *SetPasswordRequest) (_ *SetPasswordResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.SetPassword")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	if err := u.authorize(ctx, req.ID); err != nil {
+		return nil, errcode.New(err)
+	}
+	hash, err := u.passwords.Hash(req.Password)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	// database
+	if _, err := u.db.Credential.Set(ctx, req.ID, hash); err != nil {
+		return nil, errcode.New(err)
+	}
+	return &SetPasswordResponse{}, nil
+}
+
+type VerifyPasswordRequest struct {
+	ID       string `validate:"required"`
+	Password string `validate:"required,max=128"`
+}
+
+type VerifyPasswordResponse struct {
+	Valid bool
+}
+
+// VerifyPassword counts a mismatch as a failed attempt, as Login does.
+func (u *UsecaseImpl) VerifyPassword(ctx context.Context, req *VerifyPasswordRequest) (_ *VerifyPasswordResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.VerifyPassword")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	if err := u.authorize(ctx, req.ID); err != nil {
+		return nil, errcode.New(err)
+	}
+
+	// database
+	valid, err := u.checkPassword(ctx, req.ID, req.Password)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &VerifyPasswordResponse{Valid: valid}, nil
+}
+
+type LoginRequest struct {
+	// ID or Email identifies the user.
+	ID       string `validate:"required_without=Email,excluded_with=Email"`
+	Email    string `validate:"omitempty,email"`
+	Password string `validate:"required,max=128"`
+}
+
+type LoginResponse struct {
+	Token     string
+	ExpiresAt time.Time
+	User      *entity.User
+}
+
+// Login issues a session token for the user of the tenant of ctx. Unknown
+// users, users without a password and wrong passwords fail alike, in about the
+// same time. Locked out credentials fail with ResourceExhausted instead, which
+// tells that the user exists to anyone who keeps guessing its password.
+func (u *UsecaseImpl) Login(ctx context.Context, req *LoginRequest) (_ *LoginResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.Login")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	if u.tokens == nil {
+		return nil, errcode.NewUnimplemented("login is not enabled")
+	}
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	// database
+	// the state checked by checkSignIn must be current
+	latest := repository.Latest(ctx)
+	var user *entity.User
+	if req.Email != "" {
+		user, err = u.db.User.GetByEmail(latest, req.Email)
+	} else {
+		user, err = u.db.User.Get(latest, req.ID)
+	}
+	if errcode.IsNotfound(err) {
+		return nil, u.rejectLogin(req.Password)
+	}
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	valid, err := u.checkPassword(ctx, user.ID, req.Password)
+	if errcode.IsNotfound(err) {
+		return nil, u.rejectLogin(req.Password)
+	}
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	if !valid {
+		return nil, errcode.NewUnauthenticated("invalid credentials")
+	}
+	// the state is told only to those who know the password
+	if err := checkSignIn(user); err != nil {
+		return nil, errcode.New(err)
+	}
+
+	token, expiresAt, err := u.tokens.Issue(ctx, user.ID, tenantID)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &LoginResponse{Token: token, ExpiresAt: expiresAt, User: user}, nil
+}
+
+// rejectLogin fails a login without a credential after verifying password
+// against a dummy hash, so that it takes as long as a wrong password.
+func (u *UsecaseImpl) rejectLogin(password string) error {
+	u.dummyOnce.Do(func() {
+		u.dummyHash, _ = u.passwords.Hash(requestid.New())
+	})
+	if u.dummyHash != "" {
+		u.passwords.Verify(u.dummyHash, password)
+	}
+	return errcode.NewUnauthenticated("invalid credentials")
+}
+
+// checkPassword verifies password against the credential of the user and
+// records the outcome. Consecutive failures lock the credential out, after
+// which it returns ResourceExhausted until the lockout ends. The hash is
+// verified outside of a transaction since it is slow on purpose.
+func (u *UsecaseImpl) checkPassword(ctx context.Context, userID, password string) (bool, error) {
+	credential, err := u.db.Credential.Get(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	now := u.now()
+	if credential.Locked(now) {
+		return false, lockedOut(ctx, credential)
+	}
+	valid, err := u.passwords.Verify(credential.PasswordHash, password)
+	if err != nil {
+		return false, err
+	}
+	var rehash string
+	if valid && u.passwords.NeedsRehash(credential.PasswordHash) {
+		if rehash, err = u.passwords.Hash(password); err != nil {
+			return false, err
+		}
+	}
+
+	var locked, changed bool
+	updated, err := u.db.Credential.Update(ctx, userID, func(c *entity.Credential) bool {
+		locked, changed = false, false
+		// another attempt may have locked the credential out meanwhile
+		if c.Locked(now) {
+			locked = true
+			return false
+		}
+		if c.PasswordHash != credential.PasswordHash {
+			// the outcome is for a password that has been replaced
+			changed = true
+			return false
+		}
+		if !valid {
+			c.FailedAttempts++
+			if c.FailedAttempts >= u.maxLoginFailures {
+				c.FailedAttempts = 0
+				c.LockedUntil = now.Add(u.lockoutDuration)
+			}
+			return true
+		}
+		if c.FailedAttempts == 0 && rehash == "" {
+			return false
+		}
+		c.FailedAttempts = 0
+		if rehash != "" {
+			c.PasswordHash = rehash
+		}
+		return true
+	})
+	switch {
+	case err != nil:
+		return false, err
+	case locked:
+		return false, lockedOut(ctx, updated)
+	case changed:
+		return false, errcode.NewAborted("password of %s changed during verification", userID)
+	}
+	return valid, nil
+}
+
+// lockedOut tells the client no more than that it is locked out: which user
+// and until when are for the logs only.
+func lockedOut(ctx context.Context, c *entity.Credential) error {
+	log.Warn(ctx, "credential locked out", "user", c.UserID, "until", c.LockedUntil.Format(time.RFC3339))
+	return errcode.NewResourceExhausted("too many failed attempts")
+}
@@ -0,0 +1,173 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/auth"
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/repositorytest"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testPassword = "correct horse battery"
+
+func newLoginUsecase(t *testing.T) (*UsecaseImpl, *auth.JWT) {
+	secret := []byte("secret")
+	issuer, err := auth.NewIssuer(&auth.IssuerConfig{HS256Secret: secret})
+	require.NoError(t, err)
+	verifier, err := auth.NewJWT(&auth.JWTConfig{HS256Secret: secret})
+	require.NoError(t, err)
+	u := newUsecase(t)
+	u.tokens = issuer
+	return u, verifier
+}
+
+func TestUsecaseImpl_Login(t *testing.T) {
+	u, verifier := newLoginUsecase(t)
+	ctx := repositorytest.TenantContext(t)
+	tenantID, _ := tenant.FromContext(ctx)
+	_, err := u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: "id", Name: "name", Email: "name@example.com"}})
+	require.NoError(t, err)
+	_, err = u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: "no-password", Name: "no-password"}})
+	require.NoError(t, err)
+	_, err = u.SetPassword(ctx, &SetPasswordRequest{ID: "id", Password: testPassword})
+	require.NoError(t, err)
+
+	for _, req := range []*LoginRequest{
+		{ID: "id", Password: testPassword},
+		{Email: "NAME@example.com", Password: testPassword},
+	} {
+		resp, err := u.Login(ctx, req)
+		require.NoError(t, err)
+		require.Equal(t, "id", resp.User.ID)
+		require.True(t, resp.ExpiresAt.After(time.Now()))
+
+		// the token authenticates the user in the tenant
+		p, err := verifier.Authenticate(context.Background(), resp.Token)
+		require.NoError(t, err)
+		require.Equal(t, "id", p.Subject)
+		require.Equal(t, tenantID, p.Tenant)
+	}
+
+	// failures do not tell whether the user exists
+	for _, req := range []*LoginRequest{
+		{ID: "id", Password: "wrong password 1"},
+		{ID: "missing", Password: testPassword},
+		{Email: "missing@example.com", Password: testPassword},
+		{ID: "no-password", Password: testPassword},
+	} {
+		_, err := u.Login(ctx, req)
+		require.True(t, errcode.IsUnauthenticated(err), err)
+		require.Contains(t, err.Error(), "Unauthenticated: invalid credentials\n")
+	}
+
+	// another tenant has no such user
+	_, err = u.Login(repositorytest.TenantContext(t), &LoginRequest{ID: "id", Password: testPassword})
+	require.True(t, errcode.IsUnauthenticated(err), err)
+
+	// login is disabled without an issuer
+	u.tokens = nil
+	_, err = u.Login(ctx, &LoginRequest{ID: "id", Password: testPassword})
+	require.Equal(t, errcode.CodeUnimplemented, errcode.NewCode(err), err)
+}
+
+func TestUsecaseImpl_Lockout(t *testing.T) {
+	u, _ := newLoginUsecase(t)
+	now := time.Now()
+	u.now = func() time.Time { return now }
+	ctx := repositorytest.TenantContext(t)
+	_, err := u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: "id", Name: "name"}})
+	require.NoError(t, err)
+	_, err = u.SetPassword(ctx, &SetPasswordRequest{ID: "id", Password: testPassword})
+	require.NoError(t, err)
+
+	// a success resets the count
+	for i := 0; i < defaultMaxLoginFailures-1; i++ {
+		resp, err := u.VerifyPassword(ctx, &VerifyPasswordRequest{ID: "id", Password: "wrong password"})
+		require.NoError(t, err)
+		require.False(t, resp.Valid)
+	}
+	resp, err := u.VerifyPassword(ctx, &VerifyPasswordRequest{ID: "id", Password: testPassword})
+	require.NoError(t, err)
+	require.True(t, resp.Valid)
+
+	for i := 0; i < defaultMaxLoginFailures; i++ {
+		_, err := u.Login(ctx, &LoginRequest{ID: "id", Password: "wrong password"})
+		require.True(t, errcode.IsUnauthenticated(err), err)
+	}
+	// even the right password is rejected while locked out
+	_, err = u.Login(ctx, &LoginRequest{ID: "id", Password: testPassword})
+	require.True(t, errcode.IsResourceExhausted(err), err)
+	// which user is locked out and until when are not told to the client
+	require.EqualError(t, errors.Unwrap(err), "too many failed attempts")
+	_, err = u.VerifyPassword(ctx, &VerifyPasswordRequest{ID: "id", Password: testPassword})
+	require.True(t, errcode.IsResourceExhausted(err), err)
+
+	// the lockout ends
+	now = now.Add(defaultLockoutDuration)
+	_, err = u.Login(ctx, &LoginRequest{ID: "id", Password: testPassword})
+	require.NoError(t, err)
+
+	// setting the password ends it too
+	now = now.Add(time.Second)
+	for i := 0; i < defaultMaxLoginFailures; i++ {
+		_, err := u.Login(ctx, &LoginRequest{ID: "id", Password: "wrong password"})
+		require.True(t, errcode.IsUnauthenticated(err), err)
+	}
+	_, err = u.SetPassword(ctx, &SetPasswordRequest{ID: "id", Password: "another password 2"})
+	require.NoError(t, err)
+	_, err = u.Login(ctx, &LoginRequest{ID: "id", Password: "another password 2"})
+	require.NoError(t, err)
+}
+
+func TestUsecaseImpl_Rehash(t *testing.T) {
+	u, _ := newLoginUsecase(t)
+	ctx := repositorytest.TenantContext(t)
+	_, err := u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: "id", Name: "name"}})
+	require.NoError(t, err)
+
+	// an imported bcrypt hash is replaced once the password is verified
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	require.NoError(t, err)
+	_, err = u.db.Credential.Set(ctx, "id", string(hash))
+	require.NoError(t, err)
+	resp, err := u.VerifyPassword(ctx, &VerifyPasswordRequest{ID: "id", Password: testPassword})
+	require.NoError(t, err)
+	require.True(t, resp.Valid)
+
+	credential, err := u.db.Credential.Get(ctx, "id")
+	require.NoError(t, err)
+	require.False(t, u.passwords.NeedsRehash(credential.PasswordHash))
+	resp, err = u.VerifyPassword(ctx, &VerifyPasswordRequest{ID: "id", Password: testPassword})
+	require.NoError(t, err)
+	require.True(t, resp.Valid)
+}
+
+func TestUsecaseImpl_SetPassword(t *testing.T) {
+	u := New(&Config{
+		DB:         newUsecase(t).db,
+		Passwords:  auth.NewPasswords(auth.Argon2Params{Time: 1, Memory: 64, Threads: 1}),
+		Authorizer: &ownerAuthorizer{owner: "me"},
+	})
+	ctx := repositorytest.TenantContext(t)
+	_, err := u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: "me", Name: "me"}})
+	require.NoError(t, err)
+
+	_, err = u.SetPassword(ctx, &SetPasswordRequest{ID: "me", Password: testPassword})
+	require.NoError(t, err)
+	_, err = u.SetPassword(ctx, &SetPasswordRequest{ID: "other", Password: testPassword})
+	require.True(t, errcode.IsForbidden(err), err)
+	_, err = u.VerifyPassword(ctx, &VerifyPasswordRequest{ID: "other", Password: testPassword})
+	require.True(t, errcode.IsForbidden(err), err)
+
+	// the password is stored hashed
+	credential, err := u.db.Credential.Get(ctx, "me")
+	require.NoError(t, err)
+	require.NotContains(t, credential.PasswordHash, testPassword)
+}
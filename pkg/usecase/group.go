@@ -0,0 +1,392 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/pagetoken"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/trace"
+)
+
+type CreateGroupRequest struct {
+	Group *entity.Group `validate:"required"`
+	// OwnerID, unless empty, is added as the owner of the group.
+	OwnerID string
+}
+
+type CreateGroupResponse struct {
+	Group *entity.Group
+}
+
+func (u *UsecaseImpl) CreateGroup(ctx context.Context, req *CreateGroupRequest) (_ *CreateGroupResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.CreateGroup")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	// callers allowed only for themselves create groups they own
+	if err := u.authorize(ctx, req.OwnerID); err != nil {
+		return nil, errcode.New(err)
+	}
+
+	// database
+	group, err := u.db.Group.Create(ctx, req.Group, req.OwnerID)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &CreateGroupResponse{Group: group}, nil
+}
+
+type GetGroupRequest struct {
+	ID string `validate:"required"`
+}
+
+type GetGroupResponse struct {
+	Group *entity.Group
+}
+
+func (u *UsecaseImpl) GetGroup(ctx context.Context, req *GetGroupRequest) (_ *GetGroupResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.GetGroup")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	if err := u.authorizeGroup(ctx, req.ID); err != nil {
+		return nil, errcode.New(err)
+	}
+
+	// database
+	group, err := u.db.Group.Get(ctx, req.ID)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &GetGroupResponse{Group: group}, nil
+}
+
+type ListGroupsRequest struct {
+	PageSize  int `validate:"gte=0,lte=100"`
+	PageToken string
+}
+
+type ListGroupsResponse struct {
+	Groups        entity.Groups
+	NextPageToken string
+}
+
+func (u *UsecaseImpl) ListGroups(ctx context.Context, req *ListGroupsRequest) (_ *ListGroupsResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.ListGroups")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	page, err := newIDPage("", req.PageSize, req.PageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// database
+	groups, err := u.db.Group.List(ctx, &repository.ListGroupsParams{After: page.After, Limit: page.limit()})
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	resp := &ListGroupsResponse{Groups: groups}
+	if page.more(len(groups)) {
+		resp.Groups = groups[:page.size]
+		if resp.NextPageToken, err = page.next(resp.Groups[page.size-1].ID); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+type UpdateGroupRequest struct {
+	Group *entity.Group `validate:"required"`
+}
+
+type UpdateGroupResponse struct {
+	Group *entity.Group
+}
+
+func (u *UsecaseImpl) UpdateGroup(ctx context.Context, req *UpdateGroupRequest) (_ *UpdateGroupResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.UpdateGroup")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	if err := u.authorizeGroup(ctx, req.Group.ID); err != nil {
+		return nil, errcode.New(err)
+	}
+
+	// database
+	group, err := u.db.Group.Update(ctx, req.Group.ID, func(group *entity.Group) bool {
+		if group.Name == req.Group.Name && group.Description == req.Group.Description {
+			return false
+		}
+		group.Name = req.Group.Name
+		group.Description = req.Group.Description
+		return true
+	})
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &UpdateGroupResponse{Group: group}, nil
+}
+
+type DeleteGroupRequest struct {
+	ID string `validate:"required"`
+}
+
+// DeleteGroup deletes the group with its members.
+func (u *UsecaseImpl) DeleteGroup(ctx context.Context, req *DeleteGroupRequest) (err error) {
+	ctx, span := trace.Start(ctx, "usecase.DeleteGroup")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return errcode.New(err)
+	}
+	if err := u.authorizeGroup(ctx, req.ID); err != nil {
+		return errcode.New(err)
+	}
+
+	// database
+	if err := u.db.Group.Delete(ctx, req.ID); err != nil {
+		return errcode.New(err)
+	}
+	return nil
+}
+
+type AddMemberRequest struct {
+	Member *entity.GroupMember `validate:"required"`
+}
+
+type AddMemberResponse struct {
+	Member *entity.GroupMember
+}
+
+// AddMember adds an existing user to the group. Other users are a
+// FailedPrecondition. Only owners add owners and admins.
+func (u *UsecaseImpl) AddMember(ctx context.Context, req *AddMemberRequest) (_ *AddMemberResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.AddMember")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	caller, err := u.authorizeGroupMember(ctx, req.Member.GroupID)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	if err := authorizeRole(caller, req.Member.Role); err != nil {
+		return nil, errcode.New(err)
+	}
+
+	// database
+	member, err := u.db.Group.AddMember(ctx, req.Member)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &AddMemberResponse{Member: member}, nil
+}
+
+type RemoveMemberRequest struct {
+	GroupID string `validate:"required"`
+	UserID  string `validate:"required"`
+}
+
+// RemoveMember removes the user from the group. Only owners remove owners and
+// admins, and the last owner stays.
+func (u *UsecaseImpl) RemoveMember(ctx context.Context, req *RemoveMemberRequest) (err error) {
+	ctx, span := trace.Start(ctx, "usecase.RemoveMember")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return errcode.New(err)
+	}
+	caller, err := u.authorizeGroupMember(ctx, req.GroupID)
+	if err != nil {
+		return errcode.New(err)
+	}
+
+	// database
+	if caller != nil {
+		member, err := u.db.Group.GetMember(ctx, req.GroupID, req.UserID)
+		if err != nil {
+			return errcode.New(err)
+		}
+		if err := authorizeRole(caller, member.Role); err != nil {
+			return errcode.New(err)
+		}
+	}
+	if err := u.db.Group.RemoveMember(ctx, req.GroupID, req.UserID); err != nil {
+		return errcode.New(err)
+	}
+	return nil
+}
+
+type ListMembersRequest struct {
+	GroupID   string `validate:"required"`
+	PageSize  int    `validate:"gte=0,lte=100"`
+	PageToken string
+}
+
+type ListMembersResponse struct {
+	Members       entity.GroupMembers
+	NextPageToken string
+}
+
+func (u *UsecaseImpl) ListMembers(ctx context.Context, req *ListMembersRequest) (_ *ListMembersResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.ListMembers")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	if err := u.authorizeGroup(ctx, req.GroupID); err != nil {
+		return nil, errcode.New(err)
+	}
+	page, err := newIDPage(req.GroupID, req.PageSize, req.PageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// database
+	members, err := u.db.Group.ListMembers(ctx, &repository.ListGroupMembersParams{GroupID: req.GroupID, After: page.After, Limit: page.limit()})
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	resp := &ListMembersResponse{Members: members}
+	if page.more(len(members)) {
+		resp.Members = members[:page.size]
+		if resp.NextPageToken, err = page.next(resp.Members[page.size-1].UserID); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+type ListUserGroupsRequest struct {
+	UserID    string `validate:"required"`
+	PageSize  int    `validate:"gte=0,lte=100"`
+	PageToken string
+}
+
+type ListUserGroupsResponse struct {
+	Groups        []*repository.UserGroup
+	NextPageToken string
+}
+
+func (u *UsecaseImpl) ListUserGroups(ctx context.Context, req *ListUserGroupsRequest) (_ *ListUserGroupsResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.ListUserGroups")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	if err := u.authorize(ctx, req.UserID); err != nil {
+		return nil, errcode.New(err)
+	}
+	page, err := newIDPage(req.UserID, req.PageSize, req.PageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// database
+	groups, err := u.db.Group.ListUserGroups(ctx, &repository.ListUserGroupsParams{UserID: req.UserID, After: page.After, Limit: page.limit()})
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	resp := &ListUserGroupsResponse{Groups: groups}
+	if page.more(len(groups)) {
+		resp.Groups = groups[:page.size]
+		if resp.NextPageToken, err = page.next(resp.Groups[page.size-1].Group.ID); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// authorizeGroup checks that the caller may act on the group, looking up its
+// membership only for callers restricted to their own groups.
+func (u *UsecaseImpl) authorizeGroup(ctx context.Context, groupID string) error {
+	_, err := u.authorizeGroupMember(ctx, groupID)
+	return err
+}
+
+// authorizeGroupMember is authorizeGroup that also returns the membership of
+// the callers restricted to their own groups. It is nil for the others.
+func (u *UsecaseImpl) authorizeGroupMember(ctx context.Context, groupID string) (*entity.GroupMember, error) {
+	if u.authorizer == nil {
+		return nil, nil
+	}
+	var caller *entity.GroupMember
+	err := u.authorizer.AuthorizeGroup(ctx, func(subject string) (string, error) {
+		member, err := u.db.Group.GetMember(ctx, groupID, subject)
+		if errcode.IsNotfound(err) {
+			return "", nil
+		}
+		if err != nil {
+			return "", errcode.New(err)
+		}
+		caller = member
+		return member.Role.PolicyName(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return caller, nil
+}
+
+// authorizeRole checks that caller, as returned by authorizeGroupMember, may
+// grant or take away role: only owners may for the owners and the admins.
+func authorizeRole(caller *entity.GroupMember, role entity.GroupRole) error {
+	if caller == nil || caller.Role == entity.GroupRoleOwner || role < entity.GroupRoleAdmin {
+		return nil
+	}
+	return errcode.NewForbidden("only owners of group %s may add or remove its %s members", caller.GroupID, role)
+}
+
+// idPage is the page token of the lists paged by ID. After is the last ID of
+// the previous page, and Parent the group or user of the list so that a token
+// is not reused for another one.
+type idPage struct {
+	Parent string `json:"p,omitempty"`
+	After  string `json:"a"`
+	size   int
+}
+
+func newIDPage(parent string, pageSize int, token string) (*idPage, error) {
+	page := &idPage{Parent: parent, size: pageSize}
+	if page.size == 0 {
+		page.size = defaultPageSize
+	}
+	if token == "" {
+		return page, nil
+	}
+	if err := pagetoken.Decode(token, page); err != nil {
+		return nil, err
+	}
+	if page.Parent != parent || page.After == "" {
+		return nil, errcode.NewInvalidArgument("page token does not belong to this list")
+	}
+	return page, nil
+}
+
+// limit fetches one more row, which tells whether there is a next page.
+func (p *idPage) limit() int {
+	return p.size + 1
+}
+
+func (p *idPage) more(n int) bool {
+	return n > p.size
+}
+
+// next returns the token of the page after lastID.
+func (p *idPage) next(lastID string) (string, error) {
+	return pagetoken.Encode(&idPage{Parent: p.Parent, After: lastID})
+}
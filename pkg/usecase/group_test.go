@@ -0,0 +1,164 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/memory"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/repositorytest"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsecaseImpl_Group(t *testing.T) {
+	u := newUsecase(t)
+	ctx := repositorytest.TenantContext(t)
+	for _, id := range []string{"owner", "member"} {
+		_, err := u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: id, Name: id}})
+		require.NoError(t, err)
+	}
+
+	created, err := u.CreateGroup(ctx, &CreateGroupRequest{Group: &entity.Group{ID: "team", Name: "team"}, OwnerID: "owner"})
+	require.NoError(t, err)
+	require.Equal(t, "team", created.Group.Name)
+	_, err = u.CreateGroup(ctx, &CreateGroupRequest{Group: &entity.Group{ID: "no-name"}})
+	require.True(t, errcode.IsInvalidArgument(err), err)
+	_, err = u.CreateGroup(ctx, &CreateGroupRequest{Group: &entity.Group{ID: "orphan", Name: "orphan"}, OwnerID: "missing"})
+	require.True(t, errcode.IsFailedPrecondition(err), err)
+
+	updated, err := u.UpdateGroup(ctx, &UpdateGroupRequest{Group: &entity.Group{ID: "team", Name: "renamed", Description: "description"}})
+	require.NoError(t, err)
+	require.Equal(t, "renamed", updated.Group.Name)
+	got, err := u.GetGroup(ctx, &GetGroupRequest{ID: "team"})
+	require.NoError(t, err)
+	require.Equal(t, updated.Group, got.Group)
+
+	added, err := u.AddMember(ctx, &AddMemberRequest{Member: &entity.GroupMember{GroupID: "team", UserID: "member"}})
+	require.NoError(t, err)
+	require.Equal(t, entity.GroupRoleMember, added.Member.Role)
+	_, err = u.AddMember(ctx, &AddMemberRequest{Member: &entity.GroupMember{GroupID: "team", UserID: "missing"}})
+	require.True(t, errcode.IsFailedPrecondition(err), err)
+	_, err = u.AddMember(ctx, &AddMemberRequest{Member: &entity.GroupMember{GroupID: "team", UserID: "member", Role: 3}})
+	require.True(t, errcode.IsInvalidArgument(err), err)
+
+	members, err := u.ListMembers(ctx, &ListMembersRequest{GroupID: "team"})
+	require.NoError(t, err)
+	require.Len(t, members.Members, 2)
+	require.Equal(t, "member", members.Members[0].UserID)
+	require.Equal(t, "owner", members.Members[1].UserID)
+	require.Equal(t, entity.GroupRoleOwner, members.Members[1].Role)
+	groups, err := u.ListUserGroups(ctx, &ListUserGroupsRequest{UserID: "member"})
+	require.NoError(t, err)
+	require.Len(t, groups.Groups, 1)
+	require.Equal(t, "renamed", groups.Groups[0].Group.Name)
+
+	require.NoError(t, u.RemoveMember(ctx, &RemoveMemberRequest{GroupID: "team", UserID: "member"}))
+	require.True(t, errcode.IsNotfound(u.RemoveMember(ctx, &RemoveMemberRequest{GroupID: "team", UserID: "member"})))
+	err = u.RemoveMember(ctx, &RemoveMemberRequest{GroupID: "team", UserID: "owner"})
+	require.True(t, errcode.IsFailedPrecondition(err), err)
+	require.NoError(t, u.DeleteGroup(ctx, &DeleteGroupRequest{ID: "team"}))
+	_, err = u.GetGroup(ctx, &GetGroupRequest{ID: "team"})
+	require.True(t, errcode.IsNotfound(err), err)
+}
+
+func TestUsecaseImpl_GroupPages(t *testing.T) {
+	u := newUsecase(t)
+	ctx := repositorytest.TenantContext(t)
+	_, err := u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: "user", Name: "user"}})
+	require.NoError(t, err)
+	for _, id := range []string{"c", "a", "e", "b", "d"} {
+		_, err := u.CreateGroup(ctx, &CreateGroupRequest{Group: &entity.Group{ID: id, Name: id}, OwnerID: "user"})
+		require.NoError(t, err)
+	}
+
+	// pages until the token is empty
+	var ids []string
+	req := &ListGroupsRequest{PageSize: 2}
+	for {
+		resp, err := u.ListGroups(ctx, req)
+		require.NoError(t, err)
+		for _, g := range resp.Groups {
+			ids = append(ids, g.ID)
+		}
+		if resp.NextPageToken == "" {
+			break
+		}
+		req.PageToken = resp.NextPageToken
+	}
+	require.Equal(t, []string{"a", "b", "c", "d", "e"}, ids)
+
+	first, err := u.ListUserGroups(ctx, &ListUserGroupsRequest{UserID: "user", PageSize: 3})
+	require.NoError(t, err)
+	require.Len(t, first.Groups, 3)
+	next, err := u.ListUserGroups(ctx, &ListUserGroupsRequest{UserID: "user", PageSize: 3, PageToken: first.NextPageToken})
+	require.NoError(t, err)
+	require.Len(t, next.Groups, 2)
+	require.Equal(t, "d", next.Groups[0].Group.ID)
+	require.Empty(t, next.NextPageToken)
+
+	// a token belongs to the list it came from
+	_, err = u.ListMembers(ctx, &ListMembersRequest{GroupID: "a", PageToken: first.NextPageToken})
+	require.True(t, errcode.IsInvalidArgument(err), err)
+	_, err = u.ListGroups(ctx, &ListGroupsRequest{PageToken: "!"})
+	require.True(t, errcode.IsInvalidArgument(err), err)
+}
+
+func TestUsecaseImpl_GroupAuthorizer(t *testing.T) {
+	users := memory.NewUser()
+	u := New(&Config{
+		DB:         &repository.Database{User: users, Group: memory.NewGroup(users)},
+		Authorizer: &ownerAuthorizer{owner: "me"},
+	})
+	ctx := repositorytest.TenantContext(t)
+	_, err := users.Create(ctx, &entity.User{ID: "me", Name: "me"})
+	require.NoError(t, err)
+	_, err = users.Create(ctx, &entity.User{ID: "other", Name: "other"})
+	require.NoError(t, err)
+
+	// groups are created for their owners
+	_, err = u.CreateGroup(ctx, &CreateGroupRequest{Group: &entity.Group{ID: "mine", Name: "mine"}, OwnerID: "me"})
+	require.NoError(t, err)
+	_, err = u.CreateGroup(ctx, &CreateGroupRequest{Group: &entity.Group{ID: "theirs", Name: "theirs"}, OwnerID: "other"})
+	require.True(t, errcode.IsForbidden(err), err)
+	_, err = users.Create(ctx, &entity.User{ID: "third", Name: "third"})
+	require.NoError(t, err)
+	_, err = memory.NewGroup(users).Create(ctx, &entity.Group{ID: "theirs", Name: "theirs"}, "other")
+	require.NoError(t, err)
+
+	_, err = u.AddMember(ctx, &AddMemberRequest{Member: &entity.GroupMember{GroupID: "mine", UserID: "other"}})
+	require.NoError(t, err)
+	_, err = u.AddMember(ctx, &AddMemberRequest{Member: &entity.GroupMember{GroupID: "theirs", UserID: "third"}})
+	require.True(t, errcode.IsForbidden(err), err)
+	_, err = u.GetGroup(ctx, &GetGroupRequest{ID: "theirs"})
+	require.True(t, errcode.IsForbidden(err), err)
+	require.True(t, errcode.IsForbidden(u.DeleteGroup(ctx, &DeleteGroupRequest{ID: "theirs"})))
+
+	// members are not administrators
+	_, err = memory.NewGroup(users).AddMember(ctx, &entity.GroupMember{GroupID: "theirs", UserID: "me"})
+	require.NoError(t, err)
+	_, err = u.ListMembers(ctx, &ListMembersRequest{GroupID: "theirs"})
+	require.True(t, errcode.IsForbidden(err), err)
+	_, err = u.ListUserGroups(ctx, &ListUserGroupsRequest{UserID: "other"})
+	require.True(t, errcode.IsForbidden(err), err)
+
+	// only owners add and remove owners and admins
+	require.NoError(t, memory.NewGroup(users).RemoveMember(ctx, "theirs", "me"))
+	_, err = memory.NewGroup(users).AddMember(ctx, &entity.GroupMember{GroupID: "theirs", UserID: "me", Role: entity.GroupRoleAdmin})
+	require.NoError(t, err)
+	for _, role := range []entity.GroupRole{entity.GroupRoleAdmin, entity.GroupRoleOwner} {
+		_, err = u.AddMember(ctx, &AddMemberRequest{Member: &entity.GroupMember{GroupID: "theirs", UserID: "third", Role: role}})
+		require.True(t, errcode.IsForbidden(err), err)
+	}
+	require.True(t, errcode.IsForbidden(u.RemoveMember(ctx, &RemoveMemberRequest{GroupID: "theirs", UserID: "other"})))
+	_, err = u.AddMember(ctx, &AddMemberRequest{Member: &entity.GroupMember{GroupID: "theirs", UserID: "third"}})
+	require.NoError(t, err)
+	require.NoError(t, u.RemoveMember(ctx, &RemoveMemberRequest{GroupID: "theirs", UserID: "third"}))
+
+	_, err = u.AddMember(ctx, &AddMemberRequest{Member: &entity.GroupMember{GroupID: "mine", UserID: "third", Role: entity.GroupRoleAdmin}})
+	require.NoError(t, err)
+	require.NoError(t, u.RemoveMember(ctx, &RemoveMemberRequest{GroupID: "mine", UserID: "third"}))
+	// and the last owner stays
+	err = u.RemoveMember(ctx, &RemoveMemberRequest{GroupID: "mine", UserID: "me"})
+	require.True(t, errcode.IsFailedPrecondition(err), err)
+}
@@ -0,0 +1,233 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/metrics"
+)
+
+var _ Usecase = (*metricsUsecase)(nil)
+
+// metricsUsecase counts the calls of each usecase by error code.
+type metricsUsecase struct {
+	next Usecase
+	m    *metrics.Metrics
+}
+
+func WithMetrics(next Usecase, m *metrics.Metrics) Usecase {
+	return &metricsUsecase{next: next, m: m}
+}
+
+func (u *metricsUsecase) observe(usecase string, err error) {
+	u.m.UsecaseCalls.WithLabelValues(usecase, metrics.Code(err)).Inc()
+}
+
+func (u *metricsUsecase) CreateUser(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
+	resp, err := u.next.CreateUser(ctx, req)
+	u.observe("CreateUser", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) GetUser(ctx context.Context, req *GetUserRequest) (*GetUserResponse, error) {
+	resp, err := u.next.GetUser(ctx, req)
+	u.observe("GetUser", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) ListUsers(ctx context.Context, req *ListUsersRequest) (*ListUsersResponse, error) {
+	resp, err := u.next.ListUsers(ctx, req)
+	u.observe("ListUsers", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) UpdateUser(ctx context.Context, req *UpdateUserRequest) (*UpdateUserResponse, error) {
+	resp, err := u.next.UpdateUser(ctx, req)
+	u.observe("UpdateUser", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) DeleteUser(ctx context.Context, req *DeleteUserRequest) error {
+	err := u.next.DeleteUser(ctx, req)
+	u.observe("DeleteUser", err)
+	return err
+}
+
+func (u *metricsUsecase) SearchUsers(ctx context.Context, req *SearchUsersRequest) (*SearchUsersResponse, error) {
+	resp, err := u.next.SearchUsers(ctx, req)
+	u.observe("SearchUsers", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) GetUserStats(ctx context.Context, req *GetUserStatsRequest) (*GetUserStatsResponse, error) {
+	resp, err := u.next.GetUserStats(ctx, req)
+	u.observe("GetUserStats", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) SetPassword(ctx context.Context, req *SetPasswordRequest) (*SetPasswordResponse, error) {
+	resp, err := u.next.SetPassword(ctx, req)
+	u.observe("SetPassword", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) VerifyPassword(ctx context.Context, req *VerifyPasswordRequest) (*VerifyPasswordResponse, error) {
+	resp, err := u.next.VerifyPassword(ctx, req)
+	u.observe("VerifyPassword", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) Login(ctx context.Context, req *LoginRequest) (*LoginResponse, error) {
+	resp, err := u.next.Login(ctx, req)
+	u.observe("Login", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) CreateSession(ctx context.Context, req *CreateSessionRequest) (*CreateSessionResponse, error) {
+	resp, err := u.next.CreateSession(ctx, req)
+	u.observe("CreateSession", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) RefreshSession(ctx context.Context, req *RefreshSessionRequest) (*RefreshSessionResponse, error) {
+	resp, err := u.next.RefreshSession(ctx, req)
+	u.observe("RefreshSession", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) ListSessions(ctx context.Context, req *ListSessionsRequest) (*ListSessionsResponse, error) {
+	resp, err := u.next.ListSessions(ctx, req)
+	u.observe("ListSessions", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) RevokeSession(ctx context.Context, req *RevokeSessionRequest) (*RevokeSessionResponse, error) {
+	resp, err := u.next.RevokeSession(ctx, req)
+	u.observe("RevokeSession", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) SendVerificationEmail(ctx context.Context, req *SendVerificationEmailRequest) (*SendVerificationEmailResponse, error) {
+	resp, err := u.next.SendVerificationEmail(ctx, req)
+	u.observe("SendVerificationEmail", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) VerifyEmail(ctx context.Context, req *VerifyEmailRequest) (*VerifyEmailResponse, error) {
+	resp, err := u.next.VerifyEmail(ctx, req)
+	u.observe("VerifyEmail", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) ActivateUser(ctx context.Context, req *ActivateUserRequest) (*ActivateUserResponse, error) {
+	resp, err := u.next.ActivateUser(ctx, req)
+	u.observe("ActivateUser", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) SuspendUser(ctx context.Context, req *SuspendUserRequest) (*SuspendUserResponse, error) {
+	resp, err := u.next.SuspendUser(ctx, req)
+	u.observe("SuspendUser", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) DeactivateUser(ctx context.Context, req *DeactivateUserRequest) (*DeactivateUserResponse, error) {
+	resp, err := u.next.DeactivateUser(ctx, req)
+	u.observe("DeactivateUser", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) CreateGroup(ctx context.Context, req *CreateGroupRequest) (*CreateGroupResponse, error) {
+	resp, err := u.next.CreateGroup(ctx, req)
+	u.observe("CreateGroup", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) GetGroup(ctx context.Context, req *GetGroupRequest) (*GetGroupResponse, error) {
+	resp, err := u.next.GetGroup(ctx, req)
+	u.observe("GetGroup", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) ListGroups(ctx context.Context, req *ListGroupsRequest) (*ListGroupsResponse, error) {
+	resp, err := u.next.ListGroups(ctx, req)
+	u.observe("ListGroups", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) UpdateGroup(ctx context.Context, req *UpdateGroupRequest) (*UpdateGroupResponse, error) {
+	resp, err := u.next.UpdateGroup(ctx, req)
+	u.observe("UpdateGroup", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) DeleteGroup(ctx context.Context, req *DeleteGroupRequest) error {
+	err := u.next.DeleteGroup(ctx, req)
+	u.observe("DeleteGroup", err)
+	return err
+}
+
+func (u *metricsUsecase) AddMember(ctx context.Context, req *AddMemberRequest) (*AddMemberResponse, error) {
+	resp, err := u.next.AddMember(ctx, req)
+	u.observe("AddMember", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) RemoveMember(ctx context.Context, req *RemoveMemberRequest) error {
+	err := u.next.RemoveMember(ctx, req)
+	u.observe("RemoveMember", err)
+	return err
+}
+
+func (u *metricsUsecase) ListMembers(ctx context.Context, req *ListMembersRequest) (*ListMembersResponse, error) {
+	resp, err := u.next.ListMembers(ctx, req)
+	u.observe("ListMembers", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) ListUserGroups(ctx context.Context, req *ListUserGroupsRequest) (*ListUserGroupsResponse, error) {
+	resp, err := u.next.ListUserGroups(ctx, req)
+	u.observe("ListUserGroups", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) Follow(ctx context.Context, req *FollowRequest) (*FollowResponse, error) {
+	resp, err := u.next.Follow(ctx, req)
+	u.observe("Follow", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) Unfollow(ctx context.Context, req *UnfollowRequest) error {
+	err := u.next.Unfollow(ctx, req)
+	u.observe("Unfollow", err)
+	return err
+}
+
+func (u *metricsUsecase) Block(ctx context.Context, req *BlockRequest) (*BlockResponse, error) {
+	resp, err := u.next.Block(ctx, req)
+	u.observe("Block", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) Unblock(ctx context.Context, req *UnblockRequest) error {
+	err := u.next.Unblock(ctx, req)
+	u.observe("Unblock", err)
+	return err
+}
+
+func (u *metricsUsecase) ListFollowers(ctx context.Context, req *ListRelationshipsRequest) (*ListRelationshipsResponse, error) {
+	resp, err := u.next.ListFollowers(ctx, req)
+	u.observe("ListFollowers", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) ListFollowing(ctx context.Context, req *ListRelationshipsRequest) (*ListRelationshipsResponse, error) {
+	resp, err := u.next.ListFollowing(ctx, req)
+	u.observe("ListFollowing", err)
+	return resp, err
+}
+
+func (u *metricsUsecase) ListMutualFollows(ctx context.Context, req *ListRelationshipsRequest) (*ListRelationshipsResponse, error) {
+	resp, err := u.next.ListMutualFollows(ctx, req)
+	u.observe("ListMutualFollows", err)
+	return resp, err
+}
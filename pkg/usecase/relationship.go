@@ -0,0 +1,175 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/trace"
+)
+
+type FollowRequest struct {
+	UserID   string `validate:"required"`
+	TargetID string `validate:"required,nefield=UserID"`
+}
+
+type FollowResponse struct {
+	Relationship *entity.Relationship
+}
+
+func (u *UsecaseImpl) Follow(ctx context.Context, req *FollowRequest) (_ *FollowResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.Follow")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	if err := u.authorize(ctx, req.UserID); err != nil {
+		return nil, errcode.New(err)
+	}
+
+	// database
+	rel, err := u.db.Relationship.Follow(ctx, req.UserID, req.TargetID)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &FollowResponse{Relationship: rel}, nil
+}
+
+type UnfollowRequest struct {
+	UserID   string `validate:"required"`
+	TargetID string `validate:"required"`
+}
+
+func (u *UsecaseImpl) Unfollow(ctx context.Context, req *UnfollowRequest) (err error) {
+	ctx, span := trace.Start(ctx, "usecase.Unfollow")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return errcode.New(err)
+	}
+	if err := u.authorize(ctx, req.UserID); err != nil {
+		return errcode.New(err)
+	}
+
+	// database
+	if err := u.db.Relationship.Unfollow(ctx, req.UserID, req.TargetID); err != nil {
+		return errcode.New(err)
+	}
+	return nil
+}
+
+type BlockRequest struct {
+	UserID   string `validate:"required"`
+	TargetID string `validate:"required,nefield=UserID"`
+}
+
+type BlockResponse struct {
+	Relationship *entity.Relationship
+}
+
+func (u *UsecaseImpl) Block(ctx context.Context, req *BlockRequest) (_ *BlockResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.Block")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	if err := u.authorize(ctx, req.UserID); err != nil {
+		return nil, errcode.New(err)
+	}
+
+	// database
+	rel, err := u.db.Relationship.Block(ctx, req.UserID, req.TargetID)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &BlockResponse{Relationship: rel}, nil
+}
+
+type UnblockRequest struct {
+	UserID   string `validate:"required"`
+	TargetID string `validate:"required"`
+}
+
+func (u *UsecaseImpl) Unblock(ctx context.Context, req *UnblockRequest) (err error) {
+	ctx, span := trace.Start(ctx, "usecase.Unblock")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return errcode.New(err)
+	}
+	if err := u.authorize(ctx, req.UserID); err != nil {
+		return errcode.New(err)
+	}
+
+	// database
+	if err := u.db.Relationship.Unblock(ctx, req.UserID, req.TargetID); err != nil {
+		return errcode.New(err)
+	}
+	return nil
+}
+
+// ListRelationshipsRequest is the request of ListFollowers, ListFollowing
+// and ListMutualFollows. Their page tokens are not interchangeable.
+type ListRelationshipsRequest struct {
+	UserID    string `validate:"required"`
+	PageSize  int    `validate:"gte=0,lte=100"`
+	PageToken string
+}
+
+type ListRelationshipsResponse struct {
+	Relationships entity.Relationships
+	NextPageToken string
+}
+
+func (u *UsecaseImpl) ListFollowers(ctx context.Context, req *ListRelationshipsRequest) (_ *ListRelationshipsResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.ListFollowers")
+	defer func() { trace.End(span, err) }()
+
+	return u.listRelationships(ctx, "followers", req, u.db.Relationship.ListFollowers, func(rel *entity.Relationship) string { return rel.UserID })
+}
+
+func (u *UsecaseImpl) ListFollowing(ctx context.Context, req *ListRelationshipsRequest) (_ *ListRelationshipsResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.ListFollowing")
+	defer func() { trace.End(span, err) }()
+
+	return u.listRelationships(ctx, "following", req, u.db.Relationship.ListFollowing, func(rel *entity.Relationship) string { return rel.TargetID })
+}
+
+func (u *UsecaseImpl) ListMutualFollows(ctx context.Context, req *ListRelationshipsRequest) (_ *ListRelationshipsResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.ListMutualFollows")
+	defer func() { trace.End(span, err) }()
+
+	return u.listRelationships(ctx, "mutual", req, u.db.Relationship.ListMutualFollows, func(rel *entity.Relationship) string { return rel.TargetID })
+}
+
+// listRelationships pages the relationships of list by otherID, the ID of the
+// other user of each. kind keeps the page tokens of the lists apart.
+func (u *UsecaseImpl) listRelationships(ctx context.Context, kind string, req *ListRelationshipsRequest, list func(context.Context, *repository.ListRelationshipsParams) (entity.Relationships, error), otherID func(*entity.Relationship) string) (*ListRelationshipsResponse, error) {
+	if err := u.validateStruct(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	if err := u.authorize(ctx, req.UserID); err != nil {
+		return nil, errcode.New(err)
+	}
+	page, err := newIDPage(kind+"/"+req.UserID, req.PageSize, req.PageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// database
+	rels, err := list(ctx, &repository.ListRelationshipsParams{UserID: req.UserID, After: page.After, Limit: page.limit()})
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	resp := &ListRelationshipsResponse{Relationships: rels}
+	if page.more(len(rels)) {
+		resp.Relationships = rels[:page.size]
+		if resp.NextPageToken, err = page.next(otherID(resp.Relationships[page.size-1])); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
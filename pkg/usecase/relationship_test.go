@@ -0,0 +1,129 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/memory"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/repositorytest"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsecaseImpl_Relationship(t *testing.T) {
+	u := newUsecase(t)
+	ctx := repositorytest.TenantContext(t)
+	for _, id := range []string{"a", "b", "c"} {
+		_, err := u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: id, Name: id}})
+		require.NoError(t, err)
+	}
+
+	followed, err := u.Follow(ctx, &FollowRequest{UserID: "a", TargetID: "b"})
+	require.NoError(t, err)
+	require.Equal(t, entity.RelationshipKindFollow, followed.Relationship.Kind)
+	_, err = u.Follow(ctx, &FollowRequest{UserID: "a", TargetID: "a"})
+	require.True(t, errcode.IsInvalidArgument(err), err)
+	_, err = u.Follow(ctx, &FollowRequest{UserID: "a", TargetID: "missing"})
+	require.True(t, errcode.IsFailedPrecondition(err), err)
+	_, err = u.Follow(ctx, &FollowRequest{UserID: "b", TargetID: "a"})
+	require.NoError(t, err)
+	_, err = u.Follow(ctx, &FollowRequest{UserID: "c", TargetID: "a"})
+	require.NoError(t, err)
+
+	followers, err := u.ListFollowers(ctx, &ListRelationshipsRequest{UserID: "a"})
+	require.NoError(t, err)
+	require.Len(t, followers.Relationships, 2)
+	require.Equal(t, "b", followers.Relationships[0].UserID)
+	require.Equal(t, "c", followers.Relationships[1].UserID)
+	mutual, err := u.ListMutualFollows(ctx, &ListRelationshipsRequest{UserID: "a"})
+	require.NoError(t, err)
+	require.Len(t, mutual.Relationships, 1)
+	require.Equal(t, "b", mutual.Relationships[0].TargetID)
+
+	// a block ends the follows and keeps them from coming back
+	blocked, err := u.Block(ctx, &BlockRequest{UserID: "a", TargetID: "c"})
+	require.NoError(t, err)
+	require.Equal(t, entity.RelationshipKindBlock, blocked.Relationship.Kind)
+	_, err = u.Follow(ctx, &FollowRequest{UserID: "c", TargetID: "a"})
+	require.True(t, errcode.IsFailedPrecondition(err), err)
+	followers, err = u.ListFollowers(ctx, &ListRelationshipsRequest{UserID: "a"})
+	require.NoError(t, err)
+	require.Len(t, followers.Relationships, 1)
+	require.NoError(t, u.Unblock(ctx, &UnblockRequest{UserID: "a", TargetID: "c"}))
+	require.True(t, errcode.IsNotfound(u.Unblock(ctx, &UnblockRequest{UserID: "a", TargetID: "c"})))
+
+	require.NoError(t, u.Unfollow(ctx, &UnfollowRequest{UserID: "a", TargetID: "b"}))
+	following, err := u.ListFollowing(ctx, &ListRelationshipsRequest{UserID: "a"})
+	require.NoError(t, err)
+	require.Empty(t, following.Relationships)
+	_, err = u.ListFollowing(ctx, &ListRelationshipsRequest{UserID: "missing"})
+	require.True(t, errcode.IsNotfound(err), err)
+}
+
+func TestUsecaseImpl_RelationshipPages(t *testing.T) {
+	u := newUsecase(t)
+	ctx := repositorytest.TenantContext(t)
+	ids := []string{"user", "c", "a", "e", "b", "d"}
+	for _, id := range ids {
+		_, err := u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: id, Name: id}})
+		require.NoError(t, err)
+	}
+	for _, id := range ids[1:] {
+		_, err := u.Follow(ctx, &FollowRequest{UserID: "user", TargetID: id})
+		require.NoError(t, err)
+	}
+
+	// pages until the token is empty
+	var targets []string
+	req := &ListRelationshipsRequest{UserID: "user", PageSize: 2}
+	for {
+		resp, err := u.ListFollowing(ctx, req)
+		require.NoError(t, err)
+		for _, rel := range resp.Relationships {
+			targets = append(targets, rel.TargetID)
+		}
+		if resp.NextPageToken == "" {
+			break
+		}
+		req.PageToken = resp.NextPageToken
+	}
+	require.Equal(t, []string{"a", "b", "c", "d", "e"}, targets)
+
+	// a token belongs to the list it came from
+	first, err := u.ListFollowing(ctx, &ListRelationshipsRequest{UserID: "user", PageSize: 2})
+	require.NoError(t, err)
+	_, err = u.ListMutualFollows(ctx, &ListRelationshipsRequest{UserID: "user", PageToken: first.NextPageToken})
+	require.True(t, errcode.IsInvalidArgument(err), err)
+	_, err = u.ListFollowing(ctx, &ListRelationshipsRequest{UserID: "a", PageToken: first.NextPageToken})
+	require.True(t, errcode.IsInvalidArgument(err), err)
+	_, err = u.ListFollowers(ctx, &ListRelationshipsRequest{UserID: "user", PageSize: 101})
+	require.True(t, errcode.IsInvalidArgument(err), err)
+}
+
+func TestUsecaseImpl_RelationshipAuthorizer(t *testing.T) {
+	users := memory.NewUser()
+	u := New(&Config{
+		DB:         &repository.Database{User: users, Relationship: memory.NewRelationship(users)},
+		Authorizer: &ownerAuthorizer{owner: "me"},
+	})
+	ctx := repositorytest.TenantContext(t)
+	for _, id := range []string{"me", "other"} {
+		_, err := users.Create(ctx, &entity.User{ID: id, Name: id})
+		require.NoError(t, err)
+	}
+
+	// users relate only themselves to others
+	_, err := u.Follow(ctx, &FollowRequest{UserID: "me", TargetID: "other"})
+	require.NoError(t, err)
+	_, err = u.Follow(ctx, &FollowRequest{UserID: "other", TargetID: "me"})
+	require.True(t, errcode.IsForbidden(err), err)
+	_, err = u.Block(ctx, &BlockRequest{UserID: "other", TargetID: "me"})
+	require.True(t, errcode.IsForbidden(err), err)
+	require.True(t, errcode.IsForbidden(u.Unfollow(ctx, &UnfollowRequest{UserID: "other", TargetID: "me"})))
+	_, err = u.ListFollowers(ctx, &ListRelationshipsRequest{UserID: "other"})
+	require.True(t, errcode.IsForbidden(err), err)
+	following, err := u.ListFollowing(ctx, &ListRelationshipsRequest{UserID: "me"})
+	require.NoError(t, err)
+	require.Len(t, following.Relationships, 1)
+}
@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/pagetoken"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/textsearch"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/trace"
+)
+
+const defaultPageSize = 20
+
+type SearchUsersRequest struct {
+	Query     string          `validate:"required,max=255"`
+	Mode      textsearch.Mode `validate:"gte=0,lte=2"`
+	PageSize  int             `validate:"gte=0,lte=100"`
+	PageToken string
+}
+
+type SearchUsersResponse struct {
+	Users         []*repository.ScoredUser
+	NextPageToken string
+}
+
+// searchPosition is the page token of SearchUsers. It records the search so
+// that a token is not reused for another one.
+type searchPosition struct {
+	Query  string          `json:"q"`
+	Mode   textsearch.Mode `json:"m"`
+	Offset int             `json:"o"`
+}
+
+func (u *UsecaseImpl) SearchUsers(ctx context.Context, req *SearchUsersRequest) (_ *SearchUsersResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.SearchUsers")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	query := textsearch.Normalize(req.Query)
+	if query == "" {
+		return nil, errcode.NewInvalidArgument("query is blank")
+	}
+	pageSize := req.PageSize
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+	pos := &searchPosition{Query: query, Mode: req.Mode}
+	if req.PageToken != "" {
+		token := &searchPosition{}
+		if err := pagetoken.Decode(req.PageToken, token); err != nil {
+			return nil, err
+		}
+		if token.Query != query || token.Mode != req.Mode || token.Offset < 0 {
+			return nil, errcode.NewInvalidArgument("page token does not belong to this search")
+		}
+		pos.Offset = token.Offset
+	}
+
+	// database
+	// one more user tells whether there is a next page
+	users, err := u.db.User.Search(ctx, &repository.SearchUsersParams{
+		Query:  query,
+		Mode:   req.Mode,
+		Offset: pos.Offset,
+		Limit:  pageSize + 1,
+	})
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	resp := &SearchUsersResponse{Users: users}
+	if len(users) > pageSize {
+		resp.Users = users[:pageSize]
+		pos.Offset += pageSize
+		if resp.NextPageToken, err = pagetoken.Encode(pos); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,214 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/auth"
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/log"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/requestid"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/trace"
+)
+
+const (
+	defaultSessionTTL = 30 * 24 * time.Hour
+
+	maxListSessions = 100
+)
+
+type CreateSessionRequest struct {
+	UserID string `validate:"required"`
+}
+
+type CreateSessionResponse struct {
+	Session *entity.Session
+	// AccessToken expires at ExpiresAt, long before the session. RefreshToken
+	// obtains the next one once.
+	AccessToken  string
+	ExpiresAt    time.Time
+	RefreshToken string
+}
+
+// CreateSession starts a session of the user, which lasts SessionTTL unless it
+// is revoked.
+func (u *UsecaseImpl) CreateSession(ctx context.Context, req *CreateSessionRequest) (_ *CreateSessionResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.CreateSession")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	if err := u.authorizeSelf(ctx, req.UserID); err != nil {
+		return nil, errcode.New(err)
+	}
+	if u.tokens == nil {
+		return nil, errcode.NewUnimplemented("sessions are not enabled")
+	}
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	refreshToken, hash, err := auth.NewToken()
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	// database
+	user, err := u.db.User.Get(repository.Latest(ctx), req.UserID)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	if err := checkSignIn(user); err != nil {
+		return nil, errcode.New(err)
+	}
+	now := u.now()
+	session, err := u.db.Session.Create(ctx, &entity.Session{
+		ID:          requestid.New(),
+		UserID:      req.UserID,
+		CreatedAt:   now,
+		RefreshedAt: now,
+		ExpiresAt:   now.Add(u.sessionTTL),
+	}, hash)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	accessToken, expiresAt, err := u.tokens.Issue(ctx, session.UserID, tenantID)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &CreateSessionResponse{Session: session, AccessToken: accessToken, ExpiresAt: expiresAt, RefreshToken: refreshToken}, nil
+}
+
+type RefreshSessionRequest struct {
+	RefreshToken string `validate:"required,max=128"`
+}
+
+type RefreshSessionResponse struct {
+	Session      *entity.Session
+	AccessToken  string
+	ExpiresAt    time.Time
+	RefreshToken string
+}
+
+// RefreshSession exchanges a refresh token for a new access token and the
+// next refresh token. A refresh token presented twice revokes its session,
+// since either the user or a thief holds a stolen copy.
+func (u *UsecaseImpl) RefreshSession(ctx context.Context, req *RefreshSessionRequest) (_ *RefreshSessionResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.RefreshSession")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	if u.tokens == nil {
+		return nil, errcode.NewUnimplemented("sessions are not enabled")
+	}
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	refreshToken, hash, err := auth.NewToken()
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	// database
+	now := u.now()
+	session, err := u.db.Session.Rotate(ctx, auth.HashToken(req.RefreshToken), hash, now)
+	if errcode.IsNotfound(err) {
+		return nil, errcode.NewUnauthenticated("invalid refresh token")
+	}
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	if !session.Active(now) {
+		if session.ReuseDetected {
+			log.Warn(ctx, "refresh token reused, session revoked", "session", session.ID, "user", session.UserID)
+		}
+		return nil, errcode.NewUnauthenticated("invalid refresh token")
+	}
+	// the sessions of users who may no longer log in end at their next refresh
+	user, err := u.db.User.Get(repository.Latest(ctx), session.UserID)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	if err := checkSignIn(user); err != nil {
+		if _, err := u.db.Session.Revoke(ctx, session.ID, now); err != nil {
+			return nil, errcode.New(err)
+		}
+		return nil, errcode.New(err)
+	}
+
+	accessToken, expiresAt, err := u.tokens.Issue(ctx, session.UserID, tenantID)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &RefreshSessionResponse{Session: session, AccessToken: accessToken, ExpiresAt: expiresAt, RefreshToken: refreshToken}, nil
+}
+
+type ListSessionsRequest struct {
+	UserID string `validate:"required"`
+}
+
+type ListSessionsResponse struct {
+	Sessions entity.Sessions
+}
+
+// ListSessions returns the latest sessions of the user, including the revoked
+// and expired ones that have not been swept yet.
+func (u *UsecaseImpl) ListSessions(ctx context.Context, req *ListSessionsRequest) (_ *ListSessionsResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.ListSessions")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	if err := u.authorize(ctx, req.UserID); err != nil {
+		return nil, errcode.New(err)
+	}
+
+	// database
+	sessions, err := u.db.Session.List(ctx, &repository.ListSessionsParams{UserID: req.UserID, Limit: maxListSessions})
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &ListSessionsResponse{Sessions: sessions}, nil
+}
+
+type RevokeSessionRequest struct {
+	ID string `validate:"required"`
+}
+
+type RevokeSessionResponse struct {
+	Session *entity.Session
+}
+
+// RevokeSession revokes the session so that it can no longer be refreshed.
+// The access tokens already issued stay valid until they expire.
+func (u *UsecaseImpl) RevokeSession(ctx context.Context, req *RevokeSessionRequest) (_ *RevokeSessionResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.RevokeSession")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+
+	// database
+	session, err := u.db.Session.Get(ctx, req.ID)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	if err := u.authorize(ctx, session.UserID); err != nil {
+		return nil, errcode.New(err)
+	}
+	session, err = u.db.Session.Revoke(ctx, req.ID, u.now())
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &RevokeSessionResponse{Session: session}, nil
+}
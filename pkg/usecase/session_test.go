@@ -0,0 +1,110 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/auth"
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/repositorytest"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsecaseImpl_Session(t *testing.T) {
+	u, verifier := newLoginUsecase(t)
+	ctx := auth.NewContext(repositorytest.TenantContext(t), &auth.Principal{Subject: "id"})
+	_, err := u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: "id", Name: "name"}})
+	require.NoError(t, err)
+
+	created, err := u.CreateSession(ctx, &CreateSessionRequest{UserID: "id"})
+	require.NoError(t, err)
+	require.Equal(t, "id", created.Session.UserID)
+	require.True(t, created.Session.ExpiresAt.After(time.Now().Add(29*24*time.Hour)))
+	require.NotEmpty(t, created.RefreshToken)
+	p, err := verifier.Authenticate(context.Background(), created.AccessToken)
+	require.NoError(t, err)
+	require.Equal(t, "id", p.Subject)
+
+	// each refresh token is good for one refresh
+	refreshed, err := u.RefreshSession(ctx, &RefreshSessionRequest{RefreshToken: created.RefreshToken})
+	require.NoError(t, err)
+	require.Equal(t, created.Session.ID, refreshed.Session.ID)
+	require.NotEqual(t, created.RefreshToken, refreshed.RefreshToken)
+	_, err = verifier.Authenticate(context.Background(), refreshed.AccessToken)
+	require.NoError(t, err)
+	refreshed, err = u.RefreshSession(ctx, &RefreshSessionRequest{RefreshToken: refreshed.RefreshToken})
+	require.NoError(t, err)
+
+	// reusing one revokes the session, so that the latest token fails too
+	_, err = u.RefreshSession(ctx, &RefreshSessionRequest{RefreshToken: created.RefreshToken})
+	require.True(t, errcode.IsUnauthenticated(err), err)
+	_, err = u.RefreshSession(ctx, &RefreshSessionRequest{RefreshToken: refreshed.RefreshToken})
+	require.True(t, errcode.IsUnauthenticated(err), err)
+
+	listed, err := u.ListSessions(ctx, &ListSessionsRequest{UserID: "id"})
+	require.NoError(t, err)
+	require.Len(t, listed.Sessions, 1)
+	require.True(t, listed.Sessions[0].ReuseDetected)
+	require.False(t, listed.Sessions[0].RevokedAt.IsZero())
+
+	// unknown tokens and other tenants fail alike
+	_, err = u.RefreshSession(ctx, &RefreshSessionRequest{RefreshToken: "unknown"})
+	require.True(t, errcode.IsUnauthenticated(err), err)
+	other, err := u.CreateSession(ctx, &CreateSessionRequest{UserID: "id"})
+	require.NoError(t, err)
+	_, err = u.RefreshSession(repositorytest.TenantContext(t), &RefreshSessionRequest{RefreshToken: other.RefreshToken})
+	require.True(t, errcode.IsUnauthenticated(err), err)
+
+	_, err = u.CreateSession(auth.NewContext(ctx, &auth.Principal{Subject: "missing"}), &CreateSessionRequest{UserID: "missing"})
+	require.True(t, errcode.IsNotfound(err), err)
+
+	// without an Authorizer, only the user may create its sessions
+	_, err = u.CreateSession(auth.NewContext(ctx, &auth.Principal{Subject: "other"}), &CreateSessionRequest{UserID: "id"})
+	require.True(t, errcode.IsForbidden(err), err)
+	_, err = u.CreateSession(repositorytest.TenantContext(t), &CreateSessionRequest{UserID: "id"})
+	require.True(t, errcode.IsForbidden(err), err)
+
+	// sessions are disabled without an issuer
+	u.tokens = nil
+	_, err = u.CreateSession(ctx, &CreateSessionRequest{UserID: "id"})
+	require.Equal(t, errcode.CodeUnimplemented, errcode.NewCode(err), err)
+}
+
+func TestUsecaseImpl_RevokeSession(t *testing.T) {
+	u, _ := newLoginUsecase(t)
+	ctx := auth.NewContext(repositorytest.TenantContext(t), &auth.Principal{Subject: "id"})
+	_, err := u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: "id", Name: "name"}})
+	require.NoError(t, err)
+	created, err := u.CreateSession(ctx, &CreateSessionRequest{UserID: "id"})
+	require.NoError(t, err)
+
+	revoked, err := u.RevokeSession(ctx, &RevokeSessionRequest{ID: created.Session.ID})
+	require.NoError(t, err)
+	require.False(t, revoked.Session.RevokedAt.IsZero())
+	require.False(t, revoked.Session.ReuseDetected)
+	_, err = u.RefreshSession(ctx, &RefreshSessionRequest{RefreshToken: created.RefreshToken})
+	require.True(t, errcode.IsUnauthenticated(err), err)
+
+	// revoking again is a no-op
+	again, err := u.RevokeSession(ctx, &RevokeSessionRequest{ID: created.Session.ID})
+	require.NoError(t, err)
+	require.Equal(t, revoked.Session, again.Session)
+
+	_, err = u.RevokeSession(ctx, &RevokeSessionRequest{ID: "missing"})
+	require.True(t, errcode.IsNotfound(err), err)
+}
+
+func TestUsecaseImpl_RefreshSessionExpired(t *testing.T) {
+	u, _ := newLoginUsecase(t)
+	ctx := auth.NewContext(repositorytest.TenantContext(t), &auth.Principal{Subject: "id"})
+	_, err := u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: "id", Name: "name"}})
+	require.NoError(t, err)
+	created, err := u.CreateSession(ctx, &CreateSessionRequest{UserID: "id"})
+	require.NoError(t, err)
+
+	u.now = func() time.Time { return created.Session.ExpiresAt }
+	_, err = u.RefreshSession(ctx, &RefreshSessionRequest{RefreshToken: created.RefreshToken})
+	require.True(t, errcode.IsUnauthenticated(err), err)
+}
@@ -0,0 +1,257 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/auth"
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/notify"
+	"github.com/SakataAtsuki/e-architecture/pkg/tenant"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/trace"
+)
+
+const defaultVerificationTTL = 24 * time.Hour
+
+// Notifier delivers messages to users. It is implemented by notify.Log and
+// notify.File.
+type Notifier interface {
+	Notify(ctx context.Context, m *notify.Message) error
+}
+
+type SendVerificationEmailRequest struct {
+	ID string `validate:"required"`
+}
+
+type SendVerificationEmailResponse struct {
+	ExpiresAt time.Time
+}
+
+// SendVerificationEmail notifies a pending user of a token verifying its
+// email, replacing any token sent before.
+func (u *UsecaseImpl) SendVerificationEmail(ctx context.Context, req *SendVerificationEmailRequest) (_ *SendVerificationEmailResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.SendVerificationEmail")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	if err := u.authorize(ctx, req.ID); err != nil {
+		return nil, errcode.New(err)
+	}
+	if u.notifier == nil {
+		return nil, errcode.NewUnimplemented("email verification is not enabled")
+	}
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	// database
+	user, err := u.db.User.Get(ctx, req.ID)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	if user.State != entity.UserStatePending {
+		return nil, errcode.NewFailedPrecondition("user %s is %s, not PENDING", user.ID, user.State)
+	}
+	if user.Email == "" {
+		return nil, errcode.NewFailedPrecondition("user %s has no email", user.ID)
+	}
+	token, hash, err := auth.NewToken()
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	now := u.now()
+	verification, err := u.db.Verification.Create(ctx, &entity.Verification{
+		UserID:    user.ID,
+		Email:     user.Email,
+		CreatedAt: now,
+		ExpiresAt: now.Add(u.verificationTTL),
+	}, hash)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	err = u.notifier.Notify(ctx, &notify.Message{
+		Kind:   notify.KindVerifyEmail,
+		Tenant: tenantID,
+		UserID: user.ID,
+		To:     verification.Email,
+		Data: map[string]string{
+			"token":      token,
+			"expires_at": verification.ExpiresAt.Format(time.RFC3339),
+		},
+	})
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &SendVerificationEmailResponse{ExpiresAt: verification.ExpiresAt}, nil
+}
+
+type VerifyEmailRequest struct {
+	Token string `validate:"required,max=128"`
+}
+
+type VerifyEmailResponse struct {
+	User *entity.User
+}
+
+// VerifyEmail activates the pending user the token was sent to. The token
+// proves the identity of the caller, and is used up even when it fails.
+func (u *UsecaseImpl) VerifyEmail(ctx context.Context, req *VerifyEmailRequest) (_ *VerifyEmailResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.VerifyEmail")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+
+	// database
+	verification, err := u.db.Verification.Consume(ctx, auth.HashToken(req.Token))
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	if verification.Expired(u.now()) {
+		return nil, errcode.NewFailedPrecondition("verification token expired at %s", verification.ExpiresAt.Format(time.RFC3339))
+	}
+	user, err := u.transitionUser(ctx, verification.UserID, entity.UserStateActive, func(user *entity.User) error {
+		// deactivated users are reactivated only by ActivateUser
+		if user.State != entity.UserStatePending {
+			return errcode.NewFailedPrecondition("user %s is %s, not PENDING", user.ID, user.State)
+		}
+		if !strings.EqualFold(user.Email, verification.Email) {
+			return errcode.NewFailedPrecondition("email of user %s changed since the verification was sent", user.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &VerifyEmailResponse{User: user}, nil
+}
+
+type ActivateUserRequest struct {
+	ID string `validate:"required"`
+}
+
+type ActivateUserResponse struct {
+	User *entity.User
+}
+
+// ActivateUser activates a user without verifying its email, or reinstates a
+// suspended or deactivated one. Users may not activate themselves.
+func (u *UsecaseImpl) ActivateUser(ctx context.Context, req *ActivateUserRequest) (_ *ActivateUserResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.ActivateUser")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	if err := u.authorizeAdmin(ctx); err != nil {
+		return nil, errcode.New(err)
+	}
+
+	// database
+	user, err := u.transitionUser(ctx, req.ID, entity.UserStateActive, nil)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &ActivateUserResponse{User: user}, nil
+}
+
+type SuspendUserRequest struct {
+	ID string `validate:"required"`
+}
+
+type SuspendUserResponse struct {
+	User *entity.User
+}
+
+// SuspendUser stops an active user from logging in and refreshing its
+// sessions until it is activated again. Users may not suspend themselves.
+func (u *UsecaseImpl) SuspendUser(ctx context.Context, req *SuspendUserRequest) (_ *SuspendUserResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.SuspendUser")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	if err := u.authorizeAdmin(ctx); err != nil {
+		return nil, errcode.New(err)
+	}
+
+	// database
+	user, err := u.transitionUser(ctx, req.ID, entity.UserStateSuspended, nil)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &SuspendUserResponse{User: user}, nil
+}
+
+type DeactivateUserRequest struct {
+	ID string `validate:"required"`
+}
+
+type DeactivateUserResponse struct {
+	User *entity.User
+}
+
+// DeactivateUser closes the account of a user, keeping its data unlike
+// DeleteUser. Users may deactivate themselves.
+func (u *UsecaseImpl) DeactivateUser(ctx context.Context, req *DeactivateUserRequest) (_ *DeactivateUserResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.DeactivateUser")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	if err := u.authorizeSelf(ctx, req.ID); err != nil {
+		return nil, errcode.New(err)
+	}
+
+	// database
+	user, err := u.transitionUser(ctx, req.ID, entity.UserStateDeactivated, nil)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &DeactivateUserResponse{User: user}, nil
+}
+
+// transitionUser changes the state of the user to to, failing with
+// FailedPrecondition unless the transition is allowed. check, if not nil,
+// may veto the change with an error.
+func (u *UsecaseImpl) transitionUser(ctx context.Context, id string, to entity.UserState, check func(*entity.User) error) (*entity.User, error) {
+	var checkErr error
+	user, err := u.db.User.Update(ctx, id, func(user *entity.User) bool {
+		checkErr = nil
+		if !user.State.CanTransitionTo(to) {
+			checkErr = errcode.NewFailedPrecondition("user %s cannot change from %s to %s", user.ID, user.State, to)
+			return false
+		}
+		if check != nil {
+			if checkErr = check(user); checkErr != nil {
+				return false
+			}
+		}
+		user.State = to
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if checkErr != nil {
+		return nil, checkErr
+	}
+	return user, nil
+}
+
+// checkSignIn fails with FailedPrecondition when the user may not log in.
+func checkSignIn(user *entity.User) error {
+	if !user.State.CanSignIn() {
+		return errcode.NewFailedPrecondition("user %s is %s", user.ID, user.State)
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/auth"
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/notify"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/repositorytest"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder is a Notifier keeping the messages.
+type recorder struct {
+	mu       sync.Mutex
+	messages []*notify.Message
+}
+
+func (r *recorder) Notify(ctx context.Context, m *notify.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = append(r.messages, m)
+	return nil
+}
+
+func (r *recorder) last(t *testing.T) *notify.Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	require.NotEmpty(t, r.messages)
+	return r.messages[len(r.messages)-1]
+}
+
+func newVerificationUsecase(t *testing.T) (*UsecaseImpl, *recorder) {
+	u := newUsecase(t)
+	r := &recorder{}
+	u.notifier = r
+	return u, r
+}
+
+func TestUsecaseImpl_VerifyEmail(t *testing.T) {
+	u, r := newVerificationUsecase(t)
+	ctx := repositorytest.TenantContext(t)
+
+	// new users are pending whatever the request says
+	created, err := u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: "id", Name: "name", Email: "name@example.com", State: entity.UserStateActive}})
+	require.NoError(t, err)
+	require.Equal(t, entity.UserStatePending, created.User.State)
+
+	sent, err := u.SendVerificationEmail(ctx, &SendVerificationEmailRequest{ID: "id"})
+	require.NoError(t, err)
+	m := r.last(t)
+	require.Equal(t, notify.KindVerifyEmail, m.Kind)
+	require.Equal(t, "id", m.UserID)
+	require.Equal(t, "name@example.com", m.To)
+	require.Equal(t, sent.ExpiresAt.Format(time.RFC3339), m.Data["expires_at"])
+
+	verified, err := u.VerifyEmail(ctx, &VerifyEmailRequest{Token: m.Data["token"]})
+	require.NoError(t, err)
+	require.Equal(t, entity.UserStateActive, verified.User.State)
+
+	// tokens are used once, and active users have nothing to verify
+	_, err = u.VerifyEmail(ctx, &VerifyEmailRequest{Token: m.Data["token"]})
+	require.True(t, errcode.IsNotfound(err), err)
+	_, err = u.SendVerificationEmail(ctx, &SendVerificationEmailRequest{ID: "id"})
+	require.Equal(t, errcode.CodeFailedPrecondition, errcode.NewCode(err), err)
+
+	// without an email there is nothing to send to
+	_, err = u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: "no-email", Name: "no-email"}})
+	require.NoError(t, err)
+	_, err = u.SendVerificationEmail(ctx, &SendVerificationEmailRequest{ID: "no-email"})
+	require.Equal(t, errcode.CodeFailedPrecondition, errcode.NewCode(err), err)
+
+	// verification is disabled without a notifier
+	u.notifier = nil
+	_, err = u.SendVerificationEmail(ctx, &SendVerificationEmailRequest{ID: "no-email"})
+	require.Equal(t, errcode.CodeUnimplemented, errcode.NewCode(err), err)
+}
+
+func TestUsecaseImpl_VerifyEmailFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		before func(t *testing.T, u *UsecaseImpl, ctx context.Context)
+	}{
+		{
+			name: "expired",
+			before: func(t *testing.T, u *UsecaseImpl, ctx context.Context) {
+				u.now = func() time.Time { return time.Now().Add(defaultVerificationTTL) }
+			},
+		},
+		{
+			name: "email changed",
+			before: func(t *testing.T, u *UsecaseImpl, ctx context.Context) {
+				_, err := u.UpdateUser(ctx, &UpdateUserRequest{User: &entity.User{ID: "id", Name: "name", Email: "changed@example.com"}})
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "deactivated",
+			before: func(t *testing.T, u *UsecaseImpl, ctx context.Context) {
+				_, err := u.DeactivateUser(ctx, &DeactivateUserRequest{ID: "id"})
+				require.NoError(t, err)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, r := newVerificationUsecase(t)
+			ctx := auth.NewContext(repositorytest.TenantContext(t), &auth.Principal{Subject: "id"})
+			_, err := u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: "id", Name: "name", Email: "name@example.com"}})
+			require.NoError(t, err)
+			_, err = u.SendVerificationEmail(ctx, &SendVerificationEmailRequest{ID: "id"})
+			require.NoError(t, err)
+
+			tt.before(t, u, ctx)
+			_, err = u.VerifyEmail(ctx, &VerifyEmailRequest{Token: r.last(t).Data["token"]})
+			require.Equal(t, errcode.CodeFailedPrecondition, errcode.NewCode(err), err)
+			got, err := u.GetUser(ctx, &GetUserRequest{ID: "id"})
+			require.NoError(t, err)
+			require.NotEqual(t, entity.UserStateActive, got.User.State)
+		})
+	}
+}
+
+func TestUsecaseImpl_UserState(t *testing.T) {
+	u, _ := newLoginUsecase(t)
+	u.authorizer = adminAuthorizer{}
+	ctx := auth.NewContext(repositorytest.TenantContext(t), &auth.Principal{Subject: "id"})
+	_, err := u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: "id", Name: "name"}})
+	require.NoError(t, err)
+	_, err = u.SetPassword(ctx, &SetPasswordRequest{ID: "id", Password: testPassword})
+	require.NoError(t, err)
+	session, err := u.CreateSession(ctx, &CreateSessionRequest{UserID: "id"})
+	require.NoError(t, err)
+
+	// pending users cannot be suspended
+	_, err = u.SuspendUser(ctx, &SuspendUserRequest{ID: "id"})
+	require.Equal(t, errcode.CodeFailedPrecondition, errcode.NewCode(err), err)
+
+	activated, err := u.ActivateUser(ctx, &ActivateUserRequest{ID: "id"})
+	require.NoError(t, err)
+	require.Equal(t, entity.UserStateActive, activated.User.State)
+	_, err = u.ActivateUser(ctx, &ActivateUserRequest{ID: "id"})
+	require.Equal(t, errcode.CodeFailedPrecondition, errcode.NewCode(err), err)
+
+	// suspended users can neither log in nor use their sessions
+	suspended, err := u.SuspendUser(ctx, &SuspendUserRequest{ID: "id"})
+	require.NoError(t, err)
+	require.Equal(t, entity.UserStateSuspended, suspended.User.State)
+	_, err = u.Login(ctx, &LoginRequest{ID: "id", Password: testPassword})
+	require.Equal(t, errcode.CodeFailedPrecondition, errcode.NewCode(err), err)
+	_, err = u.CreateSession(ctx, &CreateSessionRequest{UserID: "id"})
+	require.Equal(t, errcode.CodeFailedPrecondition, errcode.NewCode(err), err)
+	_, err = u.RefreshSession(ctx, &RefreshSessionRequest{RefreshToken: session.RefreshToken})
+	require.Equal(t, errcode.CodeFailedPrecondition, errcode.NewCode(err), err)
+	// and the refreshed session is revoked
+	listed, err := u.ListSessions(ctx, &ListSessionsRequest{UserID: "id"})
+	require.NoError(t, err)
+	require.False(t, listed.Sessions[0].RevokedAt.IsZero())
+
+	// a wrong password still tells nothing
+	_, err = u.Login(ctx, &LoginRequest{ID: "id", Password: "wrong password 1"})
+	require.True(t, errcode.IsUnauthenticated(err), err)
+
+	deactivated, err := u.DeactivateUser(ctx, &DeactivateUserRequest{ID: "id"})
+	require.NoError(t, err)
+	require.Equal(t, entity.UserStateDeactivated, deactivated.User.State)
+	_, err = u.SuspendUser(ctx, &SuspendUserRequest{ID: "id"})
+	require.Equal(t, errcode.CodeFailedPrecondition, errcode.NewCode(err), err)
+
+	// reactivated users log in again
+	_, err = u.ActivateUser(ctx, &ActivateUserRequest{ID: "id"})
+	require.NoError(t, err)
+	_, err = u.Login(ctx, &LoginRequest{ID: "id", Password: testPassword})
+	require.NoError(t, err)
+
+	// updates keep the state
+	updated, err := u.UpdateUser(ctx, &UpdateUserRequest{User: &entity.User{ID: "id", Name: "changed", State: entity.UserStateSuspended}})
+	require.NoError(t, err)
+	require.Equal(t, entity.UserStateActive, updated.User.State)
+
+	_, err = u.SuspendUser(ctx, &SuspendUserRequest{ID: "missing"})
+	require.True(t, errcode.IsNotfound(err), err)
+}
+
+func TestUsecaseImpl_UserStateAuthorization(t *testing.T) {
+	u := newUsecase(t)
+	ctx := auth.NewContext(repositorytest.TenantContext(t), &auth.Principal{Subject: "id"})
+	_, err := u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: "id", Name: "name"}})
+	require.NoError(t, err)
+	_, err = u.db.User.Update(ctx, "id", func(user *entity.User) bool {
+		user.State = entity.UserStateSuspended
+		return true
+	})
+	require.NoError(t, err)
+
+	// without an Authorizer, no one changes states but users deactivating
+	// themselves
+	_, err = u.ActivateUser(ctx, &ActivateUserRequest{ID: "id"})
+	require.True(t, errcode.IsForbidden(err), err)
+	_, err = u.DeactivateUser(repositorytest.TenantContext(t), &DeactivateUserRequest{ID: "id"})
+	require.True(t, errcode.IsForbidden(err), err)
+
+	// a suspended user still holding a token cannot reinstate itself
+	u.authorizer = &ownerAuthorizer{owner: "id"}
+	_, err = u.ActivateUser(ctx, &ActivateUserRequest{ID: "id"})
+	require.True(t, errcode.IsForbidden(err), err)
+	_, err = u.SuspendUser(ctx, &SuspendUserRequest{ID: "id"})
+	require.True(t, errcode.IsForbidden(err), err)
+	deactivated, err := u.DeactivateUser(ctx, &DeactivateUserRequest{ID: "id"})
+	require.NoError(t, err)
+	require.Equal(t, entity.UserStateDeactivated, deactivated.User.State)
+
+	u.authorizer = adminAuthorizer{}
+	activated, err := u.ActivateUser(ctx, &ActivateUserRequest{ID: "id"})
+	require.NoError(t, err)
+	require.Equal(t, entity.UserStateActive, activated.User.State)
+}
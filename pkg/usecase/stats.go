@@ -0,0 +1,91 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/filter"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/trace"
+)
+
+const (
+	defaultStatsBuckets = 30
+	maxStatsBuckets     = 366
+)
+
+type GetUserStatsRequest struct {
+	// Filter is an AIP-160 filter on the fields of repository.UserFilterSchema.
+	Filter string
+	Bucket repository.Bucket `validate:"gte=0,lte=2"`
+	// From and To bound the sign-ups counted. A zero To is the end of the
+	// current bucket and a zero From is defaultStatsBuckets buckets before To,
+	// so that the defaults change only once per bucket and can be cached.
+	From, To time.Time
+}
+
+type GetUserStatsResponse struct {
+	Total int64
+	// ByGender has the genders of at least one user.
+	ByGender map[entity.Gender]int64
+	// Signups has every bucket from the one containing From to the one
+	// containing To, including the empty ones.
+	Signups []*repository.SignupCount
+}
+
+func (u *UsecaseImpl) GetUserStats(ctx context.Context, req *GetUserStatsRequest) (_ *GetUserStatsResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.GetUserStats")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	expr, err := filter.Parse(req.Filter, repository.UserFilterSchema)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	to := req.To
+	if to.IsZero() {
+		to = req.Bucket.Add(req.Bucket.Truncate(time.Now()), 1)
+	}
+	from := req.From
+	if from.IsZero() {
+		from = req.Bucket.Add(req.Bucket.Truncate(to), -defaultStatsBuckets)
+	}
+	if !from.Before(to) {
+		return nil, errcode.NewInvalidArgument("from must be before to")
+	}
+	var starts []time.Time
+	for start := req.Bucket.Truncate(from); start.Before(to); start = req.Bucket.Add(start, 1) {
+		if len(starts) == maxStatsBuckets {
+			return nil, errcode.NewInvalidArgument("more than %d buckets between from and to", maxStatsBuckets)
+		}
+		starts = append(starts, start)
+	}
+
+	// database
+	stats, err := u.db.User.Stats(ctx, &repository.UserStatsParams{
+		Filter: expr,
+		Bucket: req.Bucket,
+		From:   from,
+		To:     to,
+	})
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	counts := map[time.Time]int64{}
+	for _, c := range stats.Signups {
+		counts[c.Start] = c.Count
+	}
+	resp := &GetUserStatsResponse{
+		Total:    stats.Total,
+		ByGender: stats.ByGender,
+		Signups:  make([]*repository.SignupCount, 0, len(starts)),
+	}
+	for _, start := range starts {
+		resp.Signups = append(resp.Signups, &repository.SignupCount{Start: start, Count: counts[start]})
+	}
+	return resp, nil
+}
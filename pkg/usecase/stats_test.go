@@ -0,0 +1,72 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/repositorytest"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsecaseImpl_GetUserStats(t *testing.T) {
+	u := newUsecase(t)
+	ctx := repositorytest.TenantContext(t)
+	for _, user := range []*entity.User{
+		{ID: "1", Name: "alice", Gender: entity.GenderFemale},
+		{ID: "2", Name: "bob", Gender: entity.GenderMale},
+		{ID: "3", Name: "carol", Gender: entity.GenderFemale},
+	} {
+		_, err := u.CreateUser(ctx, &CreateUserRequest{User: user})
+		require.NoError(t, err)
+	}
+
+	// the default range ends with the current bucket
+	resp, err := u.GetUserStats(ctx, &GetUserStatsRequest{})
+	require.NoError(t, err)
+	require.EqualValues(t, 3, resp.Total)
+	require.Equal(t, map[entity.Gender]int64{entity.GenderMale: 1, entity.GenderFemale: 2}, resp.ByGender)
+	require.Len(t, resp.Signups, defaultStatsBuckets)
+	require.EqualValues(t, 3, sumSignups(resp.Signups))
+
+	// empty buckets are filled in
+	today := repository.BucketDay.Truncate(time.Now())
+	resp, err = u.GetUserStats(ctx, &GetUserStatsRequest{
+		Filter: `gender = FEMALE`,
+		From:   today.AddDate(0, 0, -2),
+		To:     today.AddDate(0, 0, 1),
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 2, resp.Total)
+	require.Len(t, resp.Signups, 3)
+	require.Equal(t, today.AddDate(0, 0, -2), resp.Signups[0].Start)
+	require.Zero(t, resp.Signups[0].Count)
+	require.EqualValues(t, 2, sumSignups(resp.Signups))
+
+	resp, err = u.GetUserStats(ctx, &GetUserStatsRequest{Bucket: repository.BucketMonth, From: today.AddDate(-1, 0, 0), To: today.AddDate(0, 0, 1)})
+	require.NoError(t, err)
+	require.Equal(t, repository.BucketMonth.Truncate(today.AddDate(-1, 0, 0)), resp.Signups[0].Start)
+	require.EqualValues(t, 3, sumSignups(resp.Signups))
+
+	for name, req := range map[string]*GetUserStatsRequest{
+		"unknown bucket":   {Bucket: 3},
+		"invalid filter":   {Filter: `age > 20`},
+		"from after to":    {From: today, To: today.AddDate(0, 0, -1)},
+		"too many buckets": {From: today.AddDate(-2, 0, 0), To: today},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := u.GetUserStats(ctx, req)
+			require.True(t, errcode.IsInvalidArgument(err), err)
+		})
+	}
+}
+
+func sumSignups(signups []*repository.SignupCount) int64 {
+	var n int64
+	for _, c := range signups {
+		n += c.Count
+	}
+	return n
+}
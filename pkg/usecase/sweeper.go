@@ -0,0 +1,69 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/log"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/trace"
+)
+
+const defaultSweepBatchSize = 1000
+
+// SessionSweeper deletes the expired sessions of every tenant in the
+// background, in batches so that no transaction holds many rows.
+type SessionSweeper struct {
+	repo      repository.Session
+	batchSize int
+	now       func() time.Time
+}
+
+// NewSessionSweeper returns a sweeper deleting batchSize sessions per
+// transaction, 1000 when it is not positive.
+func NewSessionSweeper(repo repository.Session, batchSize int) *SessionSweeper {
+	if batchSize <= 0 {
+		batchSize = defaultSweepBatchSize
+	}
+	return &SessionSweeper{repo: repo, batchSize: batchSize, now: time.Now}
+}
+
+// Run sweeps every interval until ctx is done. Failures are logged and retried
+// at the next interval.
+func (s *SessionSweeper) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.Sweep(ctx); err != nil {
+			log.Error(ctx, "failed to sweep expired sessions", "deleted", n, log.Err(err))
+		} else if n > 0 {
+			log.Info(ctx, "swept expired sessions", "deleted", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep deletes the sessions that have expired, and returns how many it
+// deleted.
+func (s *SessionSweeper) Sweep(ctx context.Context) (_ int64, err error) {
+	ctx, span := trace.Start(ctx, "usecase.SweepSessions")
+	defer func() { trace.End(span, err) }()
+
+	before := s.now()
+	var total int64
+	for {
+		n, err := s.repo.DeleteExpired(ctx, before, s.batchSize)
+		total += n
+		if err != nil {
+			return total, errcode.New(err)
+		}
+		if n < int64(s.batchSize) {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/memory"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/repositorytest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionSweeper_Sweep(t *testing.T) {
+	users := memory.NewUser()
+	sessions := memory.NewSession(users)
+	now := time.Now()
+	tenants := []context.Context{repositorytest.TenantContext(t), repositorytest.TenantContext(t)}
+	for _, ctx := range tenants {
+		_, err := users.Create(ctx, &entity.User{ID: "user", Name: "name"})
+		require.NoError(t, err)
+		for i, id := range []string{"a", "b", "c", "live"} {
+			expiresAt := now.Add(-time.Duration(i+1) * time.Minute)
+			if id == "live" {
+				expiresAt = now.Add(time.Hour)
+			}
+			_, err := sessions.Create(ctx, &entity.Session{ID: id, UserID: "user", CreatedAt: now, RefreshedAt: now, ExpiresAt: expiresAt}, id)
+			require.NoError(t, err)
+		}
+	}
+
+	// the expired sessions of both tenants are deleted in batches of 4
+	s := NewSessionSweeper(sessions, 4)
+	s.now = func() time.Time { return now }
+	n, err := s.Sweep(context.Background())
+	require.NoError(t, err)
+	require.EqualValues(t, 6, n)
+	n, err = s.Sweep(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	for _, ctx := range tenants {
+		got, err := sessions.List(ctx, &repository.ListSessionsParams{UserID: "user"})
+		require.NoError(t, err)
+		require.Len(t, got, 1)
+		require.Equal(t, "live", got[0].ID)
+	}
+}
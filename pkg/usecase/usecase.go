@@ -2,28 +2,192 @@ package usecase
 
 import (
 	"context"
+	"sync"
+	"time"
 
+	"github.com/SakataAtsuki/e-architecture/pkg/auth"
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
 	"github.com/SakataAtsuki/e-architecture/pkg/repository"
-	"github.com/go-playground/validator"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/trace"
+	"github.com/go-playground/validator/v10"
 )
 
 type Usecase interface {
 	CreateUser(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error)
 	GetUser(ctx context.Context, req *GetUserRequest) (*GetUserResponse, error)
+	ListUsers(ctx context.Context, req *ListUsersRequest) (*ListUsersResponse, error)
+	UpdateUser(ctx context.Context, req *UpdateUserRequest) (*UpdateUserResponse, error)
+	DeleteUser(ctx context.Context, req *DeleteUserRequest) error
+	SearchUsers(ctx context.Context, req *SearchUsersRequest) (*SearchUsersResponse, error)
+	GetUserStats(ctx context.Context, req *GetUserStatsRequest) (*GetUserStatsResponse, error)
+	SetPassword(ctx context.Context, req *SetPasswordRequest) (*SetPasswordResponse, error)
+	VerifyPassword(ctx context.Context, req *VerifyPasswordRequest) (*VerifyPasswordResponse, error)
+	Login(ctx context.Context, req *LoginRequest) (*LoginResponse, error)
+	CreateSession(ctx context.Context, req *CreateSessionRequest) (*CreateSessionResponse, error)
+	RefreshSession(ctx context.Context, req *RefreshSessionRequest) (*RefreshSessionResponse, error)
+	ListSessions(ctx context.Context, req *ListSessionsRequest) (*ListSessionsResponse, error)
+	RevokeSession(ctx context.Context, req *RevokeSessionRequest) (*RevokeSessionResponse, error)
+	SendVerificationEmail(ctx context.Context, req *SendVerificationEmailRequest) (*SendVerificationEmailResponse, error)
+	VerifyEmail(ctx context.Context, req *VerifyEmailRequest) (*VerifyEmailResponse, error)
+	ActivateUser(ctx context.Context, req *ActivateUserRequest) (*ActivateUserResponse, error)
+	SuspendUser(ctx context.Context, req *SuspendUserRequest) (*SuspendUserResponse, error)
+	DeactivateUser(ctx context.Context, req *DeactivateUserRequest) (*DeactivateUserResponse, error)
+	CreateGroup(ctx context.Context, req *CreateGroupRequest) (*CreateGroupResponse, error)
+	GetGroup(ctx context.Context, req *GetGroupRequest) (*GetGroupResponse, error)
+	ListGroups(ctx context.Context, req *ListGroupsRequest) (*ListGroupsResponse, error)
+	UpdateGroup(ctx context.Context, req *UpdateGroupRequest) (*UpdateGroupResponse, error)
+	DeleteGroup(ctx context.Context, req *DeleteGroupRequest) error
+	AddMember(ctx context.Context, req *AddMemberRequest) (*AddMemberResponse, error)
+	RemoveMember(ctx context.Context, req *RemoveMemberRequest) error
+	ListMembers(ctx context.Context, req *ListMembersRequest) (*ListMembersResponse, error)
+	ListUserGroups(ctx context.Context, req *ListUserGroupsRequest) (*ListUserGroupsResponse, error)
+	Follow(ctx context.Context, req *FollowRequest) (*FollowResponse, error)
+	Unfollow(ctx context.Context, req *UnfollowRequest) error
+	Block(ctx context.Context, req *BlockRequest) (*BlockResponse, error)
+	Unblock(ctx context.Context, req *UnblockRequest) error
+	ListFollowers(ctx context.Context, req *ListRelationshipsRequest) (*ListRelationshipsResponse, error)
+	ListFollowing(ctx context.Context, req *ListRelationshipsRequest) (*ListRelationshipsResponse, error)
+	ListMutualFollows(ctx context.Context, req *ListRelationshipsRequest) (*ListRelationshipsResponse, error)
+}
+
+// Authorizer performs resource-level authorization, e.g. allowing users to
+// update only themselves or the groups they own. It is implemented by
+// authz.Engine.
+type Authorizer interface {
+	AuthorizeResource(ctx context.Context, ownerID string) error
+	// AuthorizeGroup calls memberRole for the role of the caller in the group
+	// when it needs it, as entity.GroupRole.PolicyName or "" for none.
+	AuthorizeGroup(ctx context.Context, memberRole func(subject string) (string, error)) error
+	// AuthorizeAdmin checks that the caller may act on anyone, unlike the
+	// callers allowed only for themselves.
+	AuthorizeAdmin(ctx context.Context) error
 }
 
 type UsecaseImpl struct {
-	validate *validator.Validate
-	db       *repository.Database
+	validate   *validator.Validate
+	db         *repository.Database
+	authorizer Authorizer
+
+	passwords        PasswordHasher
+	tokens           TokenIssuer
+	maxLoginFailures int
+	lockoutDuration  time.Duration
+	sessionTTL       time.Duration
+	notifier         Notifier
+	verificationTTL  time.Duration
+	now              func() time.Time
+	// dummyHash is verified for logins without a credential.
+	dummyOnce sync.Once
+	dummyHash string
 }
 
 type Config struct {
 	DB *repository.Database
+	// Authorizer is optional. Resource-level checks are skipped when it is nil,
+	// except that only users sign in or deactivate as themselves and no one
+	// activates or suspends users.
+	Authorizer Authorizer
+	// Passwords defaults to argon2id with auth.DefaultArgon2Params.
+	Passwords PasswordHasher
+	// Tokens issues the session tokens of Login and the access tokens of
+	// sessions, which are Unimplemented when it is nil.
+	Tokens TokenIssuer
+	// MaxLoginFailures consecutive failed attempts lock a credential out for
+	// LockoutDuration. They default to 5 and 15 minutes.
+	MaxLoginFailures int
+	LockoutDuration  time.Duration
+	// SessionTTL is the lifetime of the sessions of CreateSession. It defaults
+	// to 30 days.
+	SessionTTL time.Duration
+	// Notifier sends the tokens of email verifications, which are
+	// Unimplemented when it is nil. They expire after VerificationTTL, 24 hours
+	// by default.
+	Notifier        Notifier
+	VerificationTTL time.Duration
 }
 
 func New(cfg *Config) *UsecaseImpl {
-	return &UsecaseImpl{
-		validate: validator.New(),
-		db:       cfg.DB,
+	u := &UsecaseImpl{
+		validate:         newValidator(),
+		db:               cfg.DB,
+		authorizer:       cfg.Authorizer,
+		passwords:        cfg.Passwords,
+		tokens:           cfg.Tokens,
+		maxLoginFailures: cfg.MaxLoginFailures,
+		lockoutDuration:  cfg.LockoutDuration,
+		sessionTTL:       cfg.SessionTTL,
+		notifier:         cfg.Notifier,
+		verificationTTL:  cfg.VerificationTTL,
+		now:              time.Now,
+	}
+	if u.passwords == nil {
+		u.passwords = auth.NewPasswords(auth.DefaultArgon2Params)
+	}
+	if u.maxLoginFailures <= 0 {
+		u.maxLoginFailures = defaultMaxLoginFailures
+	}
+	if u.lockoutDuration <= 0 {
+		u.lockoutDuration = defaultLockoutDuration
+	}
+	if u.sessionTTL <= 0 {
+		u.sessionTTL = defaultSessionTTL
+	}
+	if u.verificationTTL <= 0 {
+		u.verificationTTL = defaultVerificationTTL
+	}
+	return u
+}
+
+// newValidator returns a validator with the validations of the tags of
+// entity and the password policy.
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterValidation("attribute_key", func(fl validator.FieldLevel) bool {
+		return entity.ValidAttributeKey(fl.Field().String())
+	})
+	v.RegisterValidation("attribute_value", func(fl validator.FieldLevel) bool {
+		return entity.ValidAttributeValue(fl.Field().Interface())
+	})
+	v.RegisterValidation("password", func(fl validator.FieldLevel) bool {
+		return validPassword(fl.Field().String())
+	})
+	return v
+}
+
+func (u *UsecaseImpl) authorize(ctx context.Context, ownerID string) error {
+	if u.authorizer == nil {
+		return nil
+	}
+	return u.authorizer.AuthorizeResource(ctx, ownerID)
+}
+
+// authorizeAdmin checks that the caller may act on anyone, for the operations
+// that users must not perform on themselves. Without an Authorizer, no one
+// may.
+func (u *UsecaseImpl) authorizeAdmin(ctx context.Context) error {
+	if u.authorizer == nil {
+		return errcode.NewForbidden("request requires an authorization policy")
 	}
+	return u.authorizer.AuthorizeAdmin(ctx)
+}
+
+// authorizeSelf is authorize for the operations that sign in or out as the
+// user: without an Authorizer, only the user itself may perform them.
+func (u *UsecaseImpl) authorizeSelf(ctx context.Context, userID string) error {
+	if u.authorizer != nil {
+		return u.authorizer.AuthorizeResource(ctx, userID)
+	}
+	if p, ok := auth.FromContext(ctx); ok && p.Subject == userID {
+		return nil
+	}
+	return errcode.NewForbidden("only %s may do so for itself", userID)
+}
+
+// validateStruct validates req in a span of its own.
+func (u *UsecaseImpl) validateStruct(ctx context.Context, req interface{}) error {
+	_, span := trace.Start(ctx, "usecase.validate")
+	err := u.validate.Struct(req)
+	trace.End(span, errcode.New(err))
+	return err
 }
@@ -2,26 +2,41 @@ package usecase
 
 import (
 	"context"
+	"reflect"
 
 	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/filter"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
 	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/pagetoken"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/trace"
 )
 
 type CreateUserRequest struct {
-	User *entity.User
+	User *entity.User `validate:"required"`
 }
 
 type CreateUserResponse struct {
 	User *entity.User
 }
 
-func (u *UsecaseImpl) CreateUser(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
-	if err := u.validate.Struct(req); err != nil {
+func (u *UsecaseImpl) CreateUser(ctx context.Context, req *CreateUserRequest) (_ *CreateUserResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.CreateUser")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
 		return nil, errcode.New(err)
 	}
+	if err := u.authorize(ctx, req.User.ID); err != nil {
+		return nil, errcode.New(err)
+	}
+
+	// new users are pending whatever the request says
+	v := *req.User
+	v.State = entity.UserStatePending
 
 	// database
-	user, err := u.db.User.Create(ctx, req.User)
+	user, err := u.db.User.Create(ctx, &v)
 	if err != nil {
 		return nil, errcode.New(err)
 	}
@@ -29,15 +44,21 @@ func (u *UsecaseImpl) CreateUser(ctx context.Context, req *CreateUserRequest) (*
 }
 
 type GetUserRequest struct {
-	ID string
+	ID string `validate:"required"`
 }
 
 type GetUserResponse struct {
 	User *entity.User
 }
 
-func (u *UsecaseImpl) GetUser(ctx context.Context, req *GetUserRequest) (*GetUserResponse, error) {
-	if err := u.validate.Struct(req); err != nil {
+func (u *UsecaseImpl) GetUser(ctx context.Context, req *GetUserRequest) (_ *GetUserResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.GetUser")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	if err := u.authorize(ctx, req.ID); err != nil {
 		return nil, errcode.New(err)
 	}
 
@@ -48,3 +69,170 @@ func (u *UsecaseImpl) GetUser(ctx context.Context, req *GetUserRequest) (*GetUse
 	}
 	return &GetUserResponse{User: user}, nil
 }
+
+type ListUsersRequest struct {
+	Name string
+	// OrderBy is an AIP-132 order_by on the sortable fields of
+	// repository.UserFilterSchema, such as "name desc, updated_at".
+	OrderBy string
+	// Limit is the page size. Zero lists every user.
+	Limit int `validate:"gte=0"`
+	// Filter is an AIP-160 filter on the fields of repository.UserFilterSchema.
+	Filter    string
+	PageToken string
+}
+
+type ListUsersResponse struct {
+	Users         entity.Users
+	NextPageToken string
+}
+
+// listPosition is the page token of ListUsers. After holds the sort key of the
+// last user of the page, so that pages stay consistent while users are added
+// or removed. The request is recorded so that a token is not reused for
+// another one.
+type listPosition struct {
+	Name    string   `json:"n,omitempty"`
+	Filter  string   `json:"f,omitempty"`
+	OrderBy string   `json:"o"`
+	After   []string `json:"a"`
+}
+
+func (u *UsecaseImpl) ListUsers(ctx context.Context, req *ListUsersRequest) (_ *ListUsersResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.ListUsers")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	schema := repository.UserFilterSchema
+	expr, err := filter.Parse(req.Filter, schema)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	order, err := filter.ParseOrder(req.OrderBy, schema, repository.UserOrderTiebreak)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	pos := &listPosition{Name: req.Name, Filter: req.Filter, OrderBy: order.String()}
+	if req.PageToken != "" {
+		token := &listPosition{}
+		if err := pagetoken.Decode(req.PageToken, token); err != nil {
+			return nil, err
+		}
+		if token.Name != pos.Name || token.Filter != pos.Filter || token.OrderBy != pos.OrderBy {
+			return nil, errcode.NewInvalidArgument("page token does not belong to this list")
+		}
+		after, err := order.After(schema, token.After)
+		if err != nil {
+			return nil, errcode.New(err)
+		}
+		expr = filter.AndOf(expr, after)
+	}
+
+	// database
+	params := &repository.ListUsersParams{
+		Name:    req.Name,
+		Filter:  expr,
+		OrderBy: order,
+	}
+	if req.Limit > 0 {
+		// one more user tells whether there is a next page
+		params.Limit = req.Limit + 1
+	}
+	users, err := u.db.User.List(ctx, params)
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	resp := &ListUsersResponse{Users: users}
+	if req.Limit > 0 && len(users) > req.Limit {
+		resp.Users = users[:req.Limit]
+		last := resp.Users[req.Limit-1]
+		pos.After = order.Cursor(schema, func(field string) interface{} { return repository.UserFilterValue(last, field) })
+		if resp.NextPageToken, err = pagetoken.Encode(pos); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+type UpdateUserRequest struct {
+	User *entity.User `validate:"required"`
+	// UpdateMask names the fields of User to update, as in the API. Empty
+	// replaces all of them.
+	UpdateMask []string
+}
+
+// userFields copies the updatable fields of a user by their names in the API.
+var userFields = map[string]func(dst, src *entity.User){
+	"name":       func(dst, src *entity.User) { dst.Name = src.Name },
+	"gender":     func(dst, src *entity.User) { dst.Gender = src.Gender },
+	"email":      func(dst, src *entity.User) { dst.Email = src.Email },
+	"locale":     func(dst, src *entity.User) { dst.Locale = src.Locale },
+	"timezone":   func(dst, src *entity.User) { dst.Timezone = src.Timezone },
+	"attributes": func(dst, src *entity.User) { dst.Attributes = src.Attributes },
+}
+
+type UpdateUserResponse struct {
+	User *entity.User
+}
+
+func (u *UsecaseImpl) UpdateUser(ctx context.Context, req *UpdateUserRequest) (_ *UpdateUserResponse, err error) {
+	ctx, span := trace.Start(ctx, "usecase.UpdateUser")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return nil, errcode.New(err)
+	}
+	mask := req.UpdateMask
+	if len(mask) == 0 {
+		mask = []string{"name", "gender", "email", "locale", "timezone", "attributes"}
+	}
+	for _, field := range mask {
+		if _, ok := userFields[field]; !ok {
+			return nil, errcode.NewInvalidArgument("cannot update field: %q", field)
+		}
+	}
+	if err := u.authorize(ctx, req.User.ID); err != nil {
+		return nil, errcode.New(err)
+	}
+
+	// database
+	user, err := u.db.User.Update(ctx, req.User.ID, func(user *entity.User) bool {
+		changed := *user
+		for _, field := range mask {
+			userFields[field](&changed, req.User)
+		}
+		if reflect.DeepEqual(user, &changed) {
+			return false
+		}
+		*user = changed
+		return true
+	})
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	return &UpdateUserResponse{User: user}, nil
+}
+
+type DeleteUserRequest struct {
+	ID string `validate:"required"`
+}
+
+func (u *UsecaseImpl) DeleteUser(ctx context.Context, req *DeleteUserRequest) (err error) {
+	ctx, span := trace.Start(ctx, "usecase.DeleteUser")
+	defer func() { trace.End(span, err) }()
+
+	if err := u.validateStruct(ctx, req); err != nil {
+		return errcode.New(err)
+	}
+	if err := u.authorize(ctx, req.ID); err != nil {
+		return errcode.New(err)
+	}
+
+	// database
+	if err := u.db.User.Delete(ctx, req.ID); err != nil {
+		return errcode.New(err)
+	}
+	return nil
+}
@@ -0,0 +1,390 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/auth"
+	"github.com/SakataAtsuki/e-architecture/pkg/entity"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/memory"
+	"github.com/SakataAtsuki/e-architecture/pkg/repository/repositorytest"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/SakataAtsuki/e-architecture/pkg/util/textsearch"
+	"github.com/stretchr/testify/require"
+)
+
+// ownerAuthorizer allows access only to the resources of owner and the groups
+// it owns or administers.
+type ownerAuthorizer struct {
+	owner string
+}
+
+func (a *ownerAuthorizer) AuthorizeResource(ctx context.Context, ownerID string) error {
+	if ownerID != a.owner {
+		return errcode.NewForbidden("not the owner of %s", ownerID)
+	}
+	return nil
+}
+
+func (a *ownerAuthorizer) AuthorizeGroup(ctx context.Context, memberRole func(subject string) (string, error)) error {
+	role, err := memberRole(a.owner)
+	if err != nil {
+		return err
+	}
+	if role != "owner" && role != "admin" {
+		return errcode.NewForbidden("%s does not administer the group", a.owner)
+	}
+	return nil
+}
+
+func (a *ownerAuthorizer) AuthorizeAdmin(ctx context.Context) error {
+	return errcode.NewForbidden("%s may access only its own resources", a.owner)
+}
+
+// adminAuthorizer allows everything, as for callers granted by their roles.
+type adminAuthorizer struct{}
+
+func (adminAuthorizer) AuthorizeResource(ctx context.Context, ownerID string) error {
+	return nil
+}
+
+func (adminAuthorizer) AuthorizeGroup(ctx context.Context, memberRole func(subject string) (string, error)) error {
+	return nil
+}
+
+func (adminAuthorizer) AuthorizeAdmin(ctx context.Context) error {
+	return nil
+}
+
+func newUsecase(t *testing.T) *UsecaseImpl {
+	users := memory.NewUser()
+	return New(&Config{
+		DB: &repository.Database{
+			User:         users,
+			Credential:   memory.NewCredential(users),
+			Session:      memory.NewSession(users),
+			Verification: memory.NewVerification(users),
+			Group:        memory.NewGroup(users),
+			Relationship: memory.NewRelationship(users),
+		},
+		// cheap enough for tests
+		Passwords: auth.NewPasswords(auth.Argon2Params{Time: 1, Memory: 64, Threads: 1}),
+	})
+}
+
+func TestUsecaseImpl_User(t *testing.T) {
+	u := newUsecase(t)
+	ctx := repositorytest.TenantContext(t)
+
+	created, err := u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: "id", Name: "name"}})
+	require.NoError(t, err)
+	require.Equal(t, "name", created.User.Name)
+
+	got, err := u.GetUser(ctx, &GetUserRequest{ID: "id"})
+	require.NoError(t, err)
+	require.Equal(t, created.User, got.User)
+
+	updated, err := u.UpdateUser(ctx, &UpdateUserRequest{User: &entity.User{
+		ID:         "id",
+		Name:       "changed",
+		Gender:     entity.GenderFemale,
+		Email:      "changed@example.com",
+		Locale:     "ja-JP",
+		Timezone:   "Asia/Tokyo",
+		Attributes: entity.Attributes{"team": "core"},
+	}})
+	require.NoError(t, err)
+	require.Equal(t, "changed", updated.User.Name)
+	require.Equal(t, entity.GenderFemale, updated.User.Gender)
+	require.Equal(t, "changed@example.com", updated.User.Email)
+	require.Equal(t, entity.Attributes{"team": "core"}, updated.User.Attributes)
+
+	// an update without changes keeps the user as it is
+	same, err := u.UpdateUser(ctx, &UpdateUserRequest{User: updated.User})
+	require.NoError(t, err)
+	require.Equal(t, updated.User, same.User)
+
+	// a mask updates only the fields it names
+	masked, err := u.UpdateUser(ctx, &UpdateUserRequest{
+		User:       &entity.User{ID: "id", Locale: "en-US"},
+		UpdateMask: []string{"locale"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "en-US", masked.User.Locale)
+	require.Equal(t, "changed", masked.User.Name)
+	require.Equal(t, "changed@example.com", masked.User.Email)
+	require.Equal(t, entity.Attributes{"team": "core"}, masked.User.Attributes)
+	_, err = u.UpdateUser(ctx, &UpdateUserRequest{User: &entity.User{ID: "id"}, UpdateMask: []string{"state"}})
+	require.True(t, errcode.IsInvalidArgument(err))
+	// without a mask, the fields that are left empty are cleared
+	replaced, err := u.UpdateUser(ctx, &UpdateUserRequest{User: &entity.User{ID: "id", Name: "changed", Locale: "en-US"}})
+	require.NoError(t, err)
+	require.Empty(t, replaced.User.Email)
+	require.Empty(t, replaced.User.Attributes)
+	updated, err = u.UpdateUser(ctx, &UpdateUserRequest{User: updated.User})
+	require.NoError(t, err)
+
+	listed, err := u.ListUsers(ctx, &ListUsersRequest{Name: "changed"})
+	require.NoError(t, err)
+	require.Equal(t, entity.Users{updated.User}, listed.Users)
+
+	listed, err = u.ListUsers(ctx, &ListUsersRequest{Filter: `gender = FEMALE AND name = "changed"`})
+	require.NoError(t, err)
+	require.Equal(t, entity.Users{updated.User}, listed.Users)
+	listed, err = u.ListUsers(ctx, &ListUsersRequest{Filter: `attributes.team = core AND email = "changed@example.com"`})
+	require.NoError(t, err)
+	require.Equal(t, entity.Users{updated.User}, listed.Users)
+	listed, err = u.ListUsers(ctx, &ListUsersRequest{Filter: `gender = MALE`})
+	require.NoError(t, err)
+	require.Empty(t, listed.Users)
+
+	require.NoError(t, u.DeleteUser(ctx, &DeleteUserRequest{ID: "id"}))
+	_, err = u.GetUser(ctx, &GetUserRequest{ID: "id"})
+	require.True(t, errcode.IsNotfound(err), err)
+}
+
+func TestUsecaseImpl_Validation(t *testing.T) {
+	u := newUsecase(t)
+	ctx := repositorytest.TenantContext(t)
+
+	tests := []struct {
+		name string
+		call func() error
+		is   func(error) bool
+	}{
+		{
+			name: "create without user",
+			call: func() error { _, err := u.CreateUser(ctx, &CreateUserRequest{}); return err },
+			is:   errcode.IsInvalidArgument,
+		},
+		{
+			name: "get without id",
+			call: func() error { _, err := u.GetUser(ctx, &GetUserRequest{}); return err },
+			is:   errcode.IsInvalidArgument,
+		},
+		{
+			name: "list with negative limit",
+			call: func() error { _, err := u.ListUsers(ctx, &ListUsersRequest{Limit: -1}); return err },
+			is:   errcode.IsInvalidArgument,
+		},
+		{
+			name: "list with unknown filter field",
+			call: func() error { _, err := u.ListUsers(ctx, &ListUsersRequest{Filter: `age > 20`}); return err },
+			is:   errcode.IsInvalidArgument,
+		},
+		{
+			name: "list with malformed filter",
+			call: func() error { _, err := u.ListUsers(ctx, &ListUsersRequest{Filter: `gender = `}); return err },
+			is:   errcode.IsInvalidArgument,
+		},
+		{
+			name: "create with unknown gender",
+			call: func() error {
+				_, err := u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: "id", Name: "name", Gender: 3}})
+				return err
+			},
+			is: errcode.IsInvalidArgument,
+		},
+		{
+			name: "create with invalid email",
+			call: func() error {
+				_, err := u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: "id", Name: "name", Email: "name@"}})
+				return err
+			},
+			is: errcode.IsInvalidArgument,
+		},
+		{
+			name: "create with invalid locale",
+			call: func() error {
+				_, err := u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: "id", Name: "name", Locale: "japanese"}})
+				return err
+			},
+			is: errcode.IsInvalidArgument,
+		},
+		{
+			name: "create with unknown timezone",
+			call: func() error {
+				_, err := u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: "id", Name: "name", Timezone: "Mars/Olympus"}})
+				return err
+			},
+			is: errcode.IsInvalidArgument,
+		},
+		{
+			name: "create with invalid attribute key",
+			call: func() error {
+				_, err := u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: "id", Name: "name", Attributes: entity.Attributes{"a.b": "c"}}})
+				return err
+			},
+			is: errcode.IsInvalidArgument,
+		},
+		{
+			name: "create with unset attribute",
+			call: func() error {
+				_, err := u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: "id", Name: "name", Attributes: entity.Attributes{"a": nil}}})
+				return err
+			},
+			is: errcode.IsInvalidArgument,
+		},
+		{
+			name: "create with nested attribute",
+			call: func() error {
+				_, err := u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: "id", Name: "name", Attributes: entity.Attributes{"a": []string{"b"}}}})
+				return err
+			},
+			is: errcode.IsInvalidArgument,
+		},
+		{
+			name: "update without user",
+			call: func() error { _, err := u.UpdateUser(ctx, &UpdateUserRequest{}); return err },
+			is:   errcode.IsInvalidArgument,
+		},
+		{
+			name: "delete without id",
+			call: func() error { return u.DeleteUser(ctx, &DeleteUserRequest{}) },
+			is:   errcode.IsInvalidArgument,
+		},
+		{
+			name: "set short password",
+			call: func() error {
+				_, err := u.SetPassword(ctx, &SetPasswordRequest{ID: "id", Password: "short-1"})
+				return err
+			},
+			is: errcode.IsInvalidArgument,
+		},
+		{
+			name: "set password of letters only",
+			call: func() error {
+				_, err := u.SetPassword(ctx, &SetPasswordRequest{ID: "id", Password: "onlylettershere"})
+				return err
+			},
+			is: errcode.IsInvalidArgument,
+		},
+		{
+			name: "set password without letters",
+			call: func() error {
+				_, err := u.SetPassword(ctx, &SetPasswordRequest{ID: "id", Password: "1234567890123"})
+				return err
+			},
+			is: errcode.IsInvalidArgument,
+		},
+		{
+			name: "login with both id and email",
+			call: func() error {
+				_, err := u.Login(ctx, &LoginRequest{ID: "id", Email: "name@example.com", Password: "password"})
+				return err
+			},
+			is: errcode.IsInvalidArgument,
+		},
+		{
+			name: "login without id or email",
+			call: func() error { _, err := u.Login(ctx, &LoginRequest{Password: "password"}); return err },
+			is:   errcode.IsInvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			require.True(t, tt.is(err), err)
+		})
+	}
+}
+
+func TestUsecaseImpl_Authorizer(t *testing.T) {
+	u := New(&Config{
+		DB:         &repository.Database{User: memory.NewUser()},
+		Authorizer: &ownerAuthorizer{owner: "me"},
+	})
+	ctx := repositorytest.TenantContext(t)
+
+	_, err := u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: "me", Name: "me"}})
+	require.NoError(t, err)
+	_, err = u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: "other", Name: "other"}})
+	require.True(t, errcode.IsForbidden(err), err)
+	_, err = u.GetUser(ctx, &GetUserRequest{ID: "other"})
+	require.True(t, errcode.IsForbidden(err), err)
+	require.True(t, errcode.IsForbidden(u.DeleteUser(ctx, &DeleteUserRequest{ID: "other"})))
+}
+
+func TestUsecaseImpl_ListUsersPages(t *testing.T) {
+	u := newUsecase(t)
+	ctx := repositorytest.TenantContext(t)
+	for i, name := range []string{"bob", "alice", "dave", "carol", "erin"} {
+		user := &entity.User{ID: fmt.Sprintf("id-%d", i), Name: name, Gender: entity.Gender(i % 2)}
+		_, err := u.CreateUser(ctx, &CreateUserRequest{User: user})
+		require.NoError(t, err)
+	}
+
+	// pages until the token is empty
+	var names []string
+	req := &ListUsersRequest{OrderBy: "gender desc, name", Limit: 2}
+	for {
+		resp, err := u.ListUsers(ctx, req)
+		require.NoError(t, err)
+		for _, user := range resp.Users {
+			names = append(names, user.Name)
+		}
+		if resp.NextPageToken == "" {
+			break
+		}
+		req.PageToken = resp.NextPageToken
+	}
+	require.Equal(t, []string{"alice", "carol", "bob", "dave", "erin"}, names)
+
+	// users added behind the cursor do not shift the next page
+	first, err := u.ListUsers(ctx, &ListUsersRequest{OrderBy: "name", Limit: 2})
+	require.NoError(t, err)
+	_, err = u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: "id-5", Name: "aaron"}})
+	require.NoError(t, err)
+	next, err := u.ListUsers(ctx, &ListUsersRequest{OrderBy: "name", Limit: 2, PageToken: first.NextPageToken})
+	require.NoError(t, err)
+	require.Equal(t, "carol", next.Users[0].Name)
+
+	// a token only continues the list it came from
+	for _, req := range []*ListUsersRequest{
+		{OrderBy: "name desc", Limit: 2, PageToken: first.NextPageToken},
+		{OrderBy: "name", Filter: `gender = MALE`, Limit: 2, PageToken: first.NextPageToken},
+		{OrderBy: "name", Limit: 2, PageToken: "garbage"},
+	} {
+		_, err = u.ListUsers(ctx, req)
+		require.True(t, errcode.IsInvalidArgument(err), err)
+	}
+	// order_by only names allowlisted fields
+	_, err = u.ListUsers(ctx, &ListUsersRequest{OrderBy: "name; DROP TABLE users"})
+	require.True(t, errcode.IsInvalidArgument(err), err)
+}
+
+func TestUsecaseImpl_SearchUsers(t *testing.T) {
+	u := newUsecase(t)
+	ctx := repositorytest.TenantContext(t)
+	for _, name := range []string{"Alice", "Alicia", "Alina"} {
+		_, err := u.CreateUser(ctx, &CreateUserRequest{User: &entity.User{ID: name, Name: name}})
+		require.NoError(t, err)
+	}
+
+	// pages until the token is empty
+	var ids []string
+	req := &SearchUsersRequest{Query: "ALI", Mode: textsearch.ModePrefix, PageSize: 2}
+	for {
+		resp, err := u.SearchUsers(ctx, req)
+		require.NoError(t, err)
+		for _, user := range resp.Users {
+			ids = append(ids, user.User.ID)
+		}
+		if resp.NextPageToken == "" {
+			break
+		}
+		req.PageToken = resp.NextPageToken
+	}
+	require.ElementsMatch(t, []string{"Alice", "Alicia", "Alina"}, ids)
+
+	// a token only continues the search it came from
+	resp, err := u.SearchUsers(ctx, &SearchUsersRequest{Query: "ali", Mode: textsearch.ModePrefix, PageSize: 1})
+	require.NoError(t, err)
+	_, err = u.SearchUsers(ctx, &SearchUsersRequest{Query: "bob", PageToken: resp.NextPageToken})
+	require.True(t, errcode.IsInvalidArgument(err), err)
+
+	_, err = u.SearchUsers(ctx, &SearchUsersRequest{Query: "　"})
+	require.True(t, errcode.IsInvalidArgument(err), err)
+}
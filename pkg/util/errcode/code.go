@@ -3,6 +3,7 @@ package errcode
 import (
 	"errors"
 	"fmt"
+	"net/http"
 
 	"google.golang.org/grpc/codes"
 )
@@ -22,6 +23,8 @@ const (
 	CodeCancelled
 	CodeResourceExhausted
 	CodeFailedPrecondition
+	CodeUnauthenticated
+	CodeDeadlineExceeded
 )
 
 func (c Code) String() string {
@@ -46,8 +49,14 @@ func (c Code) String() string {
 		return "Unimplemented"
 	case CodeCancelled:
 		return "Cancelled"
+	case CodeResourceExhausted:
+		return "Resource exhausted"
 	case CodeFailedPrecondition:
 		return "Failed precondition"
+	case CodeUnauthenticated:
+		return "Unauthenticated"
+	case CodeDeadlineExceeded:
+		return "Deadline exceeded"
 	}
 	return fmt.Sprintf("Unknown: %d", c)
 }
@@ -74,12 +83,55 @@ func (c Code) grpcCode() codes.Code {
 		return codes.Unimplemented
 	case CodeCancelled:
 		return codes.Canceled
+	case CodeResourceExhausted:
+		return codes.ResourceExhausted
 	case CodeFailedPrecondition:
 		return codes.FailedPrecondition
+	case CodeUnauthenticated:
+		return codes.Unauthenticated
+	case CodeDeadlineExceeded:
+		return codes.DeadlineExceeded
 	}
 	return codes.Unknown
 }
 
+// HTTPStatus returns the HTTP status code for HTTP clients, following the
+// mapping of google.rpc.Code.
+func (c Code) HTTPStatus() int {
+	switch c {
+	case CodeUnknown:
+		return http.StatusInternalServerError
+	case CodeInvalidArgument:
+		return http.StatusBadRequest
+	case CodeNotFound:
+		return http.StatusNotFound
+	case CodeForbidden:
+		return http.StatusForbidden
+	case CodeAlreadyExists:
+		return http.StatusConflict
+	case CodeAborted:
+		return http.StatusConflict
+	case CodeInternal:
+		return http.StatusInternalServerError
+	case CodeUnavailable:
+		return http.StatusServiceUnavailable
+	case CodeUnimplemented:
+		return http.StatusNotImplemented
+	case CodeCancelled:
+		// 499 Client Closed Request
+		return 499
+	case CodeResourceExhausted:
+		return http.StatusTooManyRequests
+	case CodeFailedPrecondition:
+		return http.StatusBadRequest
+	case CodeUnauthenticated:
+		return http.StatusUnauthorized
+	case CodeDeadlineExceeded:
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
 func NewCode(err error) Code {
 	if err == nil {
 		return CodeUnknown
@@ -2,6 +2,7 @@ package errcode
 
 import (
 	"errors"
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -24,7 +25,10 @@ func TestCode_String(t *testing.T) {
 		{name: "unavailable", c: CodeUnavailable, want: "Unavailable"},
 		{name: "unimplemented", c: CodeUnimplemented, want: "Unimplemented"},
 		{name: "cancelled", c: CodeCancelled, want: "Cancelled"},
+		{name: "resource exhausted", c: CodeResourceExhausted, want: "Resource exhausted"},
 		{name: "failed precondition", c: CodeFailedPrecondition, want: "Failed precondition"},
+		{name: "unauthenticated", c: CodeUnauthenticated, want: "Unauthenticated"},
+		{name: "deadline exceeded", c: CodeDeadlineExceeded, want: "Deadline exceeded"},
 		{name: "error", c: -1, want: "Unknown: -1"},
 	}
 	for _, tt := range tests {
@@ -52,7 +56,10 @@ func TestCode_grpcCode(t *testing.T) {
 		{name: "unavailable", c: CodeUnavailable, want: codes.Unavailable},
 		{name: "unimplemented", c: CodeUnimplemented, want: codes.Unimplemented},
 		{name: "cancelled", c: CodeCancelled, want: codes.Canceled},
+		{name: "resource exhausted", c: CodeResourceExhausted, want: codes.ResourceExhausted},
 		{name: "failed precondition", c: CodeFailedPrecondition, want: codes.FailedPrecondition},
+		{name: "unauthenticated", c: CodeUnauthenticated, want: codes.Unauthenticated},
+		{name: "deadline exceeded", c: CodeDeadlineExceeded, want: codes.DeadlineExceeded},
 		{name: "unknown", c: -1, want: codes.Unknown},
 	}
 	for _, tt := range tests {
@@ -64,6 +71,35 @@ func TestCode_grpcCode(t *testing.T) {
 	}
 }
 
+func TestCode_HTTPStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		c    Code
+		want int
+	}{
+		{name: "unknown", c: CodeUnknown, want: http.StatusInternalServerError},
+		{name: "invalid argument", c: CodeInvalidArgument, want: http.StatusBadRequest},
+		{name: "not found", c: CodeNotFound, want: http.StatusNotFound},
+		{name: "forbidden", c: CodeForbidden, want: http.StatusForbidden},
+		{name: "already exists", c: CodeAlreadyExists, want: http.StatusConflict},
+		{name: "aborted", c: CodeAborted, want: http.StatusConflict},
+		{name: "internal", c: CodeInternal, want: http.StatusInternalServerError},
+		{name: "unavailable", c: CodeUnavailable, want: http.StatusServiceUnavailable},
+		{name: "unimplemented", c: CodeUnimplemented, want: http.StatusNotImplemented},
+		{name: "cancelled", c: CodeCancelled, want: 499},
+		{name: "resource exhausted", c: CodeResourceExhausted, want: http.StatusTooManyRequests},
+		{name: "failed precondition", c: CodeFailedPrecondition, want: http.StatusBadRequest},
+		{name: "unauthenticated", c: CodeUnauthenticated, want: http.StatusUnauthorized},
+		{name: "deadline exceeded", c: CodeDeadlineExceeded, want: http.StatusGatewayTimeout},
+		{name: "unknown value", c: -1, want: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.c.HTTPStatus())
+		})
+	}
+}
+
 func TestNewCode(t *testing.T) {
 	tests := []struct {
 		name string
@@ -4,19 +4,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"runtime"
 	"runtime/debug"
 
 	"github.com/go-playground/validator/v10"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 type Error struct {
-	Code    Code
-	origin  error
-	stack   string
-	callers []uintptr
+	Code      Code
+	origin    error
+	stack     string
+	callers   []uintptr
+	requestID string
 }
 
 func (e *Error) Error() string {
@@ -26,6 +29,45 @@ func (e *Error) Error() string {
 	return fmt.Sprintf("%s: %s\nStackTrace:\n%s", e.Code.String(), e.origin.Error(), e.stack)
 }
 
+// LogValue implements slog.LogValuer so that structured logs keep the stack
+// trace in its own field rather than in the message.
+func (e *Error) LogValue() slog.Value {
+	attrs := []slog.Attr{slog.String("code", e.Code.String())}
+	if e.origin != nil {
+		attrs = append(attrs, slog.String("message", e.origin.Error()))
+	}
+	if e.requestID != "" {
+		attrs = append(attrs, slog.String("request_id", e.requestID))
+	}
+	if e.stack != "" {
+		attrs = append(attrs, slog.String("stack", e.stack))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// GRPCStatus returns the status sent to gRPC clients. Its message leaves out
+// the stack trace, which stays in the logs through LogValue. The request ID,
+// if any, is attached as a RequestInfo detail.
+func (e *Error) GRPCStatus() *status.Status {
+	msg := e.Code.String()
+	if e.origin != nil {
+		msg = fmt.Sprintf("%s: %s", msg, e.origin.Error())
+	}
+	st := status.New(e.Code.grpcCode(), msg)
+	if e.requestID == "" {
+		return st
+	}
+	withDetails, err := st.WithDetails(&errdetails.RequestInfo{RequestId: e.requestID})
+	if err != nil {
+		return st
+	}
+	return withDetails
+}
+
+func (e *Error) RequestID() string {
+	return e.requestID
+}
+
 func (e *Error) Stack() string {
 	return e.stack
 }
@@ -51,47 +93,71 @@ func New(err error) error {
 	const maxStackDepth, skipCallers = 30, 2
 	stack := make([]uintptr, maxStackDepth)
 	runtime.Callers(skipCallers, stack)
-	newErr := &Error{
-		Code:    CodeInternal,
+	return &Error{
+		Code:    classify(err),
 		origin:  err,
 		stack:   string(debug.Stack()),
 		callers: stack,
 	}
+}
+
+// CodeOf returns the code of the Error in err, or else the code New would
+// give err, without capturing a stack. err must not be nil.
+func CodeOf(err error) Code {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return classify(err)
+}
 
+// classify returns the code of an error that is not an Error.
+func classify(err error) Code {
 	// check context cancelled
 	if errors.Is(err, context.Canceled) {
-		newErr.Code = CodeCancelled
-		return newErr
+		return CodeCancelled
+	}
+
+	// check context deadline
+	if errors.Is(err, context.DeadlineExceeded) {
+		return CodeDeadlineExceeded
 	}
 
 	// check validation error
 	var vErr validator.ValidationErrors
 	if errors.As(err, &vErr) {
-		newErr.Code = CodeInvalidArgument
-		return newErr
+		return CodeInvalidArgument
 	}
 
 	// check grpc error
 	// nolint:exhaustive
 	switch status.Code(err) {
 	case codes.InvalidArgument:
-		newErr.Code = CodeInvalidArgument
+		return CodeInvalidArgument
 	case codes.NotFound:
-		newErr.Code = CodeNotFound
-	case codes.Internal:
-		newErr.Code = CodeInternal
+		return CodeNotFound
 	case codes.AlreadyExists:
-		newErr.Code = CodeAlreadyExists
+		return CodeAlreadyExists
 	case codes.Unavailable:
-		newErr.Code = CodeUnavailable
+		return CodeUnavailable
 	case codes.Aborted:
-		newErr.Code = CodeAborted
+		return CodeAborted
 	case codes.ResourceExhausted:
-		newErr.Code = CodeResourceExhausted
+		return CodeResourceExhausted
 	case codes.Canceled:
-		newErr.Code = CodeCancelled
+		return CodeCancelled
+	case codes.Unauthenticated:
+		return CodeUnauthenticated
+	case codes.PermissionDenied:
+		return CodeForbidden
+	case codes.FailedPrecondition:
+		return CodeFailedPrecondition
+	case codes.Unimplemented:
+		return CodeUnimplemented
+	case codes.DeadlineExceeded:
+		return CodeDeadlineExceeded
 	}
-	return newErr
+	return CodeInternal
 }
 
 func newWithCode(code Code, format string, a ...interface{}) error {
@@ -110,9 +176,27 @@ func NewGrpcError(err error) error {
 
 	var e *Error
 	if !errors.As(err, &e) {
+		// already converted, e.g. by an interceptor
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
 		return status.Error(codes.Unknown, err.Error())
 	}
-	return status.Error(e.Code.grpcCode(), e.Error())
+	return e.GRPCStatus().Err()
+}
+
+// WithRequestID returns a copy of the Error in err with the request ID, so
+// that clients and logs can correlate the failure. The Error in err is left
+// as it is since it may be shared, e.g. by coalesced cache reads. err is
+// returned unchanged if it has no Error or the Error has a request ID already.
+func WithRequestID(err error, id string) error {
+	var e *Error
+	if !errors.As(err, &e) || e.requestID != "" {
+		return err
+	}
+	withID := *e
+	withID.requestID = id
+	return &withID
 }
 
 func NewNotFound(format string, a ...interface{}) error {
@@ -123,6 +207,18 @@ func NewInvalidArgument(format string, a ...interface{}) error {
 	return newWithCode(CodeInvalidArgument, format, a...)
 }
 
+func NewAlreadyExists(format string, a ...interface{}) error {
+	return newWithCode(CodeAlreadyExists, format, a...)
+}
+
+func NewDeadlineExceeded(format string, a ...interface{}) error {
+	return newWithCode(CodeDeadlineExceeded, format, a...)
+}
+
+func NewForbidden(format string, a ...interface{}) error {
+	return newWithCode(CodeForbidden, format, a...)
+}
+
 func NewAborted(format string, a ...interface{}) error {
 	return newWithCode(CodeAborted, format, a...)
 }
@@ -147,6 +243,14 @@ func NewFailedPrecondition(format string, a ...interface{}) error {
 	return newWithCode(CodeFailedPrecondition, format, a...)
 }
 
+func NewUnavailable(format string, a ...interface{}) error {
+	return newWithCode(CodeUnavailable, format, a...)
+}
+
+func NewUnauthenticated(format string, a ...interface{}) error {
+	return newWithCode(CodeUnauthenticated, format, a...)
+}
+
 func isCode(err error, code Code) bool {
 	if err == nil {
 		return false
@@ -186,6 +290,30 @@ func IsCancelled(err error) bool {
 	return isCode(err, CodeCancelled)
 }
 
+func IsResourceExhausted(err error) bool {
+	return isCode(err, CodeResourceExhausted)
+}
+
+func IsDeadlineExceeded(err error) bool {
+	return isCode(err, CodeDeadlineExceeded)
+}
+
+func IsForbidden(err error) bool {
+	return isCode(err, CodeForbidden)
+}
+
+func IsUnavailable(err error) bool {
+	return isCode(err, CodeUnavailable)
+}
+
+func IsUnauthenticated(err error) bool {
+	return isCode(err, CodeUnauthenticated)
+}
+
+func IsFailedPrecondition(err error) bool {
+	return isCode(err, CodeFailedPrecondition)
+}
+
 func IsServerError(err error) bool {
 	return IsInternal(err) || IsUnknown(err) || IsAborted(err)
 }
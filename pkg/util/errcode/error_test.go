@@ -1,12 +1,18 @@
 package errcode
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
 	"runtime"
 	"testing"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -33,6 +39,24 @@ func TestError_Error(t *testing.T) {
 	require.Equal(t, "error", err.Unwrap().Error())
 }
 
+func TestError_LogValue(t *testing.T) {
+	err := &Error{
+		Code:   CodeNotFound,
+		origin: errors.New("error"),
+		stack:  "stack",
+	}
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("failed", "error", err)
+
+	var got struct {
+		Error map[string]string `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	require.Equal(t, map[string]string{"code": "Not found", "message": "error", "stack": "stack"}, got.Error)
+
+	require.Equal(t, slog.GroupValue(slog.String("code", "Unknown")), (&Error{}).LogValue())
+}
+
 func TestError_Callers(t *testing.T) {
 	stack := make([]uintptr, 10)
 	runtime.Callers(0, stack)
@@ -123,6 +147,70 @@ func TestNew(t *testing.T) {
 				origin: status.Error(codes.Canceled, "error"),
 			},
 		},
+		{
+			name: "grpc resource exhausted",
+			arg:  status.Error(codes.ResourceExhausted, "error"),
+			want: &Error{
+				Code:   CodeResourceExhausted,
+				origin: status.Error(codes.ResourceExhausted, "error"),
+			},
+		},
+		{
+			name: "context canceled",
+			arg:  context.Canceled,
+			want: &Error{
+				Code:   CodeCancelled,
+				origin: context.Canceled,
+			},
+		},
+		{
+			name: "context deadline exceeded",
+			arg:  fmt.Errorf("query: %w", context.DeadlineExceeded),
+			want: &Error{
+				Code:   CodeDeadlineExceeded,
+				origin: fmt.Errorf("query: %w", context.DeadlineExceeded),
+			},
+		},
+		{
+			name: "grpc deadline exceeded",
+			arg:  status.Error(codes.DeadlineExceeded, "error"),
+			want: &Error{
+				Code:   CodeDeadlineExceeded,
+				origin: status.Error(codes.DeadlineExceeded, "error"),
+			},
+		},
+		{
+			name: "grpc permission denied",
+			arg:  status.Error(codes.PermissionDenied, "error"),
+			want: &Error{
+				Code:   CodeForbidden,
+				origin: status.Error(codes.PermissionDenied, "error"),
+			},
+		},
+		{
+			name: "grpc failed precondition",
+			arg:  status.Error(codes.FailedPrecondition, "error"),
+			want: &Error{
+				Code:   CodeFailedPrecondition,
+				origin: status.Error(codes.FailedPrecondition, "error"),
+			},
+		},
+		{
+			name: "grpc unimplemented",
+			arg:  status.Error(codes.Unimplemented, "error"),
+			want: &Error{
+				Code:   CodeUnimplemented,
+				origin: status.Error(codes.Unimplemented, "error"),
+			},
+		},
+		{
+			name: "grpc unauthenticated",
+			arg:  status.Error(codes.Unauthenticated, "error"),
+			want: &Error{
+				Code:   CodeUnauthenticated,
+				origin: status.Error(codes.Unauthenticated, "error"),
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -146,10 +234,16 @@ func TestGrpcNew(t *testing.T) {
 	}{
 		{name: "nil", arg: nil, want: nil},
 		{name: "Unknown error", arg: errors.New("error"), want: status.Error(codes.Unknown, "error")},
+		{name: "grpc error", arg: status.Error(codes.NotFound, "error"), want: status.Error(codes.NotFound, "error")},
 		{
 			name: "Known error",
 			arg:  &Error{Code: CodeInvalidArgument},
-			want: status.Error(codes.InvalidArgument, "Invalid argument: StackTrace:\n"),
+			want: status.Error(codes.InvalidArgument, "Invalid argument"),
+		},
+		{
+			name: "stack trace",
+			arg:  &Error{Code: CodeNotFound, origin: errors.New("error"), stack: "goroutine 1 [running]:"},
+			want: status.Error(codes.NotFound, "Not found: error"),
 		},
 	}
 	for _, tt := range tests {
@@ -160,6 +254,28 @@ func TestGrpcNew(t *testing.T) {
 	}
 }
 
+func TestWithRequestID(t *testing.T) {
+	shared := NewNotFound("foo")
+	err := WithRequestID(fmt.Errorf("wrapped: %w", shared), "req-1")
+	var e *Error
+	require.True(t, errors.As(err, &e))
+	require.Equal(t, "req-1", e.RequestID())
+	require.Equal(t, CodeNotFound, e.Code)
+	require.Empty(t, shared.(*Error).RequestID())
+
+	// the first request ID wins
+	require.Equal(t, "req-1", WithRequestID(err, "req-2").(*Error).RequestID())
+	require.Equal(t, "req-2", WithRequestID(shared, "req-2").(*Error).RequestID())
+
+	st := status.Convert(NewGrpcError(err))
+	require.Equal(t, codes.NotFound, st.Code())
+	require.Equal(t, "Not found: foo", st.Message())
+	require.Len(t, st.Details(), 1)
+	require.Equal(t, "req-1", st.Details()[0].(*errdetails.RequestInfo).GetRequestId())
+
+	require.Equal(t, "foo", WithRequestID(errors.New("foo"), "req-1").Error())
+}
+
 func TestNewError(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -168,9 +284,14 @@ func TestNewError(t *testing.T) {
 	}{
 		{name: "not found", newFunc: NewNotFound, want: CodeNotFound},
 		{name: "aborted", newFunc: NewAborted, want: CodeAborted},
+		{name: "forbidden", newFunc: NewForbidden, want: CodeForbidden},
+		{name: "already exists", newFunc: NewAlreadyExists, want: CodeAlreadyExists},
+		{name: "deadline exceeded", newFunc: NewDeadlineExceeded, want: CodeDeadlineExceeded},
+		{name: "unavailable", newFunc: NewUnavailable, want: CodeUnavailable},
 		{name: "invalid argument", newFunc: NewInvalidArgument, want: CodeInvalidArgument},
 		{name: "unimplemented", newFunc: NewUnimplemented, want: CodeUnimplemented},
 		{name: "failed precondition", newFunc: NewFailedPrecondition, want: CodeFailedPrecondition},
+		{name: "unauthenticated", newFunc: NewUnauthenticated, want: CodeUnauthenticated},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -197,6 +318,12 @@ func TestIsError(t *testing.T) {
 		{name: "server error: unknown", arg: NewUnknown("foo"), isFunc: IsServerError, want: true},
 		{name: "aborted", arg: NewAborted("foo"), isFunc: IsAborted, want: true},
 		{name: "cancelled", arg: &Error{Code: CodeCancelled}, isFunc: IsCancelled, want: true},
+		{name: "unauthenticated", arg: NewUnauthenticated("foo"), isFunc: IsUnauthenticated, want: true},
+		{name: "forbidden", arg: NewForbidden("foo"), isFunc: IsForbidden, want: true},
+		{name: "resource exhausted", arg: NewResourceExhausted("foo"), isFunc: IsResourceExhausted, want: true},
+		{name: "deadline exceeded", arg: NewDeadlineExceeded("foo"), isFunc: IsDeadlineExceeded, want: true},
+		{name: "unavailable", arg: NewUnavailable("foo"), isFunc: IsUnavailable, want: true},
+		{name: "failed precondition", arg: NewFailedPrecondition("foo"), isFunc: IsFailedPrecondition, want: true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -205,3 +332,25 @@ func TestIsError(t *testing.T) {
 		})
 	}
 }
+
+func TestCodeOf(t *testing.T) {
+	tests := []struct {
+		name string
+		arg  error
+		want Code
+	}{
+		{name: "errcode", arg: NewNotFound("not found"), want: CodeNotFound},
+		{name: "wrapped", arg: fmt.Errorf("wrapped: %w", NewForbidden("forbidden")), want: CodeForbidden},
+		{name: "canceled", arg: context.Canceled, want: CodeCancelled},
+		{name: "grpc", arg: status.Error(codes.AlreadyExists, "exists"), want: CodeAlreadyExists},
+		{name: "other", arg: errors.New("error"), want: CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, CodeOf(tt.arg))
+			if e, ok := New(tt.arg).(*Error); ok {
+				require.Equal(t, e.Code, CodeOf(tt.arg))
+			}
+		})
+	}
+}
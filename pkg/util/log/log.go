@@ -0,0 +1,116 @@
+// Package log provides structured logging on top of log/slog. Fields attached
+// to a context with With are added to every record logged with that context.
+package log
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+)
+
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// New returns a logger writing records in format ("json" or "text") at or
+// above level ("debug", "info", "warn" or "error").
+func New(w io.Writer, format, level string) (*slog.Logger, error) {
+	var lv slog.Level
+	if level != "" {
+		if err := lv.UnmarshalText([]byte(level)); err != nil {
+			return nil, errcode.NewInvalidArgument("invalid log level %q", level)
+		}
+	}
+	opts := &slog.HandlerOptions{Level: lv}
+
+	var h slog.Handler
+	switch strings.ToLower(format) {
+	case "", FormatJSON:
+		h = slog.NewJSONHandler(w, opts)
+	case FormatText:
+		h = slog.NewTextHandler(w, opts)
+	default:
+		return nil, errcode.NewInvalidArgument("invalid log format %q", format)
+	}
+	return slog.New(&contextHandler{Handler: h}), nil
+}
+
+// SetDefault makes l the logger used by this package and by log/slog.
+func SetDefault(l *slog.Logger) {
+	slog.SetDefault(l)
+}
+
+type fieldsKey struct{}
+
+// With returns a context whose records carry the given key-value pairs in
+// addition to the ones already attached to ctx.
+func With(ctx context.Context, args ...any) context.Context {
+	if len(args) == 0 {
+		return ctx
+	}
+	r := slog.Record{}
+	r.Add(args...)
+	attrs := append([]slog.Attr{}, fields(ctx)...)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return context.WithValue(ctx, fieldsKey{}, attrs)
+}
+
+func fields(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(fieldsKey{}).([]slog.Attr)
+	return attrs
+}
+
+// Err returns the attribute for err. An errcode.Error wrapped in err is logged
+// with its code and stack trace as separate fields.
+func Err(err error) slog.Attr {
+	var e *errcode.Error
+	if errors.As(err, &e) {
+		return slog.Any("error", e)
+	}
+	return slog.Any("error", err)
+}
+
+func Debug(ctx context.Context, msg string, args ...any) {
+	slog.DebugContext(ctx, msg, args...)
+}
+
+func Info(ctx context.Context, msg string, args ...any) {
+	slog.InfoContext(ctx, msg, args...)
+}
+
+func Warn(ctx context.Context, msg string, args ...any) {
+	slog.WarnContext(ctx, msg, args...)
+}
+
+func Error(ctx context.Context, msg string, args ...any) {
+	slog.ErrorContext(ctx, msg, args...)
+}
+
+// contextHandler adds the fields attached to the context to each record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs := fields(ctx); len(attrs) > 0 {
+		r = r.Clone()
+		r.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
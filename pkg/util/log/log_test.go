@@ -0,0 +1,70 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		level   string
+		wantErr bool
+	}{
+		{name: "default", format: "", level: ""},
+		{name: "json", format: "json", level: "debug"},
+		{name: "text", format: "TEXT", level: "WARN"},
+		{name: "invalid format", format: "xml", wantErr: true},
+		{name: "invalid level", level: "verbose", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(&bytes.Buffer{}, tt.format, tt.level)
+			require.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func TestWith(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(&buf, FormatJSON, "info")
+	require.NoError(t, err)
+
+	ctx := With(context.Background(), "method", "GetUser")
+	ctx = With(ctx, "user_id", "id")
+	l.InfoContext(ctx, "message", "key", "value")
+	l.DebugContext(ctx, "filtered")
+
+	var got map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	require.Equal(t, "message", got["msg"])
+	require.Equal(t, "GetUser", got["method"])
+	require.Equal(t, "id", got["user_id"])
+	require.Equal(t, "value", got["key"])
+}
+
+func TestErr(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(&buf, FormatJSON, "info")
+	require.NoError(t, err)
+
+	wrapped := fmt.Errorf("wrapped: %w", errcode.NewNotFound("user not found"))
+	l.Info("failed", Err(wrapped))
+	var got struct {
+		Error map[string]string `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	require.Equal(t, "Not found", got.Error["code"])
+	require.Equal(t, "user not found", got.Error["message"])
+	require.NotEmpty(t, got.Error["stack"])
+
+	require.Equal(t, "error", Err(errors.New("error")).Value.String())
+}
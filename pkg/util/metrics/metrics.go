@@ -0,0 +1,93 @@
+// Package metrics holds the Prometheus metrics of the service and exposes them
+// over HTTP.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "e_architecture"
+
+// CodeOK is the code label of successful calls.
+const CodeOK = "OK"
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	RPCDuration        *prometheus.HistogramVec
+	UsecaseCalls       *prometheus.CounterVec
+	RepositoryDuration *prometheus.HistogramVec
+	TxRetries          *prometheus.CounterVec
+	Panics             *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		RPCDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "rpc_duration_seconds",
+			Help:      "Duration of RPCs by method and error code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+		UsecaseCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "usecase_calls_total",
+			Help:      "Number of usecase calls by usecase and error code.",
+		}, []string{"usecase", "code"}),
+		RepositoryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "repository_duration_seconds",
+			Help:      "Duration of repository calls by repository, operation and error code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"repository", "operation", "code"}),
+		TxRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "db_tx_retries_total",
+			Help:      "Number of database transactions retried after a serialization failure or deadlock.",
+		}, []string{"repository", "operation"}),
+		Panics: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "panics_total",
+			Help:      "Number of panics recovered in RPC handlers by method.",
+		}, []string{"method"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.RPCDuration,
+		m.UsecaseCalls,
+		m.RepositoryDuration,
+		m.TxRetries,
+		m.Panics,
+	)
+	return m
+}
+
+// RegisterDB exports the connection pool statistics of db, labeled with name.
+func (m *Metrics) RegisterDB(db *sql.DB, name string) error {
+	if err := m.registry.Register(collectors.NewDBStatsCollector(db, name)); err != nil {
+		return errcode.New(err)
+	}
+	return nil
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Code returns the code label of err. gRPC status errors are classified by
+// their status code.
+func Code(err error) string {
+	if err == nil {
+		return CodeOK
+	}
+	return errcode.CodeOf(err).String()
+}
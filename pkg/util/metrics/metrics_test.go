@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "nil", err: nil, want: "OK"},
+		{name: "errcode", err: errcode.NewNotFound("foo"), want: "Not found"},
+		{name: "grpc", err: status.Error(codes.Unauthenticated, "foo"), want: "Unauthenticated"},
+		{name: "other", err: errors.New("foo"), want: "Internal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, Code(tt.err))
+		})
+	}
+}
+
+func TestMetrics_Handler(t *testing.T) {
+	m := New()
+	m.RPCDuration.WithLabelValues("/e_architecture.api.EArchitecture/GetUser", CodeOK).Observe(0.1)
+	m.TxRetries.WithLabelValues("user", "Update").Inc()
+	// sql.Open does not connect, which is enough to read the pool statistics
+	db, err := sql.Open("postgres", "")
+	require.NoError(t, err)
+	require.NoError(t, m.RegisterDB(db, "primary"))
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), `e_architecture_rpc_duration_seconds_count{code="OK",method="/e_architecture.api.EArchitecture/GetUser"} 1`)
+	require.Contains(t, string(body), `e_architecture_db_tx_retries_total{operation="Update",repository="user"} 1`)
+	require.Contains(t, string(body), `go_sql_open_connections{db_name="primary"} 0`)
+}
@@ -0,0 +1,32 @@
+// Package pagetoken encodes the position of a page into an opaque token that
+// clients pass back to fetch the next page.
+package pagetoken
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+)
+
+// Encode returns the token of the position v, which must be JSON encodable.
+func Encode(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", errcode.New(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Decode decodes token into v. Malformed tokens are invalid arguments since
+// they come from clients.
+func Decode(token string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return errcode.NewInvalidArgument("invalid page token: %w", err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return errcode.NewInvalidArgument("invalid page token: %w", err)
+	}
+	return nil
+}
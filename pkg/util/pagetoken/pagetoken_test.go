@@ -0,0 +1,25 @@
+package pagetoken
+
+import (
+	"testing"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	type position struct {
+		Offset int    `json:"o"`
+		Query  string `json:"q"`
+	}
+	token, err := Encode(&position{Offset: 10, Query: "やまだ"})
+	require.NoError(t, err)
+
+	got := &position{}
+	require.NoError(t, Decode(token, got))
+	require.Equal(t, &position{Offset: 10, Query: "やまだ"}, got)
+
+	for _, token := range []string{"!", "bm90IGpzb24"} {
+		require.True(t, errcode.IsInvalidArgument(Decode(token, got)))
+	}
+}
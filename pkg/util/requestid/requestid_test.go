@@ -0,0 +1,41 @@
+package requestid
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want bool
+	}{
+		{name: "uuid", id: "7b0e8a0c-3c8e-4b5e-9a39-0d4b8d1f5c6e", want: true},
+		{name: "generated", id: New(), want: true},
+		{name: "empty", id: "", want: false},
+		{name: "too long", id: strings.Repeat("a", 129), want: false},
+		{name: "space", id: "req 1", want: false},
+		{name: "newline", id: "req\n1", want: false},
+		{name: "non ascii", id: "リクエスト", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, Valid(tt.id))
+		})
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	require.False(t, ok)
+
+	got, ok := FromContext(NewContext(context.Background(), "req-1"))
+	require.True(t, ok)
+	require.Equal(t, "req-1", got)
+
+	require.NotEqual(t, New(), New())
+}
@@ -0,0 +1,90 @@
+// Package textsearch normalizes and matches names the same way across the
+// repository backends.
+package textsearch
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+type Mode int
+
+const (
+	// ModeSubstring matches names containing the query.
+	ModeSubstring Mode = iota
+	// ModePrefix matches names starting with the query.
+	ModePrefix
+	// ModeFuzzy matches names whose trigram similarity to the query is at
+	// least SimilarityThreshold.
+	ModeFuzzy
+)
+
+// SimilarityThreshold is the default similarity threshold of pg_trgm, so that
+// every backend finds the same names.
+const SimilarityThreshold = 0.3
+
+// Normalize folds the variants of a name that users expect to match: width
+// (NFKC), case and katakana to hiragana.
+func Normalize(s string) string {
+	s = strings.ToLower(norm.NFKC.String(strings.TrimSpace(s)))
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'ァ' && r <= 'ヶ', r == 'ヽ', r == 'ヾ':
+			return r - ('ァ' - 'ぁ')
+		}
+		return r
+	}, s)
+}
+
+// Match reports whether the normalized name matches the normalized query and
+// how relevant it is, between 0 and 1.
+func Match(mode Mode, query, name string) (float64, bool) {
+	score := Similarity(query, name)
+	switch mode {
+	case ModePrefix:
+		return score, strings.HasPrefix(name, query)
+	case ModeFuzzy:
+		return score, score >= SimilarityThreshold
+	default:
+		return score, strings.Contains(name, query)
+	}
+}
+
+// Similarity returns the ratio of the trigrams shared by a and b like the
+// similarity function of pg_trgm.
+func Similarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	common := 0
+	for t := range ta {
+		if _, ok := tb[t]; ok {
+			common++
+		}
+	}
+	return float64(common) / float64(len(ta)+len(tb)-common)
+}
+
+// trigrams returns the trigrams of each word of s, padded with two spaces in
+// front and one behind.
+func trigrams(s string) map[string]struct{} {
+	set := map[string]struct{}{}
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		runes := []rune("  " + w + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			set[string(runes[i:i+3])] = struct{}{}
+		}
+	}
+	return set
+}
+
+// EscapeLike escapes the wildcards of a LIKE pattern with a backslash.
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,60 @@
+package textsearch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{name: "full-width ascii", s: "ＡＢＣ１２３", want: "abc123"},
+		{name: "half-width katakana", s: "ﾔﾏﾀﾞ", want: "やまだ"},
+		{name: "katakana", s: "ヤマダ タロウ", want: "やまだ たろう"},
+		{name: "kanji untouched", s: " 山田 ", want: "山田"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, Normalize(tt.s))
+		})
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	// values agree with pg_trgm
+	require.Equal(t, 1.0, Similarity("word", "word"))
+	require.InDelta(t, 0.363636, Similarity("word", "two words"), 1e-6)
+	require.Equal(t, 0.0, Similarity("word", "abc"))
+	require.Equal(t, 0.0, Similarity("", "abc"))
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		mode  Mode
+		query string
+		s     string
+		want  bool
+	}{
+		{name: "substring", mode: ModeSubstring, query: "まだ", s: "やまだ", want: true},
+		{name: "substring miss", mode: ModeSubstring, query: "たろう", s: "やまだ", want: false},
+		{name: "prefix", mode: ModePrefix, query: "やま", s: "やまだ", want: true},
+		{name: "prefix miss", mode: ModePrefix, query: "まだ", s: "やまだ", want: false},
+		{name: "fuzzy", mode: ModeFuzzy, query: "alise", s: "alice", want: true},
+		{name: "fuzzy miss", mode: ModeFuzzy, query: "bob", s: "alice", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ok := Match(tt.mode, tt.query, tt.s)
+			require.Equal(t, tt.want, ok)
+		})
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	require.Equal(t, `100\%\_a\\b`, EscapeLike(`100%_a\b`))
+}
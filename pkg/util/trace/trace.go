@@ -0,0 +1,121 @@
+// Package trace sets up OpenTelemetry tracing and provides helpers to record
+// spans with errcode errors.
+package trace
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const (
+	instrumentationName = "github.com/SakataAtsuki/e-architecture"
+
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+)
+
+type Config struct {
+	ServiceName string
+	// Exporter is one of "none", "otlp", "stdout" or "file". The OTLP exporter
+	// is configured with the standard OTEL_EXPORTER_OTLP_* environment variables.
+	Exporter string
+	// File is the path spans are written to with the "file" exporter.
+	File string
+	// SampleRatio is the ratio of traces sampled when the caller is not sampling.
+	SampleRatio float64
+}
+
+// Setup installs the global tracer provider and propagator. The returned
+// function flushes the pending spans and must be called on shutdown.
+func Setup(ctx context.Context, cfg *Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var (
+		exporter sdktrace.SpanExporter
+		closer   io.Closer
+		err      error
+	)
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		exporter, err = otlptracegrpc.New(ctx)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterFile:
+		var f *os.File
+		f, err = os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, errcode.New(err)
+		}
+		closer = f
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(f))
+	default:
+		return nil, errcode.NewInvalidArgument("unknown trace exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, errcode.New(err)
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+
+	return func(ctx context.Context) error {
+		if err := tp.Shutdown(ctx); err != nil {
+			return errcode.New(err)
+		}
+		if closer != nil {
+			return closer.Close()
+		}
+		return nil
+	}, nil
+}
+
+// Start starts a span from the global tracer provider.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, oteltrace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, oteltrace.WithAttributes(attrs...))
+}
+
+// StartServer starts a span of kind server, e.g. for an incoming RPC.
+func StartServer(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, oteltrace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, oteltrace.WithAttributes(attrs...), oteltrace.WithSpanKind(oteltrace.SpanKindServer))
+}
+
+// End records err on the span and ends it. The errcode code is set as an
+// attribute; only server errors mark the span as failed.
+func End(span oteltrace.Span, err error) {
+	if err != nil {
+		span.SetAttributes(attribute.String("errcode", errcode.NewCode(err).String()))
+		span.RecordError(err)
+		if errcode.IsServerError(err) || errcode.NewCode(err) == errcode.CodeUnknown {
+			span.SetStatus(codes.Error, errcode.NewCode(err).String())
+		}
+	}
+	span.End()
+}
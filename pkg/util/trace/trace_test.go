@@ -0,0 +1,71 @@
+package trace
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SakataAtsuki/e-architecture/pkg/util/errcode"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestEnd(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   string
+		wantStatus codes.Code
+	}{
+		{name: "ok", err: nil, wantStatus: codes.Unset},
+		{name: "client error", err: errcode.NewNotFound("foo"), wantCode: "Not found", wantStatus: codes.Unset},
+		{name: "server error", err: errcode.NewInternal("foo"), wantCode: "Internal", wantStatus: codes.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, span := Start(context.Background(), tt.name)
+			End(span, tt.err)
+
+			spans := recorder.Ended()
+			got := spans[len(spans)-1]
+			require.Equal(t, tt.name, got.Name())
+			require.Equal(t, tt.wantStatus, got.Status().Code)
+			var code string
+			for _, attr := range got.Attributes() {
+				if attr.Key == attribute.Key("errcode") {
+					code = attr.Value.AsString()
+				}
+			}
+			require.Equal(t, tt.wantCode, code)
+		})
+	}
+}
+
+func TestSetup(t *testing.T) {
+	prev := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	_, err := Setup(context.Background(), &Config{Exporter: "zipkin"})
+	require.True(t, errcode.IsInvalidArgument(err))
+
+	path := filepath.Join(t.TempDir(), "spans.json")
+	shutdown, err := Setup(context.Background(), &Config{ServiceName: "test", Exporter: ExporterFile, File: path, SampleRatio: 1})
+	require.NoError(t, err)
+	_, span := Start(context.Background(), "span")
+	End(span, nil)
+	require.NoError(t, shutdown(context.Background()))
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(b), `"Name":"span"`)
+}
@@ -0,0 +1,9 @@
+BEGIN;
+
+ALTER TABLE users DROP CONSTRAINT users_tenant_id_name_key;
+ALTER TABLE users DROP CONSTRAINT users_pkey;
+ALTER TABLE users DROP COLUMN tenant_id;
+ALTER TABLE users ADD PRIMARY KEY (id);
+ALTER TABLE users ADD CONSTRAINT users_name_key UNIQUE (name);
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+ALTER TABLE users ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE users ALTER COLUMN tenant_id DROP DEFAULT;
+
+ALTER TABLE users DROP CONSTRAINT users_name_key;
+ALTER TABLE users DROP CONSTRAINT users_pkey;
+ALTER TABLE users ADD PRIMARY KEY (tenant_id, id);
+ALTER TABLE users ADD CONSTRAINT users_tenant_id_name_key UNIQUE (tenant_id, name);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP INDEX IF EXISTS users_name_search_trgm_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS name_search;
+
+COMMIT;
@@ -0,0 +1,15 @@
+BEGIN;
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- name_search is the name normalized by textsearch.Normalize: NFKC, lower case
+-- and katakana folded to hiragana. The application maintains it from now on.
+ALTER TABLE users ADD COLUMN name_search TEXT NOT NULL DEFAULT '';
+UPDATE users SET name_search = translate(lower(normalize(name, NFKC)),
+    'ァアィイゥウェエォオカガキギクグケゲコゴサザシジスズセゼソゾタダチヂッツヅテデトドナニヌネノハバパヒビピフブプヘベペホボポマミムメモャヤュユョヨラリルレロヮワヰヱヲンヴヵヶヽヾ',
+    'ぁあぃいぅうぇえぉおかがきぎくぐけげこごさざしじすずせぜそぞただちぢっつづてでとどなにぬねのはばぱひびぴふぶぷへべぺほぼぽまみむめもゃやゅゆょよらりるれろゎわゐゑをんゔゕゖゝゞ');
+ALTER TABLE users ALTER COLUMN name_search DROP DEFAULT;
+
+CREATE INDEX users_name_search_trgm_idx ON users USING GIN (name_search gin_trgm_ops);
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+-- gender follows the values of api.Gender: 0 = OTHER, 1 = MALE, 2 = FEMALE.
+ALTER TABLE users ADD COLUMN gender SMALLINT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+-- The creation time of existing users is unknown; their last update is the
+-- closest known time.
+ALTER TABLE users ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+UPDATE users SET created_at = updated_at;
+
+-- for the sign-up counts of GetUserStats
+CREATE INDEX users_tenant_id_created_at_idx ON users (tenant_id, created_at);
+
+COMMIT;
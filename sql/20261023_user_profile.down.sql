@@ -0,0 +1,10 @@
+BEGIN;
+
+DROP INDEX IF EXISTS users_attributes_idx;
+DROP INDEX IF EXISTS users_tenant_id_email_key;
+ALTER TABLE users DROP COLUMN IF EXISTS attributes;
+ALTER TABLE users DROP COLUMN IF EXISTS timezone;
+ALTER TABLE users DROP COLUMN IF EXISTS locale;
+ALTER TABLE users DROP COLUMN IF EXISTS email;
+
+COMMIT;
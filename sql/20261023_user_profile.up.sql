@@ -0,0 +1,15 @@
+BEGIN;
+
+-- Users without an email, locale or timezone have an empty one.
+ALTER TABLE users ADD COLUMN email TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN locale TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN timezone TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN attributes JSONB NOT NULL DEFAULT '{}';
+
+-- Emails are unique in a tenant regardless of case.
+CREATE UNIQUE INDEX users_tenant_id_email_key ON users (tenant_id, lower(email)) WHERE email <> '';
+
+-- for the containment and key existence filters on attributes
+CREATE INDEX users_attributes_idx ON users USING GIN (attributes);
+
+COMMIT;
@@ -0,0 +1,16 @@
+BEGIN;
+
+-- A user has at most one password. locked_until is NULL unless the user has
+-- been locked out after too many failed attempts.
+CREATE TABLE credentials(
+    tenant_id VARCHAR(63) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    password_hash TEXT NOT NULL,
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMPTZ NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, user_id),
+    FOREIGN KEY (tenant_id, user_id) REFERENCES users (tenant_id, id) ON DELETE CASCADE
+);
+
+COMMIT;
@@ -0,0 +1,34 @@
+BEGIN;
+
+-- A session is a family of refresh tokens, each replacing the previous one.
+-- revoked_at is NULL while the session is not revoked.
+CREATE TABLE sessions(
+    tenant_id VARCHAR(63) NOT NULL,
+    id VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    refreshed_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ NULL,
+    reuse_detected BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (tenant_id, id),
+    FOREIGN KEY (tenant_id, user_id) REFERENCES users (tenant_id, id) ON DELETE CASCADE
+);
+CREATE INDEX sessions_tenant_id_user_id_idx ON sessions (tenant_id, user_id, created_at DESC);
+-- for the sweeper, across tenants
+CREATE INDEX sessions_expires_at_idx ON sessions (expires_at);
+
+-- Only SHA-256 hashes of the refresh tokens are stored. used_at is set when
+-- the token is replaced; presenting it again revokes its session.
+CREATE TABLE refresh_tokens(
+    tenant_id VARCHAR(63) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    session_id VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ NULL,
+    PRIMARY KEY (tenant_id, token_hash),
+    FOREIGN KEY (tenant_id, session_id) REFERENCES sessions (tenant_id, id) ON DELETE CASCADE
+);
+CREATE INDEX refresh_tokens_tenant_id_session_id_idx ON refresh_tokens (tenant_id, session_id);
+
+COMMIT;
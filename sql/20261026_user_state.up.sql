@@ -0,0 +1,21 @@
+BEGIN;
+
+-- state follows the values of api.UserState: 0 = PENDING, 1 = ACTIVE,
+-- 2 = SUSPENDED, 3 = DEACTIVATED. Existing users are active.
+ALTER TABLE users ADD COLUMN state SMALLINT NOT NULL DEFAULT 1;
+
+-- A user has at most one outstanding verification, for the email it was sent
+-- to. Tokens are stored as the hex SHA-256 of their value.
+CREATE TABLE email_verifications(
+    tenant_id VARCHAR(63) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    email TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (tenant_id, user_id),
+    UNIQUE (tenant_id, token_hash),
+    FOREIGN KEY (tenant_id, user_id) REFERENCES users (tenant_id, id) ON DELETE CASCADE
+);
+
+COMMIT;
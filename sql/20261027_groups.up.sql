@@ -0,0 +1,28 @@
+BEGIN;
+
+CREATE TABLE groups(
+    tenant_id VARCHAR(63) NOT NULL,
+    id VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, id)
+);
+
+-- role follows the values of api.GroupRole: 0 = MEMBER, 1 = ADMIN,
+-- 2 = OWNER. Members are deleted with their groups and their users.
+CREATE TABLE group_members(
+    tenant_id VARCHAR(63) NOT NULL,
+    group_id VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    role SMALLINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, group_id, user_id),
+    FOREIGN KEY (tenant_id, group_id) REFERENCES groups (tenant_id, id) ON DELETE CASCADE,
+    FOREIGN KEY (tenant_id, user_id) REFERENCES users (tenant_id, id) ON DELETE CASCADE
+);
+-- for ListUserGroups
+CREATE INDEX group_members_tenant_id_user_id_idx ON group_members (tenant_id, user_id, group_id);
+
+COMMIT;
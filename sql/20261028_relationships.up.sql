@@ -0,0 +1,19 @@
+BEGIN;
+
+-- kind follows the values of api.RelationshipKind: 0 = FOLLOW, 1 = BLOCK.
+-- A user has at most one relationship to another, deleted with either user.
+CREATE TABLE relationships(
+    tenant_id VARCHAR(63) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    target_id VARCHAR(255) NOT NULL,
+    kind SMALLINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, user_id, target_id),
+    CHECK (user_id <> target_id),
+    FOREIGN KEY (tenant_id, user_id) REFERENCES users (tenant_id, id) ON DELETE CASCADE,
+    FOREIGN KEY (tenant_id, target_id) REFERENCES users (tenant_id, id) ON DELETE CASCADE
+);
+-- for ListFollowers
+CREATE INDEX relationships_tenant_id_target_id_idx ON relationships (tenant_id, target_id, kind, user_id);
+
+COMMIT;